@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io/fs"
 	"log"
 	"net/http"
@@ -23,6 +24,15 @@ type ActionRequest struct {
 	Verify    bool   `json:"verify"` // optional flag to trigger audit
 }
 
+// SignRequest co-signs a pending action with a signature the seat made on
+// its own side (see `dis-core keys cosign`).
+type SignRequest struct {
+	Seat      string `json:"seat"`
+	KeyID     string `json:"key_id"`
+	SignedAt  string `json:"signed_at"`
+	Signature string `json:"signature"`
+}
+
 // --- mockResponseWriter ---
 // Used for calling internal routes like /api/verify/all programmatically
 type mockResponseWriter struct {
@@ -53,6 +63,15 @@ func main() {
 	ac := console.NewConsole("domain.terra", "DIS-CORE v1.0", seats)
 	console.LoadLastVerification()
 
+	// Seat co-signing policy for this domain
+	if tc, err := console.LoadThresholdConfig("versions/v0.7/thresholds.yaml"); err != nil {
+		log.Printf("⚠️  No threshold policy loaded, any single seat may act: %v", err)
+	} else if p, ok := tc.Domains[ac.BoundDomain]; ok {
+		if err := ac.SetThreshold(p); err != nil {
+			log.Fatalf("❌ Invalid threshold policy: %v", err)
+		}
+	}
+
 	// Load network configuration
 	netCfg, err := console.LoadNetworkConfig("versions/v0.7/network.yaml")
 	if err != nil {
@@ -180,9 +199,11 @@ func main() {
 		ac.SaveState()
 
 		resp := map[string]any{
-			"status":  "ok",
-			"action":  act.Type,
-			"receipt": act.Receipt,
+			"status":    "ok",
+			"action":    act.Type,
+			"action_id": act.ID,
+			"state":     act.Status,
+			"receipt":   act.Receipt,
 		}
 
 		// Optional auto-verification trigger
@@ -203,6 +224,52 @@ func main() {
 		log.Printf("✅ Action %s logged from %s\n", act.Type, req.Initiator)
 	})
 
+	// ===========================================
+	// === POST /api/console/actions/{id}/sign ===
+	// ===========================================
+	http.HandleFunc("/api/console/actions/", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/console/actions/"), "/sign")
+		if !ok || id == "" || strings.Contains(id, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req SignRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Seat == "" || req.Signature == "" || req.SignedAt == "" {
+			http.Error(w, "invalid JSON body: seat, signed_at and signature are required", http.StatusBadRequest)
+			return
+		}
+
+		act, err := ac.SignAction(id, ledger.SeatSignature{Seat: req.Seat, KeyID: req.KeyID, SignedAt: req.SignedAt, Signature: req.Signature})
+		switch {
+		case errors.Is(err, console.ErrActionNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, ledger.ErrNotASeat), errors.Is(err, ledger.ErrBadSeatSignature):
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		ac.SaveState()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"status":    "ok",
+			"action_id": act.ID,
+			"state":     act.Status,
+			"signed_by": act.Receipt.SignedSeats(),
+			"threshold": act.Receipt.Threshold,
+			"receipt":   act.Receipt,
+		})
+	})
+
 	// ===============================
 	// === GET /api/console/state ===
 	// ===============================
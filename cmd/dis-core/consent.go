@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"dis-core/internal/app"
+	"dis-core/internal/consent"
+	"dis-core/internal/ledger"
+	"dis-core/internal/util/crypto"
+)
+
+const consentUsage = `usage: dis-core consent <command> [flags]
+
+commands:
+  simulate [-dir d] [-q query] [-limit n] [-all] <candidate.yaml>
+                                         replay past consent decisions under a
+                                         candidate config and report flips and drift
+  attest-key [-by domain] <registration.json>
+                                         vouch for a party's first key as domain
+                                         (default: this node) and print the
+                                         registration to POST to /api/consent/keys
+
+-q narrows the replayed decision receipts with a ledger query.
+-dir uses the file store in d instead of the Postgres ledger.`
+
+// runConsent implements `dis-core consent`.
+func runConsent(args []string) error {
+	if len(args) == 0 {
+		return errors.New(consentUsage)
+	}
+	if err := app.SetupKeyStore(); err != nil {
+		return err
+	}
+
+	cmd := args[0]
+	fs := flag.NewFlagSet("consent "+cmd, flag.ContinueOnError)
+	dir := fs.String("dir", "", "file store directory (default: Postgres ledger)")
+	q := fs.String("q", "", "ledger query selecting the decisions to replay")
+	limit := fs.Int("limit", 0, "replay at most n decisions, newest first (0: all)")
+	all := fs.Bool("all", false, "report every replayed decision, not only flips")
+	by := fs.String("by", ledger.NodeDomain, "domain vouching for the party's key")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "simulate":
+		if fs.NArg() != 1 {
+			return errors.New(consentUsage)
+		}
+		schema, err := app.SetupConsent("./policies")
+		if err != nil {
+			return err
+		}
+		cfg, err := consent.LoadConfig(fs.Arg(0), schema)
+		if err != nil {
+			return err
+		}
+		store, closeStore, err := openStore(*dir)
+		if err != nil {
+			return err
+		}
+		defer closeStore()
+		receipts, err := consent.LoadDecisions(store, *q, *limit)
+		if err != nil {
+			return err
+		}
+		rep, err := consent.Simulate(context.Background(), receipts, cfg, consent.SimulateOptions{All: *all})
+		if err != nil {
+			return err
+		}
+		return printJSON(rep)
+	case "attest-key":
+		if fs.NArg() != 1 {
+			return errors.New(consentUsage)
+		}
+		data, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		var reg consent.KeyRegistration
+		if err := json.Unmarshal(data, &reg); err != nil {
+			return fmt.Errorf("%s: %w", fs.Arg(0), err)
+		}
+		attested, err := consent.AttestPartyKey(crypto.DefaultKeyStore(), reg, *by)
+		if err != nil {
+			return err
+		}
+		return printJSON(attested)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", cmd, consentUsage)
+	}
+}
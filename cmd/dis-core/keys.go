@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"dis-core/internal/app"
+	"dis-core/internal/ledger"
+	"dis-core/internal/util/crypto"
+)
+
+const keysUsage = `usage: dis-core keys <command> [flags] <domain>
+
+commands:
+  generate <domain>                      create the first key for a domain
+  rotate <domain>                        retire the active key and activate a new one
+  revoke -key <key_id> [-reason r] [-compromised-at t] <domain>
+                                         revoke a retired key, from RFC 3339 time t if given
+  list <domain>                          print the domain's key history
+  enroll -pub <public_key_b64> <seat>    record a seat's public key, generated on the seat's side
+  cosign -receipt <file> <seat>          sign a receipt as seat, for a console's sign endpoint`
+
+// runKeys implements `dis-core keys`. Lifecycle receipts are written to the
+// default receipt store.
+func runKeys(args []string) error {
+	if len(args) == 0 {
+		return errors.New(keysUsage)
+	}
+	if err := app.SetupKeyStore(); err != nil {
+		return err
+	}
+
+	cmd := args[0]
+	fs := flag.NewFlagSet("keys "+cmd, flag.ContinueOnError)
+	keyID := fs.String("key", "", "key ID to revoke")
+	reason := fs.String("reason", "unspecified", "revocation reason")
+	compromisedAt := fs.String("compromised-at", "", "RFC 3339 time the key was compromised (default now)")
+	receiptFile := fs.String("receipt", "", "receipt JSON file to co-sign")
+	pub := fs.String("pub", "", "base64 public key to enroll")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New(keysUsage)
+	}
+	domain := fs.Arg(0)
+
+	var (
+		key     crypto.KeyRecord
+		receipt *ledger.Receipt
+		err     error
+	)
+	switch cmd {
+	case "generate":
+		key, receipt, err = ledger.GenerateDomainKey(domain)
+	case "rotate":
+		key, receipt, err = ledger.RotateDomainKey(domain)
+	case "revoke":
+		if *keyID == "" {
+			return errors.New("revoke requires -key")
+		}
+		var at time.Time
+		if *compromisedAt != "" {
+			if at, err = time.Parse(time.RFC3339, *compromisedAt); err != nil {
+				return fmt.Errorf("-compromised-at: %w", err)
+			}
+		}
+		key, receipt, err = ledger.RevokeDomainKey(domain, *keyID, *reason, at)
+	case "list":
+		history, err := crypto.DefaultKeyStore().History(domain)
+		if err != nil {
+			return err
+		}
+		return printJSON(history)
+	case "enroll":
+		if *pub == "" {
+			return errors.New("enroll requires -pub")
+		}
+		key, err := crypto.DefaultKeyStore().Register(domain, *pub, time.Now())
+		if err != nil {
+			return err
+		}
+		return printJSON(key)
+	case "cosign":
+		if *receiptFile == "" {
+			return errors.New("cosign requires -receipt")
+		}
+		data, err := os.ReadFile(*receiptFile)
+		if err != nil {
+			return err
+		}
+		var r ledger.Receipt
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		sig, err := ledger.SignSeat(crypto.DefaultKeyStore(), &r, domain)
+		if err != nil {
+			return err
+		}
+		return printJSON(sig)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", cmd, keysUsage)
+	}
+	if err != nil {
+		return err
+	}
+	return printJSON(map[string]any{"key": key, "receipt_id": receipt.ReceiptID})
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
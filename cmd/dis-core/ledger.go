@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"dis-core/internal/app"
+	"dis-core/internal/ledger"
+	"dis-core/internal/redaction"
+	"dis-core/internal/util/crypto"
+)
+
+const ledgerUsage = `usage: dis-core ledger <command> [flags]
+
+commands:
+  export [-dir d] -o <bundle>            write a signed, compressed ledger bundle
+  export [-dir d] -redact [-disclosures f] -o <views.json>
+                                         write redacted views of the receipts instead
+  import [-dir d] [-dry-run] [-allow-schema-drift] [-pin keys.json] <bundle>
+                                         verify a bundle and merge it into the ledger
+
+-dir uses the file store in d instead of the Postgres ledger. -pin names a
+JSON object of domain -> key history for peer domains this node holds no
+keys for; bundle signatures must verify against those or local keys.
+-redact applies policies/redaction.yaml; -disclosures writes what an
+authorized party needs to restore each receipt and check its signature.`
+
+// runLedger implements `dis-core ledger`.
+func runLedger(args []string) error {
+	if len(args) == 0 {
+		return errors.New(ledgerUsage)
+	}
+	if err := app.SetupKeyStore(); err != nil {
+		return err
+	}
+
+	cmd := args[0]
+	fs := flag.NewFlagSet("ledger "+cmd, flag.ContinueOnError)
+	dir := fs.String("dir", "", "file store directory (default: Postgres ledger)")
+	out := fs.String("o", "", "bundle file to write")
+	dryRun := fs.Bool("dry-run", false, "verify and check for conflicts without writing")
+	allowDrift := fs.Bool("allow-schema-drift", false, "import bundles exported against another schema registry")
+	pin := fs.String("pin", "", "JSON file of pinned peer key histories")
+	redact := fs.Bool("redact", false, "export redacted receipt views instead of a bundle")
+	disclosures := fs.String("disclosures", "", "with -redact, file to write the disclosures to")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	store, closeStore, err := openStore(*dir)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	switch cmd {
+	case "export":
+		if *out == "" || fs.NArg() != 0 || (*disclosures != "" && !*redact) {
+			return errors.New(ledgerUsage)
+		}
+		if *redact {
+			return exportRedacted(store, *out, *disclosures)
+		}
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		m, err := ledger.ExportBundle(f, store, ledger.ExportOptions{SchemaRegistryHash: app.SchemaRegistryHash()})
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(*out)
+			return err
+		}
+		return printJSON(map[string]any{
+			"bundle":      *out,
+			"head":        m.Head,
+			"merkle_root": m.MerkleRoot,
+			"receipts":    m.Receipts,
+		})
+	case "import":
+		if fs.NArg() != 1 {
+			return errors.New(ledgerUsage)
+		}
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		var pinned map[string][]crypto.KeyRecord
+		if *pin != "" {
+			data, err := os.ReadFile(*pin)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(data, &pinned); err != nil {
+				return fmt.Errorf("pinned keys: %w", err)
+			}
+		}
+		rep, err := ledger.ImportBundle(f, store, ledger.ImportOptions{
+			SchemaRegistryHash: app.SchemaRegistryHash(),
+			AllowSchemaDrift:   *allowDrift,
+			DryRun:             *dryRun,
+			PinnedKeys:         pinned,
+		})
+		if err != nil {
+			return err
+		}
+		return printJSON(rep)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", cmd, ledgerUsage)
+	}
+}
+
+// exportRedacted writes the redacted view of every receipt in the chain
+// to out and, if disclosures is set, the disclosures keyed by receipt ID.
+func exportRedacted(store ledger.ReceiptStore, out, disclosures string) error {
+	rp, err := redaction.LoadPolicy(filepath.Join("./policies", "redaction.yaml"))
+	if err != nil {
+		return err
+	}
+	entries, err := store.Chain()
+	if err != nil {
+		return err
+	}
+	views := []map[string]any{}
+	opened := map[string][]redaction.Disclosure{}
+	for _, e := range entries {
+		if e.Receipt == nil {
+			continue
+		}
+		view, ds, err := rp.RedactReceipt(e.Receipt)
+		if err != nil {
+			return fmt.Errorf("redact %s: %w", e.Receipt.ReceiptID, err)
+		}
+		views = append(views, view)
+		if len(ds) > 0 {
+			opened[e.Receipt.ReceiptID] = ds
+		}
+	}
+	if err := writeJSONFile(out, map[string]any{"policy": rp.Ref(), "receipts": views}); err != nil {
+		return err
+	}
+	if disclosures != "" {
+		if err := writeJSONFile(disclosures, opened); err != nil {
+			return err
+		}
+	}
+	return printJSON(map[string]any{"views": out, "policy": rp.Ref(), "receipts": len(views)})
+}
+
+// writeJSONFile writes v to path, readable only by the owner.
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// openStore opens the file store in dir, or the Postgres ledger.
+func openStore(dir string) (ledger.ReceiptStore, func() error, error) {
+	if dir != "" {
+		return ledger.NewFileStore(dir), func() error { return nil }, nil
+	}
+	return app.OpenReceiptStore()
+}
@@ -2,11 +2,31 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"dis-core/internal/app"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "keys":
+			if err := runKeys(os.Args[2:]); err != nil {
+				log.Fatalf("keys: %v", err)
+			}
+			return
+		case "ledger":
+			if err := runLedger(os.Args[2:]); err != nil {
+				log.Fatalf("ledger: %v", err)
+			}
+			return
+		case "consent":
+			if err := runConsent(os.Args[2:]); err != nil {
+				log.Fatalf("consent: %v", err)
+			}
+			return
+		}
+	}
 	if err := app.Run(); err != nil {
 		log.Fatalf("fatal: %v", err)
 	}
@@ -1,27 +1,135 @@
 package main
 
 import (
+	"database/sql"
+	"dis-core/internal/app"
+	"dis-core/internal/config"
+	"dis-core/internal/db"
+	"dis-core/internal/ledger"
 	disnet "dis-core/internal/net"
+	"dis-core/internal/schema"
+	"dis-core/internal/util/crypto"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 )
 
 var netPort = flag.Int("net_port", 9090, "DIS-Network peer port")
 var configPath = flag.String("config", "network.yaml", "network config file")
+var domainID = flag.String("domain", ledger.NodeDomain, "domain this node authenticates as")
+var advertise = flag.String("advertise", "", "address peers should dial back (host:port)")
+var coreHash = flag.String("core_hash", "", "frozen core hash (default: build revision)")
+var schemaDir = flag.String("schemas", "./disyaml/schemas", "schema directory hashed for peers")
+var receiptsDir = flag.String("receipts", "receipts", "local receipt directory served to peers")
+var usePG = flag.Bool("pg", false, "keep peers, replicated receipts and attestations in Postgres instead of memory")
+var tofu = flag.Bool("tofu", false, "accept peers from domains with no known key and pin the key they present")
+var useMDNS = flag.Bool("mdns", false, "discover peers on the LAN over multicast DNS (also enabled by mdns: true in the config)")
 
 func main() {
 	flag.Parse()
+	ledger.NodeDomain = *domainID // checkpoints are sealed as this node's domain
 
 	addr := fmt.Sprintf(":%d", *netPort)
 	log.Printf("🌐 Starting DIS-Network node on %s", addr)
 
+	if err := app.SetupKeyStore(); err != nil {
+		log.Fatalf("❌ key store: %v", err)
+	}
+	ks := crypto.DefaultKeyStore()
+	if _, err := ks.Active(*domainID); err != nil {
+		log.Fatalf("❌ no active key for %s (run `dis-core keys generate %s`): %v", *domainID, *domainID, err)
+	}
+	reg := schema.NewRegistry()
+	if err := reg.LoadDir(*schemaDir); err != nil {
+		log.Printf("⚠️  Schema load failed: %v", err)
+	}
+	core := *coreHash
+	if core == "" {
+		core = disnet.BuildCoreHash()
+	}
+
+	// Persistence, when enabled
+	var database *sql.DB
+	if *usePG {
+		cfg, err := config.Load("config.yaml")
+		if err != nil {
+			cfg = &config.Config{}
+		}
+		if database, err = db.Connect(cfg); err != nil {
+			log.Fatalf("❌ database: %v", err)
+		}
+		defer database.Close()
+		for _, step := range []struct {
+			name   string
+			ensure func(*sql.DB) error
+		}{
+			{"peers", disnet.EnsurePeersTable},
+			{"foreign_receipts", disnet.EnsureForeignReceiptsTable},
+			{"trust_entries", ledger.EnsureTrustEntriesSchema},
+		} {
+			if err := step.ensure(database); err != nil {
+				log.Fatalf("❌ %s: %v", step.name, err)
+			}
+		}
+	}
+
+	// Pin the peer keys listed in the network config
+	netCfg, err := disnet.LoadNetworkConfig(*configPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("⚠️  Network config %s: %v", *configPath, err)
+		}
+		netCfg = &disnet.NetworkConfig{}
+	}
+	identity := &disnet.Identity{
+		Domain:          *domainID,
+		Keys:            ks,
+		SchemaHash:      reg.HashAll(),
+		CoreHash:        core,
+		ListenAddr:      *advertise,
+		KnownKeys:       app.PeerKeyStore(),
+		TrustOnFirstUse: *tofu,
+	}
+	if err := identity.PinPeerKeys(netCfg.Peers); err != nil {
+		log.Fatalf("❌ peer keys: %v", err)
+	}
+	if *tofu {
+		log.Printf("⚠️  Trusting unknown peer domains on first use")
+	}
+
 	// Create a new network manager
-	manager := disnet.NewManager(nil)
+	manager := disnet.NewManager(database).WithIdentity(identity)
+	log.Printf("🔑 Authenticating as %s (core %s)", *domainID, core)
+
+	// Enforce peer trust levels from the network config
+	policy, err := disnet.TrustPolicyFromConfig(netCfg)
+	if err != nil {
+		log.Fatalf("❌ trust policy: %v", err)
+	}
+	manager.WithTrustPolicy(policy)
+
+	// Replicate receipts with peers and have them attested
+	var replicas disnet.ReplicaStore = disnet.NewMemoryReplicaStore()
+	var attestations ledger.AttestationStore = &ledger.TrustLedger{}
+	if database != nil {
+		replicas = disnet.NewPGReplicaStore(database)
+		attestations = ledger.NewPGTrustStore(database)
+	}
+	local := ledger.NewFileStore(*receiptsDir)
+	replicator, err := disnet.NewReplicator(manager, *domainID, local, replicas)
+	if err != nil {
+		log.Fatalf("❌ replication: %v", err)
+	}
+	attestor := disnet.NewAttestor(manager, local, replicas, attestations)
+	stopSync := make(chan struct{})
+	go replicator.Run(disnet.DefaultAntiEntropyInterval, stopSync)
+	go attestor.Run(disnet.DefaultAntiEntropyInterval, stopSync)
 
 	// Start listening for peers
 	go func() {
@@ -30,11 +138,32 @@ func main() {
 		}
 	}()
 
+	// Discover peers: persisted records, configured peers and seeds, the LAN
+	if err := manager.LoadPeersFromDB(database); err != nil {
+		log.Printf("⚠️  Peer records: %v", err)
+	}
+	manager.ApplyConfig(netCfg)
+	mdns := *useMDNS || netCfg.MDNS
+	if mdns {
+		port := *netPort
+		if _, p, err := net.SplitHostPort(*advertise); err == nil {
+			port, _ = strconv.Atoi(p)
+		}
+		if md, err := manager.StartMDNS(port); err != nil {
+			log.Printf("⚠️  %v", err)
+		} else {
+			defer md.Close()
+		}
+	}
+
 	// Optional: log peer count periodically
 	go func() {
 		for {
 			peers := manager.ListPeers()
-			log.Printf("🧭 Connected peers: %d", len(peers))
+			log.Printf("🧭 Connected peers: %d", len(manager.Sessions()))
+			for _, p := range peers {
+				log.Printf("   %s %s %s score=%.3f (%s)", p.Address, p.Domain, p.Status, p.Score, p.Source)
+			}
 			time.Sleep(10 * time.Second)
 		}
 	}()
@@ -45,5 +174,6 @@ func main() {
 	<-stop
 
 	log.Println("🛑 Shutdown signal received — closing DIS-Network.")
+	close(stopSync)
 	manager.Close()
 }
@@ -8,6 +8,7 @@ import (
 	"database/sql"
 	"dis-core/internal/api"
 	"dis-core/internal/config"
+	dbpkg "dis-core/internal/db"
 	"flag"
 	"fmt"
 	"log"
@@ -23,6 +24,7 @@ import (
 	"dis-core/internal/domain"
 	"dis-core/internal/ledger"
 	"dis-core/internal/policy"
+	"dis-core/internal/redaction"
 	"dis-core/internal/schema"
 )
 
@@ -107,7 +109,7 @@ func main() {
 	// --------------------------------------------------------------------
 
 	if *listReceipts {
-		list, err := store.ListReceipts()
+		list, err := store.List(ledger.ListOptions{Limit: 500})
 		if err != nil {
 			log.Fatalf("list receipts: %v", err)
 		}
@@ -161,10 +163,11 @@ func main() {
 	}
 
 	// Open ledger
-	led, err := ledger.Open(cfg.DatabaseDSN, db)
+	led, err := ledger.Open(cfg.DatabaseDSN, db, reg)
 	if err != nil {
 		log.Fatalf("open ledger: %v", err)
 	}
+	ledger.SetDefaultStore(ledger.NewStore(led.DB))
 
 	// --------------------------------------------------------------------
 	// Initialize Policy Engine
@@ -186,6 +189,7 @@ func main() {
 		log.Fatalf("failed to start policy engine: %v", err)
 	}
 	log.Printf("✅ Policy engine initialized (using %s)", base)
+	redaction.SetDefault(eng.Redaction)
 
 	// Create the API server
 	apiServer := api.NewServer(cfg, led, db)
@@ -239,18 +243,23 @@ func main() {
 
 func doFreeze(reg *schema.Registry, db *sql.DB, version string) {
 	hash := reg.HashAll()
-	r := ledger.NewReceipt(
+	r, err := ledger.NewReceipt(
 		"domain.terra",
 		fmt.Sprintf("freeze_core_%s", version),
 		hash,
 		"console-001",
 		"seat.core.architect",
 	)
-
-	ledger.SaveReceipt(r)
+	if err != nil {
+		log.Printf("⚠️ failed to create freeze receipt: %v", err)
+		return
+	}
 
 	store := ledger.NewStore(db)
-	if err := store.InsertReceipt(r); err != nil {
+	if err := dbpkg.EnsureReceiptsSchema(db); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+	if err := store.Append(r); err != nil {
 		log.Printf("⚠️ failed to insert freeze receipt: %v", err)
 	}
 
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"database/sql"
 	"dis-core/internal/db"
 	"dis-core/internal/ledger"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+
+	_ "github.com/lib/pq"
 )
 
 type AuditResult struct {
@@ -18,7 +22,12 @@ type AuditResult struct {
 	VerifiedAt string `json:"verified_at"`
 }
 
+var ledgerPath = flag.String("ledger", "receipts/ledger.jsonl", "hash-chained receipt ledger to verify")
+var dsn = flag.String("dsn", os.Getenv("DIS_DB_DSN"), "Postgres DSN; when set, the receipts table chain is verified too")
+
 func main() {
+	flag.Parse()
+
 	dir := "versions/v0.6/receipts/generated"
 	archiveDir := "versions/v0.6/receipts/archive"
 	quarantineDir := "versions/v0.6/receipts/quarantine"
@@ -87,6 +96,30 @@ func main() {
 		}
 	}
 
+	// Verify full history: chain linkage and signed checkpoints
+	chains := map[string]*ledger.ChainReport{}
+	chainOK := true
+
+	fileReport, err := ledger.VerifyLedgerFile(*ledgerPath)
+	if err != nil {
+		fmt.Printf("⚠️  could not read %s: %v\n", *ledgerPath, err)
+		chainOK = false
+	} else {
+		chains[*ledgerPath] = fileReport
+		chainOK = printChainReport(*ledgerPath, fileReport) && chainOK
+	}
+
+	if *dsn != "" {
+		pgReport, err := verifyPostgresChain(*dsn)
+		if err != nil {
+			fmt.Printf("⚠️  could not verify Postgres chain: %v\n", err)
+			chainOK = false
+		} else {
+			chains["postgres:receipts"] = pgReport
+			chainOK = printChainReport("postgres:receipts", pgReport) && chainOK
+		}
+	}
+
 	// Write the ledger
 	ledger := map[string]any{
 		"verified_at":   db.NowRFC3339Nano(),
@@ -94,6 +127,7 @@ func main() {
 		"valid":         validCount,
 		"invalid":       invalidCount,
 		"audit_results": results,
+		"chains":        chains,
 	}
 
 	outPath := "versions/v0.6/receipts/verification_ledger.json"
@@ -103,4 +137,31 @@ func main() {
 
 	fmt.Printf("✨ Verification completed: %d valid, %d invalid\n", validCount, invalidCount)
 	fmt.Printf("🪶 Ledger written to %s\n", outPath)
+
+	if !chainOK {
+		os.Exit(1)
+	}
+}
+
+// verifyPostgresChain loads and verifies the receipt chain in the receipts table.
+func verifyPostgresChain(dsn string) (*ledger.ChainReport, error) {
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return ledger.NewStore(conn).VerifyChain()
+}
+
+// printChainReport prints a chain verification summary and reports whether it passed.
+func printChainReport(name string, rep *ledger.ChainReport) bool {
+	fmt.Printf("🔗 %s: %d chained receipts, %d legacy, %d checkpoints, head seq %d, sealed through %d\n",
+		name, rep.Receipts, rep.Legacy, rep.Checkpoints, rep.Head.Seq, rep.SealedSeq)
+	if rep.Unsealed() > 0 {
+		fmt.Printf("   %d receipt(s) after the last checkpoint are not yet sealed\n", rep.Unsealed())
+	}
+	for _, e := range rep.Errors {
+		fmt.Printf("   ❌ %s\n", e)
+	}
+	return rep.OK()
 }
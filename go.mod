@@ -8,6 +8,7 @@ require (
 	github.com/lib/pq v1.10.9
 	github.com/open-policy-agent/opa v0.63.0
 	github.com/paulmach/orb v0.12.0
+	golang.org/x/crypto v0.21.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
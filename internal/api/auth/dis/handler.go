@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"dis-core/internal/db"
+	"dis-core/internal/ledger"
+	"dis-core/internal/redaction"
 )
 
 type DISAuthHandshake struct {
@@ -23,6 +25,7 @@ type DISAuthHandshake struct {
 
 // Handle returns an http.HandlerFunc bound to a specific DB.
 func Handle(store *sql.DB) http.HandlerFunc {
+	receipts := ledger.NewStore(store)
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
@@ -47,11 +50,20 @@ func Handle(store *sql.DB) http.HandlerFunc {
 				return
 			}
 
-			content := fmt.Sprintf("Handshake: %s → %s (scope: %s)", h.Initiator, h.Responder, h.Scope)
-			_, err = store.Exec(`
-				INSERT INTO receipts (receipt_id, schema_ref, content, timestamp)
-				VALUES ($1, $2, $3, NOW());
-			`, "rcpt-"+h.HandshakeID, "bridge-receipt-template.v0", content)
+			rc, err := ledger.NewEnvelope("bridge-receipt-template.v0", ledger.NodeDomain, "auth.handshake", map[string]any{
+				"handshake_id": h.HandshakeID,
+				"initiator":    h.Initiator,
+				"responder":    h.Responder,
+				"scope":        h.Scope,
+				"content":      fmt.Sprintf("Handshake: %s → %s (scope: %s)", h.Initiator, h.Responder, h.Scope),
+			})
+			if err == nil {
+				rc.ReceiptID = "rcpt-" + h.HandshakeID
+				err = rc.Seal()
+			}
+			if err == nil {
+				err = receipts.Append(rc)
+			}
 			if err != nil {
 				log.Printf("⚠️ Failed to emit consent receipt: %v", err)
 			}
@@ -83,8 +95,17 @@ func Handle(store *sql.DB) http.HandlerFunc {
 				}
 				list = append(list, h)
 			}
+			// Consent proofs are listed as redacted views when a redaction
+			// policy is loaded.
+			var items any = list
+			if rp := redaction.Default(); rp != nil {
+				if items, err = rp.RedactAll(list); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
 			w.Header().Set("Content-Type", "application/json")
-			_ = json.NewEncoder(w).Encode(list)
+			_ = json.NewEncoder(w).Encode(items)
 
 		default:
 			http.Error(w, "Unsupported method", http.StatusMethodNotAllowed)
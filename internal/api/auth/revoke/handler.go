@@ -3,12 +3,16 @@ package revoke
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"dis-core/internal/db"
+	"dis-core/internal/ledger"
+	"dis-core/internal/util/crypto"
 )
 
 type RevocationEntry struct {
@@ -24,6 +28,8 @@ type RevocationEntry struct {
 
 // Handle returns an http.HandlerFunc bound to the given DB.
 func Handle(store *sql.DB) http.HandlerFunc {
+	receipts := ledger.NewStore(store)
+	propagator := ledger.NewRevocationPropagator(receipts, ledger.NewPGStatusStore(store))
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
@@ -32,13 +38,25 @@ func Handle(store *sql.DB) http.HandlerFunc {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
+			// Only a signed revocation by a party to the ref is recorded, so
+			// nothing unauthorized is ever propagated or retried.
+			err := propagator.Authorize(crypto.DefaultKeyStore(), entry.RevokedRef, entry.RevokedType, entry.Reason, entry.RevokedBy, entry.Signature)
+			if errors.Is(err, ledger.ErrRevocationRefused) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Failed to authorize revocation: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+
 			entry.RevocationID = "rev-" + db.NowRFC3339Nano()
 			entry.RevocationTime = time.Now().UTC().Format(time.RFC3339)
 			if entry.ValidUntil == "" {
 				entry.ValidUntil = time.Now().UTC().Add(24 * time.Hour).Format(time.RFC3339)
 			}
 
-			_, err := store.Exec(`
+			_, err = store.Exec(`
 				INSERT INTO revocations 
 				(revocation_id, revoked_ref, revoked_type, reason, revoked_by, revocation_time, valid_until, signature)
 				VALUES ($1,$2,$3,$4,$5,$6::timestamptz,$7::timestamptz,$8);
@@ -49,18 +67,46 @@ func Handle(store *sql.DB) http.HandlerFunc {
 				return
 			}
 
-			content := fmt.Sprintf("Revocation: %s of %s by %s (reason: %s)",
-				entry.RevokedType, entry.RevokedRef, entry.RevokedBy, entry.Reason)
-			_, err = store.Exec(`
-				INSERT INTO receipts (receipt_id, schema_ref, content, timestamp)
-				VALUES ($1, $2, $3, NOW());
-			`, "rcpt-"+entry.RevocationID, "bridge-receipt-template.v0", content)
+			rc, err := ledger.NewEnvelope("bridge-receipt-template.v0", ledger.NodeDomain, "auth.revoke", map[string]any{
+				"revoked_by":    entry.RevokedBy,
+				"revocation_id": entry.RevocationID,
+				"revoked_ref":   entry.RevokedRef,
+				"revoked_type":  entry.RevokedType,
+				"reason":        entry.Reason,
+				"content": fmt.Sprintf("Revocation: %s of %s by %s (reason: %s)",
+					entry.RevokedType, entry.RevokedRef, entry.RevokedBy, entry.Reason),
+			})
+			if err == nil {
+				rc.ReceiptID = "rcpt-" + entry.RevocationID
+				err = rc.Seal()
+			}
+			if err == nil {
+				err = receipts.Append(rc)
+			}
 			if err != nil {
 				log.Printf("⚠️ Failed to emit receipt: %v", err)
 			}
 
+			// A revocation that could not be propagated stays pending and is
+			// retried by RetryPropagation; the client learns it from
+			// propagated: false and a 202.
+			resp := map[string]any{"status": "revoked", "entry": entry}
+			status := http.StatusOK
+			impact, err := propagate(store, propagator, entry.RevocationID, entry.RevokedRef)
+			if err != nil {
+				log.Printf("⚠️ Failed to propagate revocation %s, will retry: %v", entry.RevocationID, err)
+				resp["propagated"] = false
+				resp["error"] = err.Error()
+				status = http.StatusAccepted
+			} else {
+				resp["propagated"] = true
+				resp["affected"] = len(impact.Statuses)
+				resp["domains"] = impact.Domains
+			}
+
 			w.Header().Set("Content-Type", "application/json")
-			_ = json.NewEncoder(w).Encode(map[string]any{"status": "revoked", "entry": entry})
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(resp)
 
 		case http.MethodGet:
 			rows, err := store.Query(`
@@ -94,3 +140,45 @@ func Handle(store *sql.DB) http.HandlerFunc {
 		}
 	}
 }
+
+// HandleImpact serves GET /api/revocations/{id}/impact: the receipts a
+// revocation reached through provenance links, their recorded statuses and
+// the affected domains.
+func HandleImpact(store *sql.DB) http.HandlerFunc {
+	propagator := ledger.NewRevocationPropagator(ledger.NewStore(store), ledger.NewPGStatusStore(store))
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Unsupported method", http.StatusMethodNotAllowed)
+			return
+		}
+		rest := strings.TrimPrefix(r.URL.Path, "/api/revocations/")
+		id, ok := strings.CutSuffix(rest, "/impact")
+		if !ok || id == "" || strings.Contains(id, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		var ref string
+		err := store.QueryRow(`SELECT revoked_ref FROM revocations WHERE revocation_id = $1`, id).Scan(&ref)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "revocation not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to query revocation: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		impact, err := propagator.Impact(id, ref)
+		if errors.Is(err, ledger.ErrBadQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to compute impact: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(impact)
+	}
+}
@@ -5,7 +5,12 @@ import (
 	"net/http"
 )
 
-// Register wires revocation routes to the mux.
+// Register wires revocation routes to the mux and retries revocations
+// whose propagation failed.
 func Register(mux *http.ServeMux, store *sql.DB) {
 	mux.HandleFunc("/api/auth/revoke", Handle(store))
+	mux.HandleFunc("/api/revocations/", HandleImpact(store))
+	if store != nil {
+		go RetryPropagation(store, DefaultRetryInterval, nil)
+	}
 }
@@ -0,0 +1,79 @@
+package revoke
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"dis-core/internal/ledger"
+)
+
+// DefaultRetryInterval is how often RetryPropagation looks for revocations
+// that have not been propagated.
+const DefaultRetryInterval = time.Minute
+
+// propagate propagates a revocation and records that it was propagated.
+// Propagation is idempotent, so a revocation whose record fails to update
+// is simply propagated again on the next retry.
+func propagate(store *sql.DB, p *ledger.RevocationPropagator, revocationID, ref string) (*ledger.RevocationImpact, error) {
+	impact, err := p.Propagate(revocationID, ref)
+	if err != nil {
+		return nil, err
+	}
+	_, err = store.Exec(`UPDATE revocations SET propagated_at = now() WHERE revocation_id = $1`, revocationID)
+	if err != nil {
+		return nil, err
+	}
+	return impact, nil
+}
+
+// RetryPropagation propagates, every interval until stop is closed, the
+// revocations not yet marked as propagated.
+func RetryPropagation(store *sql.DB, interval time.Duration, stop <-chan struct{}) {
+	propagator := ledger.NewRevocationPropagator(ledger.NewStore(store), ledger.NewPGStatusStore(store))
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			retryPending(store, propagator)
+		}
+	}
+}
+
+func retryPending(store *sql.DB, p *ledger.RevocationPropagator) {
+	rows, err := store.Query(`
+		SELECT revocation_id, revoked_ref FROM revocations
+		WHERE propagated_at IS NULL
+		ORDER BY revocation_time
+		LIMIT 100;
+	`)
+	if err != nil {
+		log.Printf("⚠️ Pending revocations: %v", err)
+		return
+	}
+	type pending struct{ id, ref string }
+	var list []pending
+	for rows.Next() {
+		var r pending
+		if err := rows.Scan(&r.id, &r.ref); err != nil {
+			log.Printf("⚠️ Pending revocations: %v", err)
+			break
+		}
+		list = append(list, r)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("⚠️ Pending revocations: %v", err)
+	}
+	rows.Close()
+
+	for _, r := range list {
+		if _, err := propagate(store, p, r.id, r.ref); err != nil {
+			log.Printf("⚠️ Retry propagation of revocation %s: %v", r.id, err)
+			continue
+		}
+		log.Printf("🔁 Propagated revocation %s", r.id)
+	}
+}
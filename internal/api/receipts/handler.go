@@ -1,39 +1,85 @@
-package ledger
+package receipts
 
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
-	"dis-core/internal/db"
+	"dis-core/internal/ledger"
+	"dis-core/internal/redaction"
 )
 
 // Handle returns an http.HandlerFunc bound to the provided DB connection.
 func Handle(store *sql.DB) http.HandlerFunc {
+	rs := ledger.NewStore(store)
+	trust := ledger.NewPGTrustStore(store)
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
 			q := r.URL.Query()
 			limit, _ := strconv.Atoi(q.Get("limit"))
-			offset, _ := strconv.Atoi(q.Get("offset"))
-			if limit <= 0 {
-				limit = 100
+
+			var (
+				list []ledger.Receipt
+				next string
+				err  error
+			)
+			if q.Has("offset") {
+				// Offset paging is kept for existing clients.
+				offset, _ := strconv.Atoi(q.Get("offset"))
+				list, err = rs.List(ledger.ListOptions{
+					Limit:     limit,
+					Offset:    offset,
+					SchemaRef: q.Get("schema_ref"),
+				})
+			} else {
+				var page *ledger.SearchPage
+				page, err = rs.Search(ledger.SearchOptions{
+					Query:  searchQuery(q.Get("q"), q.Get("schema_ref")),
+					Limit:  limit,
+					Cursor: q.Get("cursor"),
+				})
+				if page != nil {
+					list, next = page.Items, page.NextCursor
+				}
+			}
+			switch {
+			case errors.Is(err, ledger.ErrBadQuery), errors.Is(err, ledger.ErrBadCursor):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			case err != nil:
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
 			}
 
-			list, err := db.ListReceipts(store, db.ListOpts{
-				Limit:  limit,
-				Offset: offset,
-			})
+			ids := make([]string, len(list))
+			for i, rc := range list {
+				ids[i] = rc.ReceiptID
+			}
+			attesters, err := trust.AttesterCounts(ids)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
 
+			// Responses carry redacted views when a redaction policy is loaded.
+			var items any = list
+			if rp := redaction.Default(); rp != nil {
+				if items, err = rp.RedactAll(list); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+
 			w.Header().Set("Content-Type", "application/json")
 			_ = json.NewEncoder(w).Encode(map[string]any{
-				"count": len(list),
-				"items": list,
+				"count":        len(list),
+				"items":        items,
+				"attestations": attesters,
+				"next_cursor":  next,
 			})
 
 		default:
@@ -41,3 +87,15 @@ func Handle(store *sql.DB) http.HandlerFunc {
 		}
 	}
 }
+
+// searchQuery narrows the q expression to schemaRef when one is given.
+func searchQuery(expr, schemaRef string) string {
+	if schemaRef == "" {
+		return expr
+	}
+	cond := "schema_ref = " + ledger.QuoteQueryValue(schemaRef)
+	if strings.TrimSpace(expr) == "" {
+		return cond
+	}
+	return "(" + expr + ") and " + cond
+}
@@ -1,4 +1,4 @@
-package ledger
+package receipts
 
 import (
 	"database/sql"
@@ -8,7 +8,11 @@ import (
 // Register wires the receipt endpoints into the server mux.
 //
 // Exposes:
-//   - GET /api/receipts → list stored receipts
+//   - GET /api/receipts[?q=&limit=&cursor=&schema_ref=] → search receipts, newest first
+//   - GET /api/receipts?offset=N[&limit=&schema_ref=]   → offset-paged list (legacy)
+//
+// Both carry "attestations": the number of independent peers that have
+// attested each listed receipt, by receipt ID.
 func Register(mux *http.ServeMux, store *sql.DB) {
 	mux.HandleFunc("/api/receipts", Handle(store))
 }
@@ -5,11 +5,11 @@ import (
 	"log"
 	"net/http"
 
+	"dis-core/internal/api/auth"
+	"dis-core/internal/api/receipts"
 	"dis-core/internal/canon"
 	"dis-core/internal/registry/atlas"
-	"dis-core/internal/registry/auth"
 	"dis-core/internal/registry/identities"
-	"dis-core/internal/registry/receipts"
 	"dis-core/internal/registry/terra"
 )
 
@@ -69,6 +69,12 @@ func (s *Server) RegisterAPIs() {
 
 	s.registerDBRoutes() //
 
+	s.registerLedgerRoutes()
+
+	s.registerConsentRoutes()
+
+	s.registerTrustRoutes()
+
 	s.registerVersionRoutes()
 	s.registerMirrorSpinRoutes() //
 	//s.registerStatusRoutes()
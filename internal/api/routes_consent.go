@@ -0,0 +1,370 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dis-core/internal/consent"
+	"dis-core/internal/ledger"
+	"dis-core/internal/util/crypto"
+)
+
+// openConsentRequest is the body of POST /api/consent/requests.
+type openConsentRequest struct {
+	consent.OpenRequest
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+}
+
+// consentRequestView adds the running tally to a request.
+type consentRequestView struct {
+	*consent.Request
+	Tally consent.Tally `json:"tally"`
+}
+
+func viewConsentRequest(r *consent.Request) consentRequestView {
+	return consentRequestView{Request: r, Tally: r.Tally()}
+}
+
+// registerConsentRoutes exposes the multi-party consent request lifecycle.
+// Votes are signed by the voter over consent.VoteDigest, with a key the
+// node holds or one the party registered here and keeps itself. A granted
+// request, named by evidence_id and loaded here, is the evidence
+// Gate.VerifyConsent accepts for affected individuals.
+//
+// Exposes:
+//   - POST /api/consent/requests              → open a request
+//   - GET  /api/consent/requests[?state=&limit=] → list requests, newest first
+//   - GET  /api/consent/requests/{id}         → request with votes and tally
+//   - POST /api/consent/requests/{id}/votes   → cast a signed approve/reject vote
+//   - POST /api/consent/authorize             → decide a ConsentRequest; 403 if blocked,
+//     429 with Retry-After while the initiator is throttled
+//   - POST /api/consent/simulate              → replay past decisions under a candidate config
+//   - POST /api/consent/mandates              → store a mandate signed by its grantor
+//   - GET  /api/consent/mandates[?grantor=&delegate=] → list mandates
+//   - GET  /api/consent/mandates/{id}         → one mandate
+//   - POST /api/consent/mandates/{id}/revoke  → record the grantor's signed revocation
+//   - POST /api/consent/keys                  → register a party's own public key
+//   - GET  /api/consent/keys/{party}          → the party's key history
+//
+// Requests, mandates, decisions and simulation reports are returned as
+// redacted views when a redaction policy is loaded.
+//
+// A proxy vote (on_behalf_of without a delegation) and an initiator whose
+// via names only a principal have their mandate chain resolved from the
+// stored mandates; chains sent in full are reloaded from them.
+func (s *Server) registerConsentRoutes() {
+	mux := s.mux
+	s.ConsentMandates = consent.NewMandates(consent.NewPGMandateStore(s.db), s.Store)
+	s.ConsentRequests = consent.NewRequests(consent.NewPGRequestStore(s.db), s.Store).WithMandates(s.ConsentMandates)
+
+	mux.HandleFunc("/api/consent/requests", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			var body openConsentRequest
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+				return
+			}
+			body.TTL = time.Duration(body.TTLSeconds) * time.Second
+			req, err := s.ConsentRequests.Open(body.OpenRequest)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+				return
+			}
+			writeRedacted(w, http.StatusCreated, viewConsentRequest(req))
+
+		case http.MethodGet:
+			q := r.URL.Query()
+			limit, _ := strconv.Atoi(q.Get("limit"))
+			list, err := s.ConsentRequests.List(consent.RequestState(q.Get("state")), limit)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+				return
+			}
+			items := make([]consentRequestView, 0, len(list))
+			for _, req := range list {
+				items = append(items, viewConsentRequest(req))
+			}
+			writeRedactedList(w, len(items), items)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/consent/requests/", func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, "/api/consent/requests/")
+		id, voting := strings.CutSuffix(rest, "/votes")
+		if id == "" || strings.Contains(id, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch {
+		case !voting && r.Method == http.MethodGet:
+			req, err := s.ConsentRequests.Get(id)
+			if err != nil {
+				writeConsentError(w, err)
+				return
+			}
+			writeRedacted(w, http.StatusOK, viewConsentRequest(req))
+
+		case voting && r.Method == http.MethodPost:
+			var vote consent.Vote
+			if err := json.NewDecoder(r.Body).Decode(&vote); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+				return
+			}
+			req, err := s.ConsentRequests.Vote(id, vote)
+			if err != nil {
+				writeConsentError(w, err)
+				return
+			}
+			writeRedacted(w, http.StatusOK, viewConsentRequest(req))
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/consent/authorize", s.handleConsentAuthorize)
+	mux.HandleFunc("/api/consent/simulate", s.handleConsentSimulate)
+
+	mux.HandleFunc("/api/consent/mandates", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			var m consent.Mandate
+			if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+				return
+			}
+			issued, err := s.ConsentMandates.Issue(&m)
+			if err != nil {
+				writeConsentError(w, err)
+				return
+			}
+			writeRedacted(w, http.StatusCreated, issued)
+
+		case http.MethodGet:
+			q := r.URL.Query()
+			list, err := s.ConsentMandates.List(q.Get("grantor"), q.Get("delegate"))
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+				return
+			}
+			if list == nil {
+				list = []*consent.Mandate{}
+			}
+			writeRedactedList(w, len(list), list)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/consent/mandates/", func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, "/api/consent/mandates/")
+		id, revoking := strings.CutSuffix(rest, "/revoke")
+		if id == "" || strings.Contains(id, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch {
+		case !revoking && r.Method == http.MethodGet:
+			m, err := s.ConsentMandates.Get(id)
+			if err != nil {
+				writeConsentError(w, err)
+				return
+			}
+			writeRedacted(w, http.StatusOK, m)
+
+		case revoking && r.Method == http.MethodPost:
+			var body struct {
+				RevokedAt string `json:"revoked_at"`
+				KeyID     string `json:"key_id"`
+				Signature string `json:"signature"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+				return
+			}
+			m, err := s.ConsentMandates.Revoke(id, body.RevokedAt, body.KeyID, body.Signature)
+			if err != nil {
+				writeConsentError(w, err)
+				return
+			}
+			writeRedacted(w, http.StatusOK, m)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/consent/keys", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var reg consent.KeyRegistration
+		if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+			return
+		}
+		key, err := consent.RegisterPartyKey(reg, s.db)
+		if err != nil {
+			writeConsentError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, key)
+	})
+
+	mux.HandleFunc("/api/consent/keys/", func(w http.ResponseWriter, r *http.Request) {
+		party := strings.TrimPrefix(r.URL.Path, "/api/consent/keys/")
+		if party == "" || strings.Contains(party, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		history, err := crypto.DefaultKeyStore().History(party)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		if history == nil {
+			history = []crypto.KeyRecord{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"count": len(history), "items": history})
+	})
+}
+
+func (s *Server) handleConsentAuthorize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.Consent == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"error": "consent gate not configured"})
+		return
+	}
+	var req consent.ConsentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json"})
+		return
+	}
+	req.Evidence = nil
+	if req.EvidenceID != "" {
+		if s.ConsentRequests == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"error": "consent requests not configured"})
+			return
+		}
+		evidence, err := s.ConsentRequests.Get(req.EvidenceID)
+		if err != nil {
+			writeConsentError(w, err)
+			return
+		}
+		req.Evidence = evidence
+	}
+	if req.Initiator.Via != nil {
+		// Mandates are always read from the store, whatever the client sent.
+		if s.ConsentMandates == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"error": "consent mandates not configured"})
+			return
+		}
+		if err := s.ConsentMandates.ResolveActor(&req.Initiator, req.Action, req.SchemaRef); err != nil {
+			writeConsentError(w, err)
+			return
+		}
+	}
+	dec, rcpt, err := s.Consent.AuthorizeAction(r.Context(), req)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	resp := map[string]any{"decision": dec}
+	if rcpt != nil {
+		resp["receipt_id"] = rcpt.ReceiptID
+	}
+	switch {
+	case dec.Allowed:
+		writeRedacted(w, http.StatusOK, resp)
+	case dec.ThrottleUntil != nil:
+		wait := time.Until(*dec.ThrottleUntil)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(math.Max(wait.Seconds(), 1)))))
+		writeRedacted(w, http.StatusTooManyRequests, resp)
+	default:
+		writeRedacted(w, http.StatusForbidden, resp)
+	}
+}
+
+// simulateRequest is the body of POST /api/consent/simulate. Config is the
+// candidate consent config, either as an object or as YAML text; Query and
+// Limit select the decision receipts to replay.
+type simulateRequest struct {
+	Config json.RawMessage `json:"config"`
+	Query  string          `json:"q,omitempty"`
+	Limit  int             `json:"limit,omitempty"`
+	All    bool            `json:"all,omitempty"`
+}
+
+func (s *Server) handleConsentSimulate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var body simulateRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Config) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "bad json: config required"})
+		return
+	}
+	// JSON is YAML, so an object parses as is; a string holds YAML text.
+	src := []byte(body.Config)
+	var text string
+	if json.Unmarshal(body.Config, &text) == nil {
+		src = []byte(text)
+	}
+	cfg, err := consent.ParseConfig(src, s.ConsentSchema)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	receipts, err := consent.LoadDecisions(s.Store, body.Query, body.Limit)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ledger.ErrBadQuery) {
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, map[string]any{"error": err.Error()})
+		return
+	}
+	rep, err := consent.Simulate(r.Context(), receipts, cfg, consent.SimulateOptions{All: body.All})
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	writeRedacted(w, http.StatusOK, rep)
+}
+
+func writeConsentError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, consent.ErrRequestNotFound), errors.Is(err, consent.ErrMandateNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, consent.ErrNotAffected), errors.Is(err, consent.ErrNoMandate):
+		status = http.StatusForbidden
+	case errors.Is(err, consent.ErrRequestClosed), errors.Is(err, consent.ErrAlreadyVoted):
+		status = http.StatusConflict
+	case errors.Is(err, consent.ErrBadVote), errors.Is(err, consent.ErrBadMandate),
+		errors.Is(err, consent.ErrBadKeyRegistration):
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, map[string]any{"error": err.Error()})
+}
@@ -25,14 +25,17 @@ func (s *Server) RegisterEvalRoute(engine policy.PolicyEngine) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		receipt := ledger.NewReceipt(
+		receipt, err := ledger.NewReceipt(
 			input["by"].(string),
 			input["action"].(string),
 			"",          // TODO: frozenCoreHash
 			"console-1", // TODO: consoleID
 			"seat-1",    // TODO: issuerSeat
 		)
-		if err := ledger.SaveReceipt(receipt); err != nil {
+		if err == nil {
+			err = ledger.SaveReceipt(receipt)
+		}
+		if err != nil {
 			log.Printf("receipt save error: %v", err)
 		}
 		w.Header().Set("Content-Type", "application/json")
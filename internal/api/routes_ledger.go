@@ -0,0 +1,140 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"dis-core/internal/ledger"
+)
+
+// registerLedgerRoutes exposes Merkle proofs over the receipt chain so that
+// auditors can check individual receipts without downloading the ledger.
+//
+// Exposes:
+//   - GET /api/receipts/{id}/proof[?size=N] → inclusion proof + signed tree head
+//   - GET /api/receipts/{id}/attestations   → peer attestations of the receipt
+//   - GET /api/ledger/head                  → signed tree head for the current ledger
+//   - GET /api/ledger/consistency?from=N&to=M → consistency proof between two tree heads
+//
+// Proofs and attestations are returned as redacted views when a redaction
+// policy is loaded.
+func (s *Server) registerLedgerRoutes() {
+	mux := s.mux
+
+	mux.HandleFunc("/api/receipts/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		rest := strings.TrimPrefix(r.URL.Path, "/api/receipts/")
+		if id, ok := strings.CutSuffix(rest, "/attestations"); ok && id != "" && !strings.Contains(id, "/") {
+			s.handleAttestations(w, id)
+			return
+		}
+		id, ok := strings.CutSuffix(rest, "/proof")
+		if !ok || id == "" || strings.Contains(id, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		size, err := parseTreeSize(r.URL.Query().Get("size"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			return
+		}
+
+		proof, err := s.Store.ProveReceipt(id, size)
+		if err != nil {
+			writeJSON(w, proofStatus(err), map[string]any{"error": err.Error()})
+			return
+		}
+		writeRedacted(w, http.StatusOK, proof)
+	})
+
+	mux.HandleFunc("/api/ledger/head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		head, err := s.Store.TreeHead()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeRedacted(w, http.StatusOK, head)
+	})
+
+	mux.HandleFunc("/api/ledger/consistency", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		from, err1 := parseTreeSize(q.Get("from"))
+		to, err2 := parseTreeSize(q.Get("to"))
+		if err1 != nil || err2 != nil || q.Get("from") == "" || q.Get("to") == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "from and to must be tree sizes"})
+			return
+		}
+
+		proof, err := s.Store.ProveConsistency(from, to)
+		if err != nil {
+			writeJSON(w, proofStatus(err), map[string]any{"error": err.Error()})
+			return
+		}
+		writeRedacted(w, http.StatusOK, proof)
+	})
+}
+
+// handleAttestations lists the trust entries recorded for a receipt and
+// how many independent peers attested it.
+func (s *Server) handleAttestations(w http.ResponseWriter, id string) {
+	entries, err := ledger.NewPGTrustStore(s.db).ForReceipt(id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	if entries == nil {
+		entries = []ledger.TrustEntry{}
+	}
+	items, err := redactItems(entries)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"receipt_id":   id,
+		"attesters":    ledger.CountAttesters(entries),
+		"attestations": items,
+	})
+}
+
+// proofStatus maps a proof error to its HTTP status: unknown receipts are
+// 404, receipts outside the chain 409, sizes the log does not have 400,
+// and storage failures 500.
+func proofStatus(err error) int {
+	switch {
+	case errors.Is(err, ledger.ErrReceiptNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ledger.ErrReceiptNotChained):
+		return http.StatusConflict
+	case errors.Is(err, ledger.ErrBadTreeSize):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parseTreeSize parses an optional non-negative tree size; empty means zero.
+func parseTreeSize(v string) (uint64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, errors.New("tree size must be a non-negative integer")
+	}
+	return n, nil
+}
@@ -3,22 +3,40 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+
+	"dis-core/internal/net"
 )
 
+// registerNetworkRoutes exposes the peer records dis-netd keeps in the
+// peers table.
+//
+// Exposes:
+//   - GET /api/net/peers[?status=healthy] → peer records, best scored first
 func (s *Server) registerNetworkRoutes() {
 	mux := s.mux
 
 	mux.HandleFunc("/api/net/peers", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			peers := []string{"localhost"} // placeholder list
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]any{
-				"count": len(peers),
-				"peers": peers,
-			})
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		records, err := net.LoadPeerRecords(s.db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		peers := []*net.Peer{}
+		status := r.URL.Query().Get("status")
+		for _, p := range records {
+			if status == "" || string(p.Status) == status {
+				peers = append(peers, p)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"count": len(peers),
+			"peers": peers,
+		})
 	})
 
 }
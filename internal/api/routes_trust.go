@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"dis-core/internal/feedback"
+	"dis-core/internal/trust"
+)
+
+// registerTrustRoutes exposes scores from the shared trust store
+// (trust.DefaultStore):
+//   - GET /api/trust/{actor}[?as_of=receipt_id] → current or point-in-time scores
+//   - GET /api/trust/{actor}/history            → every recorded change
+func (s *Server) registerTrustRoutes() {
+	s.mux.HandleFunc("/api/trust/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		rest := strings.TrimPrefix(r.URL.Path, "/api/trust/")
+		actor, history := strings.CutSuffix(rest, "/history")
+		if actor == "" || strings.Contains(actor, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		store := trust.DefaultStore()
+		hs, ok := store.(feedback.HistoryStore)
+
+		if history {
+			if !ok {
+				writeJSON(w, http.StatusNotImplemented, map[string]any{"error": "trust store keeps no history"})
+				return
+			}
+			changes, err := hs.History(r.Context(), actor)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"actor": actor, "history": changes})
+			return
+		}
+
+		resp := map[string]any{"actor": actor}
+		asOf := r.URL.Query().Get("as_of")
+		if asOf != "" && !ok {
+			writeJSON(w, http.StatusNotImplemented, map[string]any{"error": "trust store keeps no history"})
+			return
+		}
+		for _, kind := range []string{feedback.KindTrust, feedback.KindEthics} {
+			var v float64
+			var found bool
+			var err error
+			switch {
+			case asOf != "":
+				v, found, err = hs.ScoreAsOf(r.Context(), actor, kind, asOf)
+			case kind == feedback.KindTrust:
+				v, found, err = store.GetTrust(r.Context(), actor)
+			default:
+				v, found, err = store.GetEthics(r.Context(), actor)
+			}
+			if errors.Is(err, feedback.ErrUnknownReceipt) {
+				writeJSON(w, http.StatusNotFound, map[string]any{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+				return
+			}
+			if found {
+				resp[kind] = v
+			}
+		}
+		if asOf != "" {
+			resp["as_of"] = asOf
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"dis-core/internal/config"
+	"dis-core/internal/consent"
 	"dis-core/internal/domain"
 	"dis-core/internal/ledger"
 	"dis-core/internal/overlay"
@@ -47,6 +48,19 @@ type Server struct {
 
 	// Optional schema registry (for validation)
 	schemas *schema.Registry
+
+	// Optional consent gate (hot-reloaded from policies/consent.yaml)
+	Consent *consent.Gate
+
+	// Optional consent config schema; candidate configs posted to
+	// /api/consent/simulate are validated against it
+	ConsentSchema *consent.Schema
+
+	// Consent request lifecycle (see routes_consent.go)
+	ConsentRequests *consent.Requests
+
+	// Consent delegation mandates (see routes_consent.go)
+	ConsentMandates *consent.Mandates
 }
 
 // Mux returns the internal HTTP mux for this server.
@@ -221,6 +235,18 @@ func (s *Server) WithLogger(l *log.Logger) *Server {
 	return s
 }
 
+// WithConsent sets the consent gate and returns the server (chainable)
+func (s *Server) WithConsent(g *consent.Gate) *Server {
+	s.Consent = g
+	return s
+}
+
+// WithConsentSchema sets the consent config schema and returns the server (chainable)
+func (s *Server) WithConsentSchema(sch *consent.Schema) *Server {
+	s.ConsentSchema = sch
+	return s
+}
+
 // WithSchemas sets a schema registry and returns the server (chainable)
 func (s *Server) WithSchemas(reg *schema.Registry) *Server {
 	s.schemas = reg
@@ -3,6 +3,8 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+
+	"dis-core/internal/redaction"
 )
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -10,3 +12,39 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
+
+// writeRedacted writes the redacted view of v when a redaction policy is
+// loaded, and v itself otherwise.
+func writeRedacted(w http.ResponseWriter, status int, v any) {
+	rp := redaction.Default()
+	if rp == nil {
+		writeJSON(w, status, v)
+		return
+	}
+	view, _, err := rp.Redact(v)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, status, view)
+}
+
+// redactItems redacts each element of a list response on its own, so rule
+// paths stay relative to the element rather than the envelope around it.
+func redactItems(items any) (any, error) {
+	rp := redaction.Default()
+	if rp == nil {
+		return items, nil
+	}
+	return rp.RedactAll(items)
+}
+
+// writeRedactedList writes {"count", "items"} with each item redacted.
+func writeRedactedList(w http.ResponseWriter, count int, items any) {
+	view, err := redactItems(items)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"count": count, "items": view})
+}
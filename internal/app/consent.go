@@ -0,0 +1,27 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"dis-core/internal/consent"
+)
+
+// SetupConsent registers the Rego consent rules under policyDir/consent with
+// the default rule registry and loads the consent config schema, which
+// validates rule IDs against that registry.
+func SetupConsent(policyDir string) (*consent.Schema, error) {
+	regoRules, err := consent.LoadRegoRules(filepath.Join(policyDir, "consent"), consent.DefaultRules())
+	if err != nil {
+		return nil, fmt.Errorf("consent rules: %w", err)
+	}
+	if len(regoRules) > 0 {
+		log.Printf("✅ Registered Rego consent rules: %v", regoRules)
+	}
+	schema, err := consent.LoadSchema("./disyaml/schemas")
+	if err != nil {
+		return nil, fmt.Errorf("consent schema: %w", err)
+	}
+	return schema, nil
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"os"
+
+	"dis-core/internal/ledger"
+	"dis-core/internal/util/crypto"
+)
+
+// keyDir is where file-backed key stores keep domain keys.
+const keyDir = "versions/v0.6/keys"
+
+// peerKeyDir is where the public keys of peer domains are pinned, apart
+// from the node's own key store.
+const peerKeyDir = keyDir + "/peers"
+
+// SetupKeyStore selects the process key store. With DIS_KEY_PASSPHRASE set,
+// private keys are kept encrypted; otherwise the plain file store is used.
+func SetupKeyStore() error {
+	pass := os.Getenv("DIS_KEY_PASSPHRASE")
+	if pass == "" {
+		crypto.SetDefaultKeyStore(crypto.NewFileKeyStore(keyDir))
+		return nil
+	}
+	ks, err := crypto.NewEncryptedFileKeyStore(keyDir, pass)
+	if err != nil {
+		return err
+	}
+	crypto.SetDefaultKeyStore(ks)
+	log.Println("🔐 Using encrypted key store")
+	return nil
+}
+
+// PeerKeyStore returns the store peer domains' public keys are pinned in.
+// It holds no private keys and is never the default KeyStore.
+func PeerKeyStore() crypto.KeyStore {
+	return crypto.NewFileKeyStore(peerKeyDir)
+}
+
+// ensureNodeKey generates the node's signing key on first start, recording a
+// key.genesis.v1 receipt. Other domains must be generated explicitly with
+// `dis-core keys generate`.
+func ensureNodeKey() error {
+	_, err := crypto.DefaultKeyStore().Active(ledger.NodeDomain)
+	if !errors.Is(err, crypto.ErrNoActiveKey) {
+		return err
+	}
+	key, _, err := ledger.GenerateDomainKey(ledger.NodeDomain)
+	if err != nil {
+		return err
+	}
+	log.Printf("🔑 Generated node key %s for %s", key.KeyID, ledger.NodeDomain)
+	return nil
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"log"
+
+	"dis-core/internal/config"
+	"dis-core/internal/db"
+	"dis-core/internal/ledger"
+	"dis-core/internal/schema"
+)
+
+// OpenReceiptStore opens the node's Postgres receipt store for command-line
+// tools, using config.yaml when present. The returned closer releases the
+// connection.
+func OpenReceiptStore() (ledger.ReceiptStore, func() error, error) {
+	cfg, err := config.Load("config.yaml")
+	if err != nil {
+		cfg = &config.Config{}
+	}
+	database, err := db.Connect(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := db.EnsureReceiptsSchema(database); err != nil {
+		database.Close()
+		return nil, nil, err
+	}
+	return ledger.NewStore(database), database.Close, nil
+}
+
+// SchemaRegistryHash loads the disyaml schema tree the node serves and
+// returns its registry hash.
+func SchemaRegistryHash() string {
+	reg := schema.NewRegistry()
+	for _, dir := range []string{"./disyaml/schemas", "./disyaml/domains"} {
+		if err := reg.LoadDir(dir); err != nil {
+			log.Printf("⚠️  Schema load from %s failed: %v", dir, err)
+		}
+	}
+	return reg.HashAll()
+}
@@ -1,14 +1,19 @@
 package app
 
 import (
+	"context"
 	"dis-core/internal/api"
 	"dis-core/internal/bootstrap"
 	"dis-core/internal/config"
+	"dis-core/internal/consent"
 	"dis-core/internal/db"
+	"dis-core/internal/feedback"
 	"dis-core/internal/ledger"
 	"dis-core/internal/mirrorspin"
 	"dis-core/internal/policy"
+	"dis-core/internal/redaction"
 	"dis-core/internal/schema"
+	"dis-core/internal/trust"
 	"fmt"
 	"log"
 	"net/http"
@@ -28,6 +33,10 @@ func Run() error {
 		cfg = &config.Config{}
 	}
 
+	if err := SetupKeyStore(); err != nil {
+		return fmt.Errorf("key store: %w", err)
+	}
+
 	// ------------------------------------------------------------
 	// 1. Connect to database
 	// ------------------------------------------------------------
@@ -60,9 +69,21 @@ func Run() error {
 		return err
 	}
 	defer led.Close()
+	// Receipts emitted in-process (console, Jikka, daemons) go to Postgres.
+	ledger.SetDefaultStore(ledger.NewStore(led.DB))
+	if err := ensureNodeKey(); err != nil {
+		return fmt.Errorf("node key: %w", err)
+	}
+	if err := setupTimestamping(ledger.DefaultStore()); err != nil {
+		return fmt.Errorf("timestamp authority: %w", err)
+	}
 	log.Println("✅ Ledger ready")
 
 	domainDir := filepath.Join(".", "disyaml/domains")
+	domains, err := led.LoadDomainsFromFS(domainDir, reg)
+	if err != nil {
+		log.Printf("⚠️  Domain seat registry unavailable: %v", err)
+	}
 	if err := led.BootstrapDomains(reg, domainDir); err != nil {
 		log.Printf("⚠️  Domain bootstrap failed: %v", err)
 	} else {
@@ -99,10 +120,39 @@ func Run() error {
 	engine := policy.NewPolicyEngineImpl(opaEngine)
 	log.Printf("✅ Policy engine initialized (using %s)", base)
 
+	rp, err := redaction.LoadPolicy(filepath.Join(base, "redaction.yaml"))
+	if err != nil {
+		return fmt.Errorf("redaction policy: %w", err)
+	}
+	redaction.SetDefault(rp)
+
+	consentSchema, err := SetupConsent(base)
+	if err != nil {
+		return err
+	}
+	consentPath := filepath.Join(base, "consent.yaml")
+	consentCfg, err := consent.LoadConfig(consentPath, consentSchema)
+	if err != nil {
+		return fmt.Errorf("consent config: %w", err)
+	}
+	// One trust model: domain feedback, the feedback loop and actor
+	// hydration all score in the same store.
+	trustStore := feedback.NewPGStore(database)
+	trust.SetDefaultStore(trustStore)
+	resolver := consent.NewStoreResolver(trustStore, database, consent.NewDomainSeats(domains))
+	throttle := consent.NewThrottleLedger(consent.NewPGThrottleStore(database))
+	sink := consent.NewLoopSink(feedback.NewLoop(trustStore, nil)).WithLedger(throttle)
+	gate := consent.NewGate(consentCfg, consentCfg.Version, sink, ledger.DefaultStore()).
+		WithThrottle(throttle).
+		WithResolver(resolver)
+	go gate.Watch(context.Background(), consentPath, consentSchema, consent.DefaultReloadInterval)
+	log.Printf("✅ Consent gate configured from %s (%s)", consentPath, consentCfg.Version)
+
 	// ------------------------------------------------------------
 	// 6. Start API server
 	// ------------------------------------------------------------
 	server := api.NewServer(cfg, led, database)
+	server.WithConsent(gate).WithConsentSchema(consentSchema)
 	server.RegisterEvalRoute(engine)
 	log.Println("✅ Registered route(s)")
 
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"log"
+
+	"dis-core/internal/ledger"
+)
+
+// setupTimestamping makes the node its own timestamp authority, signing
+// tokens with the node key. Numbering resumes after the highest serial
+// and latest time this authority issued anywhere in the chain, so tokens
+// keep increasing across restarts even when receipts share a created_at.
+func setupTimestamping(store ledger.ReceiptStore) error {
+	tsa := ledger.NewLocalTSA(ledger.NodeDomain)
+	chain, err := store.Chain()
+	if err != nil {
+		return err
+	}
+	for _, e := range chain {
+		if r := e.Receipt; r != nil && r.Timestamp != nil && r.Timestamp.TSA == tsa.Name {
+			tsa.Resume(r.Timestamp)
+		}
+	}
+	ledger.SetDefaultTSA(tsa)
+	log.Printf("⏱️  Timestamping receipts as %s", ledger.NodeDomain)
+	return nil
+}
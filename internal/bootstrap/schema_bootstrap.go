@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"log"
 
+	"dis-core/internal/consent"
 	"dis-core/internal/db"
 	"dis-core/internal/domain"
+	"dis-core/internal/feedback"
 	"dis-core/internal/ledger"
 	"dis-core/internal/mirrorspin"
 	"dis-core/internal/net"
@@ -33,6 +35,13 @@ func BootstrapAllTables(dbConn *sql.DB) error {
 		{"handshakes", db.EnsureHandshakesSchema},
 		{"import_receipts", ledger.EnsureImportReceiptsSchema},
 		{"receipts", db.EnsureReceiptsSchema},
+		{"consent_requests", consent.EnsureRequestsSchema},
+		{"consent_throttle", consent.EnsureThrottleSchema},
+		{"consent_mandates", consent.EnsureMandatesSchema},
+		{"receipt_status", ledger.EnsureReceiptStatusSchema},
+		{"trust_scores", feedback.EnsureTrustSchema},
+		{"foreign_receipts", net.EnsureForeignReceiptsTable},
+		{"trust_entries", ledger.EnsureTrustEntriesSchema},
 	}
 
 	for _, step := range steps {
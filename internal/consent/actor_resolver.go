@@ -0,0 +1,211 @@
+package consent
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+
+	"dis-core/internal/db"
+	"dis-core/internal/feedback"
+	"dis-core/internal/ledger"
+)
+
+// Hydration sources: where an ActorRef field's value came from.
+const (
+	SourceSelfReported = "self-reported" // as sent by the caller, unverified
+	SourceDefault      = "default"       // no record; the neutral default
+	SourceFeedback     = "feedback"      // the feedback TrustStore
+	SourceIdentities   = "identities"    // the identities table
+	SourceSeats        = "seats"         // the domain seat registry
+)
+
+// neutralScore is the trust and ethics of an actor with no feedback
+// history, as in feedback.Loop.
+const neutralScore = 0.5
+
+// DefaultSeatLegitimacy is the legitimacy floor of an actor holding a
+// domain seat.
+const DefaultSeatLegitimacy = 0.8
+
+// Hydration records where each of an actor's fields came from when the
+// gate evaluated it.
+type Hydration struct {
+	Actor      string `json:"actor"`
+	Trust      string `json:"trust"`
+	Legitimacy string `json:"legitimacy"`
+	Domain     string `json:"domain"`
+	Seats      string `json:"seats,omitempty"`
+}
+
+// ActorResolver fills in an actor's trust, legitimacy, domain and seats
+// from authoritative records, replacing whatever the caller sent.
+type ActorResolver interface {
+	Hydrate(ctx context.Context, ref ActorRef) (ActorRef, Hydration, error)
+}
+
+// SeatRegistry reports the domain seats an actor holds.
+type SeatRegistry interface {
+	SeatsOf(actorID string) ([]string, error)
+}
+
+// DomainSeats is a SeatRegistry built from domain records: an actor holds
+// the seat of every domain naming it as authority.
+type DomainSeats struct {
+	mu    sync.RWMutex
+	seats map[string][]string
+}
+
+// NewDomainSeats indexes the authorities of records.
+func NewDomainSeats(records []*ledger.DomainRecord) *DomainSeats {
+	s := &DomainSeats{seats: map[string][]string{}}
+	for _, r := range records {
+		if r.Authority != "" && r.ID != "" {
+			s.Assign(r.Authority, r.ID)
+		}
+	}
+	return s
+}
+
+// Assign gives actorID the seat of domainID.
+func (s *DomainSeats) Assign(actorID, domainID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.seats[actorID] {
+		if d == domainID {
+			return
+		}
+	}
+	s.seats[actorID] = append(s.seats[actorID], domainID)
+	sort.Strings(s.seats[actorID])
+}
+
+func (s *DomainSeats) SeatsOf(actorID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.seats[actorID]...), nil
+}
+
+// StoreResolver hydrates actors from the feedback TrustStore, the
+// identities table and a seat registry. Any of them may be nil.
+//
+// Trust is the actor's feedback trust score and legitimacy its ethics
+// score, both neutral without history; seat holders get at least
+// SeatLegitimacy. The actor's domain is its identity's namespace; the
+// domain the caller sent is discarded, so an actor with no identity
+// record is treated as an individual. An inactive identity zeroes trust
+// and legitimacy.
+type StoreResolver struct {
+	trust          feedback.TrustStore
+	identities     *sql.DB
+	seats          SeatRegistry
+	SeatLegitimacy float64
+}
+
+// NewStoreResolver returns a resolver over the given sources.
+func NewStoreResolver(trust feedback.TrustStore, identities *sql.DB, seats SeatRegistry) *StoreResolver {
+	return &StoreResolver{trust: trust, identities: identities, seats: seats, SeatLegitimacy: DefaultSeatLegitimacy}
+}
+
+func (r *StoreResolver) Hydrate(ctx context.Context, ref ActorRef) (ActorRef, Hydration, error) {
+	out := ref
+	h := Hydration{Actor: ref.ID, Trust: SourceDefault, Legitimacy: SourceDefault, Domain: SourceDefault}
+	out.Trust, out.Legitimacy, out.Domain = neutralScore, neutralScore, ""
+
+	if r.trust != nil {
+		t, ok, err := r.trust.GetTrust(ctx, ref.ID)
+		if err != nil {
+			return ActorRef{}, Hydration{}, fmt.Errorf("trust of %s: %w", ref.ID, err)
+		}
+		if ok {
+			out.Trust, h.Trust = t, SourceFeedback
+		}
+		e, ok, err := r.trust.GetEthics(ctx, ref.ID)
+		if err != nil {
+			return ActorRef{}, Hydration{}, fmt.Errorf("ethics of %s: %w", ref.ID, err)
+		}
+		if ok {
+			out.Legitimacy, h.Legitimacy = e, SourceFeedback
+		}
+	}
+
+	if r.seats != nil {
+		seats, err := r.seats.SeatsOf(ref.ID)
+		if err != nil {
+			return ActorRef{}, Hydration{}, fmt.Errorf("seats of %s: %w", ref.ID, err)
+		}
+		out.Seats, h.Seats = seats, SourceSeats
+		if len(seats) > 0 && out.Legitimacy < r.SeatLegitimacy {
+			out.Legitimacy, h.Legitimacy = r.SeatLegitimacy, SourceSeats
+		}
+	} else if len(ref.Seats) > 0 {
+		h.Seats = SourceSelfReported
+	}
+
+	if r.identities != nil {
+		ident, err := db.GetIdentity(r.identities, ref.ID)
+		if err != nil {
+			return ActorRef{}, Hydration{}, err
+		}
+		if ident != nil {
+			if ident.Namespace != "" {
+				out.Domain, h.Domain = ident.Namespace, SourceIdentities
+			}
+			if !ident.Active {
+				out.Trust, out.Legitimacy = 0, 0
+				h.Trust, h.Legitimacy = SourceIdentities, SourceIdentities
+			}
+		}
+	}
+	return out, h, nil
+}
+
+// selfReported is the hydration of an actor taken as sent.
+func selfReported(ref ActorRef) Hydration {
+	h := Hydration{Actor: ref.ID, Trust: SourceSelfReported, Legitimacy: SourceSelfReported, Domain: SourceSelfReported}
+	if len(ref.Seats) > 0 {
+		h.Seats = SourceSelfReported
+	}
+	return h
+}
+
+func (g *Gate) actorResolver() ActorResolver {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.resolver
+}
+
+// hydrate returns req with its initiator and affected parties resolved,
+// and where each one's standing came from. Without a resolver req is
+// returned as sent and marked self-reported.
+func (g *Gate) hydrate(ctx context.Context, req ConsentRequest) (ConsentRequest, []Hydration, error) {
+	r := g.actorResolver()
+	hs := make([]Hydration, 0, 1+len(req.Affected))
+	if r == nil {
+		hs = append(hs, selfReported(req.Initiator))
+		for _, a := range req.Affected {
+			hs = append(hs, selfReported(a))
+		}
+		return req, hs, nil
+	}
+
+	initiator, h, err := r.Hydrate(ctx, req.Initiator)
+	if err != nil {
+		return req, nil, fmt.Errorf("hydrate initiator: %w", err)
+	}
+	req.Initiator = initiator
+	hs = append(hs, h)
+	var affected []ActorRef
+	if req.Affected != nil {
+		affected = make([]ActorRef, len(req.Affected))
+	}
+	for i, a := range req.Affected {
+		if affected[i], h, err = r.Hydrate(ctx, a); err != nil {
+			return req, nil, fmt.Errorf("hydrate affected %s: %w", a.ID, err)
+		}
+		hs = append(hs, h)
+	}
+	req.Affected = affected
+	return req, hs, nil
+}
@@ -0,0 +1,66 @@
+package consent_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"dis-core/internal/consent"
+	"dis-core/internal/feedback"
+	"dis-core/internal/ledger"
+)
+
+func TestStoreResolverHydrate(t *testing.T) {
+	ctx := context.Background()
+	scores := feedback.NewMemStore()
+	if err := scores.SetTrust(ctx, "alice", 0.9); err != nil {
+		t.Fatalf("SetTrust: %v", err)
+	}
+	if err := scores.SetEthics(ctx, "alice", 0.3); err != nil {
+		t.Fatalf("SetEthics: %v", err)
+	}
+	seats := consent.NewDomainSeats([]*ledger.DomainRecord{{ID: "domain.terra", Authority: "bob"}})
+	r := consent.NewStoreResolver(scores, nil, seats)
+
+	cases := []struct {
+		name  string
+		ref   consent.ActorRef
+		want  consent.ActorRef
+		trust string
+		legit string
+	}{
+		{
+			"feedback history",
+			consent.ActorRef{ID: "alice", Trust: 1, Legitimacy: 1},
+			consent.ActorRef{ID: "alice", Trust: 0.9, Legitimacy: 0.3, Seats: []string{}},
+			consent.SourceFeedback, consent.SourceFeedback,
+		},
+		{
+			"seat holder",
+			consent.ActorRef{ID: "bob", Seats: []string{"domain.null"}},
+			consent.ActorRef{ID: "bob", Trust: 0.5, Legitimacy: consent.DefaultSeatLegitimacy, Seats: []string{"domain.terra"}},
+			consent.SourceDefault, consent.SourceSeats,
+		},
+		{
+			"claimed domain is discarded",
+			consent.ActorRef{ID: "carol", Domain: "corp", Trust: 1},
+			consent.ActorRef{ID: "carol", Trust: 0.5, Legitimacy: 0.5, Seats: []string{}},
+			consent.SourceDefault, consent.SourceDefault,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, h, err := r.Hydrate(ctx, tc.ref)
+			if err != nil {
+				t.Fatalf("Hydrate: %v", err)
+			}
+			if got.ID != tc.want.ID || got.Trust != tc.want.Trust || got.Legitimacy != tc.want.Legitimacy ||
+				got.Domain != tc.want.Domain || strings.Join(got.Seats, ",") != strings.Join(tc.want.Seats, ",") {
+				t.Fatalf("hydrated %+v, want %+v", got, tc.want)
+			}
+			if h.Trust != tc.trust || h.Legitimacy != tc.legit || h.Domain != consent.SourceDefault || h.Seats != consent.SourceSeats {
+				t.Fatalf("hydration %+v", h)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package consent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"dis-core/internal/bridge"
+)
+
+// ErrInvalidConfig is returned when a consent config fails schema validation.
+var ErrInvalidConfig = errors.New("invalid consent config")
+
+// Schema files the loader validates against, relative to the schema dir.
+const (
+	schemaV01File = "dis_consent.v0.1.yaml"
+	schemaV02File = "schema.dis_consent.v0.2.disyaml"
+)
+
+// DynamicThreshold marks a gate rule whose threshold is resolved at runtime.
+const DynamicThreshold = -1
+
+// UnmarshalYAML decodes backoff_ms and window_ms as milliseconds.
+func (t *ThrottleRule) UnmarshalYAML(n *yaml.Node) error {
+	var raw struct {
+		Enabled       bool         `yaml:"enabled"`
+		LowTrustFloor float64      `yaml:"low_trust_floor"`
+		BackoffMS     int64        `yaml:"backoff_ms"`
+		Mode          ThrottleMode `yaml:"mode"`
+		MaxAttempts   int          `yaml:"max_attempts"`
+		WindowMS      int64        `yaml:"window_ms"`
+	}
+	if err := n.Decode(&raw); err != nil {
+		return err
+	}
+	t.Enabled = raw.Enabled
+	t.LowTrustFloor = raw.LowTrustFloor
+	t.Backoff = time.Duration(raw.BackoffMS) * time.Millisecond
+	t.Mode = raw.Mode
+	t.MaxAttempts = raw.MaxAttempts
+	t.Window = time.Duration(raw.WindowMS) * time.Millisecond
+	return nil
+}
+
+// LoadConfig reads a consent config and validates it against schema. A nil
+// schema skips validation.
+func LoadConfig(path string, schema *Schema) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data, schema)
+}
+
+// ParseConfig decodes and validates a consent config.
+func ParseConfig(data []byte, schema *Schema) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	if schema != nil {
+		if err := schema.Validate(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	return &cfg, nil
+}
+
+// Hash returns the hex SHA-256 of the config's canonical JSON, including
+// the throttle durations that the JSON form otherwise omits.
+func (c *Config) Hash() string {
+	doc := map[string]any{
+		"config":     c,
+		"backoff_ms": c.Throttle.Backoff.Milliseconds(),
+	}
+	if c.Throttle.Window != 0 {
+		doc["window_ms"] = c.Throttle.Window.Milliseconds()
+	}
+	canon, err := bridge.CanonicalJSON(doc)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(canon)
+	return hex.EncodeToString(sum[:])
+}
+
+// Schema holds the constraints a consent config must meet: the versions
+// and field ranges of dis_consent.v0.1 and v0.2. Gate rule IDs must be
+// registered in Rules, or in the default registry when Rules is nil; the
+// built-in rules are those dis_consent.v0.1 defines.
+type Schema struct {
+	Versions []string
+	Ranges   map[string][2]float64
+	Rules    *RuleRegistry
+}
+
+// LoadSchema reads the dis_consent schema files from dir.
+func LoadSchema(dir string) (*Schema, error) {
+	s := &Schema{Ranges: map[string][2]float64{}}
+
+	data, err := os.ReadFile(filepath.Join(dir, schemaV01File))
+	if err != nil {
+		return nil, err
+	}
+	var v01 Config
+	if err := yaml.Unmarshal(data, &v01); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", schemaV01File, err)
+	}
+	s.Versions = append(s.Versions, v01.Version)
+
+	data, err = os.ReadFile(filepath.Join(dir, schemaV02File))
+	if err != nil {
+		return nil, err
+	}
+	var v02 struct {
+		Meta struct {
+			SchemaVersion string `yaml:"schema_version"`
+		} `yaml:"meta"`
+		Fields []struct {
+			ID    string    `yaml:"id"`
+			Range []float64 `yaml:"range"`
+		} `yaml:"fields"`
+	}
+	if err := yaml.Unmarshal(data, &v02); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", schemaV02File, err)
+	}
+	for _, f := range v02.Fields {
+		if len(f.Range) == 2 {
+			s.Ranges[f.ID] = [2]float64{f.Range[0], f.Range[1]}
+		}
+	}
+	s.Versions = append(s.Versions, v02.Meta.SchemaVersion)
+	return s, nil
+}
+
+// Validate checks cfg against the schema, reporting every problem found.
+func (s *Schema) Validate(cfg *Config) error {
+	var problems []string
+	bad := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	known := false
+	for _, v := range s.Versions {
+		known = known || v == cfg.Version
+	}
+	if !known {
+		bad("version %q is not one of %s", cfg.Version, strings.Join(s.Versions, ", "))
+	}
+
+	if len(cfg.GateRules) == 0 {
+		bad("no gate_rules")
+	}
+	rules := s.Rules
+	if rules == nil {
+		rules = DefaultRules()
+	}
+	seen := map[string]bool{}
+	for i, r := range cfg.GateRules {
+		switch {
+		case r.ID == "":
+			bad("gate_rules[%d]: missing id", i)
+		case seen[r.ID]:
+			bad("gate_rules[%d]: duplicate id %q", i, r.ID)
+		default:
+			if _, ok := rules.Get(r.ID); !ok {
+				bad("gate_rules[%d]: unknown rule %q", i, r.ID)
+			}
+		}
+		seen[r.ID] = true
+		if rng, ok := s.Ranges["threshold"]; ok && r.Threshold != DynamicThreshold &&
+			(r.Threshold < rng[0] || r.Threshold > rng[1]) {
+			bad("gate_rules[%d] (%s): threshold %g outside [%g, %g]", i, r.ID, r.Threshold, rng[0], rng[1])
+		}
+	}
+
+	w := cfg.Weights
+	for _, wv := range []struct {
+		name string
+		v    float64
+	}{
+		{"trust_increase", w.TrustIncrease},
+		{"trust_decrease", w.TrustDecrease},
+		{"ethics_bonus", w.EthicsBonus},
+		{"ethics_penalty", w.EthicsPenalty},
+	} {
+		if wv.v < -1 || wv.v > 1 {
+			bad("weights.%s: %g outside [-1, 1]", wv.name, wv.v)
+		}
+	}
+	if f := cfg.Throttle.LowTrustFloor; f < 0 || f > 1 {
+		bad("throttle.low_trust_floor: %g outside [0, 1]", f)
+	}
+	if cfg.Throttle.Backoff < 0 {
+		bad("throttle.backoff_ms: negative")
+	}
+	switch cfg.Throttle.Mode {
+	case "", SlidingWindow, TokenBucket:
+	default:
+		bad("throttle.mode: %q is not %s or %s", cfg.Throttle.Mode, SlidingWindow, TokenBucket)
+	}
+	if cfg.Throttle.MaxAttempts < 0 {
+		bad("throttle.max_attempts: negative")
+	}
+	if cfg.Throttle.Window < 0 {
+		bad("throttle.window_ms: negative")
+	}
+	if cfg.Throttle.MaxAttempts > 0 && cfg.Throttle.window() == 0 {
+		bad("throttle.max_attempts: needs window_ms or backoff_ms")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package consent_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"dis-core/internal/consent"
+	"dis-core/internal/ledger"
+	"dis-core/internal/util/crypto"
+)
+
+func loadSchema(t *testing.T) *consent.Schema {
+	t.Helper()
+	schema, err := consent.LoadSchema("../../disyaml/schemas")
+	if err != nil {
+		t.Fatalf("LoadSchema: %v", err)
+	}
+	return schema
+}
+
+func shippedConfig(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("../../policies/consent.yaml")
+	if err != nil {
+		t.Fatalf("read consent.yaml: %v", err)
+	}
+	return data
+}
+
+func TestParseConfig(t *testing.T) {
+	schema := loadSchema(t)
+	shipped := string(shippedConfig(t))
+	edit := func(pairs ...string) string {
+		out := shipped
+		for i := 0; i < len(pairs); i += 2 {
+			if !strings.Contains(out, pairs[i]) {
+				t.Fatalf("consent.yaml has no %q", pairs[i])
+			}
+			out = strings.Replace(out, pairs[i], pairs[i+1], 1)
+		}
+		return out
+	}
+
+	cases := []struct {
+		name    string
+		yaml    string
+		problem string // substring of the error; empty when valid
+	}{
+		{"shipped", shipped, ""},
+		{"v0.2", edit("version: v0.1", "version: v0.2"), ""},
+		{"unknown version", edit("version: v0.1", "version: v9"), `version "v9"`},
+		{"unknown rule", edit("id: moral_feedback", "id: no_such_rule"), `unknown rule "no_such_rule"`},
+		{"duplicate rule", edit("id: moral_feedback", "id: trust_decay"), `duplicate id "trust_decay"`},
+		{"threshold out of range", edit("threshold: 0.80", "threshold: 1.5"), "threshold 1.5 outside"},
+		{"weight out of range", edit("trust_increase: 0.05", "trust_increase: 2"), "weights.trust_increase"},
+		{"unknown throttle mode", edit("mode: sliding_window", "mode: leaky"), "throttle.mode"},
+		{"rate without window", edit("backoff_ms: 15000", "backoff_ms: 0", "max_attempts: 0", "max_attempts: 3"), "needs window_ms or backoff_ms"},
+		{"not yaml", "gate_rules: [", "invalid consent config"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := consent.ParseConfig([]byte(tc.yaml), schema)
+			if tc.problem == "" {
+				if err != nil {
+					t.Fatalf("ParseConfig: %v", err)
+				}
+				if cfg.Throttle.Backoff != 15*time.Second || len(cfg.GateRules) != 4 {
+					t.Fatalf("decoded %+v", cfg)
+				}
+				return
+			}
+			if !errors.Is(err, consent.ErrInvalidConfig) || !strings.Contains(err.Error(), tc.problem) {
+				t.Fatalf("ParseConfig: %v, want %q", err, tc.problem)
+			}
+		})
+	}
+}
+
+// TestConfigReload swaps configs in through Watch and checks that each
+// change is receipted and that an invalid revision is ignored.
+func TestConfigReload(t *testing.T) {
+	schema := loadSchema(t)
+	shipped := shippedConfig(t)
+	path := filepath.Join(t.TempDir(), "consent.yaml")
+	if err := os.WriteFile(path, shipped, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := consent.LoadConfig(path, schema)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	store := ledger.NewMemoryStore()
+	gate := consent.NewGate(cfg, cfg.Version, nil, store)
+
+	if rcpt, err := gate.SetConfig(cfg, path); rcpt != nil || err != nil {
+		t.Fatalf("unchanged config receipted: %v, %v", rcpt, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go gate.Watch(ctx, path, schema, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond) // let Watch read the current revision
+
+	steps := []struct {
+		name     string
+		yaml     string
+		floor    float64
+		receipts int
+	}{
+		{"valid revision", strings.Replace(string(shipped), "low_trust_floor: 0.30", "low_trust_floor: 0.40", 1), 0.40, 1},
+		{"invalid revision kept out", strings.Replace(string(shipped), "low_trust_floor: 0.30", "low_trust_floor: 7", 1), 0.40, 1},
+		{"back to the original", string(shipped), 0.30, 2},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(step.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			deadline := time.Now().Add(2 * time.Second)
+			for {
+				list, err := store.List(ledger.ListOptions{SchemaRef: consent.ConfigSchemaRef})
+				if err != nil {
+					t.Fatalf("List: %v", err)
+				}
+				floor := gate.Config().Throttle.LowTrustFloor
+				if floor == step.floor && len(list) == step.receipts {
+					// Give an unwanted reload the chance to show up.
+					time.Sleep(20 * time.Millisecond)
+					if gate.Config().Throttle.LowTrustFloor == step.floor {
+						return
+					}
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("floor %v with %d receipts, want %v with %d", floor, len(list), step.floor, step.receipts)
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+		})
+	}
+}
+
+// downStore refuses appends while down is set.
+type downStore struct {
+	ledger.ReceiptStore
+	down atomic.Bool
+}
+
+func (s *downStore) Append(r *ledger.Receipt) error {
+	if s.down.Load() {
+		return errors.New("store down")
+	}
+	return s.ReceiptStore.Append(r)
+}
+
+// TestConfigReloadUnrecorded checks that a config change is only swapped in
+// once its receipt is appended, and that Watch retries a revision whose
+// receipt could not be appended.
+func TestConfigReloadUnrecorded(t *testing.T) {
+	schema := loadSchema(t)
+	shipped := shippedConfig(t)
+	path := filepath.Join(t.TempDir(), "consent.yaml")
+	if err := os.WriteFile(path, shipped, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := consent.LoadConfig(path, schema)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	store := &downStore{ReceiptStore: ledger.NewMemoryStore()}
+	store.down.Store(true)
+	gate := consent.NewGate(cfg, cfg.Version, nil, store)
+
+	next := *cfg
+	next.Throttle.LowTrustFloor = 0.4
+	if rcpt, err := gate.SetConfig(&next, "test"); err == nil || rcpt != nil {
+		t.Fatalf("SetConfig with the store down = %v, %v", rcpt, err)
+	}
+	if gate.Config() != cfg {
+		t.Fatal("config swapped in without a recorded receipt")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go gate.Watch(ctx, path, schema, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond) // let Watch read the current revision
+
+	revised := strings.Replace(string(shipped), "low_trust_floor: 0.30", "low_trust_floor: 0.40", 1)
+	if err := os.WriteFile(path, []byte(revised), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if floor := gate.Config().Throttle.LowTrustFloor; floor != 0.30 {
+		t.Fatalf("floor %v swapped in with the store down", floor)
+	}
+
+	store.down.Store(false)
+	deadline := time.Now().Add(2 * time.Second)
+	for gate.Config().Throttle.LowTrustFloor != 0.40 {
+		if time.Now().After(deadline) {
+			t.Fatal("revision not retried once the store recovered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if list, _ := store.List(ledger.ListOptions{SchemaRef: consent.ConfigSchemaRef}); len(list) != 1 {
+		t.Fatalf("%d config receipts, want 1", len(list))
+	}
+}
+
+// TestConfigReloadUnsealed checks that a config change whose receipt
+// cannot be signed is refused and the old config stays in force.
+func TestConfigReloadUnsealed(t *testing.T) {
+	cfg, err := consent.ParseConfig(shippedConfig(t), loadSchema(t))
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	store := ledger.NewMemoryStore()
+	gate := consent.NewGate(cfg, cfg.Version, nil, store)
+
+	token := crypto.NewMockPKCS11Token("1234")
+	if err := token.Login("1234"); err != nil {
+		t.Fatal(err)
+	}
+	ks := crypto.DefaultKeyStore()
+	crypto.SetDefaultKeyStore(crypto.NewPKCS11KeyStore(token)) // no node key
+	defer crypto.SetDefaultKeyStore(ks)
+
+	next := *cfg
+	next.Throttle.LowTrustFloor = 0.4
+	if rcpt, err := gate.SetConfig(&next, "test"); err == nil || rcpt != nil {
+		t.Fatalf("SetConfig without a node key = %v, %v", rcpt, err)
+	}
+	if gate.Config() != cfg {
+		t.Fatal("config swapped in without a receipt")
+	}
+	if list, _ := store.List(ledger.ListOptions{}); len(list) != 0 {
+		t.Fatalf("%d receipts appended", len(list))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"math"
 	"sync"
@@ -17,10 +18,14 @@ import (
 // ActorRef represents an initiator/affected party at decision time.
 // Trust/Legitimacy may be hydrated from your identity/ledger layer.
 type ActorRef struct {
-	ID         string  `json:"id"`
-	Domain     string  `json:"domain"`
-	Trust      float64 `json:"trust"`      // 0.0 - 1.0
-	Legitimacy float64 `json:"legitimacy"` // 0.0 - 1.0 (domain or seat-derived)
+	ID         string   `json:"id"`
+	Domain     string   `json:"domain"`
+	Trust      float64  `json:"trust"`           // 0.0 - 1.0
+	Legitimacy float64  `json:"legitimacy"`      // 0.0 - 1.0 (domain or seat-derived)
+	Seats      []string `json:"seats,omitempty"` // domain seats held
+	// Via, when set, is the mandate chain through which this actor acts
+	// for Via.Principal.
+	Via *Delegation `json:"via,omitempty"`
 }
 
 type ConsentGate struct {
@@ -54,31 +59,42 @@ type ConsentRequest struct {
 	Affected  []ActorRef        `json:"affected"`
 	Metadata  map[string]string `json:"metadata,omitempty"` // free-form; kept in receipt
 	Context   map[string]any    `json:"context,omitempty"`  // domain-specific
+	// EvidenceID names a granted consent request covering the affected
+	// individuals. Evidence is that request as loaded from the request
+	// store by the server; a copy sent by a client is discarded.
+	EvidenceID string   `json:"evidence_id,omitempty"`
+	Evidence   *Request `json:"evidence,omitempty"`
 }
 
 // Decision captures the gate outcome and moral math used.
 type Decision struct {
-	Allowed        bool       `json:"allowed"`
-	Reason         string     `json:"reason"`
-	Legitimacy     float64    `json:"legitimacy"`
-	ThrottleUntil  *time.Time `json:"throttle_until,omitempty"`
-	AppliedRules   []string   `json:"applied_rules"`
-	TrustDelta     float64    `json:"trust_delta"`
-	EthicsDelta    float64    `json:"ethics_delta"`
-	LegitimacyRule string     `json:"legitimacy_rule"` // the decisive rule id
+	Allowed        bool        `json:"allowed"`
+	Reason         string      `json:"reason"`
+	Legitimacy     float64     `json:"legitimacy"`
+	ThrottleUntil  *time.Time  `json:"throttle_until,omitempty"`
+	AppliedRules   []string    `json:"applied_rules"`
+	TrustDelta     float64     `json:"trust_delta"`
+	EthicsDelta    float64     `json:"ethics_delta"`
+	LegitimacyRule string      `json:"legitimacy_rule"` // the decisive rule id
+	Trace          []RuleTrace `json:"trace,omitempty"` // one entry per rule evaluated
+	ConfigVersion  string      `json:"config_version"`
+	ConfigHash     string      `json:"config_hash"` // Config.Hash of the config in force
+	// Hydration records where the initiator's and affected parties' trust,
+	// legitimacy, domain and seats came from; see WithResolver.
+	Hydration []Hydration `json:"hydration,omitempty"`
 }
 
-// Use canonical receipts.Receipt type
-
 // FeedbackSink receives receipts to drive moral feedback loops.
 type FeedbackSink interface {
 	Apply(ctx context.Context, rcpt ledger.Receipt) error
 }
 
-// ReceiptPoster lets you plug your ledger post operation.
-type ReceiptPoster interface {
-	Post(ctx context.Context, rcpt ledger.Receipt) (string, error) // returns receipt ID
-}
+// delegationRule is the trace entry for an initiator's mandate chain.
+const delegationRule = "delegation"
+
+// DecisionSchemaRef is the schema_ref of receipts emitted by the gate when
+// the request does not bind one.
+const DecisionSchemaRef = "consent.decision.v0"
 
 // ---- Config (loaded from YAML) ----
 
@@ -103,21 +119,37 @@ type Weights struct {
 	EthicsPenalty float64 `json:"ethics_penalty" yaml:"ethics_penalty"`
 }
 
+// ThrottleRule configures throttling of low-trust actors. By default every
+// request from an initiator below LowTrustFloor is throttled for Backoff.
+// With MaxAttempts set, such actors are instead admitted at MaxAttempts per
+// Window by the gate's ThrottleLedger, counted in Mode.
 type ThrottleRule struct {
 	Enabled       bool          `json:"enabled" yaml:"enabled"`
 	LowTrustFloor float64       `json:"low_trust_floor" yaml:"low_trust_floor"` // e.g., 0.3
-	Backoff       time.Duration `json:"-" yaml:"backoff_ms"`                    // decode as ms in loader
+	Backoff       time.Duration `json:"-" yaml:"backoff_ms"`                    // milliseconds in YAML; see UnmarshalYAML
+	Mode          ThrottleMode  `json:"mode,omitempty" yaml:"mode"`
+	MaxAttempts   int           `json:"max_attempts,omitempty" yaml:"max_attempts"`
+	Window        time.Duration `json:"-" yaml:"window_ms"` // milliseconds in YAML; zero uses Backoff
 }
 
 // ---- Gate ----
 
+// Gate evaluates consent requests. Its config may be swapped at runtime
+// (see SetConfig and Watch); mu guards cfg and version, and setMu orders
+// config changes so each receipt's old_hash is the config it replaces.
 type Gate struct {
 	mu      sync.RWMutex
+	setMu   sync.Mutex
 	cfg     *Config
 	sink    FeedbackSink
-	poster  ReceiptPoster
+	store   ledger.ReceiptStore
 	timeNow func() time.Time
 	version string
+	rules   *RuleRegistry
+	// throttle, when set, holds off throttled actors; see AuthorizeAction.
+	throttle *ThrottleLedger
+	// resolver, when set, replaces caller-reported actor standing.
+	resolver ActorResolver
 }
 
 // CheckConsent runs a basic threshold-based consent validation.
@@ -159,89 +191,181 @@ func CheckConsent(input ConsentInput) (*ConsentGate, error) {
 	return gate, nil
 }
 
-// NewGate constructs a Gate with injected sinks/posters (can be nil during bootstrapping).
-func NewGate(cfg *Config, version string, sink FeedbackSink, poster ReceiptPoster) *Gate {
+// NewGate constructs a Gate with an injected sink and receipt store. Either
+// can be nil: without a store receipts go to the default ledger store.
+func NewGate(cfg *Config, version string, sink FeedbackSink, store ledger.ReceiptStore) *Gate {
 	return &Gate{
 		cfg:     cfg,
 		sink:    sink,
-		poster:  poster,
+		store:   store,
 		timeNow: time.Now,
 		version: version,
 	}
 }
 
+// WithRules makes the gate resolve rule IDs in reg instead of the default
+// registry, and returns the gate (chainable).
+func (g *Gate) WithRules(reg *RuleRegistry) *Gate {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.rules = reg
+	return g
+}
+
+// WithThrottle makes AuthorizeAction enforce throttles through l, and
+// returns the gate (chainable).
+func (g *Gate) WithThrottle(l *ThrottleLedger) *Gate {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.throttle = l
+	return g
+}
+
+// WithResolver makes the gate hydrate the initiator and affected parties
+// of every request through r, ignoring the trust, legitimacy, domain and
+// seats the caller sent, and returns the gate (chainable).
+func (g *Gate) WithResolver(r ActorResolver) *Gate {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.resolver = r
+	return g
+}
+
+func (g *Gate) ruleRegistry() *RuleRegistry {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.rules != nil {
+		return g.rules
+	}
+	return DefaultRules()
+}
+
 // VerifyConsent checks legitimacy/consent without posting a receipt.
 func (g *Gate) VerifyConsent(ctx context.Context, req ConsentRequest) (Decision, error) {
-	if g.cfg == nil {
+	req, hs, err := g.hydrate(ctx, req)
+	if err != nil {
+		return Decision{}, err
+	}
+	dec, err := g.verify(ctx, g.Config(), req)
+	dec.Hydration = hs
+	return dec, err
+}
+
+// verify evaluates req under cfg. Callers take one config snapshot so the
+// decision and its receipt agree on the config in force.
+func (g *Gate) verify(ctx context.Context, cfg *Config, req ConsentRequest) (Decision, error) {
+	if cfg == nil {
 		return Decision{}, errors.New("consent gate not configured")
 	}
+	dec, err := g.evaluate(ctx, cfg, req)
+	if err != nil {
+		return Decision{}, err
+	}
+	dec.ConfigVersion = cfg.Version
+	dec.ConfigHash = cfg.Hash()
+	return dec, nil
+}
+
+func (g *Gate) evaluate(ctx context.Context, cfg *Config, req ConsentRequest) (Decision, error) {
 
 	// Base legitimacy: initiator legitimacy blended with transparency reciprocity.
 	// You can make this richer (e.g., weighted by affected party count).
 	leg := clamp((req.Initiator.Legitimacy+req.Initiator.Trust)/2.0, 0, 1)
 
 	applied := []string{}
+	trace := []RuleTrace{}
 	decisive := "personal_autonomy"
+	now := g.timeNow()
+
+	// An initiator acting for someone else must hold a mandate chain for it.
+	if via := req.Initiator.Via; via != nil {
+		if err := via.Verify(req.Initiator.ID, req.Action, req.SchemaRef, now); err != nil {
+			reason := "delegation rejected: " + err.Error()
+			return Decision{
+				Allowed:        false,
+				Reason:         reason,
+				Legitimacy:     leg,
+				AppliedRules:   applied,
+				TrustDelta:     cfg.Weights.TrustDecrease,
+				EthicsDelta:    cfg.Weights.EthicsPenalty,
+				LegitimacyRule: delegationRule,
+				Trace:          []RuleTrace{{Rule: delegationRule, Verdict: Block, Reason: reason, LegitimacyBefore: leg, LegitimacyAfter: leg}},
+			}, nil
+		}
+		trace = append(trace, RuleTrace{Rule: delegationRule, Verdict: Pass, Reason: "acting for " + via.Principal, LegitimacyBefore: leg, LegitimacyAfter: leg})
+	}
+	rules := g.ruleRegistry()
 
 	// Apply rules in priority order (as listed)
-	for _, r := range g.cfg.GateRules {
-		applied = append(applied, r.ID)
-		switch r.ID {
-		case "personal_autonomy":
-			// Require explicit consent when individuals are affected
-			// Here we simulate "explicit consent present?" via Metadata["consent"]="yes"
-			if anyIndividuals(req) && (req.Metadata == nil || req.Metadata["consent"] != "yes") {
-				return Decision{
-					Allowed:        false,
-					Reason:         "missing explicit consent",
-					Legitimacy:     leg,
-					AppliedRules:   applied,
-					TrustDelta:     g.cfg.Weights.TrustDecrease,
-					EthicsDelta:    g.cfg.Weights.EthicsPenalty,
-					LegitimacyRule: r.ID,
-				}, nil
+	for _, gr := range cfg.GateRules {
+		rule, ok := rules.Get(gr.ID)
+		if !ok {
+			return Decision{}, fmt.Errorf("%w: %s", ErrUnknownRule, gr.ID)
+		}
+		v, err := rule.Evaluate(ctx, RuleContext{Request: req, Rule: gr, Config: cfg, Legitimacy: leg, Now: now})
+		if err != nil {
+			return Decision{}, fmt.Errorf("consent rule %s: %w", gr.ID, err)
+		}
+		applied = append(applied, gr.ID)
+		before := leg
+		if v.Kind == Adjust {
+			leg = clamp(leg+v.LegitimacyDelta, 0, 1)
+		}
+		trace = append(trace, RuleTrace{Rule: gr.ID, Verdict: v.Kind, Reason: v.Reason, LegitimacyBefore: before, LegitimacyAfter: leg})
+
+		switch v.Kind {
+		case Block:
+			return Decision{
+				Allowed:        false,
+				Reason:         v.Reason,
+				Legitimacy:     leg,
+				AppliedRules:   applied,
+				TrustDelta:     cfg.Weights.TrustDecrease,
+				EthicsDelta:    cfg.Weights.EthicsPenalty,
+				LegitimacyRule: gr.ID,
+				Trace:          trace,
+			}, nil
+		case Throttle:
+			backoff := v.Backoff
+			if backoff <= 0 {
+				backoff = cfg.Throttle.Backoff
 			}
-			if leg < r.Threshold {
-				return Decision{
-					Allowed:        false,
-					Reason:         "insufficient legitimacy for personal autonomy",
-					Legitimacy:     leg,
-					AppliedRules:   applied,
-					TrustDelta:     g.cfg.Weights.TrustDecrease,
-					EthicsDelta:    g.cfg.Weights.EthicsPenalty,
-					LegitimacyRule: r.ID,
-				}, nil
+			tu := now.Add(backoff)
+			return Decision{
+				Allowed:        false,
+				Reason:         v.Reason,
+				Legitimacy:     leg,
+				ThrottleUntil:  &tu,
+				AppliedRules:   applied,
+				TrustDelta:     cfg.Weights.TrustDecrease,
+				LegitimacyRule: gr.ID,
+				Trace:          trace,
+			}, nil
+		case Pass, Adjust:
+			if v.Decisive {
+				decisive = gr.ID
 			}
-
-		case "reciprocal_transparency":
-			// If all affected parties are informed, boost perceived legitimacy slightly
-			if informedAll(req) {
-				leg = clamp(leg+0.03, 0, 1)
-			}
-			decisive = r.ID
-
-		case "trust_decay":
-			// Declarative hook; actual decay is applied by feedback loop from prior receipts
-			decisive = r.ID
-
-		case "moral_feedback":
-			// Declarative hook; actual post-decision effects happen in feedback loop
-			decisive = r.ID
+		default:
+			return Decision{}, fmt.Errorf("consent rule %s: unknown verdict %q", gr.ID, v.Kind)
 		}
 	}
 
-	// Optional throttling: low trust leads to timed backoff instead of hard block
-	if g.cfg.Throttle.Enabled && req.Initiator.Trust < g.cfg.Throttle.LowTrustFloor {
-		tu := g.timeNow().Add(g.cfg.Throttle.Backoff)
+	// Optional throttling: low trust leads to timed backoff instead of hard block.
+	// A rate limit is enforced by the throttle ledger before evaluation instead.
+	if cfg.Throttle.Enabled && lowTrust(cfg, req) && cfg.Throttle.MaxAttempts == 0 {
+		tu := now.Add(cfg.Throttle.Backoff)
+		reason := "throttled: low trust backoff"
+		trace = append(trace, RuleTrace{Rule: "throttle.low_trust", Verdict: Throttle, Reason: reason, LegitimacyBefore: leg, LegitimacyAfter: leg})
 		return Decision{
 			Allowed:        false,
-			Reason:         "throttled: low trust backoff",
+			Reason:         reason,
 			Legitimacy:     leg,
 			ThrottleUntil:  &tu,
 			AppliedRules:   applied,
-			TrustDelta:     g.cfg.Weights.TrustDecrease,
+			TrustDelta:     cfg.Weights.TrustDecrease,
 			EthicsDelta:    0,
 			LegitimacyRule: "throttle.low_trust",
+			Trace:          trace,
 		}, nil
 	}
 
@@ -251,59 +375,158 @@ func (g *Gate) VerifyConsent(ctx context.Context, req ConsentRequest) (Decision,
 		Reason:         "consent verified",
 		Legitimacy:     leg,
 		AppliedRules:   applied,
-		TrustDelta:     g.cfg.Weights.TrustIncrease,
-		EthicsDelta:    g.cfg.Weights.EthicsBonus,
+		TrustDelta:     cfg.Weights.TrustIncrease,
+		EthicsDelta:    cfg.Weights.EthicsBonus,
 		LegitimacyRule: decisive,
+		Trace:          trace,
 	}, nil
 }
 
-// AuthorizeAction runs VerifyConsent, emits a receipt, and forwards to feedback sink.
+// AuthorizeAction runs VerifyConsent, emits a signed receipt of the
+// decision, and forwards it to the feedback sink.
+//
+// The receipt is issued by the node (ledger.NodeDomain) and embeds the
+// request as evaluated, the decision with its rule trace, and the hash of
+// the config in force, so the decision can be replayed and explained.
+//
+// With a throttle ledger, the initiator's throttle state for the action is
+// consulted before any rule runs. While it is held off, or over its rate
+// limit, the request is refused with a throttled decision and no receipt;
+// a throttled decision holds it off until its ThrottleUntil.
 func (g *Gate) AuthorizeAction(ctx context.Context, req ConsentRequest) (Decision, *ledger.Receipt, error) {
-	dec, err := g.VerifyConsent(ctx, req)
+	req, hs, err := g.hydrate(ctx, req)
+	if err != nil {
+		return Decision{}, nil, err
+	}
+	cfg := g.Config()
+	if dec, held, err := g.admit(cfg, req); err != nil || held {
+		dec.Hydration = hs
+		return dec, nil, err
+	}
+	dec, err := g.verify(ctx, cfg, req)
 	if err != nil {
 		return Decision{}, nil, err
 	}
+	dec.Hydration = hs
+	if err := g.hold(req, dec); err != nil {
+		return dec, nil, err
+	}
 
+	rcpt, err := g.decisionReceipt(req, dec)
+	if err != nil {
+		return dec, nil, err
+	}
+	if err := g.receiptStore().Append(rcpt); err != nil {
+		return dec, rcpt, err
+	}
+
+	// Feed moral dynamics.
+	if g.sink != nil {
+		if err := g.sink.Apply(ctx, *rcpt); err != nil {
+			log.Printf("⚠️  consent feedback for %s: %v", rcpt.ReceiptID, err)
+		}
+	}
+
+	return dec, rcpt, nil
+}
+
+// decisionReceipt builds and seals the receipt recording dec.
+func (g *Gate) decisionReceipt(req ConsentRequest, dec Decision) (*ledger.Receipt, error) {
+	schemaRef := req.SchemaRef
+	if schemaRef == "" {
+		schemaRef = DecisionSchemaRef
+	}
+	payload := map[string]any{
+		"decision":        decisionWord(dec),
+		"allowed":         dec.Allowed,
+		"reason":          dec.Reason,
+		"legitimacy":      dec.Legitimacy,
+		"legitimacy_rule": dec.LegitimacyRule,
+		"applied_rules":   dec.AppliedRules,
+		"trace":           dec.Trace,
+		"trust_delta":     dec.TrustDelta,
+		"ethics_delta":    dec.EthicsDelta,
+		"initiator":       req.Initiator.ID,
+		"affected":        collectIDs(req.Affected),
+		"config_version":  dec.ConfigVersion,
+		"config_hash":     dec.ConfigHash,
+		"request":         req,
+		"hydration":       dec.Hydration,
+	}
+	if dec.ThrottleUntil != nil {
+		payload["throttle_until"] = dec.ThrottleUntil.UTC().Format(time.RFC3339Nano)
+	}
+
+	now := g.timeNow().UTC().Format(time.RFC3339Nano)
 	rcpt := &ledger.Receipt{
-		By:        req.Initiator.ID,
+		ReceiptID: ledger.GenerateUUID(),
+		SchemaRef: schemaRef,
+		By:        ledger.NodeDomain,
 		Action:    req.Action,
-		CreatedAt: g.timeNow().Format(time.RFC3339Nano),
-		// Optionally fill Provenance, Metadata, etc. if available
+		CreatedAt: now,
 		Metadata: ledger.Metadata{
-			IssuedFromConsole:  "consent-gate",
-			IssuerSeat:         "gate",
-			VerifiedAt:         g.timeNow().Format(time.RFC3339Nano),
-			VerificationMethod: "consent-gate",
+			IssuedFromConsole: "consent-gate",
+			IssuerSeat:        "gate",
 		},
+		Payload: payload,
 	}
-
-	// Persist receipt if a poster is configured.
-	if g.poster != nil {
-		_, perr := g.poster.Post(ctx, *rcpt)
-		if perr != nil {
-			return dec, rcpt, perr
+	if req.Evidence != nil {
+		payload["consent_request"] = req.Evidence.ID
+		rcpt.Provenance = []ledger.Provenance{{Type: "consent_request", Ref: req.Evidence.ID, Status: string(req.Evidence.State)}}
+	}
+	if ds := req.delegations(); len(ds) > 0 {
+		records := make([]map[string]any, len(ds))
+		for i, d := range ds {
+			records[i] = d.record()
+			for _, m := range d.Mandates {
+				rcpt.Provenance = append(rcpt.Provenance, ledger.Provenance{Type: "consent_mandate", Ref: m.ID, Status: "active"})
+			}
 		}
-		// Optionally set ReceiptID if your poster returns it
+		payload["delegations"] = records
 	}
-
-	// Feed moral dynamics.
-	if g.sink != nil {
-		_ = g.sink.Apply(ctx, *rcpt) // non-fatal; log in real impl
+	if err := rcpt.Seal(); err != nil {
+		return nil, fmt.Errorf("seal consent receipt: %w", err)
 	}
+	return rcpt, nil
+}
 
-	return dec, rcpt, nil
+// receiptStore is where the gate records receipts: its own store, or the
+// default ledger store when none was given.
+func (g *Gate) receiptStore() ledger.ReceiptStore {
+	if g.store != nil {
+		return g.store
+	}
+	return ledger.DefaultStore()
 }
 
 // ---- Helpers ----
 
-func anyIndividuals(req ConsentRequest) bool {
+// delegations lists the mandate chains the request relies on: the
+// initiator's, then those of proxy votes in its evidence.
+func (req ConsentRequest) delegations() []*Delegation {
+	var out []*Delegation
+	if req.Initiator.Via != nil {
+		out = append(out, req.Initiator.Via)
+	}
+	if req.Evidence != nil {
+		for _, v := range req.Evidence.Votes {
+			if v.Delegation != nil {
+				out = append(out, v.Delegation)
+			}
+		}
+	}
+	return out
+}
+
+func individuals(req ConsentRequest) []ActorRef {
 	// Placeholder heuristic: if any affected has Domain "persona" or empty -> treat as individual.
+	var out []ActorRef
 	for _, a := range req.Affected {
 		if a.Domain == "" || a.Domain == "persona" {
-			return true
+			out = append(out, a)
 		}
 	}
-	return false
+	return out
 }
 
 func informedAll(req ConsentRequest) bool {
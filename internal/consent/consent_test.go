@@ -0,0 +1,207 @@
+package consent_test
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+
+	"dis-core/internal/consent"
+	"dis-core/internal/feedback"
+	"dis-core/internal/ledger"
+	"dis-core/internal/util/crypto"
+)
+
+// TestMain signs everything in this package with keys held on a mock
+// PKCS#11 token, so tests never touch the on-disk key directory.
+func TestMain(m *testing.M) {
+	token := crypto.NewMockPKCS11Token("1234")
+	if err := token.Login("1234"); err != nil {
+		panic(err)
+	}
+	ks := crypto.NewPKCS11KeyStore(token)
+	for _, d := range []string{ledger.NodeDomain, "alice", "bob", "carol", "dave"} {
+		if _, err := ks.Generate(d); err != nil {
+			panic(err)
+		}
+	}
+	crypto.SetDefaultKeyStore(ks)
+	os.Exit(m.Run())
+}
+
+// sinkFunc adapts a function to consent.FeedbackSink.
+type sinkFunc func(ctx context.Context, rcpt ledger.Receipt) error
+
+func (f sinkFunc) Apply(ctx context.Context, rcpt ledger.Receipt) error { return f(ctx, rcpt) }
+
+// TestAuthorizeActionReceipt checks that every decision is recorded in a
+// signed receipt that embeds it and the config it was made under.
+func TestAuthorizeActionReceipt(t *testing.T) {
+	cfg, err := consent.ParseConfig(shippedConfig(t), loadSchema(t))
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	store := ledger.NewMemoryStore()
+	var fed []string
+	sink := sinkFunc(func(_ context.Context, rcpt ledger.Receipt) error {
+		fed = append(fed, rcpt.ReceiptID)
+		return nil
+	})
+	gate := consent.NewGate(cfg, cfg.Version, sink, store)
+	evidence := grantedRequest(t)
+	dave := consent.ActorRef{ID: "dave", Domain: "org", Trust: 0.9, Legitimacy: 0.9}
+	people := []consent.ActorRef{{ID: "alice"}, {ID: "bob"}}
+
+	cases := []struct {
+		name      string
+		req       consent.ConsentRequest
+		schemaRef string
+		decision  string
+		evidence  string // consent_request provenance, if any
+	}{
+		{
+			name:      "organisation affected",
+			req:       consent.ConsentRequest{Action: "trade.execute", SchemaRef: "trade.v1", Initiator: dave, Affected: []consent.ActorRef{{ID: "acme", Domain: "org", Trust: 0.9}}},
+			schemaRef: "trade.v1",
+			decision:  "allowed",
+		},
+		{
+			name:      "no schema bound",
+			req:       consent.ConsentRequest{Action: "policy.update", Initiator: dave, Affected: []consent.ActorRef{{ID: "acme", Domain: "org", Trust: 0.9}}},
+			schemaRef: consent.DecisionSchemaRef,
+			decision:  "allowed",
+		},
+		{
+			name:      "individuals without consent",
+			req:       consent.ConsentRequest{Action: "trade.execute", SchemaRef: "trade.v1", Initiator: dave, Affected: people},
+			schemaRef: "trade.v1",
+			decision:  "blocked",
+		},
+		{
+			name:      "individuals with consent",
+			req:       consent.ConsentRequest{Action: "trade.execute", SchemaRef: "trade.v1", Initiator: dave, Affected: people, EvidenceID: evidence.ID, Evidence: evidence},
+			schemaRef: "trade.v1",
+			decision:  "allowed",
+			evidence:  evidence.ID,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dec, rcpt, err := gate.AuthorizeAction(context.Background(), tc.req)
+			if err != nil {
+				t.Fatalf("AuthorizeAction: %v", err)
+			}
+			if rcpt == nil || rcpt.By != ledger.NodeDomain || rcpt.SchemaRef != tc.schemaRef || rcpt.Action != tc.req.Action {
+				t.Fatalf("receipt %+v", rcpt)
+			}
+			stored, err := store.Get(rcpt.ReceiptID)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			raw, _ := json.Marshal(stored)
+			if ok, err := ledger.VerifyReceiptJSON(raw); !ok || err != nil {
+				t.Fatalf("stored receipt does not verify: %v", err)
+			}
+
+			var payload struct {
+				Decision      string              `json:"decision"`
+				Allowed       bool                `json:"allowed"`
+				Reason        string              `json:"reason"`
+				AppliedRules  []string            `json:"applied_rules"`
+				Trace         []consent.RuleTrace `json:"trace"`
+				Affected      []string            `json:"affected"`
+				ConfigVersion string              `json:"config_version"`
+				ConfigHash    string              `json:"config_hash"`
+			}
+			raw, _ = json.Marshal(stored.Payload)
+			if err := json.Unmarshal(raw, &payload); err != nil {
+				t.Fatalf("payload: %v", err)
+			}
+			if payload.Decision != tc.decision || payload.Allowed != dec.Allowed || payload.Reason != dec.Reason {
+				t.Fatalf("payload decision %+v, decision %+v", payload, dec)
+			}
+			if payload.ConfigHash != cfg.Hash() || payload.ConfigVersion != cfg.Version {
+				t.Fatalf("payload config %s@%s, want %s@%s", payload.ConfigVersion, payload.ConfigHash, cfg.Version, cfg.Hash())
+			}
+			if !reflect.DeepEqual(payload.AppliedRules, dec.AppliedRules) || !reflect.DeepEqual(payload.Trace, dec.Trace) {
+				t.Fatalf("payload rules %v %+v, decision %v %+v", payload.AppliedRules, payload.Trace, dec.AppliedRules, dec.Trace)
+			}
+			if want := collect(tc.req.Affected); !reflect.DeepEqual(payload.Affected, want) {
+				t.Fatalf("payload affected %v, want %v", payload.Affected, want)
+			}
+			var prov string
+			for _, p := range stored.Provenance {
+				if p.Type == "consent_request" {
+					prov = p.Ref
+				}
+			}
+			if prov != tc.evidence {
+				t.Fatalf("consent_request provenance %q, want %q", prov, tc.evidence)
+			}
+			if len(fed) == 0 || fed[len(fed)-1] != rcpt.ReceiptID {
+				t.Fatalf("receipt not fed back: %v", fed)
+			}
+		})
+	}
+}
+
+// TestLoopSinkMovesScores checks that the gate's decisions reach the
+// feedback loop and move the stored scores by the configured weights, once
+// per initiator, action and decision.
+func TestLoopSinkMovesScores(t *testing.T) {
+	cfg, err := consent.ParseConfig(shippedConfig(t), loadSchema(t))
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	ctx := context.Background()
+	scores := feedback.NewMemStore()
+	sink := consent.NewLoopSink(feedback.NewLoop(scores, nil))
+	gate := consent.NewGate(cfg, cfg.Version, sink, ledger.NewMemoryStore())
+	dave := consent.ActorRef{ID: "dave", Domain: "org", Trust: 0.9, Legitimacy: 0.9}
+
+	trust := func(id string) float64 {
+		t.Helper()
+		v, ok, err := scores.GetTrust(ctx, id)
+		if err != nil || !ok {
+			t.Fatalf("GetTrust(%s) = %v, %v, %v", id, v, ok, err)
+		}
+		return v
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+	allowed := consent.ConsentRequest{Action: "trade.execute", Initiator: dave, Affected: []consent.ActorRef{{ID: "acme", Domain: "org", Trust: 0.9}}}
+	if dec, _, err := gate.AuthorizeAction(ctx, allowed); err != nil || !dec.Allowed {
+		t.Fatalf("AuthorizeAction = %+v, %v", dec, err)
+	}
+	up := 0.5 + cfg.Weights.TrustIncrease
+	if got := trust("dave"); !near(got, up) {
+		t.Fatalf("dave's trust after allowed decision = %v, want %v", got, up)
+	}
+	if got, want := trust("acme"), 0.5+cfg.Weights.TrustIncrease/2; !near(got, want) {
+		t.Fatalf("acme's trust after allowed decision = %v, want %v", got, want)
+	}
+	if dec, _, err := gate.AuthorizeAction(ctx, allowed); err != nil || !dec.Allowed {
+		t.Fatalf("AuthorizeAction = %+v, %v", dec, err)
+	}
+	if got := trust("dave"); !near(got, up) {
+		t.Fatalf("dave's trust after a repeated decision = %v, want %v", got, up)
+	}
+
+	blocked := consent.ConsentRequest{Action: "trade.execute", Initiator: dave, Affected: []consent.ActorRef{{ID: "alice"}}}
+	if dec, _, err := gate.AuthorizeAction(ctx, blocked); err != nil || dec.Allowed {
+		t.Fatalf("AuthorizeAction = %+v, %v", dec, err)
+	}
+	if got, want := trust("dave"), up+cfg.Weights.TrustDecrease; !near(got, want) {
+		t.Fatalf("dave's trust after blocked decision = %v, want %v", got, want)
+	}
+}
+
+func collect(actors []consent.ActorRef) []string {
+	out := make([]string, len(actors))
+	for i, a := range actors {
+		out[i] = a.ID
+	}
+	return out
+}
@@ -0,0 +1,85 @@
+package consent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"dis-core/internal/feedback"
+	"dis-core/internal/ledger"
+)
+
+// FeedbackRule limits how often decisions move scores: the initiator named
+// in a request is not authenticated, so repeating a request must not move
+// its scores, or its affected parties', again and again.
+var FeedbackRule = ThrottleRule{Enabled: true, Mode: SlidingWindow, MaxAttempts: 1, Window: time.Hour}
+
+// LoopSink feeds the gate's decision receipts to a feedback.Loop, moving
+// the initiator's and affected parties' scores by the decision's deltas at
+// most once per initiator, action and decision under FeedbackRule.
+type LoopSink struct {
+	loop   *feedback.Loop
+	ledger *ThrottleLedger
+	now    func() time.Time
+}
+
+// NewLoopSink returns a FeedbackSink applying decisions to loop. It counts
+// them in memory until WithLedger is called.
+func NewLoopSink(loop *feedback.Loop) *LoopSink {
+	return &LoopSink{loop: loop, ledger: NewThrottleLedger(nil), now: time.Now}
+}
+
+// WithLedger makes the sink count decisions in l, and returns the sink
+// (chainable).
+func (s *LoopSink) WithLedger(l *ThrottleLedger) *LoopSink {
+	s.ledger = l
+	return s
+}
+
+// Apply implements FeedbackSink. A decision already counted inside the
+// window is dropped.
+func (s *LoopSink) Apply(ctx context.Context, rcpt ledger.Receipt) error {
+	fr, err := FeedbackReceipt(rcpt)
+	if err != nil {
+		return err
+	}
+	retry, err := s.ledger.Admit(fr.InitiatorID, "feedback:"+fr.Action+":"+fr.Decision, FeedbackRule, true, s.now())
+	if err != nil || !retry.IsZero() {
+		return err
+	}
+	return s.loop.Apply(ctx, fr)
+}
+
+// FeedbackReceipt converts a decision receipt emitted by the gate into the
+// feedback loop's receipt.
+func FeedbackReceipt(rcpt ledger.Receipt) (feedback.Receipt, error) {
+	p := rcpt.Payload
+	initiator, _ := p["initiator"].(string)
+	decision, _ := p["decision"].(string)
+	if initiator == "" || decision == "" {
+		return feedback.Receipt{}, fmt.Errorf("receipt %s is not a consent decision", rcpt.ReceiptID)
+	}
+	fr := feedback.Receipt{
+		ID:          rcpt.ReceiptID,
+		Action:      rcpt.Action,
+		InitiatorID: initiator,
+		Decision:    decision,
+	}
+	fr.Time, _ = time.Parse(time.RFC3339Nano, rcpt.CreatedAt)
+	fr.TrustChange, _ = p["trust_delta"].(float64)
+	fr.EthicsChange, _ = p["ethics_delta"].(float64)
+	fr.LegitimacyRef, _ = p["legitimacy_rule"].(string)
+
+	// Payloads decoded from JSON carry []any rather than []string.
+	switch ids := p["affected"].(type) {
+	case []string:
+		fr.AffectedIDs = ids
+	case []any:
+		for _, id := range ids {
+			if s, ok := id.(string); ok {
+				fr.AffectedIDs = append(fr.AffectedIDs, s)
+			}
+		}
+	}
+	return fr, nil
+}
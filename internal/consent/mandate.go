@@ -0,0 +1,461 @@
+package consent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"time"
+
+	"dis-core/internal/bridge"
+	"dis-core/internal/ledger"
+	"dis-core/internal/util/crypto"
+)
+
+// MandateSchemaRef is the schema_ref of mandate lifecycle receipts.
+const MandateSchemaRef = "consent.mandate.v0"
+
+// MaxDelegationDepth caps the number of mandates in a delegation chain,
+// whatever the mandates themselves allow.
+const MaxDelegationDepth = 3
+
+var (
+	ErrMandateNotFound = errors.New("consent mandate not found")
+	ErrBadMandate      = errors.New("invalid consent mandate")
+	ErrNoMandate       = errors.New("no mandate chain authorizes the delegate")
+)
+
+// Mandate lets Delegate consent on Grantor's behalf to Actions under
+// SchemaRefs between NotBefore and ExpiresAt. The grantor signs its
+// Digest. MaxDepth is how many further mandates may follow this one
+// in a chain: 0 means the delegate must act itself.
+//
+// Actions and SchemaRefs are path.Match patterns ("trade.*"); an empty
+// SchemaRefs covers any schema.
+type Mandate struct {
+	ID           string   `json:"id"`
+	Grantor      string   `json:"grantor"`
+	Delegate     string   `json:"delegate"`
+	Actions      []string `json:"actions"`
+	SchemaRefs   []string `json:"schema_refs,omitempty"`
+	MaxDepth     int      `json:"max_depth"`
+	NotBefore    string   `json:"not_before,omitempty"`
+	ExpiresAt    string   `json:"expires_at"`
+	IssuedAt     string   `json:"issued_at"`
+	KeyID        string   `json:"key_id"`
+	PublicKeyB64 string   `json:"public_key_b64,omitempty"`
+	Signature    string   `json:"signature"`
+	// Revocation, signed by the grantor over MandateRevocationDigest.
+	RevokedAt       string `json:"revoked_at,omitempty"`
+	RevokeKeyID     string `json:"revoke_key_id,omitempty"`
+	RevokeSignature string `json:"revoke_signature,omitempty"`
+}
+
+// Digest is the hex SHA-256 of the mandate's signed fields.
+func (m *Mandate) Digest() string {
+	canon, err := bridge.CanonicalJSON(map[string]any{
+		"id":          m.ID,
+		"grantor":     m.Grantor,
+		"delegate":    m.Delegate,
+		"actions":     m.Actions,
+		"schema_refs": m.SchemaRefs,
+		"max_depth":   m.MaxDepth,
+		"not_before":  m.NotBefore,
+		"expires_at":  m.ExpiresAt,
+		"issued_at":   m.IssuedAt,
+	})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(canon)
+	return hex.EncodeToString(sum[:])
+}
+
+// MandateRevocationDigest is the message a grantor signs to revoke a
+// mandate.
+func MandateRevocationDigest(mandateDigest, revokedAt string) string {
+	canon, _ := bridge.CanonicalJSON(map[string]any{
+		"mandate":    mandateDigest,
+		"revoked_at": revokedAt,
+	})
+	sum := sha256.Sum256(canon)
+	return hex.EncodeToString(sum[:])
+}
+
+// SignMandate fills in the ID and issue time if unset and signs m with the
+// grantor's active key in the default KeyStore.
+func SignMandate(m *Mandate) error {
+	ks := crypto.DefaultKeyStore()
+	key, err := ks.Active(m.Grantor)
+	if err != nil {
+		return err
+	}
+	if m.ID == "" {
+		m.ID = ledger.GenerateUUID()
+	}
+	if m.IssuedAt == "" {
+		m.IssuedAt = ledger.NowRFC3339Nano()
+	}
+	m.KeyID = key.KeyID
+	if m.Signature, err = ks.Sign(key.KeyID, []byte(m.Digest())); err != nil {
+		return err
+	}
+	m.PublicKeyB64 = key.PublicKeyB64
+	return nil
+}
+
+// SignMandateRevocation revokes m at revokedAt with the grantor's active key.
+func SignMandateRevocation(m *Mandate, revokedAt string) error {
+	ks := crypto.DefaultKeyStore()
+	key, err := ks.Active(m.Grantor)
+	if err != nil {
+		return err
+	}
+	sig, err := ks.Sign(key.KeyID, []byte(MandateRevocationDigest(m.Digest(), revokedAt)))
+	if err != nil {
+		return err
+	}
+	m.RevokedAt, m.RevokeKeyID, m.RevokeSignature = revokedAt, key.KeyID, sig
+	return nil
+}
+
+// Validate checks the mandate's shape and the grantor's signature against
+// its key history at IssuedAt.
+func (m *Mandate) Validate() error {
+	switch {
+	case m.ID == "" || m.Grantor == "" || m.Delegate == "":
+		return fmt.Errorf("%w: id, grantor and delegate are required", ErrBadMandate)
+	case m.Grantor == m.Delegate:
+		return fmt.Errorf("%w: %s delegates to itself", ErrBadMandate, m.Grantor)
+	case len(m.Actions) == 0:
+		return fmt.Errorf("%w: %s covers no actions", ErrBadMandate, m.ID)
+	case m.MaxDepth < 0 || m.MaxDepth >= MaxDelegationDepth:
+		return fmt.Errorf("%w: max_depth %d outside [0, %d]", ErrBadMandate, m.MaxDepth, MaxDelegationDepth-1)
+	}
+	for _, p := range append(append([]string{}, m.Actions...), m.SchemaRefs...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("%w: pattern %q: %v", ErrBadMandate, p, err)
+		}
+	}
+	issued, err := time.Parse(time.RFC3339Nano, m.IssuedAt)
+	if err != nil {
+		return fmt.Errorf("%w: issued_at: %v", ErrBadMandate, err)
+	}
+	expires, err := time.Parse(time.RFC3339Nano, m.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%w: expires_at: %v", ErrBadMandate, err)
+	}
+	if !expires.After(issued) {
+		return fmt.Errorf("%w: expires before it is issued", ErrBadMandate)
+	}
+	if m.NotBefore != "" {
+		if _, err := time.Parse(time.RFC3339Nano, m.NotBefore); err != nil {
+			return fmt.Errorf("%w: not_before: %v", ErrBadMandate, err)
+		}
+	}
+	key, err := crypto.KeyAt(crypto.DefaultKeyStore(), m.Grantor, m.KeyID, issued)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadMandate, err)
+	}
+	if !key.Verify([]byte(m.Digest()), m.Signature) {
+		return fmt.Errorf("%w: signature by %s does not verify", ErrBadMandate, m.Grantor)
+	}
+	return nil
+}
+
+// verifyRevocation checks the grantor's signature on a revocation.
+func (m *Mandate) verifyRevocation() error {
+	at, err := time.Parse(time.RFC3339Nano, m.RevokedAt)
+	if err != nil {
+		return fmt.Errorf("%w: revoked_at: %v", ErrBadMandate, err)
+	}
+	key, err := crypto.KeyAt(crypto.DefaultKeyStore(), m.Grantor, m.RevokeKeyID, at)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadMandate, err)
+	}
+	if !key.Verify([]byte(MandateRevocationDigest(m.Digest(), m.RevokedAt)), m.RevokeSignature) {
+		return fmt.Errorf("%w: revocation by %s does not verify", ErrBadMandate, m.Grantor)
+	}
+	return nil
+}
+
+// Covers reports whether the mandate is in force at at and covers action
+// under schemaRef.
+func (m *Mandate) Covers(action, schemaRef string, at time.Time) error {
+	if m.NotBefore != "" {
+		if nb, err := time.Parse(time.RFC3339Nano, m.NotBefore); err != nil || at.Before(nb) {
+			return fmt.Errorf("%w: mandate %s is not yet in force", ErrNoMandate, m.ID)
+		}
+	}
+	if exp, err := time.Parse(time.RFC3339Nano, m.ExpiresAt); err != nil || !at.Before(exp) {
+		return fmt.Errorf("%w: mandate %s has expired", ErrNoMandate, m.ID)
+	}
+	if m.RevokedAt != "" {
+		if rev, err := time.Parse(time.RFC3339Nano, m.RevokedAt); err != nil || !at.Before(rev) {
+			return fmt.Errorf("%w: mandate %s was revoked", ErrNoMandate, m.ID)
+		}
+	}
+	if !matchAny(m.Actions, action) {
+		return fmt.Errorf("%w: mandate %s does not cover action %q", ErrNoMandate, m.ID, action)
+	}
+	if len(m.SchemaRefs) > 0 && !matchAny(m.SchemaRefs, schemaRef) {
+		return fmt.Errorf("%w: mandate %s does not cover schema %q", ErrNoMandate, m.ID, schemaRef)
+	}
+	return nil
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Delegation is the chain of mandates through which an identity acts for
+// Principal: the first mandate is granted by Principal, each next one by
+// the previous delegate, and the last names the acting identity.
+type Delegation struct {
+	Principal string    `json:"principal"`
+	Mandates  []Mandate `json:"mandates"`
+}
+
+// Verify checks that the chain lets agent act for Principal on action
+// under schemaRef at at: each mandate is signed by its grantor, links to
+// the next, covers the action, and allows the hops that follow it.
+func (d *Delegation) Verify(agent, action, schemaRef string, at time.Time) error {
+	n := len(d.Mandates)
+	if n == 0 || n > MaxDelegationDepth {
+		return fmt.Errorf("%w: chain of %d mandates (limit %d)", ErrNoMandate, n, MaxDelegationDepth)
+	}
+	grantor := d.Principal
+	for i := range d.Mandates {
+		m := &d.Mandates[i]
+		if m.Grantor != grantor {
+			return fmt.Errorf("%w: mandate %s is granted by %s, not %s", ErrNoMandate, m.ID, m.Grantor, grantor)
+		}
+		if err := m.Validate(); err != nil {
+			return err
+		}
+		if m.RevokedAt != "" {
+			if err := m.verifyRevocation(); err != nil {
+				return err
+			}
+		}
+		if err := m.Covers(action, schemaRef, at); err != nil {
+			return err
+		}
+		if rest := n - 1 - i; rest > m.MaxDepth {
+			return fmt.Errorf("%w: mandate %s allows %d further hops, chain has %d", ErrNoMandate, m.ID, m.MaxDepth, rest)
+		}
+		grantor = m.Delegate
+	}
+	if grantor != agent {
+		return fmt.Errorf("%w: chain ends at %s, not %s", ErrNoMandate, grantor, agent)
+	}
+	return nil
+}
+
+// Chain lists the identities from Principal to the acting one.
+func (d *Delegation) Chain() []string {
+	ids := []string{d.Principal}
+	for _, m := range d.Mandates {
+		ids = append(ids, m.Delegate)
+	}
+	return ids
+}
+
+// record is the receipt form of the delegation.
+func (d *Delegation) record() map[string]any {
+	ids := make([]string, len(d.Mandates))
+	for i, m := range d.Mandates {
+		ids[i] = m.ID
+	}
+	return map[string]any{
+		"principal": d.Principal,
+		"chain":     d.Chain(),
+		"mandates":  ids,
+	}
+}
+
+// Mandates issues, revokes and resolves mandates over a MandateStore,
+// receipting each issue and revocation to receipts (when non-nil).
+type Mandates struct {
+	store    MandateStore
+	receipts ledger.ReceiptStore
+	now      func() time.Time
+}
+
+// NewMandates constructs a mandate registry.
+func NewMandates(store MandateStore, receipts ledger.ReceiptStore) *Mandates {
+	return &Mandates{store: store, receipts: receipts, now: time.Now}
+}
+
+// Issue stores a mandate signed by its grantor. Its issued_at picks the
+// grantor key it is checked against, so it must be within ClockSkew of now.
+func (s *Mandates) Issue(m *Mandate) (*Mandate, error) {
+	m.RevokedAt, m.RevokeKeyID, m.RevokeSignature = "", "", ""
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	issued, _ := time.Parse(time.RFC3339Nano, m.IssuedAt)
+	if d := s.now().Sub(issued); d > ClockSkew || d < -ClockSkew {
+		return nil, fmt.Errorf("%w: issued_at %s is not within %s of now", ErrBadMandate, m.IssuedAt, ClockSkew)
+	}
+	if err := s.store.Create(m); err != nil {
+		return nil, err
+	}
+	s.receipt(m, "consent.mandate.issue")
+	return m, nil
+}
+
+// Revoke records the grantor's signed revocation of mandate id.
+func (s *Mandates) Revoke(id, revokedAt, keyID, signature string) (*Mandate, error) {
+	m, err := s.store.Update(id, func(m *Mandate) error {
+		if m.RevokedAt != "" {
+			return fmt.Errorf("%w: %s is already revoked", ErrBadMandate, m.ID)
+		}
+		m.RevokedAt, m.RevokeKeyID, m.RevokeSignature = revokedAt, keyID, signature
+		return m.verifyRevocation()
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.receipt(m, "consent.mandate.revoke")
+	return m, nil
+}
+
+// Get returns a mandate by ID.
+func (s *Mandates) Get(id string) (*Mandate, error) {
+	return s.store.Get(id)
+}
+
+// List returns the mandates granted by grantor and/or held by delegate.
+func (s *Mandates) List(grantor, delegate string) ([]*Mandate, error) {
+	return s.store.List(grantor, delegate)
+}
+
+// Resolve finds the shortest chain of stored mandates in force at at that
+// lets agent act for principal on action under schemaRef.
+func (s *Mandates) Resolve(agent, principal, action, schemaRef string, at time.Time) (*Delegation, error) {
+	type hop struct {
+		holder string
+		chain  []Mandate
+	}
+	frontier := []hop{{holder: principal}}
+	seen := map[string]bool{principal: true}
+	for depth := 0; depth < MaxDelegationDepth && len(frontier) > 0; depth++ {
+		var next []hop
+		for _, p := range frontier {
+			granted, err := s.store.List(p.holder, "")
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range granted {
+				if m.Covers(action, schemaRef, at) != nil {
+					continue
+				}
+				chain := append(append([]Mandate{}, p.chain...), *m)
+				if m.Delegate == agent {
+					d := &Delegation{Principal: principal, Mandates: chain}
+					if d.Verify(agent, action, schemaRef, at) == nil {
+						return d, nil
+					}
+					continue
+				}
+				if !seen[m.Delegate] && m.MaxDepth > 0 {
+					seen[m.Delegate] = true
+					next = append(next, hop{holder: m.Delegate, chain: chain})
+				}
+			}
+		}
+		frontier = next
+	}
+	return nil, fmt.Errorf("%w: %s for %s on %q", ErrNoMandate, agent, principal, action)
+}
+
+// Reload replaces each mandate of d with the stored one of the same ID, so
+// a chain sent by a client carries the revocations the store holds rather
+// than whatever the client left in. A mandate missing from the store, or
+// stored with different signed fields, fails with ErrNoMandate.
+func (s *Mandates) Reload(d *Delegation) (*Delegation, error) {
+	out := &Delegation{Principal: d.Principal, Mandates: make([]Mandate, len(d.Mandates))}
+	for i, m := range d.Mandates {
+		stored, err := s.store.Get(m.ID)
+		if errors.Is(err, ErrMandateNotFound) {
+			return nil, fmt.Errorf("%w: mandate %s is not on record", ErrNoMandate, m.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if stored.Digest() != m.Digest() {
+			return nil, fmt.Errorf("%w: mandate %s differs from the one on record", ErrNoMandate, m.ID)
+		}
+		out.Mandates[i] = *stored
+	}
+	return out, nil
+}
+
+// ResolveActor completes ref.Via from the store when it names a principal
+// but carries no mandates, or reloads the mandates it carries, and
+// verifies it.
+func (s *Mandates) ResolveActor(ref *ActorRef, action, schemaRef string) error {
+	if ref.Via == nil {
+		return nil
+	}
+	at := s.now()
+	var d *Delegation
+	var err error
+	if len(ref.Via.Mandates) == 0 {
+		d, err = s.Resolve(ref.ID, ref.Via.Principal, action, schemaRef, at)
+	} else {
+		d, err = s.Reload(ref.Via)
+	}
+	if err != nil {
+		return err
+	}
+	ref.Via = d
+	return ref.Via.Verify(ref.ID, action, schemaRef, at)
+}
+
+// receipt records a mandate event. Failures are logged: the mandate store
+// is the source of truth.
+func (s *Mandates) receipt(m *Mandate, action string) {
+	if s.receipts == nil {
+		return
+	}
+	payload := map[string]any{
+		"mandate_id":   m.ID,
+		"mandate_hash": m.Digest(),
+		"grantor":      m.Grantor,
+		"delegate":     m.Delegate,
+		"actions":      m.Actions,
+		"schema_refs":  m.SchemaRefs,
+		"max_depth":    m.MaxDepth,
+		"expires_at":   m.ExpiresAt,
+	}
+	status := "active"
+	if m.RevokedAt != "" {
+		payload["revoked_at"] = m.RevokedAt
+		status = "revoked"
+	}
+	rcpt := &ledger.Receipt{
+		ReceiptID:  ledger.GenerateUUID(),
+		SchemaRef:  MandateSchemaRef,
+		By:         ledger.NodeDomain,
+		Action:     action,
+		CreatedAt:  s.now().UTC().Format(time.RFC3339Nano),
+		Payload:    payload,
+		Provenance: []ledger.Provenance{{Type: "consent_mandate", Ref: m.ID, Status: status}},
+	}
+	if err := rcpt.Seal(); err != nil {
+		log.Printf("⚠️  consent mandate %s receipt: %v", m.ID, err)
+		return
+	}
+	if err := s.receipts.Append(rcpt); err != nil {
+		log.Printf("⚠️  consent mandate %s receipt: %v", m.ID, err)
+	}
+}
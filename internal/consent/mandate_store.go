@@ -0,0 +1,208 @@
+package consent
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// MandateStore persists consent mandates.
+type MandateStore interface {
+	// Create stores a new mandate.
+	Create(m *Mandate) error
+	// Get returns a mandate by ID, or ErrMandateNotFound.
+	Get(id string) (*Mandate, error)
+	// List returns the mandates granted by grantor and held by delegate;
+	// an empty filter matches any.
+	List(grantor, delegate string) ([]*Mandate, error)
+	// Update applies fn to the stored mandate and saves the result, with
+	// no other update in between. When fn fails nothing is saved.
+	Update(id string, fn func(*Mandate) error) (*Mandate, error)
+}
+
+// EnsureMandatesSchema creates the consent_mandates table. The signed
+// mandate is kept in doc; the columns are for lookup.
+func EnsureMandatesSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS consent_mandates (
+		id TEXT PRIMARY KEY,
+		grantor TEXT NOT NULL,
+		delegate TEXT NOT NULL,
+		issued_at TIMESTAMPTZ NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		revoked_at TIMESTAMPTZ,
+		doc JSONB NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_consent_mandates_grantor ON consent_mandates(grantor);
+	CREATE INDEX IF NOT EXISTS idx_consent_mandates_delegate ON consent_mandates(delegate);
+	`)
+	return err
+}
+
+// PGMandateStore keeps mandates in Postgres.
+type PGMandateStore struct {
+	db *sql.DB
+}
+
+// NewPGMandateStore returns a store over db. EnsureMandatesSchema must have
+// been run.
+func NewPGMandateStore(db *sql.DB) *PGMandateStore {
+	return &PGMandateStore{db: db}
+}
+
+func (s *PGMandateStore) Create(m *Mandate) error {
+	doc, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO consent_mandates (id, grantor, delegate, issued_at, expires_at, doc)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		m.ID, m.Grantor, m.Delegate, m.IssuedAt, m.ExpiresAt, doc)
+	if err != nil {
+		return fmt.Errorf("insert consent mandate: %w", err)
+	}
+	return nil
+}
+
+func (s *PGMandateStore) Get(id string) (*Mandate, error) {
+	return scanMandate(s.db.QueryRow(`SELECT doc FROM consent_mandates WHERE id = $1`, id))
+}
+
+func (s *PGMandateStore) List(grantor, delegate string) ([]*Mandate, error) {
+	rows, err := s.db.Query(`
+		SELECT doc FROM consent_mandates
+		WHERE ($1 = '' OR grantor = $1) AND ($2 = '' OR delegate = $2)
+		ORDER BY issued_at DESC, id DESC`, grantor, delegate)
+	if err != nil {
+		return nil, fmt.Errorf("list consent mandates: %w", err)
+	}
+	defer rows.Close()
+	var out []*Mandate
+	for rows.Next() {
+		m, err := scanMandate(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (s *PGMandateStore) Update(id string, fn func(*Mandate) error) (*Mandate, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	m, err := scanMandate(tx.QueryRow(`SELECT doc FROM consent_mandates WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(m); err != nil {
+		return nil, err
+	}
+	doc, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var revoked any
+	if m.RevokedAt != "" {
+		revoked = m.RevokedAt
+	}
+	if _, err := tx.Exec(`UPDATE consent_mandates SET revoked_at = $2, doc = $3 WHERE id = $1`,
+		id, revoked, doc); err != nil {
+		return nil, fmt.Errorf("update consent mandate: %w", err)
+	}
+	return m, tx.Commit()
+}
+
+func scanMandate(row interface{ Scan(...any) error }) (*Mandate, error) {
+	var doc []byte
+	if err := row.Scan(&doc); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMandateNotFound
+		}
+		return nil, err
+	}
+	var m Mandate
+	if err := json.Unmarshal(doc, &m); err != nil {
+		return nil, fmt.Errorf("decode consent mandate: %w", err)
+	}
+	return &m, nil
+}
+
+// MemoryMandateStore keeps mandates in memory, for tests and tools.
+type MemoryMandateStore struct {
+	mu       sync.Mutex
+	mandates map[string]*Mandate
+}
+
+// NewMemoryMandateStore returns an empty in-memory store.
+func NewMemoryMandateStore() *MemoryMandateStore {
+	return &MemoryMandateStore{mandates: map[string]*Mandate{}}
+}
+
+func (s *MemoryMandateStore) Create(m *Mandate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.mandates[m.ID]; ok {
+		return fmt.Errorf("consent mandate %s already exists", m.ID)
+	}
+	s.mandates[m.ID] = cloneMandate(m)
+	return nil
+}
+
+func (s *MemoryMandateStore) Get(id string) (*Mandate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.mandates[id]
+	if !ok {
+		return nil, ErrMandateNotFound
+	}
+	return cloneMandate(m), nil
+}
+
+func (s *MemoryMandateStore) List(grantor, delegate string) ([]*Mandate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*Mandate
+	for _, m := range s.mandates {
+		if (grantor == "" || m.Grantor == grantor) && (delegate == "" || m.Delegate == delegate) {
+			out = append(out, cloneMandate(m))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].IssuedAt != out[j].IssuedAt {
+			return out[i].IssuedAt > out[j].IssuedAt
+		}
+		return out[i].ID > out[j].ID
+	})
+	return out, nil
+}
+
+func (s *MemoryMandateStore) Update(id string, fn func(*Mandate) error) (*Mandate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.mandates[id]
+	if !ok {
+		return nil, ErrMandateNotFound
+	}
+	m := cloneMandate(stored)
+	if err := fn(m); err != nil {
+		return nil, err
+	}
+	s.mandates[id] = cloneMandate(m)
+	return m, nil
+}
+
+func cloneMandate(m *Mandate) *Mandate {
+	cp := *m
+	cp.Actions = append([]string(nil), m.Actions...)
+	cp.SchemaRefs = append([]string(nil), m.SchemaRefs...)
+	return &cp
+}
@@ -0,0 +1,182 @@
+package consent_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"dis-core/internal/consent"
+	"dis-core/internal/ledger"
+)
+
+// mandate returns a mandate from grantor to delegate for trade actions,
+// valid for a day and signed by the grantor.
+func mandate(t *testing.T, grantor, delegate string, maxDepth int) consent.Mandate {
+	t.Helper()
+	m := consent.Mandate{
+		Grantor:   grantor,
+		Delegate:  delegate,
+		Actions:   []string{"trade.*"},
+		MaxDepth:  maxDepth,
+		ExpiresAt: time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339Nano),
+	}
+	if err := consent.SignMandate(&m); err != nil {
+		t.Fatalf("SignMandate: %v", err)
+	}
+	return m
+}
+
+func TestDelegationVerify(t *testing.T) {
+	direct := mandate(t, "alice", "bob", 0)
+	hop1, hop2 := mandate(t, "alice", "bob", 1), mandate(t, "bob", "carol", 0)
+	tampered := direct
+	tampered.Actions = []string{"*"}
+	revoked := mandate(t, "alice", "bob", 0)
+	if err := consent.SignMandateRevocation(&revoked, ledger.NowRFC3339Nano()); err != nil {
+		t.Fatalf("SignMandateRevocation: %v", err)
+	}
+	now := time.Now()
+
+	cases := []struct {
+		name     string
+		mandates []consent.Mandate
+		agent    string
+		action   string
+		want     error
+	}{
+		{"direct", []consent.Mandate{direct}, "bob", "trade.execute", nil},
+		{"two hops", []consent.Mandate{hop1, hop2}, "carol", "trade.execute", nil},
+		{"too deep", []consent.Mandate{direct, hop2}, "carol", "trade.execute", consent.ErrNoMandate},
+		{"wrong agent", []consent.Mandate{direct}, "carol", "trade.execute", consent.ErrNoMandate},
+		{"action not covered", []consent.Mandate{direct}, "bob", "policy.update", consent.ErrNoMandate},
+		{"broken link", []consent.Mandate{hop2}, "carol", "trade.execute", consent.ErrNoMandate},
+		{"tampered", []consent.Mandate{tampered}, "bob", "policy.update", consent.ErrBadMandate},
+		{"revoked", []consent.Mandate{revoked}, "bob", "trade.execute", consent.ErrNoMandate},
+		{"empty", nil, "bob", "trade.execute", consent.ErrNoMandate},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := consent.Delegation{Principal: "alice", Mandates: tc.mandates}
+			if err := d.Verify(tc.agent, tc.action, "", now); !errors.Is(err, tc.want) {
+				t.Fatalf("Verify: %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+// TestMandateIssuedAt checks that a mandate is only issued with an
+// issued_at near the node's clock.
+func TestMandateIssuedAt(t *testing.T) {
+	s := consent.NewMandates(consent.NewMemoryMandateStore(), nil)
+	issuedAt := func(at time.Time) *consent.Mandate {
+		m := consent.Mandate{
+			Grantor:   "alice",
+			Delegate:  "bob",
+			Actions:   []string{"trade.*"},
+			IssuedAt:  at.UTC().Format(time.RFC3339Nano),
+			ExpiresAt: at.Add(24 * time.Hour).UTC().Format(time.RFC3339Nano),
+		}
+		if err := consent.SignMandate(&m); err != nil {
+			t.Fatalf("SignMandate: %v", err)
+		}
+		return &m
+	}
+
+	cases := []struct {
+		name string
+		at   time.Time
+		want error
+	}{
+		{"now", time.Now(), nil},
+		{"backdated", time.Now().Add(-time.Hour), consent.ErrBadMandate},
+		{"postdated", time.Now().Add(consent.ClockSkew + time.Minute), consent.ErrBadMandate},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := s.Issue(issuedAt(tc.at)); !errors.Is(err, tc.want) {
+				t.Fatalf("Issue: %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+// TestMandateResolveActor checks that a chain sent by a client is checked
+// against the stored mandates, so a revocation cannot be stripped off.
+func TestMandateResolveActor(t *testing.T) {
+	reg := consent.NewMandates(consent.NewMemoryMandateStore(), ledger.NewMemoryStore())
+	hop1, hop2 := mandate(t, "alice", "bob", 1), mandate(t, "bob", "carol", 0)
+	revoked := mandate(t, "alice", "dave", 0)
+	for _, m := range []consent.Mandate{hop1, hop2, revoked} {
+		m := m
+		if _, err := reg.Issue(&m); err != nil {
+			t.Fatalf("Issue: %v", err)
+		}
+	}
+	stripped := revoked // the copy the client kept before the revocation
+	signed := revoked
+	if err := consent.SignMandateRevocation(&signed, ledger.NowRFC3339Nano()); err != nil {
+		t.Fatalf("SignMandateRevocation: %v", err)
+	}
+	if _, err := reg.Revoke(revoked.ID, signed.RevokedAt, signed.RevokeKeyID, signed.RevokeSignature); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	widened := hop1
+	widened.Actions = []string{"*"}
+	unknown := mandate(t, "alice", "bob", 0)
+
+	cases := []struct {
+		name     string
+		agent    string
+		mandates []consent.Mandate // nil: resolve from the store
+		want     error
+	}{
+		{"resolved", "carol", nil, nil},
+		{"sent chain", "carol", []consent.Mandate{hop1, hop2}, nil},
+		{"revocation stripped", "dave", []consent.Mandate{stripped}, consent.ErrNoMandate},
+		{"revoked, resolved", "dave", nil, consent.ErrNoMandate},
+		{"not on record", "bob", []consent.Mandate{unknown}, consent.ErrNoMandate},
+		{"differs from record", "bob", []consent.Mandate{widened}, consent.ErrNoMandate},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ref := consent.ActorRef{ID: tc.agent, Via: &consent.Delegation{Principal: "alice", Mandates: tc.mandates}}
+			err := reg.ResolveActor(&ref, "trade.execute", "")
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("ResolveActor: %v, want %v", err, tc.want)
+			}
+			if err == nil && len(ref.Via.Mandates) == 0 {
+				t.Fatal("resolved chain is empty")
+			}
+		})
+	}
+}
+
+// TestMandateReceipts checks that issuing and revoking a mandate each
+// record a receipt sealed by the node that verifies.
+func TestMandateReceipts(t *testing.T) {
+	store := ledger.NewMemoryStore()
+	reg := consent.NewMandates(consent.NewMemoryMandateStore(), store)
+	m := mandate(t, "alice", "bob", 0)
+	if _, err := reg.Issue(&m); err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	signed := m
+	if err := consent.SignMandateRevocation(&signed, ledger.NowRFC3339Nano()); err != nil {
+		t.Fatalf("SignMandateRevocation: %v", err)
+	}
+	if _, err := reg.Revoke(m.ID, signed.RevokedAt, signed.RevokeKeyID, signed.RevokeSignature); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+
+	list, err := store.List(ledger.ListOptions{SchemaRef: consent.MandateSchemaRef})
+	if err != nil || len(list) != 2 {
+		t.Fatalf("List = %d receipts, %v; want 2", len(list), err)
+	}
+	for _, rcpt := range list {
+		raw, _ := json.Marshal(rcpt)
+		if ok, err := ledger.VerifyReceiptJSON(raw); !ok || err != nil || rcpt.By != ledger.NodeDomain {
+			t.Fatalf("receipt %s (%s) by %s does not verify: %v", rcpt.ReceiptID, rcpt.Action, rcpt.By, err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package consent
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"dis-core/internal/bridge"
+	"dis-core/internal/db"
+	"dis-core/internal/ledger"
+	"dis-core/internal/util/crypto"
+)
+
+var ErrBadKeyRegistration = errors.New("invalid party key registration")
+
+// KeyRegistration enrolls a public key whose private half a party keeps on
+// its own side, so its votes, mandates and revocations are signed there
+// and the node cannot make them. Signature, by the new key over
+// KeyRegistrationDigest, proves the party holds it. A party that already
+// has an active key must also have that key sign the same digest as
+// Endorsement. A party with no keys must be vouched for instead: the
+// domain PartyAttester names signs the same digest as Attestation.
+type KeyRegistration struct {
+	Party        string `json:"party"`
+	PublicKeyB64 string `json:"public_key_b64"`
+	RegisteredAt string `json:"registered_at"`
+	Signature    string `json:"signature"`
+	EndorseKeyID string `json:"endorse_key_id,omitempty"`
+	Endorsement  string `json:"endorsement,omitempty"`
+	AttestedBy   string `json:"attested_by,omitempty"`
+	AttestKeyID  string `json:"attest_key_id,omitempty"`
+	Attestation  string `json:"attestation,omitempty"`
+}
+
+// KeyRegistrationDigest is the message both the new key and, when there
+// is one, the party's current key sign.
+func KeyRegistrationDigest(party, publicKeyB64, registeredAt string) string {
+	canon, _ := bridge.CanonicalJSON(map[string]any{
+		"party":          party,
+		"public_key_b64": publicKeyB64,
+		"registered_at":  registeredAt,
+	})
+	sum := sha256.Sum256(canon)
+	return hex.EncodeToString(sum[:])
+}
+
+// PartyAttester returns the domain that must vouch for party's first key:
+// the namespace of its identity record, or, for a party with no record
+// (or no identities table), this node's domain. A party whose identity is
+// inactive cannot be enrolled.
+func PartyAttester(identities *sql.DB, party string) (string, error) {
+	if identities == nil {
+		return ledger.NodeDomain, nil
+	}
+	ident, err := db.GetIdentity(identities, party)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case ident == nil || ident.Namespace == "":
+		return ledger.NodeDomain, nil
+	case !ident.Active:
+		return "", fmt.Errorf("%w: identity %s is inactive", ErrBadKeyRegistration, party)
+	default:
+		return ident.Namespace, nil
+	}
+}
+
+// AttestPartyKey signs reg as attester with attester's active key in ks,
+// vouching for the party's first key.
+func AttestPartyKey(ks crypto.KeyStore, reg KeyRegistration, attester string) (KeyRegistration, error) {
+	key, err := ks.Active(attester)
+	if err != nil {
+		return KeyRegistration{}, err
+	}
+	sig, err := ks.Sign(key.KeyID, []byte(KeyRegistrationDigest(reg.Party, reg.PublicKeyB64, reg.RegisteredAt)))
+	if err != nil {
+		return KeyRegistration{}, err
+	}
+	reg.AttestedBy, reg.AttestKeyID, reg.Attestation = attester, key.KeyID, sig
+	return reg, nil
+}
+
+// RegisterPartyKey checks reg and records its key in the default KeyStore
+// as the party's active key from now on, retiring the previous one. The
+// node holds no private key for it. identities, when set, decides who may
+// vouch for a first key; see PartyAttester.
+func RegisterPartyKey(reg KeyRegistration, identities *sql.DB) (crypto.KeyRecord, error) {
+	if reg.Party == "" || reg.PublicKeyB64 == "" {
+		return crypto.KeyRecord{}, fmt.Errorf("%w: party and public_key_b64 are required", ErrBadKeyRegistration)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, reg.RegisteredAt); err != nil {
+		return crypto.KeyRecord{}, fmt.Errorf("%w: registered_at: %v", ErrBadKeyRegistration, err)
+	}
+	digest := []byte(KeyRegistrationDigest(reg.Party, reg.PublicKeyB64, reg.RegisteredAt))
+	key := crypto.KeyRecord{Domain: reg.Party, PublicKeyB64: reg.PublicKeyB64}
+	if !key.Verify(digest, reg.Signature) {
+		return crypto.KeyRecord{}, fmt.Errorf("%w: signature by the new key does not verify", ErrBadKeyRegistration)
+	}
+
+	ks := crypto.DefaultKeyStore()
+	history, err := ks.History(reg.Party)
+	if err != nil {
+		return crypto.KeyRecord{}, err
+	}
+	if current, err := ks.Active(reg.Party); err == nil {
+		if reg.EndorseKeyID != current.KeyID {
+			return crypto.KeyRecord{}, fmt.Errorf("%w: endorsement must be by %s's active key %s", ErrBadKeyRegistration, reg.Party, current.KeyID)
+		}
+		if !current.Verify(digest, reg.Endorsement) {
+			return crypto.KeyRecord{}, fmt.Errorf("%w: endorsement by %s does not verify", ErrBadKeyRegistration, reg.Party)
+		}
+	} else if len(history) > 0 {
+		return crypto.KeyRecord{}, fmt.Errorf("%w: %s has no active key to endorse a new one", ErrBadKeyRegistration, reg.Party)
+	} else if err := verifyAttestation(ks, identities, reg, digest); err != nil {
+		return crypto.KeyRecord{}, err
+	}
+	return ks.Register(reg.Party, reg.PublicKeyB64, time.Now())
+}
+
+// verifyAttestation checks that the domain entitled to vouch for reg's
+// party signed digest with its active key.
+func verifyAttestation(ks crypto.KeyStore, identities *sql.DB, reg KeyRegistration, digest []byte) error {
+	attester, err := PartyAttester(identities, reg.Party)
+	if err != nil {
+		return err
+	}
+	if reg.AttestedBy != attester {
+		return fmt.Errorf("%w: %s's first key must be attested by %s", ErrBadKeyRegistration, reg.Party, attester)
+	}
+	current, err := ks.Active(attester)
+	if err != nil || reg.AttestKeyID != current.KeyID {
+		return fmt.Errorf("%w: attestation must be by %s's active key", ErrBadKeyRegistration, attester)
+	}
+	if !current.Verify(digest, reg.Attestation) {
+		return fmt.Errorf("%w: attestation by %s does not verify", ErrBadKeyRegistration, attester)
+	}
+	return nil
+}
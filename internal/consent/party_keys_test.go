@@ -0,0 +1,161 @@
+package consent_test
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"dis-core/internal/consent"
+	"dis-core/internal/db"
+	"dis-core/internal/ledger"
+	"dis-core/internal/util/crypto"
+)
+
+// partyKey is a key pair a party keeps on its own side.
+type partyKey struct {
+	pub  string
+	priv ed25519.PrivateKey
+}
+
+func newPartyKey(t *testing.T) partyKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return partyKey{pub: base64.StdEncoding.EncodeToString(pub), priv: priv}
+}
+
+func (k partyKey) sign(msg string) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(k.priv, []byte(msg)))
+}
+
+func (k partyKey) id(t *testing.T) string {
+	t.Helper()
+	pub, err := crypto.KeyRecord{PublicKeyB64: k.pub}.PublicKey()
+	if err != nil {
+		t.Fatalf("PublicKey: %v", err)
+	}
+	return crypto.KeyID(pub)
+}
+
+func TestRegisterPartyKey(t *testing.T) {
+	party := "erin-" + ledger.GenerateUUID()
+	first, second, third := newPartyKey(t), newPartyKey(t), newPartyKey(t)
+	register := func(next, proof, endorser *partyKey) consent.KeyRegistration {
+		reg := consent.KeyRegistration{Party: party, PublicKeyB64: next.pub, RegisteredAt: ledger.NowRFC3339Nano()}
+		digest := consent.KeyRegistrationDigest(reg.Party, reg.PublicKeyB64, reg.RegisteredAt)
+		reg.Signature = proof.sign(digest)
+		if endorser != nil {
+			reg.EndorseKeyID, reg.Endorsement = endorser.id(t), endorser.sign(digest)
+		}
+		return reg
+	}
+	attest := func(reg consent.KeyRegistration, by string) consent.KeyRegistration {
+		attested, err := consent.AttestPartyKey(crypto.DefaultKeyStore(), reg, by)
+		if err != nil {
+			t.Fatalf("AttestPartyKey: %v", err)
+		}
+		return attested
+	}
+
+	// Steps run in order against the same party.
+	steps := []struct {
+		name   string
+		reg    consent.KeyRegistration
+		active *partyKey
+		want   error
+	}{
+		{"without proof of the key", register(&first, &second, nil), nil, consent.ErrBadKeyRegistration},
+		{"first use unattested", register(&first, &first, nil), nil, consent.ErrBadKeyRegistration},
+		{"first use attested by another party", attest(register(&first, &first, nil), "alice"), nil, consent.ErrBadKeyRegistration},
+		{"first use attested by the node", attest(register(&first, &first, nil), ledger.NodeDomain), &first, nil},
+		{"replacement without endorsement", register(&second, &second, nil), &first, consent.ErrBadKeyRegistration},
+		{"replacement endorsed by another key", register(&second, &second, &third), &first, consent.ErrBadKeyRegistration},
+		{"replacement endorsed", register(&second, &second, &first), &second, nil},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if _, err := consent.RegisterPartyKey(step.reg, nil); !errors.Is(err, step.want) {
+				t.Fatalf("RegisterPartyKey: %v, want %v", err, step.want)
+			}
+			if step.active == nil {
+				return
+			}
+			active, err := crypto.DefaultKeyStore().Active(party)
+			if err != nil || active.PublicKeyB64 != step.active.pub {
+				t.Fatalf("active key: %+v, %v", active, err)
+			}
+		})
+	}
+
+	// A vote the party signs on its own side counts; the node cannot sign
+	// one for it.
+	m := consent.NewRequests(consent.NewMemoryRequestStore(), nil)
+	req, err := m.Open(consent.OpenRequest{
+		Action:    "trade.execute",
+		Initiator: consent.ActorRef{ID: "dave"},
+		Affected:  []consent.ActorRef{{ID: party}},
+		TTL:       time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := req.SignVote(party, consent.Approve); err == nil {
+		t.Fatal("node signed a vote with a key it does not hold")
+	}
+	v := consent.Vote{Voter: party, Choice: consent.Approve, CastAt: ledger.NowRFC3339Nano(), KeyID: second.id(t)}
+	v.Signature = second.sign(consent.VoteDigest(req.Digest(), v.Voter, v.Choice, v.CastAt))
+	if req, err = m.Vote(req.ID, v); err != nil {
+		t.Fatalf("Vote: %v", err)
+	}
+	if req.State != consent.RequestGranted {
+		t.Fatalf("request state %s, want granted", req.State)
+	}
+}
+
+// TestPartyAttester checks who may vouch for a first key against the
+// identities table of the scratch database named by DIS_TEST_DB_DSN.
+func TestPartyAttester(t *testing.T) {
+	if got, err := consent.PartyAttester(nil, "anyone"); err != nil || got != ledger.NodeDomain {
+		t.Fatalf("without identities: %q, %v", got, err)
+	}
+	dsn := os.Getenv("DIS_TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("DIS_TEST_DB_DSN not set")
+	}
+	database, err := db.ConnectPostgres(dsn)
+	if err != nil {
+		t.Fatalf("ConnectPostgres: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := db.EnsureIdentitiesSchema(database); err != nil {
+		t.Fatalf("EnsureIdentitiesSchema: %v", err)
+	}
+	member, retired, individual := "member-"+ledger.GenerateUUID(), "retired-"+ledger.GenerateUUID(), "solo-"+ledger.GenerateUUID()
+	if _, err := db.UpsertIdentity(database, member, "domain.org", true); err != nil {
+		t.Fatalf("UpsertIdentity: %v", err)
+	}
+	if _, err := db.UpsertIdentity(database, retired, "domain.org", false); err != nil {
+		t.Fatalf("UpsertIdentity: %v", err)
+	}
+
+	cases := []struct {
+		party string
+		want  string
+		err   error
+	}{
+		{member, "domain.org", nil},
+		{retired, "", consent.ErrBadKeyRegistration},
+		{individual, ledger.NodeDomain, nil},
+	}
+	for _, tc := range cases {
+		got, err := consent.PartyAttester(database, tc.party)
+		if got != tc.want || !errors.Is(err, tc.err) {
+			t.Fatalf("PartyAttester(%s) = %q, %v; want %q, %v", tc.party, got, err, tc.want, tc.err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package consent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// RegoRulePackage is the package prefix of consent rules written in Rego. A
+// module declaring `package consent.rules.<id>` defines rule <id>.
+const RegoRulePackage = "consent.rules"
+
+// RegoRule is a ConsentRule written in Rego. Its `verdict` document must be
+// an object such as
+//
+//	{"kind": "block", "reason": "...", "legitimacy_delta": 0.1, "backoff_ms": 5000, "decisive": true}
+//
+// evaluated against the input {"request", "rule", "legitimacy", "now"}.
+// An undefined verdict passes.
+type RegoRule struct {
+	id    string
+	query rego.PreparedEvalQuery
+}
+
+// NewRegoRule compiles a Rego consent rule module.
+func NewRegoRule(name, src string) (*RegoRule, error) {
+	mod, err := ast.ParseModule(name, src)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", name, err)
+	}
+	pkg := strings.TrimPrefix(mod.Package.Path.String(), "data.")
+	id, ok := strings.CutPrefix(pkg, RegoRulePackage+".")
+	if !ok || id == "" || strings.Contains(id, ".") {
+		return nil, fmt.Errorf("%s: package %s is not %s.<rule_id>", name, pkg, RegoRulePackage)
+	}
+	q, err := rego.New(
+		rego.Query("data."+pkg+".verdict"),
+		rego.Module(name, src),
+	).PrepareForEval(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("prepare %s: %w", name, err)
+	}
+	return &RegoRule{id: id, query: q}, nil
+}
+
+func (r *RegoRule) ID() string { return r.id }
+
+func (r *RegoRule) Evaluate(ctx context.Context, rc RuleContext) (Verdict, error) {
+	input, err := regoInput(rc)
+	if err != nil {
+		return Verdict{}, err
+	}
+	rs, err := r.query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return Verdict{}, err
+	}
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return Verdict{Kind: Pass}, nil
+	}
+	raw, err := json.Marshal(rs[0].Expressions[0].Value)
+	if err != nil {
+		return Verdict{}, err
+	}
+	var out struct {
+		Kind            VerdictKind `json:"kind"`
+		Reason          string      `json:"reason"`
+		LegitimacyDelta float64     `json:"legitimacy_delta"`
+		BackoffMS       int64       `json:"backoff_ms"`
+		Decisive        bool        `json:"decisive"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return Verdict{}, fmt.Errorf("verdict: %w", err)
+	}
+	switch out.Kind {
+	case Pass, Block, Throttle, Adjust:
+	case "":
+		out.Kind = Pass
+	default:
+		return Verdict{}, fmt.Errorf("verdict: unknown kind %q", out.Kind)
+	}
+	return Verdict{
+		Kind:            out.Kind,
+		Reason:          out.Reason,
+		LegitimacyDelta: out.LegitimacyDelta,
+		Backoff:         time.Duration(out.BackoffMS) * time.Millisecond,
+		Decisive:        out.Decisive,
+	}, nil
+}
+
+// regoInput is the JSON form of rc handed to Rego rules.
+func regoInput(rc RuleContext) (map[string]any, error) {
+	raw, err := json.Marshal(map[string]any{
+		"request":    rc.Request,
+		"rule":       rc.Rule,
+		"legitimacy": rc.Legitimacy,
+		"now":        rc.Now.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var input map[string]any
+	err = json.Unmarshal(raw, &input)
+	return input, err
+}
+
+// LoadRegoRules compiles every .rego file in dir and registers the rules
+// in reg. A missing directory registers nothing.
+func LoadRegoRules(dir string, reg *RuleRegistry) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.rego"))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, p := range paths {
+		src, err := os.ReadFile(p)
+		if err != nil {
+			return ids, err
+		}
+		rule, err := NewRegoRule(filepath.Base(p), string(src))
+		if err != nil {
+			return ids, err
+		}
+		if err := reg.Register(rule); err != nil {
+			return ids, err
+		}
+		ids = append(ids, rule.ID())
+	}
+	return ids, nil
+}
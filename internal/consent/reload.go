@@ -0,0 +1,109 @@
+package consent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"dis-core/internal/ledger"
+)
+
+// ConfigSchemaRef is the schema_ref of receipts recording a config change.
+const ConfigSchemaRef = "consent.config.v0"
+
+// DefaultReloadInterval is how often Watch checks the config file.
+const DefaultReloadInterval = 2 * time.Second
+
+// Config returns the configuration currently in force.
+func (g *Gate) Config() *Config {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.cfg
+}
+
+// SetConfig atomically replaces the gate configuration. Decisions already
+// in progress finish under the config they started with. Unless the new
+// config hashes the same as the old one, a consent.config.v0 receipt
+// recording both hashes is appended to the gate's receipt store before the
+// swap; if that receipt cannot be sealed or appended the old config stays
+// in force.
+func (g *Gate) SetConfig(cfg *Config, source string) (*ledger.Receipt, error) {
+	g.setMu.Lock()
+	defer g.setMu.Unlock()
+	old := g.Config()
+	oldHash := ""
+	if old != nil {
+		oldHash = old.Hash()
+	}
+	newHash := cfg.Hash()
+	var rcpt *ledger.Receipt
+	if oldHash != newHash {
+		rcpt = &ledger.Receipt{
+			ReceiptID: ledger.GenerateUUID(),
+			SchemaRef: ConfigSchemaRef,
+			By:        ledger.NodeDomain,
+			Action:    "consent.config.update",
+			CreatedAt: g.timeNow().UTC().Format(time.RFC3339Nano),
+			Payload: map[string]any{
+				"source":   source,
+				"version":  cfg.Version,
+				"old_hash": oldHash,
+				"new_hash": newHash,
+			},
+		}
+		if err := rcpt.Seal(); err != nil {
+			return nil, fmt.Errorf("seal consent config receipt: %w", err)
+		}
+		if err := g.receiptStore().Append(rcpt); err != nil {
+			return nil, fmt.Errorf("append consent config receipt: %w", err)
+		}
+	}
+	g.mu.Lock()
+	g.cfg = cfg
+	g.version = cfg.Version
+	g.mu.Unlock()
+	return rcpt, nil
+}
+
+// Watch polls the config file at path until ctx is done, validating each
+// new revision against schema and swapping it in with SetConfig. A revision
+// that fails to load or validate is logged once and the current config is
+// kept; one SetConfig refuses is retried on the next tick.
+func (g *Gate) Watch(ctx context.Context, path string, schema *Schema, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	last, _ := os.ReadFile(path)
+	var rejected []byte
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || bytes.Equal(data, last) || bytes.Equal(data, rejected) {
+			continue
+		}
+		cfg, err := ParseConfig(data, schema)
+		if err != nil {
+			rejected = data
+			log.Printf("⚠️  consent config %s rejected: %v", path, err)
+			continue
+		}
+		rcpt, err := g.SetConfig(cfg, path)
+		if err != nil {
+			log.Printf("⚠️  consent config %s: %v", path, err)
+			continue
+		}
+		last = data
+		if rcpt != nil {
+			log.Printf("🔁 Consent config reloaded from %s (%s)", path, rcpt.ReceiptID)
+		}
+	}
+}
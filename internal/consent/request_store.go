@@ -0,0 +1,222 @@
+package consent
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RequestStore persists consent requests.
+type RequestStore interface {
+	// Create stores a new request.
+	Create(r *Request) error
+	// Get returns a request by ID, or ErrRequestNotFound.
+	Get(id string) (*Request, error)
+	// List returns requests in state (all when empty), newest first.
+	List(state RequestState, limit int) ([]*Request, error)
+	// Update applies fn to the stored request and saves the result, with
+	// no other update to the same request in between. When fn fails
+	// nothing is saved.
+	Update(id string, fn func(*Request) error) (*Request, error)
+}
+
+// EnsureRequestsSchema creates the consent_requests table. The full request,
+// votes included, is kept in doc; the columns are for listing and expiry.
+func EnsureRequestsSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS consent_requests (
+		id TEXT PRIMARY KEY,
+		action TEXT NOT NULL,
+		initiator TEXT,
+		state TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		decided_at TIMESTAMPTZ,
+		doc JSONB NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_consent_requests_state ON consent_requests(state, created_at DESC);
+	`)
+	return err
+}
+
+// PGRequestStore keeps consent requests in Postgres.
+type PGRequestStore struct {
+	db *sql.DB
+}
+
+// NewPGRequestStore returns a store over db. EnsureRequestsSchema must have
+// been run.
+func NewPGRequestStore(db *sql.DB) *PGRequestStore {
+	return &PGRequestStore{db: db}
+}
+
+func (s *PGRequestStore) Create(r *Request) error {
+	doc, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO consent_requests (id, action, initiator, state, created_at, expires_at, doc)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		r.ID, r.Action, r.Initiator.ID, string(r.State), r.CreatedAt, r.ExpiresAt, doc)
+	if err != nil {
+		return fmt.Errorf("insert consent request: %w", err)
+	}
+	return nil
+}
+
+func (s *PGRequestStore) Get(id string) (*Request, error) {
+	return scanRequest(s.db.QueryRow(`SELECT doc FROM consent_requests WHERE id = $1`, id))
+}
+
+func (s *PGRequestStore) List(state RequestState, limit int) ([]*Request, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	rows, err := s.db.Query(`
+		SELECT doc FROM consent_requests
+		WHERE $1 = '' OR state = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`, string(state), limit)
+	if err != nil {
+		return nil, fmt.Errorf("list consent requests: %w", err)
+	}
+	defer rows.Close()
+	var out []*Request
+	for rows.Next() {
+		r, err := scanRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func (s *PGRequestStore) Update(id string, fn func(*Request) error) (*Request, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	r, err := scanRequest(tx.QueryRow(`SELECT doc FROM consent_requests WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(r); err != nil {
+		return nil, err
+	}
+	doc, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	var decided any
+	if r.DecidedAt != "" {
+		decided = r.DecidedAt
+	}
+	if _, err := tx.Exec(`
+		UPDATE consent_requests SET state = $2, decided_at = $3, doc = $4 WHERE id = $1`,
+		id, string(r.State), decided, doc); err != nil {
+		return nil, fmt.Errorf("update consent request: %w", err)
+	}
+	return r, tx.Commit()
+}
+
+func scanRequest(row interface{ Scan(...any) error }) (*Request, error) {
+	var doc []byte
+	if err := row.Scan(&doc); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRequestNotFound
+		}
+		return nil, err
+	}
+	var r Request
+	if err := json.Unmarshal(doc, &r); err != nil {
+		return nil, fmt.Errorf("decode consent request: %w", err)
+	}
+	return &r, nil
+}
+
+// MemoryRequestStore keeps consent requests in memory, for tests and tools.
+type MemoryRequestStore struct {
+	mu   sync.Mutex
+	reqs map[string]*Request
+}
+
+// NewMemoryRequestStore returns an empty in-memory store.
+func NewMemoryRequestStore() *MemoryRequestStore {
+	return &MemoryRequestStore{reqs: map[string]*Request{}}
+}
+
+func (s *MemoryRequestStore) Create(r *Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.reqs[r.ID]; ok {
+		return fmt.Errorf("consent request %s already exists", r.ID)
+	}
+	s.reqs[r.ID] = cloneRequest(r)
+	return nil
+}
+
+func (s *MemoryRequestStore) Get(id string) (*Request, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.reqs[id]
+	if !ok {
+		return nil, ErrRequestNotFound
+	}
+	return cloneRequest(r), nil
+}
+
+func (s *MemoryRequestStore) List(state RequestState, limit int) ([]*Request, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*Request
+	for _, r := range s.reqs {
+		if state == "" || r.State == state {
+			out = append(out, cloneRequest(r))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		ti, _ := time.Parse(time.RFC3339Nano, out[i].CreatedAt)
+		tj, _ := time.Parse(time.RFC3339Nano, out[j].CreatedAt)
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return out[i].ID > out[j].ID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *MemoryRequestStore) Update(id string, fn func(*Request) error) (*Request, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.reqs[id]
+	if !ok {
+		return nil, ErrRequestNotFound
+	}
+	r := cloneRequest(stored)
+	if err := fn(r); err != nil {
+		return nil, err
+	}
+	s.reqs[id] = cloneRequest(r)
+	return r, nil
+}
+
+func cloneRequest(r *Request) *Request {
+	cp := *r
+	cp.Affected = append([]ActorRef(nil), r.Affected...)
+	cp.Votes = append([]Vote{}, r.Votes...)
+	return &cp
+}
@@ -0,0 +1,601 @@
+package consent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"dis-core/internal/bridge"
+	"dis-core/internal/ledger"
+	"dis-core/internal/util/crypto"
+)
+
+// RequestSchemaRef is the schema_ref of consent request lifecycle receipts.
+const RequestSchemaRef = "consent.request.v0"
+
+// RequestState is where a consent request is in its lifecycle.
+type RequestState string
+
+const (
+	RequestOpen    RequestState = "open"
+	RequestGranted RequestState = "granted"
+	RequestDenied  RequestState = "denied"
+	RequestExpired RequestState = "expired"
+)
+
+// Vote choices.
+const (
+	Approve = "approve"
+	Reject  = "reject"
+)
+
+// Defaults from dis_consent.v0.2 for requests that leave them unset.
+const (
+	DefaultRequestThreshold = 0.66
+	DefaultRequestQuorum    = 3
+	DefaultRequestTTL       = 24 * time.Hour
+)
+
+// ClockSkew bounds how far the time a party stamps on what it signs, such
+// as a vote's cast_at, may be from this node's clock when it is accepted.
+// The stamp picks the key, and the mandates, the signature is checked
+// against, so a backdated one could otherwise revive a revoked key.
+var ClockSkew = 5 * time.Minute
+
+var (
+	ErrRequestNotFound = errors.New("consent request not found")
+	ErrRequestClosed   = errors.New("consent request is no longer open")
+	ErrNotAffected     = errors.New("voter is not an affected party of the request")
+	ErrAlreadyVoted    = errors.New("voter has already voted on the request")
+	ErrBadVote         = errors.New("invalid vote")
+	ErrBadEvidence     = errors.New("consent evidence does not verify")
+)
+
+// Request collects the consent of the parties affected by an action. Each
+// affected party casts one signed vote; once Quorum votes are in and the
+// approval ratio reaches Threshold the request is granted, and it is denied
+// as soon as the outstanding votes could no longer carry it. Requests still
+// open at ExpiresAt expire.
+type Request struct {
+	ID        string       `json:"id"`
+	Action    string       `json:"action"`
+	SchemaRef string       `json:"schema_ref,omitempty"`
+	Initiator ActorRef     `json:"initiator"`
+	Affected  []ActorRef   `json:"affected"`
+	Quorum    int          `json:"quorum"`
+	Threshold float64      `json:"threshold"`
+	CreatedAt string       `json:"created_at"`
+	ExpiresAt string       `json:"expires_at"`
+	State     RequestState `json:"state"`
+	DecidedAt string       `json:"decided_at,omitempty"`
+	Votes     []Vote       `json:"votes"`
+}
+
+// Vote is one affected party's signature over VoteDigest. A proxy vote is
+// cast by Voter for the affected party OnBehalfOf, signs ProxyVoteDigest,
+// and carries the Delegation that authorizes it.
+type Vote struct {
+	Voter        string      `json:"voter"`
+	OnBehalfOf   string      `json:"on_behalf_of,omitempty"`
+	Delegation   *Delegation `json:"delegation,omitempty"`
+	Choice       string      `json:"choice"`
+	CastAt       string      `json:"cast_at"`
+	KeyID        string      `json:"key_id"`
+	PublicKeyB64 string      `json:"public_key_b64,omitempty"`
+	Signature    string      `json:"signature"`
+}
+
+// Party is the affected party the vote counts for.
+func (v Vote) Party() string {
+	if v.OnBehalfOf != "" {
+		return v.OnBehalfOf
+	}
+	return v.Voter
+}
+
+func (v Vote) digest(requestDigest string) string {
+	if v.OnBehalfOf != "" {
+		return ProxyVoteDigest(requestDigest, v.Voter, v.OnBehalfOf, v.Choice, v.CastAt)
+	}
+	return VoteDigest(requestDigest, v.Voter, v.Choice, v.CastAt)
+}
+
+// Tally counts the votes on a request.
+type Tally struct {
+	Approvals  int     `json:"approvals"`
+	Rejections int     `json:"rejections"`
+	Eligible   int     `json:"eligible"`
+	Quorum     int     `json:"quorum"`
+	Threshold  float64 `json:"threshold"`
+}
+
+// Digest is the hex SHA-256 of the request's immutable fields. Votes sign
+// it, so a vote cannot be moved to another request or survive a change to
+// the action or the parties.
+func (r *Request) Digest() string {
+	affected := make([]string, len(r.Affected))
+	for i, a := range r.Affected {
+		affected[i] = a.ID
+	}
+	canon, err := bridge.CanonicalJSON(map[string]any{
+		"id":         r.ID,
+		"action":     r.Action,
+		"schema_ref": r.SchemaRef,
+		"initiator":  r.Initiator.ID,
+		"affected":   affected,
+		"quorum":     r.Quorum,
+		"threshold":  r.Threshold,
+		"created_at": r.CreatedAt,
+		"expires_at": r.ExpiresAt,
+	})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(canon)
+	return hex.EncodeToString(sum[:])
+}
+
+// VoteDigest is the message a voter signs.
+func VoteDigest(requestDigest, voter, choice, castAt string) string {
+	canon, _ := bridge.CanonicalJSON(map[string]any{
+		"request": requestDigest,
+		"voter":   voter,
+		"choice":  choice,
+		"cast_at": castAt,
+	})
+	sum := sha256.Sum256(canon)
+	return hex.EncodeToString(sum[:])
+}
+
+// ProxyVoteDigest is the message a delegate signs when voting for
+// onBehalfOf.
+func ProxyVoteDigest(requestDigest, voter, onBehalfOf, choice, castAt string) string {
+	canon, _ := bridge.CanonicalJSON(map[string]any{
+		"request":      requestDigest,
+		"voter":        voter,
+		"on_behalf_of": onBehalfOf,
+		"choice":       choice,
+		"cast_at":      castAt,
+	})
+	sum := sha256.Sum256(canon)
+	return hex.EncodeToString(sum[:])
+}
+
+// SignVote casts voter's vote with its active key in the default KeyStore.
+// A party with a key registered by RegisterPartyKey signs VoteDigest
+// itself instead.
+func (r *Request) SignVote(voter, choice string) (Vote, error) {
+	return r.sign(Vote{Voter: voter, Choice: choice})
+}
+
+// SignProxyVote casts voter's vote for the affected party d.Principal. A
+// nil d leaves the chain to be resolved by Requests.Vote.
+func (r *Request) SignProxyVote(voter string, d *Delegation, principal, choice string) (Vote, error) {
+	return r.sign(Vote{Voter: voter, OnBehalfOf: principal, Delegation: d, Choice: choice})
+}
+
+func (r *Request) sign(v Vote) (Vote, error) {
+	ks := crypto.DefaultKeyStore()
+	key, err := ks.Active(v.Voter)
+	if err != nil {
+		return Vote{}, err
+	}
+	v.CastAt, v.KeyID = ledger.NowRFC3339Nano(), key.KeyID
+	if v.Signature, err = ks.Sign(key.KeyID, []byte(v.digest(r.Digest()))); err != nil {
+		return Vote{}, err
+	}
+	v.PublicKeyB64 = key.PublicKeyB64
+	return v, nil
+}
+
+// verifyVote checks v against the voter's key history at CastAt and
+// returns the key it was made with.
+func (r *Request) verifyVote(v Vote) (crypto.KeyRecord, error) {
+	if v.Choice != Approve && v.Choice != Reject {
+		return crypto.KeyRecord{}, fmt.Errorf("%w: choice %q", ErrBadVote, v.Choice)
+	}
+	at, err := time.Parse(time.RFC3339Nano, v.CastAt)
+	if err != nil {
+		return crypto.KeyRecord{}, fmt.Errorf("%w: cast_at: %v", ErrBadVote, err)
+	}
+	if created, err := time.Parse(time.RFC3339Nano, r.CreatedAt); err == nil && at.Before(created) {
+		return crypto.KeyRecord{}, fmt.Errorf("%w: cast_at %s is before the request was opened", ErrBadVote, v.CastAt)
+	}
+	key, err := crypto.KeyAt(crypto.DefaultKeyStore(), v.Voter, v.KeyID, at)
+	if err != nil {
+		return crypto.KeyRecord{}, fmt.Errorf("%w: %v", ErrBadVote, err)
+	}
+	if !key.Verify([]byte(v.digest(r.Digest())), v.Signature) {
+		return crypto.KeyRecord{}, fmt.Errorf("%w: signature by %s does not verify", ErrBadVote, v.Voter)
+	}
+	if v.OnBehalfOf != "" {
+		if v.Delegation == nil || v.Delegation.Principal != v.OnBehalfOf {
+			return crypto.KeyRecord{}, fmt.Errorf("%w: proxy vote by %s carries no mandate from %s", ErrBadVote, v.Voter, v.OnBehalfOf)
+		}
+		if err := v.Delegation.Verify(v.Voter, r.Action, r.SchemaRef, at); err != nil {
+			return crypto.KeyRecord{}, fmt.Errorf("%w: %v", ErrBadVote, err)
+		}
+	}
+	return key, nil
+}
+
+func (r *Request) affects(id string) bool {
+	for _, a := range r.Affected {
+		if a.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// addVote checks and records a vote on an open request. Its cast_at must
+// be within ClockSkew of now.
+func (r *Request) addVote(v Vote, now time.Time) error {
+	if r.State != RequestOpen {
+		return fmt.Errorf("%w: %s", ErrRequestClosed, r.State)
+	}
+	if !r.affects(v.Party()) {
+		return fmt.Errorf("%w: %s", ErrNotAffected, v.Party())
+	}
+	for _, prev := range r.Votes {
+		if prev.Party() == v.Party() {
+			return fmt.Errorf("%w: %s", ErrAlreadyVoted, v.Party())
+		}
+	}
+	if v.CastAt == "" {
+		v.CastAt = now.UTC().Format(time.RFC3339Nano)
+	}
+	if at, err := time.Parse(time.RFC3339Nano, v.CastAt); err == nil {
+		if d := now.Sub(at); d > ClockSkew || d < -ClockSkew {
+			return fmt.Errorf("%w: cast_at %s is not within %s of now", ErrBadVote, v.CastAt, ClockSkew)
+		}
+	}
+	key, err := r.verifyVote(v)
+	if err != nil {
+		return err
+	}
+	v.KeyID, v.PublicKeyB64 = key.KeyID, key.PublicKeyB64
+	r.Votes = append(r.Votes, v)
+	return nil
+}
+
+// Tally counts the recorded votes.
+func (r *Request) Tally() Tally {
+	t := Tally{Eligible: len(r.Affected), Quorum: r.Quorum, Threshold: r.Threshold}
+	for _, v := range r.Votes {
+		switch v.Choice {
+		case Approve:
+			t.Approvals++
+		case Reject:
+			t.Rejections++
+		}
+	}
+	return t
+}
+
+// Input converts the tally into a CheckConsent input, so the legacy
+// threshold check can run over collected votes rather than bare counts.
+func (r *Request) Input(domainRef string) ConsentInput {
+	t := r.Tally()
+	return ConsentInput{
+		DomainRef:   domainRef,
+		ActionRef:   r.Action,
+		Approvals:   t.Approvals,
+		Rejections:  t.Rejections,
+		MoralWeight: 1,
+		Quorum:      r.Quorum,
+	}
+}
+
+// outcome computes the state the votes and the clock put the request in.
+func (r *Request) outcome(now time.Time) RequestState {
+	t := r.Tally()
+	cast := t.Approvals + t.Rejections
+	if cast >= t.Quorum && float64(t.Approvals) >= t.Threshold*float64(cast) {
+		return RequestGranted
+	}
+	// Even if every outstanding party approved, the ratio would fall short.
+	outstanding := t.Eligible - cast
+	if float64(t.Approvals+outstanding) < t.Threshold*float64(cast+outstanding) {
+		return RequestDenied
+	}
+	if exp, err := time.Parse(time.RFC3339Nano, r.ExpiresAt); err == nil && !now.Before(exp) {
+		return RequestExpired
+	}
+	return RequestOpen
+}
+
+// advance moves an open request to the state its votes and the clock call
+// for, reporting whether it changed.
+func (r *Request) advance(now time.Time) bool {
+	if r.State != RequestOpen {
+		return false
+	}
+	next := r.outcome(now)
+	if next == RequestOpen {
+		return false
+	}
+	r.State = next
+	r.DecidedAt = now.UTC().Format(time.RFC3339Nano)
+	return true
+}
+
+// Verify checks a request presented as evidence: every vote must come from
+// an affected party, at most once, with a signature that verifies, and the
+// recorded state must be the one the votes produce.
+func (r *Request) Verify() error {
+	seen := map[string]bool{}
+	for _, v := range r.Votes {
+		if !r.affects(v.Party()) || seen[v.Party()] {
+			return fmt.Errorf("%w: unexpected vote for %s", ErrBadEvidence, v.Party())
+		}
+		seen[v.Party()] = true
+		if _, err := r.verifyVote(v); err != nil {
+			return fmt.Errorf("%w: %v", ErrBadEvidence, err)
+		}
+	}
+	if r.State == RequestGranted || r.State == RequestDenied {
+		decided, err := time.Parse(time.RFC3339Nano, r.DecidedAt)
+		if err != nil {
+			return fmt.Errorf("%w: decided_at: %v", ErrBadEvidence, err)
+		}
+		if got := r.outcome(decided); got != r.State {
+			return fmt.Errorf("%w: votes give %s, request says %s", ErrBadEvidence, got, r.State)
+		}
+	}
+	return nil
+}
+
+// Grants reports whether the request is verified evidence, still in force
+// at at, of consent by every party in affected to what req asks: the same
+// action under the same schema, by the same initiator.
+func (r *Request) Grants(req ConsentRequest, affected []ActorRef, at time.Time) error {
+	if err := r.Verify(); err != nil {
+		return err
+	}
+	if r.State != RequestGranted {
+		return fmt.Errorf("%w: request %s is %s", ErrBadEvidence, r.ID, r.State)
+	}
+	if r.Action != req.Action {
+		return fmt.Errorf("%w: request %s covers %q, not %q", ErrBadEvidence, r.ID, r.Action, req.Action)
+	}
+	if r.SchemaRef != req.SchemaRef {
+		return fmt.Errorf("%w: request %s covers schema %q, not %q", ErrBadEvidence, r.ID, r.SchemaRef, req.SchemaRef)
+	}
+	if r.Initiator.ID != req.Initiator.ID {
+		return fmt.Errorf("%w: request %s was opened by %s, not %s", ErrBadEvidence, r.ID, r.Initiator.ID, req.Initiator.ID)
+	}
+	if exp, err := time.Parse(time.RFC3339Nano, r.ExpiresAt); err != nil || !at.Before(exp) {
+		return fmt.Errorf("%w: request %s expired at %s", ErrBadEvidence, r.ID, r.ExpiresAt)
+	}
+	for _, a := range affected {
+		if !r.affects(a.ID) {
+			return fmt.Errorf("%w: %s is not a party to request %s", ErrBadEvidence, a.ID, r.ID)
+		}
+	}
+	return nil
+}
+
+// OpenRequest is the input to Requests.Open.
+type OpenRequest struct {
+	Action    string        `json:"action"`
+	SchemaRef string        `json:"schema_ref,omitempty"`
+	Initiator ActorRef      `json:"initiator"`
+	Affected  []ActorRef    `json:"affected"`
+	Quorum    int           `json:"quorum,omitempty"`
+	Threshold float64       `json:"threshold,omitempty"`
+	TTL       time.Duration `json:"-"`
+}
+
+// Requests runs the consent request lifecycle over a RequestStore,
+// receipting each opening and decision to receipts (when non-nil).
+type Requests struct {
+	store    RequestStore
+	receipts ledger.ReceiptStore
+	mandates *Mandates
+	now      func() time.Time
+}
+
+// NewRequests constructs a lifecycle manager.
+func NewRequests(store RequestStore, receipts ledger.ReceiptStore) *Requests {
+	return &Requests{store: store, receipts: receipts, now: time.Now}
+}
+
+// WithMandates lets proxy votes that name only the party they are cast for
+// have their mandate chain resolved from m, and has chains sent with a
+// vote reloaded from m, and returns the manager (chainable).
+func (m *Requests) WithMandates(mandates *Mandates) *Requests {
+	m.mandates = mandates
+	return m
+}
+
+// Open starts collecting consent from the affected parties.
+func (m *Requests) Open(in OpenRequest) (*Request, error) {
+	if in.Action == "" || len(in.Affected) == 0 {
+		return nil, errors.New("consent request needs an action and affected parties")
+	}
+	seen := map[string]bool{}
+	for _, a := range in.Affected {
+		if a.ID == "" || seen[a.ID] {
+			return nil, fmt.Errorf("affected party %q is empty or repeated", a.ID)
+		}
+		seen[a.ID] = true
+	}
+	if in.Threshold == 0 {
+		in.Threshold = DefaultRequestThreshold
+	}
+	if in.Threshold < 0 || in.Threshold > 1 {
+		return nil, fmt.Errorf("threshold %g outside [0, 1]", in.Threshold)
+	}
+	if in.Quorum == 0 {
+		in.Quorum = min(DefaultRequestQuorum, len(in.Affected))
+	}
+	if in.Quorum < 1 || in.Quorum > len(in.Affected) {
+		return nil, fmt.Errorf("quorum %d is not reachable by %d parties", in.Quorum, len(in.Affected))
+	}
+	if in.TTL <= 0 {
+		in.TTL = DefaultRequestTTL
+	}
+
+	now := m.now().UTC()
+	req := &Request{
+		ID:        ledger.GenerateUUID(),
+		Action:    in.Action,
+		SchemaRef: in.SchemaRef,
+		Initiator: in.Initiator,
+		Affected:  in.Affected,
+		Quorum:    in.Quorum,
+		Threshold: in.Threshold,
+		CreatedAt: now.Format(time.RFC3339Nano),
+		ExpiresAt: now.Add(in.TTL).Format(time.RFC3339Nano),
+		State:     RequestOpen,
+		Votes:     []Vote{},
+	}
+	if err := m.store.Create(req); err != nil {
+		return nil, err
+	}
+	m.receipt(req, "consent.request.open")
+	return req, nil
+}
+
+// Vote records a signed vote and settles the request if the vote decides it.
+// A vote arriving after the request expired settles the expiry and is
+// refused with ErrRequestClosed.
+func (m *Requests) Vote(id string, v Vote) (*Request, error) {
+	var decided bool
+	var closed error
+	req, err := m.store.Update(id, func(r *Request) error {
+		if v.OnBehalfOf != "" && v.Delegation == nil && m.mandates != nil {
+			at := m.now()
+			if cast, err := time.Parse(time.RFC3339Nano, v.CastAt); err == nil {
+				at = cast
+			}
+			d, err := m.mandates.Resolve(v.Voter, v.OnBehalfOf, r.Action, r.SchemaRef, at)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrBadVote, err)
+			}
+			v.Delegation = d
+		} else if v.Delegation != nil && m.mandates != nil {
+			d, err := m.mandates.Reload(v.Delegation)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrBadVote, err)
+			}
+			v.Delegation = d
+		}
+		if r.advance(m.now()) {
+			decided = true
+			closed = fmt.Errorf("%w: %s", ErrRequestClosed, r.State)
+			return nil
+		}
+		if err := r.addVote(v, m.now()); err != nil {
+			return err
+		}
+		decided = r.advance(m.now())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if decided {
+		m.receipt(req, "consent.request."+string(req.State))
+	}
+	return req, closed
+}
+
+// Get returns a request, expiring it first if its time is up.
+func (m *Requests) Get(id string) (*Request, error) {
+	req, err := m.store.Get(id)
+	if err != nil || req.State != RequestOpen {
+		return req, err
+	}
+	return m.settle(req)
+}
+
+// List returns requests in state (all when empty), newest first, settling
+// any that have expired.
+func (m *Requests) List(state RequestState, limit int) ([]*Request, error) {
+	list, err := m.store.List(state, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := list[:0]
+	for _, req := range list {
+		if req.State == RequestOpen {
+			if req, err = m.settle(req); err != nil {
+				return nil, err
+			}
+		}
+		if state == "" || req.State == state {
+			out = append(out, req)
+		}
+	}
+	return out, nil
+}
+
+// settle persists the expiry of an open request whose time is up.
+func (m *Requests) settle(req *Request) (*Request, error) {
+	if req.outcome(m.now()) != RequestExpired {
+		return req, nil
+	}
+	var decided bool
+	req, err := m.store.Update(req.ID, func(r *Request) error {
+		decided = r.advance(m.now())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if decided {
+		m.receipt(req, "consent.request."+string(req.State))
+	}
+	return req, nil
+}
+
+// receipt records a lifecycle event. Failures are logged: the request
+// store, not the receipt, is the source of truth for the request's state.
+func (m *Requests) receipt(req *Request, action string) {
+	if m.receipts == nil {
+		return
+	}
+	t := req.Tally()
+	rcpt := &ledger.Receipt{
+		ReceiptID: ledger.GenerateUUID(),
+		SchemaRef: RequestSchemaRef,
+		By:        ledger.NodeDomain,
+		Action:    action,
+		CreatedAt: m.now().UTC().Format(time.RFC3339Nano),
+		Payload: map[string]any{
+			"request_id":   req.ID,
+			"request_hash": req.Digest(),
+			"action":       req.Action,
+			"initiator":    req.Initiator.ID,
+			"affected":     collectIDs(req.Affected),
+			"state":        string(req.State),
+			"approvals":    t.Approvals,
+			"rejections":   t.Rejections,
+		},
+		Provenance: []ledger.Provenance{{Type: "consent_request", Ref: req.ID, Status: string(req.State)}},
+	}
+	var delegations []map[string]any
+	for _, v := range req.Votes {
+		if v.Delegation != nil {
+			delegations = append(delegations, v.Delegation.record())
+			for _, md := range v.Delegation.Mandates {
+				rcpt.Provenance = append(rcpt.Provenance, ledger.Provenance{Type: "consent_mandate", Ref: md.ID, Status: "active"})
+			}
+		}
+	}
+	if len(delegations) > 0 {
+		rcpt.Payload["delegations"] = delegations
+	}
+	if err := rcpt.Seal(); err != nil {
+		log.Printf("⚠️  consent request %s receipt: %v", req.ID, err)
+		return
+	}
+	if err := m.receipts.Append(rcpt); err != nil {
+		log.Printf("⚠️  consent request %s receipt: %v", req.ID, err)
+	}
+}
@@ -0,0 +1,177 @@
+package consent_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"dis-core/internal/consent"
+	"dis-core/internal/ledger"
+	"dis-core/internal/util/crypto"
+)
+
+// grantedRequest opens a trade request by dave affecting alice and bob and
+// has both approve it.
+func grantedRequest(t *testing.T) *consent.Request {
+	t.Helper()
+	m := consent.NewRequests(consent.NewMemoryRequestStore(), nil)
+	req, err := m.Open(consent.OpenRequest{
+		Action:    "trade.execute",
+		SchemaRef: "trade.v1",
+		Initiator: consent.ActorRef{ID: "dave"},
+		Affected:  []consent.ActorRef{{ID: "alice"}, {ID: "bob"}},
+		TTL:       time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for _, voter := range []string{"alice", "bob"} {
+		v, err := req.SignVote(voter, consent.Approve)
+		if err != nil {
+			t.Fatalf("SignVote %s: %v", voter, err)
+		}
+		if req, err = m.Vote(req.ID, v); err != nil {
+			t.Fatalf("Vote %s: %v", voter, err)
+		}
+	}
+	if req.State != consent.RequestGranted {
+		t.Fatalf("request state %s, want granted", req.State)
+	}
+	return req
+}
+
+func TestRequestGrants(t *testing.T) {
+	granted := grantedRequest(t)
+	forged := *granted
+	forged.Votes = append([]consent.Vote(nil), granted.Votes...)
+	forged.Votes[0].Choice = consent.Reject
+	open := *granted
+	open.State = consent.RequestOpen
+
+	ask := consent.ConsentRequest{
+		Action:    "trade.execute",
+		SchemaRef: "trade.v1",
+		Initiator: consent.ActorRef{ID: "dave"},
+	}
+	now := time.Now()
+	with := func(edit func(*consent.ConsentRequest)) consent.ConsentRequest {
+		r := ask
+		edit(&r)
+		return r
+	}
+
+	cases := []struct {
+		name     string
+		req      *consent.Request
+		ask      consent.ConsentRequest
+		affected []string
+		at       time.Time
+		want     error
+	}{
+		{"granted", granted, ask, []string{"alice", "bob"}, now, nil},
+		{"subset of parties", granted, ask, []string{"bob"}, now, nil},
+		{"party not asked", granted, ask, []string{"carol"}, now, consent.ErrBadEvidence},
+		{"other initiator", granted, with(func(r *consent.ConsentRequest) { r.Initiator.ID = "carol" }), []string{"alice"}, now, consent.ErrBadEvidence},
+		{"other action", granted, with(func(r *consent.ConsentRequest) { r.Action = "trade.cancel" }), []string{"alice"}, now, consent.ErrBadEvidence},
+		{"other schema", granted, with(func(r *consent.ConsentRequest) { r.SchemaRef = "policy.v1" }), []string{"alice"}, now, consent.ErrBadEvidence},
+		{"expired", granted, ask, []string{"alice"}, now.Add(2 * time.Hour), consent.ErrBadEvidence},
+		{"not decided", &open, ask, []string{"alice"}, now, consent.ErrBadEvidence},
+		{"tampered vote", &forged, ask, []string{"alice"}, now, consent.ErrBadEvidence},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var affected []consent.ActorRef
+			for _, id := range tc.affected {
+				affected = append(affected, consent.ActorRef{ID: id})
+			}
+			if err := tc.req.Grants(tc.ask, affected, tc.at); !errors.Is(err, tc.want) {
+				t.Fatalf("Grants: %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+// TestVoteCastAt checks that a vote's cast_at, which picks the key it is
+// checked against, must fall after the request opened and near the node's
+// clock.
+func TestVoteCastAt(t *testing.T) {
+	m := consent.NewRequests(consent.NewMemoryRequestStore(), nil)
+	req, err := m.Open(consent.OpenRequest{
+		Action:    "trade.execute",
+		Initiator: consent.ActorRef{ID: "dave"},
+		Affected:  []consent.ActorRef{{ID: "alice"}},
+		TTL:       time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	created, err := time.Parse(time.RFC3339Nano, req.CreatedAt)
+	if err != nil {
+		t.Fatalf("created_at: %v", err)
+	}
+	ks := crypto.DefaultKeyStore()
+	key, err := ks.Active("alice")
+	if err != nil {
+		t.Fatalf("Active: %v", err)
+	}
+	castAt := func(at time.Time) consent.Vote {
+		v := consent.Vote{Voter: "alice", Choice: consent.Approve, CastAt: at.UTC().Format(time.RFC3339Nano), KeyID: key.KeyID}
+		if v.Signature, err = ks.Sign(key.KeyID, []byte(consent.VoteDigest(req.Digest(), v.Voter, v.Choice, v.CastAt))); err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		return v
+	}
+
+	// Steps run in order against the same request.
+	steps := []struct {
+		name string
+		vote consent.Vote
+		want error
+	}{
+		{"before the request opened", castAt(created.Add(-time.Second)), consent.ErrBadVote},
+		{"ahead of the node's clock", castAt(time.Now().Add(consent.ClockSkew + time.Minute)), consent.ErrBadVote},
+		{"now", castAt(time.Now()), nil},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if _, err := m.Vote(req.ID, step.vote); !errors.Is(err, step.want) {
+				t.Fatalf("Vote: %v, want %v", err, step.want)
+			}
+		})
+	}
+}
+
+// TestRequestReceipts checks that every lifecycle receipt of a request is
+// sealed by the node and verifies.
+func TestRequestReceipts(t *testing.T) {
+	store := ledger.NewMemoryStore()
+	m := consent.NewRequests(consent.NewMemoryRequestStore(), store)
+	req, err := m.Open(consent.OpenRequest{
+		Action:    "trade.execute",
+		Initiator: consent.ActorRef{ID: "dave"},
+		Affected:  []consent.ActorRef{{ID: "alice"}},
+		TTL:       time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	v, err := req.SignVote("alice", consent.Approve)
+	if err != nil {
+		t.Fatalf("SignVote: %v", err)
+	}
+	if _, err := m.Vote(req.ID, v); err != nil {
+		t.Fatalf("Vote: %v", err)
+	}
+
+	list, err := store.List(ledger.ListOptions{SchemaRef: consent.RequestSchemaRef})
+	if err != nil || len(list) == 0 {
+		t.Fatalf("List = %d receipts, %v", len(list), err)
+	}
+	for _, rcpt := range list {
+		raw, _ := json.Marshal(rcpt)
+		if ok, err := ledger.VerifyReceiptJSON(raw); !ok || err != nil || rcpt.By != ledger.NodeDomain {
+			t.Fatalf("receipt %s (%s) by %s does not verify: %v", rcpt.ReceiptID, rcpt.Action, rcpt.By, err)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package consent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// VerdictKind is what a consent rule decided.
+type VerdictKind string
+
+const (
+	// Pass lets evaluation continue unchanged.
+	Pass VerdictKind = "pass"
+	// Block denies the action.
+	Block VerdictKind = "block"
+	// Throttle denies the action until a backoff has passed.
+	Throttle VerdictKind = "throttle"
+	// Adjust moves the running legitimacy by LegitimacyDelta and continues.
+	Adjust VerdictKind = "adjust"
+)
+
+// Verdict is a rule's structured result.
+type Verdict struct {
+	Kind            VerdictKind   `json:"kind"`
+	Reason          string        `json:"reason,omitempty"`
+	LegitimacyDelta float64       `json:"legitimacy_delta,omitempty"`
+	Backoff         time.Duration `json:"-"` // Throttle only; zero uses the config backoff
+	// Decisive marks a passing rule as the one the decision rests on.
+	Decisive bool `json:"decisive,omitempty"`
+}
+
+// RuleContext is what a rule sees: the request, its own config entry, and
+// the legitimacy accumulated by the rules before it.
+type RuleContext struct {
+	Request    ConsentRequest
+	Rule       GateRule
+	Config     *Config
+	Legitimacy float64
+	Now        time.Time
+}
+
+// ConsentRule is one step of the consent gate. Rules run in the order the
+// config lists them; the first Block or Throttle ends evaluation.
+type ConsentRule interface {
+	ID() string
+	Evaluate(ctx context.Context, rc RuleContext) (Verdict, error)
+}
+
+// RuleTrace records one rule's verdict in a Decision.
+type RuleTrace struct {
+	Rule             string      `json:"rule"`
+	Verdict          VerdictKind `json:"verdict"`
+	Reason           string      `json:"reason,omitempty"`
+	LegitimacyBefore float64     `json:"legitimacy_before"`
+	LegitimacyAfter  float64     `json:"legitimacy_after"`
+}
+
+var (
+	ErrUnknownRule   = errors.New("consent rule is not registered")
+	ErrDuplicateRule = errors.New("consent rule is already registered")
+)
+
+// RuleRegistry maps rule IDs to implementations.
+type RuleRegistry struct {
+	mu    sync.RWMutex
+	rules map[string]ConsentRule
+}
+
+// NewRuleRegistry returns a registry holding the built-in rules.
+func NewRuleRegistry() *RuleRegistry {
+	r := &RuleRegistry{rules: map[string]ConsentRule{}}
+	for _, rule := range builtinRules() {
+		r.rules[rule.ID()] = rule
+	}
+	return r
+}
+
+// Register adds a rule. IDs are unique; use Replace to swap an existing one.
+func (r *RuleRegistry) Register(rule ConsentRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.rules[rule.ID()]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateRule, rule.ID())
+	}
+	r.rules[rule.ID()] = rule
+	return nil
+}
+
+// Replace adds or replaces a rule.
+func (r *RuleRegistry) Replace(rule ConsentRule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules[rule.ID()] = rule
+}
+
+// Get returns the rule registered under id.
+func (r *RuleRegistry) Get(id string) (ConsentRule, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rule, ok := r.rules[id]
+	return rule, ok
+}
+
+// IDs lists the registered rule IDs in order.
+func (r *RuleRegistry) IDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]string, 0, len(r.rules))
+	for id := range r.rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+var (
+	defaultRulesMu sync.RWMutex
+	defaultRules   = NewRuleRegistry()
+)
+
+// DefaultRules returns the process-wide rule registry gates use unless
+// given their own with WithRules.
+func DefaultRules() *RuleRegistry {
+	defaultRulesMu.RLock()
+	defer defaultRulesMu.RUnlock()
+	return defaultRules
+}
+
+// SetDefaultRules replaces the process-wide rule registry.
+func SetDefaultRules(r *RuleRegistry) {
+	defaultRulesMu.Lock()
+	defer defaultRulesMu.Unlock()
+	defaultRules = r
+}
+
+// RegisterRule adds a rule to the default registry.
+func RegisterRule(rule ConsentRule) error {
+	return DefaultRules().Register(rule)
+}
+
+// RuleFunc adapts a function to ConsentRule.
+type RuleFunc struct {
+	Name string
+	Fn   func(ctx context.Context, rc RuleContext) (Verdict, error)
+}
+
+func (f RuleFunc) ID() string { return f.Name }
+
+func (f RuleFunc) Evaluate(ctx context.Context, rc RuleContext) (Verdict, error) {
+	return f.Fn(ctx, rc)
+}
+
+// builtinRules are the rules of dis_consent.v0.1.
+func builtinRules() []ConsentRule {
+	return []ConsentRule{
+		RuleFunc{Name: "personal_autonomy", Fn: personalAutonomy},
+		RuleFunc{Name: "reciprocal_transparency", Fn: func(_ context.Context, rc RuleContext) (Verdict, error) {
+			// If all affected parties are informed, boost perceived legitimacy slightly
+			if informedAll(rc.Request) {
+				return Verdict{Kind: Adjust, LegitimacyDelta: 0.03, Reason: "all affected parties informed", Decisive: true}, nil
+			}
+			return Verdict{Kind: Pass, Decisive: true}, nil
+		}},
+		RuleFunc{Name: "trust_decay", Fn: func(context.Context, RuleContext) (Verdict, error) {
+			// Declarative hook; actual decay is applied by feedback loop from prior receipts
+			return Verdict{Kind: Pass, Decisive: true}, nil
+		}},
+		RuleFunc{Name: "moral_feedback", Fn: func(context.Context, RuleContext) (Verdict, error) {
+			// Declarative hook; actual post-decision effects happen in feedback loop
+			return Verdict{Kind: Pass, Decisive: true}, nil
+		}},
+	}
+}
+
+// personalAutonomy requires explicit consent when individuals are affected:
+// a granted, unexpired consent request for the same action, schema and
+// initiator, whose signed votes verify.
+func personalAutonomy(_ context.Context, rc RuleContext) (Verdict, error) {
+	req := rc.Request
+	if people := individuals(req); len(people) > 0 {
+		if req.Evidence == nil {
+			return Verdict{Kind: Block, Reason: "missing explicit consent"}, nil
+		}
+		if err := req.Evidence.Grants(req, people, rc.Now); err != nil {
+			return Verdict{Kind: Block, Reason: "consent evidence rejected: " + err.Error()}, nil
+		}
+	}
+	if rc.Legitimacy < rc.Rule.Threshold {
+		return Verdict{Kind: Block, Reason: "insufficient legitimacy for personal autonomy"}, nil
+	}
+	return Verdict{Kind: Pass}, nil
+}
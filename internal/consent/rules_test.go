@@ -0,0 +1,244 @@
+package consent_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"dis-core/internal/consent"
+	"dis-core/internal/ledger"
+)
+
+// bonus is a custom rule that raises legitimacy by a fixed step.
+var bonus = consent.RuleFunc{Name: "bonus", Fn: func(context.Context, consent.RuleContext) (consent.Verdict, error) {
+	return consent.Verdict{Kind: consent.Adjust, LegitimacyDelta: 0.1, Reason: "bonus", Decisive: true}, nil
+}}
+
+func TestRuleRegistry(t *testing.T) {
+	reg := consent.NewRuleRegistry()
+	builtin := []string{"moral_feedback", "personal_autonomy", "reciprocal_transparency", "trust_decay"}
+	if got := reg.IDs(); !reflect.DeepEqual(got, builtin) {
+		t.Fatalf("IDs %v, want %v", got, builtin)
+	}
+
+	cases := []struct {
+		name string
+		rule consent.ConsentRule
+		want error
+	}{
+		{"new rule", bonus, nil},
+		{"same rule twice", bonus, consent.ErrDuplicateRule},
+		{"built-in id", consent.RuleFunc{Name: "trust_decay"}, consent.ErrDuplicateRule},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := reg.Register(tc.rule); !errors.Is(err, tc.want) {
+				t.Fatalf("Register: %v, want %v", err, tc.want)
+			}
+		})
+	}
+
+	replaced := consent.RuleFunc{Name: "trust_decay", Fn: func(context.Context, consent.RuleContext) (consent.Verdict, error) {
+		return consent.Verdict{Kind: consent.Block, Reason: "replaced"}, nil
+	}}
+	reg.Replace(replaced)
+	rule, ok := reg.Get("trust_decay")
+	if !ok {
+		t.Fatal("trust_decay missing after Replace")
+	}
+	if v, err := rule.Evaluate(context.Background(), consent.RuleContext{}); err != nil || v.Reason != "replaced" {
+		t.Fatalf("replaced rule: %+v, %v", v, err)
+	}
+	if _, ok := reg.Get("no_such_rule"); ok {
+		t.Fatal("unknown rule found")
+	}
+	if len(reg.IDs()) != len(builtin)+1 {
+		t.Fatalf("IDs %v", reg.IDs())
+	}
+}
+
+func TestRegoRule(t *testing.T) {
+	cases := []struct {
+		name    string
+		src     string
+		verdict consent.Verdict
+		problem string // substring of the error; empty when valid
+	}{
+		{
+			name: "throttle with backoff",
+			src: `package consent.rules.slow
+verdict = {"kind": "throttle", "reason": "slow down", "backoff_ms": 5000}`,
+			verdict: consent.Verdict{Kind: consent.Throttle, Reason: "slow down", Backoff: 5 * time.Second},
+		},
+		{
+			name: "adjust",
+			src: `package consent.rules.lift
+verdict = {"kind": "adjust", "legitimacy_delta": 0.2, "decisive": true}`,
+			verdict: consent.Verdict{Kind: consent.Adjust, LegitimacyDelta: 0.2, Decisive: true},
+		},
+		{
+			name: "undefined verdict passes",
+			src: `package consent.rules.quiet
+verdict = {"kind": "block"} { input.legitimacy > 2 }`,
+			verdict: consent.Verdict{Kind: consent.Pass},
+		},
+		{
+			name:    "unknown kind",
+			src:     `package consent.rules.odd` + "\n" + `verdict = {"kind": "maybe"}`,
+			problem: `unknown kind "maybe"`,
+		},
+		{
+			name:    "outside the rules package",
+			src:     `package consent.other` + "\n" + `verdict = {"kind": "pass"}`,
+			problem: "is not consent.rules.<rule_id>",
+		},
+		{
+			name:    "not rego",
+			src:     "package consent.rules.broken\nverdict = {",
+			problem: "parse",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := consent.NewRegoRule(tc.name+".rego", tc.src)
+			var v consent.Verdict
+			if err == nil {
+				v, err = rule.Evaluate(context.Background(), consent.RuleContext{Legitimacy: 0.5, Now: time.Now()})
+			}
+			if tc.problem != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.problem) {
+					t.Fatalf("err %v, want %q", err, tc.problem)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewRegoRule/Evaluate: %v", err)
+			}
+			if !reflect.DeepEqual(v, tc.verdict) {
+				t.Fatalf("verdict %+v, want %+v", v, tc.verdict)
+			}
+		})
+	}
+}
+
+func TestLoadRegoRules(t *testing.T) {
+	reg := consent.NewRuleRegistry()
+	ids, err := consent.LoadRegoRules("../../policies/consent", reg)
+	if err != nil || !reflect.DeepEqual(ids, []string{"affected_trust_floor"}) {
+		t.Fatalf("LoadRegoRules: %v, %v", ids, err)
+	}
+	if _, err := consent.LoadRegoRules("../../policies/consent", reg); !errors.Is(err, consent.ErrDuplicateRule) {
+		t.Fatalf("second load: %v, want ErrDuplicateRule", err)
+	}
+	if ids, err := consent.LoadRegoRules(t.TempDir(), reg); err != nil || len(ids) != 0 {
+		t.Fatalf("empty dir: %v, %v", ids, err)
+	}
+}
+
+// TestGateTrace runs built-in, Go and Rego rules through one gate and
+// checks the decision and the legitimacy recorded at every step.
+func TestGateTrace(t *testing.T) {
+	reg := consent.NewRuleRegistry()
+	if err := reg.Register(bonus); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := consent.LoadRegoRules("../../policies/consent", reg); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &consent.Config{
+		Version: "v0.1",
+		GateRules: []consent.GateRule{
+			{ID: "reciprocal_transparency"},
+			{ID: "bonus"},
+			{ID: "affected_trust_floor", Threshold: 0.4},
+			{ID: "personal_autonomy", Threshold: 0.5},
+		},
+		Weights: consent.Weights{TrustIncrease: 0.05, TrustDecrease: -0.1},
+	}
+	gate := consent.NewGate(cfg, cfg.Version, nil, ledger.NewMemoryStore()).WithRules(reg)
+
+	type step struct {
+		rule          string
+		verdict       consent.VerdictKind
+		before, after float64
+	}
+	cases := []struct {
+		name      string
+		initiator float64 // trust and legitimacy
+		affected  float64 // trust
+		informed  bool
+		allowed   bool
+		decisive  string
+		trace     []step
+	}{
+		{
+			name: "allowed", initiator: 0.6, affected: 0.9, informed: true, allowed: true, decisive: "bonus",
+			trace: []step{
+				{"reciprocal_transparency", consent.Adjust, 0.6, 0.63},
+				{"bonus", consent.Adjust, 0.63, 0.73},
+				{"affected_trust_floor", consent.Pass, 0.73, 0.73},
+				{"personal_autonomy", consent.Pass, 0.73, 0.73},
+			},
+		},
+		{
+			name: "blocked by rego rule", initiator: 0.6, affected: 0.2, decisive: "affected_trust_floor",
+			trace: []step{
+				{"reciprocal_transparency", consent.Pass, 0.6, 0.6},
+				{"bonus", consent.Adjust, 0.6, 0.7},
+				{"affected_trust_floor", consent.Block, 0.7, 0.7},
+			},
+		},
+		{
+			name: "blocked on legitimacy", initiator: 0.3, affected: 0.9, decisive: "personal_autonomy",
+			trace: []step{
+				{"reciprocal_transparency", consent.Pass, 0.3, 0.3},
+				{"bonus", consent.Adjust, 0.3, 0.4},
+				{"affected_trust_floor", consent.Pass, 0.4, 0.4},
+				{"personal_autonomy", consent.Block, 0.4, 0.4},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := consent.ConsentRequest{
+				Action:    "trade.execute",
+				SchemaRef: "trade.v1",
+				Initiator: consent.ActorRef{ID: "dave", Domain: "org", Trust: tc.initiator, Legitimacy: tc.initiator},
+				Affected:  []consent.ActorRef{{ID: "acme", Domain: "org", Trust: tc.affected}},
+			}
+			if tc.informed {
+				req.Metadata = map[string]string{"informed_all": "yes"}
+			}
+			dec, err := gate.VerifyConsent(context.Background(), req)
+			if err != nil {
+				t.Fatalf("VerifyConsent: %v", err)
+			}
+			if dec.Allowed != tc.allowed || dec.LegitimacyRule != tc.decisive || dec.ConfigHash != cfg.Hash() {
+				t.Fatalf("decision %+v", dec)
+			}
+			if len(dec.Trace) != len(tc.trace) {
+				t.Fatalf("trace %+v", dec.Trace)
+			}
+			for i, want := range tc.trace {
+				got := dec.Trace[i]
+				if got.Rule != want.rule || got.Verdict != want.verdict ||
+					math.Abs(got.LegitimacyBefore-want.before) > 1e-9 || math.Abs(got.LegitimacyAfter-want.after) > 1e-9 {
+					t.Fatalf("trace[%d] %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+
+	unknown := *cfg
+	unknown.GateRules = append([]consent.GateRule{{ID: "no_such_rule"}}, cfg.GateRules...)
+	if _, err := gate.SetConfig(&unknown, "test"); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+	if _, err := gate.VerifyConsent(context.Background(), consent.ConsentRequest{}); !errors.Is(err, consent.ErrUnknownRule) {
+		t.Fatalf("unknown rule: %v", err)
+	}
+}
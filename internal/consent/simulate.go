@@ -0,0 +1,181 @@
+package consent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"dis-core/internal/ledger"
+)
+
+// DecisionQuery selects the receipts AuthorizeAction emits, whatever
+// schema_ref the request bound them to.
+const DecisionQuery = `metadata.issued_from_console = "consent-gate"`
+
+// Drift totals the change in feedback deltas a candidate config causes.
+type Drift struct {
+	Trust  float64 `json:"trust"`
+	Ethics float64 `json:"ethics"`
+}
+
+// ReplayResult compares one recorded decision with its replay.
+type ReplayResult struct {
+	ReceiptID    string    `json:"receipt_id"`
+	Action       string    `json:"action"`
+	Initiator    string    `json:"initiator"`
+	CreatedAt    string    `json:"created_at"`
+	ConfigHash   string    `json:"config_hash,omitempty"` // config the decision was made under
+	Before       string    `json:"before"`
+	After        string    `json:"after"`
+	BeforeReason string    `json:"before_reason,omitempty"`
+	AfterReason  string    `json:"after_reason,omitempty"`
+	Flipped      bool      `json:"flipped"`
+	Drift        Drift     `json:"drift"`
+	Decision     *Decision `json:"decision,omitempty"` // the replayed decision, with its trace
+}
+
+// SimulationReport summarizes a replay of past decisions under a candidate
+// config.
+type SimulationReport struct {
+	ConfigVersion string           `json:"config_version"`
+	ConfigHash    string           `json:"config_hash"`
+	Replayed      int              `json:"replayed"`
+	Skipped       []string         `json:"skipped,omitempty"` // receipts that could not be replayed, with why
+	Flipped       int              `json:"flipped"`
+	Drift         Drift            `json:"drift"`
+	DriftByActor  map[string]Drift `json:"drift_by_actor,omitempty"`
+	Flips         []ReplayResult   `json:"flips"`
+	Results       []ReplayResult   `json:"results,omitempty"`
+}
+
+// SimulateOptions tunes Simulate.
+type SimulateOptions struct {
+	// Rules resolves rule IDs; nil uses the default registry.
+	Rules *RuleRegistry
+	// All keeps every result in the report, not only the flips.
+	All bool
+}
+
+// Simulate replays recorded consent decisions through VerifyConsent under
+// candidate and reports which decisions flip and how the trust and ethics
+// deltas fed back would have drifted. Each request is evaluated as it was
+// recorded, at its original time; nothing is written.
+func Simulate(ctx context.Context, receipts []ledger.Receipt, candidate *Config, opts SimulateOptions) (*SimulationReport, error) {
+	if candidate == nil {
+		return nil, fmt.Errorf("no candidate config")
+	}
+	g := NewGate(candidate, candidate.Version, nil, nil)
+	if opts.Rules != nil {
+		g.WithRules(opts.Rules)
+	}
+	rep := &SimulationReport{
+		ConfigVersion: candidate.Version,
+		ConfigHash:    candidate.Hash(),
+		DriftByActor:  map[string]Drift{},
+		Flips:         []ReplayResult{},
+	}
+
+	for _, r := range receipts {
+		rec, err := recordedDecision(r)
+		if err != nil {
+			rep.Skipped = append(rep.Skipped, fmt.Sprintf("%s: %v", r.ReceiptID, err))
+			continue
+		}
+		at, err := time.Parse(time.RFC3339Nano, r.CreatedAt)
+		if err != nil {
+			rep.Skipped = append(rep.Skipped, fmt.Sprintf("%s: created_at: %v", r.ReceiptID, err))
+			continue
+		}
+		g.timeNow = func() time.Time { return at }
+		dec, err := g.VerifyConsent(ctx, rec.Request)
+		if err != nil {
+			rep.Skipped = append(rep.Skipped, fmt.Sprintf("%s: %v", r.ReceiptID, err))
+			continue
+		}
+
+		res := ReplayResult{
+			ReceiptID:    r.ReceiptID,
+			Action:       r.Action,
+			Initiator:    rec.Request.Initiator.ID,
+			CreatedAt:    r.CreatedAt,
+			ConfigHash:   rec.ConfigHash,
+			Before:       rec.Decision,
+			After:        decisionWord(dec),
+			BeforeReason: rec.Reason,
+			AfterReason:  dec.Reason,
+			Drift: Drift{
+				Trust:  dec.TrustDelta - rec.TrustDelta,
+				Ethics: dec.EthicsDelta - rec.EthicsDelta,
+			},
+			Decision: &dec,
+		}
+		res.Flipped = res.Before != res.After
+
+		rep.Replayed++
+		rep.Drift.Trust += res.Drift.Trust
+		rep.Drift.Ethics += res.Drift.Ethics
+		d := rep.DriftByActor[res.Initiator]
+		d.Trust += res.Drift.Trust
+		d.Ethics += res.Drift.Ethics
+		rep.DriftByActor[res.Initiator] = d
+		if res.Flipped {
+			rep.Flipped++
+			rep.Flips = append(rep.Flips, res)
+		}
+		if opts.All {
+			rep.Results = append(rep.Results, res)
+		}
+	}
+	return rep, nil
+}
+
+// recorded is the part of a decision receipt's payload a replay needs.
+type recorded struct {
+	Decision    string         `json:"decision"`
+	Reason      string         `json:"reason"`
+	TrustDelta  float64        `json:"trust_delta"`
+	EthicsDelta float64        `json:"ethics_delta"`
+	ConfigHash  string         `json:"config_hash"`
+	Request     ConsentRequest `json:"request"`
+}
+
+func recordedDecision(r ledger.Receipt) (*recorded, error) {
+	if _, ok := r.Payload["request"]; !ok {
+		return nil, fmt.Errorf("receipt does not record its consent request")
+	}
+	raw, err := json.Marshal(r.Payload)
+	if err != nil {
+		return nil, err
+	}
+	var rec recorded
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return nil, fmt.Errorf("decode decision: %w", err)
+	}
+	return &rec, nil
+}
+
+// LoadDecisions pages through store for consent decision receipts matching
+// the optional query q, newest first, up to limit (0 for all).
+func LoadDecisions(store ledger.ReceiptStore, q string, limit int) ([]ledger.Receipt, error) {
+	query := DecisionQuery
+	if q != "" {
+		query = "(" + q + ") and " + DecisionQuery
+	}
+	var out []ledger.Receipt
+	cursor := ""
+	for {
+		page, err := store.Search(ledger.SearchOptions{Query: query, Limit: 500, Cursor: cursor})
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, page.Items...)
+		if limit > 0 && len(out) >= limit {
+			return out[:limit], nil
+		}
+		if page.NextCursor == "" {
+			return out, nil
+		}
+		cursor = page.NextCursor
+	}
+}
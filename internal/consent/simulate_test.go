@@ -0,0 +1,115 @@
+package consent_test
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"dis-core/internal/consent"
+	"dis-core/internal/ledger"
+)
+
+// TestSimulate records decisions under the shipped config and replays them
+// under candidates that move the personal_autonomy threshold.
+func TestSimulate(t *testing.T) {
+	cfg, err := consent.ParseConfig(shippedConfig(t), loadSchema(t))
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	store := ledger.NewMemoryStore()
+	gate := consent.NewGate(cfg, cfg.Version, nil, store)
+	// personal_autonomy needs 0.80: dave and frank are allowed, erin is not.
+	for _, who := range []struct {
+		id    string
+		score float64
+	}{{"dave", 0.9}, {"erin", 0.75}, {"frank", 0.95}} {
+		req := consent.ConsentRequest{
+			Action:    "trade.execute",
+			SchemaRef: "trade.v1",
+			Initiator: consent.ActorRef{ID: who.id, Domain: "org", Trust: who.score, Legitimacy: who.score},
+			Affected:  []consent.ActorRef{{ID: "acme", Domain: "org", Trust: 0.9}},
+		}
+		if _, _, err := gate.AuthorizeAction(context.Background(), req); err != nil {
+			t.Fatalf("AuthorizeAction %s: %v", who.id, err)
+		}
+	}
+	other, err := ledger.NewEnvelope("test.event.v0", ledger.NodeDomain, "unrelated", map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append(other); err != nil {
+		t.Fatal(err)
+	}
+
+	receipts, err := consent.LoadDecisions(store, "", 0)
+	if err != nil || len(receipts) != 3 {
+		t.Fatalf("LoadDecisions: %d receipts, %v", len(receipts), err)
+	}
+	if some, err := consent.LoadDecisions(store, "", 2); err != nil || len(some) != 2 {
+		t.Fatalf("LoadDecisions limit 2: %d receipts, %v", len(some), err)
+	}
+	if dave, err := consent.LoadDecisions(store, `payload.initiator = "dave"`, 0); err != nil || len(dave) != 1 {
+		t.Fatalf("LoadDecisions for dave: %d receipts, %v", len(dave), err)
+	}
+
+	withThreshold := func(th float64) *consent.Config {
+		c := *cfg
+		c.GateRules = append([]consent.GateRule(nil), cfg.GateRules...)
+		c.GateRules[0].Threshold = th
+		return &c
+	}
+	// Flipping between allowed and blocked moves trust by 0.05 - -0.10 and
+	// ethics by 0.03 - -0.07.
+	cases := []struct {
+		name          string
+		candidate     *consent.Config
+		flips         []string
+		trust, ethics float64
+	}{
+		{"unchanged", cfg, nil, 0, 0},
+		{"lower threshold", withThreshold(0.70), []string{"erin"}, 0.15, 0.10},
+		{"higher threshold", withThreshold(0.92), []string{"dave"}, -0.15, -0.10},
+		{"much higher threshold", withThreshold(0.99), []string{"dave", "frank"}, -0.30, -0.20},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rep, err := consent.Simulate(context.Background(), receipts, tc.candidate, consent.SimulateOptions{All: true})
+			if err != nil {
+				t.Fatalf("Simulate: %v", err)
+			}
+			if rep.Replayed != 3 || len(rep.Results) != 3 || rep.ConfigHash != tc.candidate.Hash() {
+				t.Fatalf("report %+v", rep)
+			}
+			flipped := map[string]bool{}
+			for _, f := range rep.Flips {
+				if f.ConfigHash != cfg.Hash() || f.Before == f.After || f.Decision == nil {
+					t.Fatalf("flip %+v", f)
+				}
+				flipped[f.Initiator] = true
+			}
+			if rep.Flipped != len(tc.flips) || len(flipped) != len(tc.flips) {
+				t.Fatalf("flips %+v, want %v", rep.Flips, tc.flips)
+			}
+			for _, id := range tc.flips {
+				if !flipped[id] {
+					t.Fatalf("flips %+v, want %v", rep.Flips, tc.flips)
+				}
+			}
+			if math.Abs(rep.Drift.Trust-tc.trust) > 1e-9 || math.Abs(rep.Drift.Ethics-tc.ethics) > 1e-9 {
+				t.Fatalf("drift %+v, want trust %v ethics %v", rep.Drift, tc.trust, tc.ethics)
+			}
+		})
+	}
+
+	rep, err := consent.Simulate(context.Background(), append(receipts, *other), cfg, consent.SimulateOptions{})
+	if err != nil {
+		t.Fatalf("Simulate: %v", err)
+	}
+	if rep.Replayed != 3 || len(rep.Skipped) != 1 || !strings.HasPrefix(rep.Skipped[0], other.ReceiptID) || rep.Results != nil {
+		t.Fatalf("report %+v", rep)
+	}
+	if _, err := consent.Simulate(context.Background(), receipts, nil, consent.SimulateOptions{}); err == nil {
+		t.Fatal("nil candidate accepted")
+	}
+}
@@ -0,0 +1,348 @@
+package consent
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ThrottleMode is how a ThrottleLedger counts a low-trust actor's attempts.
+type ThrottleMode string
+
+const (
+	// SlidingWindow admits at most MaxAttempts in any Window.
+	SlidingWindow ThrottleMode = "sliding_window"
+	// TokenBucket admits bursts of up to MaxAttempts, refilling one attempt
+	// every Window/MaxAttempts.
+	TokenBucket ThrottleMode = "token_bucket"
+)
+
+// throttleLedgerRule is the LegitimacyRule of decisions the ledger refuses.
+const throttleLedgerRule = "throttle.ledger"
+
+// ThrottleState is one actor's throttle state for one action.
+type ThrottleState struct {
+	Actor     string      `json:"actor"`
+	Action    string      `json:"action"`
+	HeldUntil time.Time   `json:"held_until,omitempty"` // refuse every attempt before this
+	Attempts  []time.Time `json:"attempts,omitempty"`   // sliding window: admitted attempts
+	Tokens    float64     `json:"tokens"`               // token bucket: attempts left
+	Refilled  time.Time   `json:"refilled,omitempty"`   // token bucket: last refill
+}
+
+// ThrottleStore persists throttle state.
+type ThrottleStore interface {
+	// LoadThrottle returns the state for actor and action, or nil if none.
+	LoadThrottle(actor, action string) (*ThrottleState, error)
+	// SaveThrottle stores st, replacing any previous state.
+	SaveThrottle(st *ThrottleState) error
+}
+
+type throttleKey struct{ actor, action string }
+
+// throttleSweepSize is the cache size past which Admit sweeps expired
+// state even if a window has not passed since the last sweep.
+const throttleSweepSize = 4096
+
+// ThrottleLedger tracks throttle state by actor and action. State is cached
+// in memory and written through to the store, so one ledger should front
+// a given store. Expired state is evicted from the cache once per window.
+type ThrottleLedger struct {
+	mu    sync.Mutex
+	store ThrottleStore
+	cache map[throttleKey]*ThrottleState
+	swept time.Time
+}
+
+// NewThrottleLedger returns a ledger over store; nil keeps state in memory
+// only.
+func NewThrottleLedger(store ThrottleStore) *ThrottleLedger {
+	return &ThrottleLedger{store: store, cache: map[throttleKey]*ThrottleState{}}
+}
+
+// Admit reports when actor may next attempt action. The zero time means
+// the attempt is admitted now, and is counted against the rate limit of
+// rule when the actor is lowTrust.
+func (l *ThrottleLedger) Admit(actor, action string, rule ThrottleRule, lowTrust bool, now time.Time) (time.Time, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(rule, now)
+	st, err := l.state(actor, action)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if now.Before(st.HeldUntil) {
+		return st.HeldUntil, nil
+	}
+	if !lowTrust || rule.MaxAttempts <= 0 || rule.window() <= 0 {
+		l.evictIdle(st)
+		return time.Time{}, nil
+	}
+
+	var retry time.Time
+	switch rule.Mode {
+	case TokenBucket:
+		retry = st.take(rule, now)
+	default:
+		retry = st.slide(rule, now)
+	}
+	if !retry.IsZero() {
+		return retry, nil
+	}
+	return time.Time{}, l.save(st)
+}
+
+// Hold refuses actor's attempts at action until until.
+func (l *ThrottleLedger) Hold(actor, action string, until time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st, err := l.state(actor, action)
+	if err != nil {
+		return err
+	}
+	if !until.After(st.HeldUntil) {
+		return nil
+	}
+	st.HeldUntil = until
+	return l.save(st)
+}
+
+// State returns a copy of the state for actor and action.
+func (l *ThrottleLedger) State(actor, action string) (ThrottleState, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st, err := l.state(actor, action)
+	if err != nil {
+		return ThrottleState{}, err
+	}
+	cp := *st
+	cp.Attempts = append([]time.Time(nil), st.Attempts...)
+	return cp, nil
+}
+
+func (l *ThrottleLedger) state(actor, action string) (*ThrottleState, error) {
+	key := throttleKey{actor, action}
+	if st, ok := l.cache[key]; ok {
+		return st, nil
+	}
+	var st *ThrottleState
+	if l.store != nil {
+		var err error
+		if st, err = l.store.LoadThrottle(actor, action); err != nil {
+			return nil, fmt.Errorf("load throttle state: %w", err)
+		}
+	}
+	if st == nil {
+		st = &ThrottleState{Actor: actor, Action: action}
+	}
+	l.cache[key] = st
+	return st, nil
+}
+
+// evictIdle drops st from the cache when it holds nothing the store lacks,
+// so actors that are never throttled do not accumulate in memory.
+func (l *ThrottleLedger) evictIdle(st *ThrottleState) {
+	if st.HeldUntil.IsZero() && len(st.Attempts) == 0 && st.Refilled.IsZero() {
+		delete(l.cache, throttleKey{st.Actor, st.Action})
+	}
+}
+
+// sweep evicts the cached state that has expired under rule, at most once
+// per window unless the cache has grown past throttleSweepSize. Evicted
+// state is reloaded from the store when next needed.
+func (l *ThrottleLedger) sweep(rule ThrottleRule, now time.Time) {
+	w := rule.window()
+	if now.Sub(l.swept) < w && len(l.cache) < throttleSweepSize {
+		return
+	}
+	l.swept = now
+	for key, st := range l.cache {
+		if st.expired(rule, now) {
+			delete(l.cache, key)
+		}
+	}
+}
+
+// expired reports whether st has nothing left to enforce at now under
+// rule: no hold, no attempts inside the window and a full bucket.
+func (st *ThrottleState) expired(rule ThrottleRule, now time.Time) bool {
+	if now.Before(st.HeldUntil) {
+		return false
+	}
+	w := rule.window()
+	for _, t := range st.Attempts {
+		if now.Sub(t) < w {
+			return false
+		}
+	}
+	if !st.Refilled.IsZero() && rule.MaxAttempts > 0 {
+		missing := float64(rule.MaxAttempts) - st.Tokens
+		refill := time.Duration(missing / float64(rule.MaxAttempts) * float64(w))
+		if now.Before(st.Refilled.Add(refill)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *ThrottleLedger) save(st *ThrottleState) error {
+	if l.store == nil {
+		return nil
+	}
+	if err := l.store.SaveThrottle(st); err != nil {
+		return fmt.Errorf("save throttle state: %w", err)
+	}
+	return nil
+}
+
+// slide admits an attempt if fewer than MaxAttempts fall in the window
+// ending now, or returns when the oldest of them leaves it.
+func (st *ThrottleState) slide(rule ThrottleRule, now time.Time) time.Time {
+	w := rule.window()
+	kept := st.Attempts[:0]
+	for _, t := range st.Attempts {
+		if now.Sub(t) < w {
+			kept = append(kept, t)
+		}
+	}
+	st.Attempts = kept
+	if len(st.Attempts) >= rule.MaxAttempts {
+		return st.Attempts[len(st.Attempts)-rule.MaxAttempts].Add(w)
+	}
+	st.Attempts = append(st.Attempts, now)
+	return time.Time{}
+}
+
+// take admits an attempt if the bucket holds a token, or returns when the
+// next one will have refilled.
+func (st *ThrottleState) take(rule ThrottleRule, now time.Time) time.Time {
+	capacity := float64(rule.MaxAttempts)
+	rate := capacity / float64(rule.window()) // tokens per nanosecond
+	if st.Refilled.IsZero() {
+		st.Tokens = capacity
+	} else if now.After(st.Refilled) {
+		st.Tokens = math.Min(capacity, st.Tokens+float64(now.Sub(st.Refilled))*rate)
+	}
+	st.Refilled = now
+	if st.Tokens < 1 {
+		return now.Add(time.Duration(math.Ceil((1 - st.Tokens) / rate)))
+	}
+	st.Tokens--
+	return time.Time{}
+}
+
+// window is the rate-limit window, defaulting to the backoff.
+func (t ThrottleRule) window() time.Duration {
+	if t.Window > 0 {
+		return t.Window
+	}
+	return t.Backoff
+}
+
+func lowTrust(cfg *Config, req ConsentRequest) bool {
+	return req.Initiator.Trust < cfg.Throttle.LowTrustFloor
+}
+
+func (g *Gate) throttleLedger() *ThrottleLedger {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.throttle
+}
+
+// admit consults the throttle ledger for req. When the initiator is held
+// off it returns the throttled decision and true.
+func (g *Gate) admit(cfg *Config, req ConsentRequest) (Decision, bool, error) {
+	l := g.throttleLedger()
+	if l == nil || cfg == nil || !cfg.Throttle.Enabled {
+		return Decision{}, false, nil
+	}
+	now := g.timeNow()
+	retry, err := l.Admit(req.Initiator.ID, req.Action, cfg.Throttle, lowTrust(cfg, req), now)
+	if err != nil || retry.IsZero() {
+		return Decision{}, false, err
+	}
+	reason := "throttled: retry after " + retry.UTC().Format(time.RFC3339)
+	return Decision{
+		Allowed:        false,
+		Reason:         reason,
+		ThrottleUntil:  &retry,
+		AppliedRules:   []string{},
+		LegitimacyRule: throttleLedgerRule,
+		Trace:          []RuleTrace{{Rule: throttleLedgerRule, Verdict: Throttle, Reason: reason}},
+		ConfigVersion:  cfg.Version,
+		ConfigHash:     cfg.Hash(),
+	}, true, nil
+}
+
+// hold records a throttled decision in the throttle ledger.
+func (g *Gate) hold(req ConsentRequest, dec Decision) error {
+	l := g.throttleLedger()
+	if l == nil || dec.ThrottleUntil == nil {
+		return nil
+	}
+	return l.Hold(req.Initiator.ID, req.Action, *dec.ThrottleUntil)
+}
+
+// EnsureThrottleSchema creates the consent_throttle table.
+func EnsureThrottleSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS consent_throttle (
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		held_until TIMESTAMPTZ,
+		doc JSONB NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		PRIMARY KEY (actor, action)
+	);
+	`)
+	return err
+}
+
+// PGThrottleStore keeps throttle state in Postgres.
+type PGThrottleStore struct {
+	db *sql.DB
+}
+
+// NewPGThrottleStore returns a store over db. EnsureThrottleSchema must have
+// been run.
+func NewPGThrottleStore(db *sql.DB) *PGThrottleStore {
+	return &PGThrottleStore{db: db}
+}
+
+func (s *PGThrottleStore) LoadThrottle(actor, action string) (*ThrottleState, error) {
+	var doc []byte
+	err := s.db.QueryRow(`SELECT doc FROM consent_throttle WHERE actor = $1 AND action = $2`, actor, action).Scan(&doc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var st ThrottleState
+	if err := json.Unmarshal(doc, &st); err != nil {
+		return nil, fmt.Errorf("decode throttle state: %w", err)
+	}
+	return &st, nil
+}
+
+func (s *PGThrottleStore) SaveThrottle(st *ThrottleState) error {
+	doc, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	var held any
+	if !st.HeldUntil.IsZero() {
+		held = st.HeldUntil
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO consent_throttle (actor, action, held_until, doc, updated_at)
+		VALUES ($1, $2, $3, $4, now())
+		ON CONFLICT (actor, action) DO UPDATE
+		SET held_until = EXCLUDED.held_until, doc = EXCLUDED.doc, updated_at = now()`,
+		st.Actor, st.Action, held, doc)
+	return err
+}
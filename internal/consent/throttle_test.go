@@ -0,0 +1,225 @@
+package consent_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"dis-core/internal/consent"
+	"dis-core/internal/db"
+	"dis-core/internal/ledger"
+)
+
+// memThrottleStore is a ThrottleStore in a map, standing in for Postgres.
+type memThrottleStore struct {
+	mu    sync.Mutex
+	state map[[2]string]consent.ThrottleState
+}
+
+func (s *memThrottleStore) LoadThrottle(actor, action string) (*consent.ThrottleState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.state[[2]string{actor, action}]
+	if !ok {
+		return nil, nil
+	}
+	return &st, nil
+}
+
+func (s *memThrottleStore) SaveThrottle(st *consent.ThrottleState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp := *st
+	cp.Attempts = append([]time.Time(nil), st.Attempts...)
+	s.state[[2]string{st.Actor, st.Action}] = cp
+	return nil
+}
+
+// throttleStores returns the stores under test: a map, and Postgres when
+// DIS_TEST_DB_DSN names a scratch database.
+func throttleStores(t *testing.T) map[string]consent.ThrottleStore {
+	t.Helper()
+	out := map[string]consent.ThrottleStore{"memory": &memThrottleStore{state: map[[2]string]consent.ThrottleState{}}}
+	dsn := os.Getenv("DIS_TEST_DB_DSN")
+	if dsn == "" {
+		return out
+	}
+	database, err := db.ConnectPostgres(dsn)
+	if err != nil {
+		t.Fatalf("ConnectPostgres: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := consent.EnsureThrottleSchema(database); err != nil {
+		t.Fatalf("EnsureThrottleSchema: %v", err)
+	}
+	out["postgres"] = consent.NewPGThrottleStore(database)
+	return out
+}
+
+func TestThrottleLedgerAdmit(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(s float64) time.Time { return t0.Add(time.Duration(s * float64(time.Second))) }
+	rule := func(mode consent.ThrottleMode) consent.ThrottleRule {
+		return consent.ThrottleRule{Enabled: true, LowTrustFloor: 0.3, Mode: mode, MaxAttempts: 2, Window: 10 * time.Second}
+	}
+
+	type attempt struct {
+		at       float64 // seconds after t0
+		lowTrust bool
+		retry    float64 // seconds after t0; -1 when admitted
+	}
+	cases := []struct {
+		name     string
+		rule     consent.ThrottleRule
+		attempts []attempt
+	}{
+		{"sliding window", rule(consent.SlidingWindow), []attempt{
+			{0, true, -1},
+			{1, true, -1},
+			{2, true, 10},
+			{10, true, -1},
+			{10.5, true, 11},
+		}},
+		{"token bucket", rule(consent.TokenBucket), []attempt{
+			{0, true, -1},
+			{0, true, -1},
+			{1, true, 5},
+			{5, true, -1},
+			{5, true, 10},
+		}},
+		{"trusted actors are not counted", rule(consent.SlidingWindow), []attempt{
+			{0, false, -1},
+			{0, false, -1},
+			{0, false, -1},
+			{0, true, -1},
+		}},
+		{"no rate limit", consent.ThrottleRule{Enabled: true, LowTrustFloor: 0.3, Backoff: time.Second}, []attempt{
+			{0, true, -1},
+			{0, true, -1},
+			{0, true, -1},
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := consent.NewThrottleLedger(nil)
+			for i, a := range tc.attempts {
+				retry, err := l.Admit("erin", "trade.execute", tc.rule, a.lowTrust, at(a.at))
+				if err != nil {
+					t.Fatalf("attempt %d: %v", i, err)
+				}
+				want := time.Time{}
+				if a.retry >= 0 {
+					want = at(a.retry)
+				}
+				if !retry.Equal(want) {
+					t.Fatalf("attempt %d at +%vs: retry %v, want %v", i, a.at, retry, want)
+				}
+			}
+		})
+	}
+}
+
+// TestThrottleLedgerStore checks that holds and counted attempts are
+// written through, so a ledger over the same store enforces them.
+func TestThrottleLedgerStore(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	rule := consent.ThrottleRule{Enabled: true, LowTrustFloor: 0.3, Mode: consent.SlidingWindow, MaxAttempts: 1, Window: time.Minute}
+	for name, store := range throttleStores(t) {
+		t.Run(name, func(t *testing.T) {
+			held, counted := "held-"+ledger.GenerateUUID(), "counted-"+ledger.GenerateUUID()
+			l := consent.NewThrottleLedger(store)
+			if err := l.Hold(held, "trade.execute", t0.Add(30*time.Second)); err != nil {
+				t.Fatalf("Hold: %v", err)
+			}
+			if err := l.Hold(held, "trade.execute", t0.Add(10*time.Second)); err != nil {
+				t.Fatalf("shorter Hold: %v", err)
+			}
+			if retry, err := l.Admit(counted, "trade.execute", rule, true, t0); err != nil || !retry.IsZero() {
+				t.Fatalf("first attempt: %v, %v", retry, err)
+			}
+
+			restarted := consent.NewThrottleLedger(store)
+			cases := []struct {
+				actor, action string
+				lowTrust      bool
+				at            time.Time
+				retry         time.Time
+			}{
+				{held, "trade.execute", false, t0.Add(20 * time.Second), t0.Add(30 * time.Second)},
+				{held, "trade.cancel", false, t0.Add(20 * time.Second), time.Time{}},
+				{held, "trade.execute", false, t0.Add(30 * time.Second), time.Time{}},
+				{counted, "trade.execute", true, t0.Add(time.Second), t0.Add(time.Minute)},
+				{counted, "trade.execute", true, t0.Add(time.Minute), time.Time{}},
+			}
+			for i, tc := range cases {
+				retry, err := restarted.Admit(tc.actor, tc.action, rule, tc.lowTrust, tc.at)
+				if err != nil || !retry.Equal(tc.retry) {
+					t.Fatalf("case %d: retry %v, %v; want %v", i, retry, err, tc.retry)
+				}
+			}
+			st, err := restarted.State(counted, "trade.execute")
+			if err != nil || len(st.Attempts) != 1 || !st.Attempts[0].Equal(t0.Add(time.Minute)) {
+				t.Fatalf("State: %+v, %v", st, err)
+			}
+		})
+	}
+}
+
+// TestGateThrottle checks that a throttled decision holds the initiator
+// off: the next attempt is refused before any rule runs, with no receipt.
+func TestGateThrottle(t *testing.T) {
+	cfg, err := consent.ParseConfig(shippedConfig(t), loadSchema(t))
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	// Let low-trust initiators past personal_autonomy so the low-trust
+	// backoff decides.
+	cfg.GateRules = append([]consent.GateRule(nil), cfg.GateRules...)
+	cfg.GateRules[0].Threshold = 0
+	store := ledger.NewMemoryStore()
+	throttle := consent.NewThrottleLedger(nil)
+	gate := consent.NewGate(cfg, cfg.Version, nil, store).WithThrottle(throttle)
+	req := consent.ConsentRequest{
+		Action:    "trade.execute",
+		SchemaRef: "trade.v1",
+		Initiator: consent.ActorRef{ID: "erin", Domain: "org", Trust: 0.1, Legitimacy: 1},
+		Affected:  []consent.ActorRef{{ID: "acme", Domain: "org", Trust: 0.9}},
+	}
+
+	cases := []struct {
+		name    string
+		rule    string
+		receipt bool
+	}{
+		{"low trust backoff", "throttle.low_trust", true},
+		{"held by the ledger", "throttle.ledger", false},
+	}
+	var until time.Time
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dec, rcpt, err := gate.AuthorizeAction(context.Background(), req)
+			if err != nil {
+				t.Fatalf("AuthorizeAction: %v", err)
+			}
+			if dec.Allowed || dec.ThrottleUntil == nil || dec.LegitimacyRule != tc.rule || (rcpt != nil) != tc.receipt {
+				t.Fatalf("decision %+v, receipt %v", dec, rcpt)
+			}
+			if until.IsZero() {
+				until = *dec.ThrottleUntil
+			} else if !dec.ThrottleUntil.Equal(until) {
+				t.Fatalf("held until %v, want %v", dec.ThrottleUntil, until)
+			}
+		})
+	}
+	if list, err := store.List(ledger.ListOptions{}); err != nil || len(list) != 1 {
+		t.Fatalf("receipts: %d, %v", len(list), err)
+	}
+
+	trusted := req
+	trusted.Initiator.ID, trusted.Initiator.Trust = "dave", 0.9
+	if dec, _, err := gate.AuthorizeAction(context.Background(), trusted); err != nil || !dec.Allowed {
+		t.Fatalf("trusted initiator: %+v, %v", dec, err)
+	}
+}
@@ -3,8 +3,10 @@ package console
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 
 	"dis-core/internal/db"
 	"dis-core/internal/ledger"
@@ -16,9 +18,21 @@ type Console struct {
 	BoundDomain string
 	BoundCore   string
 	SeatHolders []string
+	Threshold   *ledger.ThresholdPolicy `json:",omitempty"`
 	Actions     []ConsoleAction
+
+	mu sync.Mutex
 }
 
+// Action statuses. An action is pending until its receipt carries enough
+// seat signatures to meet the console's threshold.
+const (
+	ActionPending  = "pending"
+	ActionExecuted = "executed"
+)
+
+var ErrActionNotFound = errors.New("console action not found")
+
 // ConsoleAction represents a single policy or operational act recorded by the console.
 type ConsoleAction struct {
 	ID        string
@@ -47,7 +61,10 @@ func NewConsole(boundDomain, boundCore string, seats []string) *Console {
 	}
 }
 
-// LogAction creates and records a new action and its signed receipt.
+// LogAction proposes a new action on behalf of the initiating seat. The
+// action stays pending until the console's threshold of seats, the
+// initiator included, has co-signed it through SignAction; then it
+// executes and its receipt is saved.
 func (c *Console) LogAction(actionType, policyRef, initiator string) (*ConsoleAction, error) {
 	// Validate initiator
 	valid := false
@@ -61,15 +78,21 @@ func (c *Console) LogAction(actionType, policyRef, initiator string) (*ConsoleAc
 		return nil, fmt.Errorf("unauthorized initiator: %s", initiator)
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	createdAt := db.NowRFC3339Nano()
 	actionID := generateActionID()
 
-	// Generate the receipt via receipts.NewReceipt
-	r := ledger.NewReceipt(c.BoundDomain, actionType, c.BoundCore, c.ID, initiator)
-
-	// Save the receipt to disk under the current version’s receipt folder
-	if err := ledger.SaveReceipt(r); err != nil {
-		return nil, fmt.Errorf("failed to save receipt: %v", err)
+	// The domain commits to the threshold policy when it seals the receipt.
+	r, err := ledger.NewReceipt(c.BoundDomain, actionType, c.BoundCore, c.ID, initiator)
+	if err != nil {
+		return nil, err
+	}
+	policy := c.policy()
+	r.Threshold = &policy
+	if err := r.Seal(); err != nil {
+		return nil, fmt.Errorf("failed to seal receipt: %v", err)
 	}
 
 	act := ConsoleAction{
@@ -78,15 +101,58 @@ func (c *Console) LogAction(actionType, policyRef, initiator string) (*ConsoleAc
 		PolicyRef: policyRef,
 		CreatedAt: createdAt,
 		Initiator: initiator,
-		Status:    "executed",
+		Status:    ActionPending,
 		Receipt:   r,
 	}
-
 	c.Actions = append(c.Actions, act)
-	log.Printf("🧾 Action logged: %s (%s)\n", actionType, actionID)
+	log.Printf("🧾 Action logged: %s (%s, %s)\n", actionType, actionID, act.Status)
 	return &act, nil
 }
 
+// SignAction adds a seat's co-signature to a pending action. The console
+// holds no seat keys: sig is made by the seat on its own side, over
+// ledger.SeatSignatureDigest of the receipt hash.
+func (c *Console) SignAction(actionID string, sig ledger.SeatSignature) (*ConsoleAction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.Actions {
+		act := &c.Actions[i]
+		if act.ID != actionID {
+			continue
+		}
+		if act.Status != ActionPending {
+			return nil, fmt.Errorf("action %s is %s, not pending", actionID, act.Status)
+		}
+		if err := act.Receipt.AddSignature(sig); err != nil {
+			return nil, err
+		}
+		if err := act.executeIfReady(); err != nil {
+			return nil, err
+		}
+		log.Printf("✍️  Seat %s signed %s (%s)", sig.Seat, actionID, act.Status)
+		cp := *act
+		return &cp, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrActionNotFound, actionID)
+}
+
+// executeIfReady saves the receipt and marks the action executed once the
+// threshold is met.
+func (a *ConsoleAction) executeIfReady() error {
+	if err := a.Receipt.VerifyThreshold(); err != nil {
+		if errors.Is(err, ledger.ErrThresholdNotMet) {
+			return nil
+		}
+		return err
+	}
+	if err := ledger.SaveReceipt(a.Receipt); err != nil {
+		return fmt.Errorf("failed to save receipt: %v", err)
+	}
+	a.Status = ActionExecuted
+	return nil
+}
+
 func generateActionID() string {
 	b := make([]byte, 6)
 	rand.Read(b)
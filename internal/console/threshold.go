@@ -0,0 +1,56 @@
+package console
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"dis-core/internal/ledger"
+)
+
+// ThresholdConfig maps each domain to the seat co-signing policy its
+// console actions must satisfy.
+type ThresholdConfig struct {
+	Domains map[string]ledger.ThresholdPolicy `yaml:"domains"`
+}
+
+// LoadThresholdConfig reads per-domain threshold policies from path.
+func LoadThresholdConfig(path string) (*ThresholdConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg ThresholdConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// SetThreshold installs the M-of-N policy for this console's actions. Every
+// seat in the policy must be one of the console's seat holders.
+func (c *Console) SetThreshold(p ledger.ThresholdPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	for _, s := range p.Seats {
+		if !slices.Contains(c.SeatHolders, s) {
+			return fmt.Errorf("threshold seat %s is not a seat holder", s)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Threshold = &p
+	return nil
+}
+
+// policy returns the active threshold policy: the configured one, or any
+// single seat holder when none is set. Callers must hold c.mu.
+func (c *Console) policy() ledger.ThresholdPolicy {
+	if c.Threshold != nil {
+		return *c.Threshold
+	}
+	return ledger.ThresholdPolicy{M: 1, Seats: c.SeatHolders}
+}
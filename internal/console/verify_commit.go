@@ -15,6 +15,9 @@ type VerifyReport struct {
 	Valid      int              `json:"valid"`
 	Invalid    int              `json:"invalid"`
 	Results    []map[string]any `json:"results"`
+
+	Chain  *ledger.ChainReport `json:"chain,omitempty"`
+	Proofs *ProofAudit         `json:"proofs,omitempty"`
 }
 
 // CommitVerification creates a signed receipt for an audit run
@@ -35,7 +38,10 @@ func (c *Console) CommitVerification(report VerifyReport) (*ledger.Receipt, erro
 	os.WriteFile(filename, payloadBytes, 0644)
 
 	// Generate the signed receipt
-	r := ledger.NewReceipt(c.BoundDomain, "domain.verify.v1", c.BoundCore, c.ID, c.SeatHolders[0])
+	r, err := ledger.NewReceipt(c.BoundDomain, "domain.verify.v1", c.BoundCore, c.ID, c.SeatHolders[0])
+	if err != nil {
+		return nil, err
+	}
 	if err := ledger.SaveReceipt(r); err != nil {
 		return nil, err
 	}
@@ -67,6 +67,15 @@ func (c *Console) RunVerification() (VerifyReport, *ledger.Receipt, error) {
 		Results:    results,
 	}
 
+	// Check full-ledger integrity with offline inclusion/consistency proofs
+	chain, proofs, err := auditLedgerProofs(ledgerFile)
+	if err != nil {
+		log.Printf("⚠️ Ledger proof audit skipped: %v", err)
+	} else {
+		report.Chain = chain
+		report.Proofs = proofs
+	}
+
 	// Keep a human-readable copy of the report
 	// (CommitVerification also saves a copy and issues a signed receipt)
 	b, _ := json.MarshalIndent(report, "", "  ")
@@ -9,13 +9,13 @@ import (
 
 	"dis-core/internal/bridge"
 	"dis-core/internal/config"
-	dbpkg "dis-core/internal/db"
+	"dis-core/internal/ledger"
 	"dis-core/internal/policy"
 	"dis-core/internal/util/crypto"
 )
 
 // PerformConsentAction validates policy and inserts a receipt.
-// Returns: receiptID, nonce, createdAt, signature, error
+// Returns: receipt chain seq, nonce, createdAt, signature, error
 func PerformConsentAction(sqlDB *sql.DB, by string, scope string, providedNonce string, cfg *config.Config, pol *policy.Policy, polSum string) (int64, string, string, string, error) {
 	var id string
 	if err := sqlDB.QueryRow("SELECT id FROM identities ORDER BY created_at DESC LIMIT 1").Scan(&id); err != nil {
@@ -45,18 +45,28 @@ func PerformConsentAction(sqlDB *sql.DB, by string, scope string, providedNonce
 	// Signature includes policy checksum
 	sig := crypto.Sign(action, id, by, scope, nonce, bridge.CanonicalTime(ts), polSum)
 
-	// 1️⃣ Construct new-style receipt record
-	r := &dbpkg.Receipt{
-		ReceiptID: fmt.Sprintf("rcpt-%s", nonce[:8]),
-		SchemaRef: "bridge-receipt-template.v0",
-		Content:   fmt.Sprintf("Consent granted by %s for scope '%s'. Sig=%s", by, scope, sig[:16]),
-		CreatedAt: ts,
+	// 1️⃣ Construct canonical receipt envelope
+	r, err := ledger.NewEnvelope("bridge-receipt-template.v0", by, action, map[string]any{
+		"identity_id":     id,
+		"scope":           scope,
+		"nonce":           nonce,
+		"policy_checksum": polSum,
+		"signature":       sig,
+		"content":         fmt.Sprintf("Consent granted by %s for scope '%s'. Sig=%s", by, scope, sig[:16]),
+	})
+	if err != nil {
+		return 0, "", "", "", err
+	}
+	r.ReceiptID = fmt.Sprintf("rcpt-%s", nonce[:8])
+	r.CreatedAt = bridge.CanonicalTime(ts)
+	if err := r.Seal(); err != nil {
+		return 0, "", "", "", err
 	}
 
-	recID, err := dbpkg.InsertReceipt(sqlDB, r)
-	if err != nil {
+	if err := ledger.NewStore(sqlDB).Append(r); err != nil {
 		return 0, "", "", "", err
 	}
+	recID := int64(r.Seq)
 
 	log.Printf("✅ Consent action recorded: by=%s scope=%s receipt_id=%d", by, scope, recID)
 	return recID, nonce, bridge.CanonicalTime(ts), sig, nil
@@ -5,7 +5,7 @@ import (
 	"log"
 	"time"
 
-	"dis-core/internal/db"
+	"dis-core/internal/ledger"
 )
 
 // AutoRevocationDaemon scans for expired, non-revoked handshakes
@@ -14,9 +14,9 @@ import (
 // Integration points expected (once db layer is ready):
 //   - db.ListExpiredActiveHandshakes(now time.Time) ([]db.Handshake, error)
 //   - db.MarkHandshakeRevoked(id int64, when time.Time, reason string) error
-//   - db.SaveReceipt(r db.Receipt) error
 //
-// Until then, stubbed safe no-op versions are provided below.
+// Until then, stubbed safe no-op versions are provided below. Receipts are
+// written through ledger.SaveReceipt.
 func StartAutoRevocationDaemon(ctx context.Context, interval time.Duration) {
 	if interval <= 0 {
 		interval = 60 * time.Second
@@ -45,14 +45,20 @@ func StartAutoRevocationDaemon(ctx context.Context, interval time.Duration) {
 				continue
 			}
 
-			rc := db.Receipt{
-				ReceiptID: generateReceiptID("rcpt-revoke"),
-				SchemaRef: "revocation.v0",
-				Content:   "Revocation: handshake " + hs.Token + " for " + hs.Subject + " (reason: expired)",
-				CreatedAt: now, // now is time.Time
+			rc, err := ledger.NewEnvelope("revocation.v0", ledger.NodeDomain, "auth.revoke", map[string]any{
+				"token":   hs.Token,
+				"subject": hs.Subject,
+				"reason":  reason,
+				"content": "Revocation: handshake " + hs.Token + " for " + hs.Subject + " (reason: expired)",
+			})
+			if err == nil {
+				rc.ReceiptID = generateReceiptID("rcpt-revoke-" + hs.Token)
+				err = rc.Seal()
 			}
-
-			if err := safeSaveReceipt(rc); err != nil {
+			if err == nil {
+				err = ledger.SaveReceipt(rc)
+			}
+			if err != nil {
 				log.Printf("auto-revoke: save receipt failed for token=%s: %v", hs.Token, err)
 				continue
 			}
@@ -104,9 +110,3 @@ func safeMarkHandshakeRevoked(id int64, when time.Time, reason string) error {
 	// Replace with: return db.MarkHandshakeRevoked(id, when, reason)
 	return nil
 }
-
-// safeSaveReceipt safely wraps db.SaveReceipt.
-func safeSaveReceipt(r db.Receipt) error {
-	// Replace with: return db.SaveReceipt(r)
-	return nil
-}
@@ -10,15 +10,11 @@ import (
 
 // CreateSchema lays down all base DIS-CORE tables for PostgreSQL.
 func CreateSchema(db *sql.DB) error {
-	schema := []string{
-		`CREATE TABLE IF NOT EXISTS receipts (
-		       id SERIAL PRIMARY KEY,
-		       receipt_id TEXT UNIQUE NOT NULL,
-		       schema_ref TEXT,
-		       content TEXT,
-		       created_at TIMESTAMPTZ NOT NULL DEFAULT now()
-	       );`,
+	if err := EnsureReceiptsSchema(db); err != nil {
+		return err
+	}
 
+	schema := []string{
 		`CREATE TABLE IF NOT EXISTS revocations (
 				id SERIAL PRIMARY KEY,
 				revocation_id TEXT UNIQUE NOT NULL,
@@ -28,8 +24,10 @@ func CreateSchema(db *sql.DB) error {
 				revoked_by TEXT,
 				revocation_time TIMESTAMPTZ,
 				valid_until TIMESTAMPTZ,
-				signature TEXT
+				signature TEXT,
+				propagated_at TIMESTAMPTZ
 			);`,
+		`ALTER TABLE revocations ADD COLUMN IF NOT EXISTS propagated_at TIMESTAMPTZ;`,
 
 		// Updated domains table schema with JSONB 'data' column and new structure
 		`CREATE TABLE IF NOT EXISTS domains (
@@ -1,32 +1,65 @@
 package db
 
 import (
-	"context"
 	"database/sql"
 	"fmt"
-	"time"
 )
 
-// Receipt matches the current receipts table schema.
-type Receipt struct {
-	ID        int64     `json:"id"`
-	ReceiptID string    `json:"receipt_id"`
-	SchemaRef string    `json:"schema_ref"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
-}
-
-// EnsureReceiptsSchema creates the receipts table if missing (for safety).
+// EnsureReceiptsSchema creates the receipts table if missing and migrates
+// older layouts to the canonical one used by ledger.Store: the signed
+// receipt envelope is kept as JSON in content, with its hash-chain position
+// in seq/hash/prev_hash/chain_hash. doc is a JSONB copy of content that
+// receipt queries filter on; legacy rows whose content is not JSON leave it
+// NULL.
 func EnsureReceiptsSchema(db *sql.DB) error {
 	schema := `
 	CREATE TABLE IF NOT EXISTS receipts (
 		id SERIAL PRIMARY KEY,
 		receipt_id TEXT UNIQUE NOT NULL,
 		schema_ref TEXT,
+		actor TEXT,
+		action TEXT,
 		content TEXT,
-		created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		seq BIGINT,
+		hash TEXT,
+		prev_hash TEXT,
+		chain_hash TEXT,
+		doc JSONB
 	);
+	ALTER TABLE receipts ADD COLUMN IF NOT EXISTS receipt_id TEXT;
+	ALTER TABLE receipts ADD COLUMN IF NOT EXISTS schema_ref TEXT;
+	ALTER TABLE receipts ADD COLUMN IF NOT EXISTS actor TEXT;
+	ALTER TABLE receipts ADD COLUMN IF NOT EXISTS action TEXT;
+	ALTER TABLE receipts ADD COLUMN IF NOT EXISTS content TEXT;
+	ALTER TABLE receipts ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+	ALTER TABLE receipts ADD COLUMN IF NOT EXISTS seq BIGINT;
+	ALTER TABLE receipts ADD COLUMN IF NOT EXISTS hash TEXT;
+	ALTER TABLE receipts ADD COLUMN IF NOT EXISTS prev_hash TEXT;
+	ALTER TABLE receipts ADD COLUMN IF NOT EXISTS chain_hash TEXT;
+	ALTER TABLE receipts ADD COLUMN IF NOT EXISTS doc JSONB;
+	CREATE OR REPLACE FUNCTION dis_try_jsonb(t TEXT) RETURNS JSONB AS $$
+	BEGIN
+		RETURN t::jsonb;
+	EXCEPTION WHEN others THEN
+		RETURN NULL;
+	END $$ LANGUAGE plpgsql IMMUTABLE;
+	UPDATE receipts SET doc = dis_try_jsonb(content) WHERE doc IS NULL AND content LIKE '{%';
+	DO $$
+	BEGIN
+		-- The ledger.Open event table required a type column.
+		IF EXISTS (SELECT 1 FROM information_schema.columns
+		           WHERE table_name = 'receipts' AND column_name = 'type') THEN
+			ALTER TABLE receipts ALTER COLUMN type DROP NOT NULL;
+		END IF;
+	END $$;
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_receipts_receipt_id ON receipts(receipt_id);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_receipts_seq ON receipts(seq) WHERE seq IS NOT NULL;
 	CREATE INDEX IF NOT EXISTS idx_receipts_created_at ON receipts(created_at);
+	CREATE INDEX IF NOT EXISTS idx_receipts_page ON receipts(created_at DESC, receipt_id DESC);
+	CREATE INDEX IF NOT EXISTS idx_receipts_actor_action ON receipts(actor, action, created_at);
+	CREATE INDEX IF NOT EXISTS idx_receipts_schema_ref ON receipts(schema_ref, created_at);
+	CREATE INDEX IF NOT EXISTS idx_receipts_doc ON receipts USING GIN (doc jsonb_path_ops);
 	`
 	_, err := db.Exec(schema)
 	if err != nil {
@@ -36,80 +69,6 @@ func EnsureReceiptsSchema(db *sql.DB) error {
 	return nil
 }
 
-// InsertReceipt adds a new receipt entry into the receipts table.
-func InsertReceipt(db *sql.DB, r *Receipt) (int64, error) {
-	if r.CreatedAt.IsZero() {
-		r.CreatedAt = time.Now().UTC()
-	}
-	q := `
-       INSERT INTO receipts (receipt_id, schema_ref, content, created_at)
-       VALUES ($1, $2, $3, $4)
-       RETURNING id;
-       `
-	var id int64
-	err := db.QueryRow(q, r.ReceiptID, r.SchemaRef, r.Content, r.CreatedAt).Scan(&id)
-	if err != nil {
-		return 0, fmt.Errorf("failed to insert receipt: %w", err)
-	}
-	return id, nil
-}
-
-// ListOpts provides filtering/pagination parameters.
-type ListOpts struct {
-	Limit  int
-	Offset int
-}
-
-// ListReceipts fetches recent receipts with optional limit/offset.
-func ListReceipts(db *sql.DB, opts ListOpts) ([]Receipt, error) {
-	if opts.Limit <= 0 || opts.Limit > 500 {
-		opts.Limit = 100
-	}
-	if opts.Offset < 0 {
-		opts.Offset = 0
-	}
-
-	rows, err := db.QueryContext(context.Background(), `
-	       SELECT id, receipt_id, schema_ref, content, created_at
-	       FROM receipts
-	       ORDER BY id DESC
-	       LIMIT $1 OFFSET $2;
-       `, opts.Limit, opts.Offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list receipts: %w", err)
-	}
-	defer rows.Close()
-
-	var out []Receipt
-	for rows.Next() {
-		var r Receipt
-		if err := rows.Scan(&r.ID, &r.ReceiptID, &r.SchemaRef, &r.Content, &r.CreatedAt); err != nil {
-			return nil, err
-		}
-		out = append(out, r)
-	}
-	return out, rows.Err()
-}
-
-//
-// === v0.9.3 Additions for AutoRevocation + /api/status ===
-//
-
-// SaveReceipt inserts a receipt directly using the default DB handle.
-func SaveReceipt(r Receipt) error {
-	if DefaultDB == nil {
-		return fmt.Errorf("db not initialized")
-	}
-	if r.CreatedAt.IsZero() {
-		r.CreatedAt = time.Now().UTC()
-	}
-	_, err := DefaultDB.Exec(`
-	       INSERT INTO receipts (receipt_id, schema_ref, content, created_at)
-	       VALUES ($1, $2, $3, $4);
-       `, r.ReceiptID, r.SchemaRef, r.Content, r.CreatedAt)
-	return err
-}
-
 // CountReceipts returns total count of receipts in the database.
 func CountReceipts() (int64, error) {
 	if DefaultDB == nil {
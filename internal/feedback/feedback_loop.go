@@ -2,6 +2,7 @@ package feedback
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 )
@@ -26,6 +27,11 @@ type TrustStore interface {
 	SetTrust(ctx context.Context, actorID string, v float64) error
 	GetEthics(ctx context.Context, actorID string) (float64, bool, error)
 	SetEthics(ctx context.Context, actorID string, v float64) error
+	// Update sets an actor's kind score (KindTrust or KindEthics) to
+	// next(current, ok) and returns it. The read and the write are one
+	// atomic step for every process sharing the store, so concurrent
+	// updates are never lost.
+	Update(ctx context.Context, actorID, kind string, next func(v float64, ok bool) float64) (float64, error)
 }
 
 // Simulator receives feedback events (e.g., Simula Terra).
@@ -49,7 +55,6 @@ type Event struct {
 type Loop struct {
 	store     TrustStore
 	sim       Simulator
-	mu        sync.Mutex
 	softFloor float64
 	softCeil  float64
 }
@@ -85,32 +90,24 @@ func (l *Loop) Apply(ctx context.Context, rcpt Receipt) error {
 }
 
 func (l *Loop) bump(ctx context.Context, actorID string, rcpt Receipt) error {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	t0, ok, err := l.store.GetTrust(ctx, actorID)
-	if err != nil {
-		return err
+	if rcpt.ID != "" {
+		ctx = WithReceipt(ctx, rcpt.ID)
 	}
-	if !ok {
-		t0 = 0.5 // neutral default
+	shift := func(delta float64) func(float64, bool) float64 {
+		return func(v float64, ok bool) float64 {
+			if !ok {
+				v = 0.5 // neutral default
+			}
+			return clamp(v+delta, l.softFloor, l.softCeil)
+		}
 	}
 
-	e0, ok, err := l.store.GetEthics(ctx, actorID)
+	t1, err := l.store.Update(ctx, actorID, KindTrust, shift(rcpt.TrustChange))
 	if err != nil {
 		return err
 	}
-	if !ok {
-		e0 = 0.5
-	}
-
-	t1 := clamp(t0+rcpt.TrustChange, l.softFloor, l.softCeil)
-	e1 := clamp(e0+rcpt.EthicsChange, l.softFloor, l.softCeil)
-
-	if err := l.store.SetTrust(ctx, actorID, t1); err != nil {
-		return err
-	}
-	if err := l.store.SetEthics(ctx, actorID, e1); err != nil {
+	e1, err := l.store.Update(ctx, actorID, KindEthics, shift(rcpt.EthicsChange))
+	if err != nil {
 		return err
 	}
 
@@ -142,9 +139,10 @@ func clamp(v, lo, hi float64) float64 {
 // --------- In-memory store (baseline) ---------
 
 type MemStore struct {
-	mu     sync.RWMutex
-	trust  map[string]float64
-	ethics map[string]float64
+	mu      sync.RWMutex
+	trust   map[string]float64
+	ethics  map[string]float64
+	history []Change
 }
 
 func NewMemStore() *MemStore {
@@ -164,7 +162,7 @@ func (m *MemStore) GetTrust(ctx context.Context, actorID string) (float64, bool,
 func (m *MemStore) SetTrust(ctx context.Context, actorID string, v float64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.trust[actorID] = v
+	m.record(ctx, actorID, KindTrust, m.trust, v)
 	return nil
 }
 
@@ -178,6 +176,72 @@ func (m *MemStore) GetEthics(ctx context.Context, actorID string) (float64, bool
 func (m *MemStore) SetEthics(ctx context.Context, actorID string, v float64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.ethics[actorID] = v
+	m.record(ctx, actorID, KindEthics, m.ethics, v)
 	return nil
 }
+
+func (m *MemStore) Update(ctx context.Context, actorID, kind string, next func(v float64, ok bool) float64) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	scores := m.trust
+	switch kind {
+	case KindTrust:
+	case KindEthics:
+		scores = m.ethics
+	default:
+		return 0, fmt.Errorf("unknown score kind %q", kind)
+	}
+	v, ok := scores[actorID]
+	v = next(v, ok)
+	m.record(ctx, actorID, kind, scores, v)
+	return v, nil
+}
+
+// record sets scores[actorID] and appends the change; m.mu must be held.
+func (m *MemStore) record(ctx context.Context, actorID, kind string, scores map[string]float64, v float64) {
+	c := Change{
+		Seq:       int64(len(m.history) + 1),
+		ActorID:   actorID,
+		Kind:      kind,
+		Value:     v,
+		ReceiptID: ReceiptFrom(ctx),
+		ChangedAt: time.Now().UTC(),
+	}
+	if prev, ok := scores[actorID]; ok {
+		c.Previous = &prev
+	}
+	scores[actorID] = v
+	m.history = append(m.history, c)
+}
+
+func (m *MemStore) History(ctx context.Context, actorID string) ([]Change, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := []Change{}
+	for _, c := range m.history {
+		if c.ActorID == actorID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (m *MemStore) ScoreAsOf(ctx context.Context, actorID, kind, receiptID string) (float64, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cut := -1
+	for i, c := range m.history {
+		if receiptID != "" && c.ReceiptID == receiptID {
+			cut = i
+		}
+	}
+	if cut < 0 {
+		return 0, false, fmt.Errorf("%w: %s", ErrUnknownReceipt, receiptID)
+	}
+	for i := cut; i >= 0; i-- {
+		if c := m.history[i]; c.ActorID == actorID && c.Kind == kind {
+			return c.Value, true, nil
+		}
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,116 @@
+package feedback_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"sync"
+	"testing"
+
+	"dis-core/internal/db"
+	"dis-core/internal/feedback"
+	"dis-core/internal/ledger"
+)
+
+// stores returns the stores under test: always a MemStore, and a PGStore
+// when DIS_TEST_DB_DSN names a scratch database.
+func stores(t *testing.T) map[string]feedback.HistoryStore {
+	t.Helper()
+	out := map[string]feedback.HistoryStore{"memory": feedback.NewMemStore()}
+	dsn := os.Getenv("DIS_TEST_DB_DSN")
+	if dsn == "" {
+		return out
+	}
+	database, err := db.ConnectPostgres(dsn)
+	if err != nil {
+		t.Fatalf("ConnectPostgres: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := feedback.EnsureTrustSchema(database); err != nil {
+		t.Fatalf("EnsureTrustSchema: %v", err)
+	}
+	out["postgres"] = feedback.NewPGStore(database)
+	return out
+}
+
+// fresh returns an ID no earlier run has scored.
+func fresh(name string) string {
+	return name + "-" + ledger.GenerateUUID()
+}
+
+func TestLoopApply(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			initiator, affected := fresh("init"), fresh("aff")
+			loop := feedback.NewLoop(store, nil)
+			cases := []struct {
+				rcpt                  feedback.Receipt
+				initTrust, affTrust   float64
+				initEthics, affEthics float64
+			}{
+				{feedback.Receipt{ID: "r1", TrustChange: 0.2, EthicsChange: -0.1}, 0.7, 0.6, 0.4, 0.45},
+				{feedback.Receipt{ID: "r2", TrustChange: 0.5, EthicsChange: -0.5}, 1, 0.85, 0, 0.2},
+			}
+			for _, tc := range cases {
+				tc.rcpt.ID = fresh(tc.rcpt.ID)
+				tc.rcpt.InitiatorID, tc.rcpt.AffectedIDs = initiator, []string{affected}
+				if err := loop.Apply(ctx, tc.rcpt); err != nil {
+					t.Fatalf("Apply %s: %v", tc.rcpt.ID, err)
+				}
+				for _, want := range []struct {
+					actor, kind string
+					v           float64
+				}{
+					{initiator, feedback.KindTrust, tc.initTrust},
+					{initiator, feedback.KindEthics, tc.initEthics},
+					{affected, feedback.KindTrust, tc.affTrust},
+					{affected, feedback.KindEthics, tc.affEthics},
+				} {
+					v, ok, err := store.ScoreAsOf(ctx, want.actor, want.kind, tc.rcpt.ID)
+					if err != nil || !ok || math.Abs(v-want.v) > 1e-9 {
+						t.Fatalf("%s %s as of %s: %v, %v, %v; want %v", want.actor, want.kind, tc.rcpt.ID, v, ok, err, want.v)
+					}
+				}
+			}
+
+			history, err := store.History(ctx, initiator)
+			if err != nil || len(history) != 4 || history[0].Previous != nil || history[2].Previous == nil {
+				t.Fatalf("history: %+v, %v", history, err)
+			}
+			if _, _, err := store.ScoreAsOf(ctx, initiator, feedback.KindTrust, "never"); !errors.Is(err, feedback.ErrUnknownReceipt) {
+				t.Fatalf("unknown receipt: %v", err)
+			}
+		})
+	}
+}
+
+// TestUpdateConcurrent checks that concurrent read-modify-write updates
+// are not lost.
+func TestUpdateConcurrent(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			id := fresh("busy")
+			const n = 20
+			var wg sync.WaitGroup
+			for i := 0; i < n; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := store.Update(ctx, id, feedback.KindTrust, func(v float64, ok bool) float64 { return v + 1 }); err != nil {
+						t.Errorf("Update: %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+			if v, ok, err := store.GetTrust(ctx, id); err != nil || !ok || v != n {
+				t.Fatalf("trust after %d updates: %v, %v, %v", n, v, ok, err)
+			}
+			if _, err := store.Update(ctx, id, "charisma", func(v float64, ok bool) float64 { return v }); err == nil {
+				t.Fatal("unknown kind accepted")
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package feedback
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Score kinds recorded in the history.
+const (
+	KindTrust  = "trust"
+	KindEthics = "ethics"
+)
+
+// ErrUnknownReceipt is returned by point-in-time queries for a receipt that
+// changed no score.
+var ErrUnknownReceipt = errors.New("receipt changed no score")
+
+// Change is one entry in the append-only score history.
+type Change struct {
+	Seq       int64     `json:"seq"`
+	ActorID   string    `json:"actor_id"`
+	Kind      string    `json:"kind"` // KindTrust or KindEthics
+	Value     float64   `json:"value"`
+	Previous  *float64  `json:"previous,omitempty"` // nil for an actor's first score
+	ReceiptID string    `json:"receipt_id,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// HistoryStore is a TrustStore that keeps every change.
+type HistoryStore interface {
+	TrustStore
+	// History returns an actor's changes, oldest first.
+	History(ctx context.Context, actorID string) ([]Change, error)
+	// ScoreAsOf returns an actor's kind score right after the changes
+	// caused by receiptID were applied; receiptID must have changed some
+	// actor's score.
+	ScoreAsOf(ctx context.Context, actorID, kind, receiptID string) (float64, bool, error)
+}
+
+type receiptKey struct{}
+
+// WithReceipt returns ctx carrying the ID of the receipt that causes the
+// score changes made under it.
+func WithReceipt(ctx context.Context, receiptID string) context.Context {
+	return context.WithValue(ctx, receiptKey{}, receiptID)
+}
+
+// ReceiptFrom returns the receipt ID carried by ctx, if any.
+func ReceiptFrom(ctx context.Context) string {
+	id, _ := ctx.Value(receiptKey{}).(string)
+	return id
+}
+
+// EnsureTrustSchema creates the trust_scores and trust_history tables.
+func EnsureTrustSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS trust_scores (
+		actor_id TEXT PRIMARY KEY,
+		trust DOUBLE PRECISION,
+		ethics DOUBLE PRECISION,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	);
+	CREATE TABLE IF NOT EXISTS trust_history (
+		seq BIGSERIAL PRIMARY KEY,
+		actor_id TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		value DOUBLE PRECISION NOT NULL,
+		previous DOUBLE PRECISION,
+		receipt_id TEXT,
+		changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	);
+	CREATE INDEX IF NOT EXISTS trust_history_actor_idx ON trust_history (actor_id, kind, seq);
+	CREATE INDEX IF NOT EXISTS trust_history_receipt_idx ON trust_history (receipt_id);
+	`)
+	return err
+}
+
+// PGStore is a HistoryStore in Postgres. Each Set or Update changes
+// trust_scores and appends to trust_history in one transaction, linking
+// the change to the receipt carried by the context (see WithReceipt).
+type PGStore struct {
+	db *sql.DB
+}
+
+// NewPGStore returns a store over db. EnsureTrustSchema must have been run.
+func NewPGStore(db *sql.DB) *PGStore {
+	return &PGStore{db: db}
+}
+
+func (s *PGStore) GetTrust(ctx context.Context, actorID string) (float64, bool, error) {
+	return s.get(ctx, actorID, KindTrust)
+}
+
+func (s *PGStore) SetTrust(ctx context.Context, actorID string, v float64) error {
+	return s.set(ctx, actorID, KindTrust, v)
+}
+
+func (s *PGStore) GetEthics(ctx context.Context, actorID string) (float64, bool, error) {
+	return s.get(ctx, actorID, KindEthics)
+}
+
+func (s *PGStore) SetEthics(ctx context.Context, actorID string, v float64) error {
+	return s.set(ctx, actorID, KindEthics, v)
+}
+
+// column maps a kind to its trust_scores column; kinds never come from
+// callers unchecked.
+func column(kind string) (string, error) {
+	switch kind {
+	case KindTrust, KindEthics:
+		return kind, nil
+	}
+	return "", fmt.Errorf("unknown score kind %q", kind)
+}
+
+func (s *PGStore) get(ctx context.Context, actorID, kind string) (float64, bool, error) {
+	col, err := column(kind)
+	if err != nil {
+		return 0, false, err
+	}
+	var v sql.NullFloat64
+	err = s.db.QueryRowContext(ctx, `SELECT `+col+` FROM trust_scores WHERE actor_id = $1`, actorID).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return v.Float64, v.Valid, nil
+}
+
+func (s *PGStore) set(ctx context.Context, actorID, kind string, v float64) error {
+	_, err := s.Update(ctx, actorID, kind, func(float64, bool) float64 { return v })
+	return err
+}
+
+// Update locks the actor's trust_scores row with SELECT … FOR UPDATE,
+// creating it first if needed so there is a row to lock, and computes the
+// new score while holding it.
+func (s *PGStore) Update(ctx context.Context, actorID, kind string, next func(v float64, ok bool) float64) (float64, error) {
+	col, err := column(kind)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO trust_scores (actor_id) VALUES ($1) ON CONFLICT (actor_id) DO NOTHING`, actorID); err != nil {
+		return 0, err
+	}
+	var prev sql.NullFloat64
+	if err := tx.QueryRowContext(ctx, `SELECT `+col+` FROM trust_scores WHERE actor_id = $1 FOR UPDATE`, actorID).Scan(&prev); err != nil {
+		return 0, err
+	}
+	v := next(prev.Float64, prev.Valid)
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE trust_scores SET `+col+` = $2, updated_at = now() WHERE actor_id = $1`,
+		actorID, v); err != nil {
+		return 0, err
+	}
+	var receipt any
+	if id := ReceiptFrom(ctx); id != "" {
+		receipt = id
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO trust_history (actor_id, kind, value, previous, receipt_id)
+		VALUES ($1, $2, $3, $4, $5)`,
+		actorID, kind, v, prev, receipt); err != nil {
+		return 0, err
+	}
+	return v, tx.Commit()
+}
+
+func (s *PGStore) History(ctx context.Context, actorID string) ([]Change, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT seq, actor_id, kind, value, previous, COALESCE(receipt_id, ''), changed_at
+		FROM trust_history WHERE actor_id = $1 ORDER BY seq`, actorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Change{}
+	for rows.Next() {
+		var c Change
+		var prev sql.NullFloat64
+		if err := rows.Scan(&c.Seq, &c.ActorID, &c.Kind, &c.Value, &prev, &c.ReceiptID, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		if prev.Valid {
+			c.Previous = &prev.Float64
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (s *PGStore) ScoreAsOf(ctx context.Context, actorID, kind, receiptID string) (float64, bool, error) {
+	if _, err := column(kind); err != nil {
+		return 0, false, err
+	}
+	var cut sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, `SELECT MAX(seq) FROM trust_history WHERE receipt_id = $1`, receiptID).Scan(&cut); err != nil {
+		return 0, false, err
+	}
+	if !cut.Valid {
+		return 0, false, fmt.Errorf("%w: %s", ErrUnknownReceipt, receiptID)
+	}
+	var v float64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT value FROM trust_history
+		WHERE actor_id = $1 AND kind = $2 AND seq <= $3
+		ORDER BY seq DESC LIMIT 1`, actorID, kind, cut.Int64).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return v, true, nil
+}
@@ -3,12 +3,16 @@ package jikka
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"time"
 
 	"dis-core/internal/ledger"
 )
 
+// JikkaSchemaRef is the schema_ref of jikka lifecycle receipts.
+const JikkaSchemaRef = "jikka.field.v0"
+
 // JikkaField represents an emergent moral field between two domains.
 // It can exist only where both domains act from free will and mutual recognition.
 type JikkaField struct {
@@ -97,13 +101,10 @@ func CreateJikka(domainA, domainB string, freeWillA, freeWillB, recognition, con
 	data, _ := json.MarshalIndent(field, "", "  ")
 	log.Printf("🌌 Jikka Field created between %s ↔ %s:\n%s", domainA, domainB, string(data))
 
-	// Save receipt for provenance
-	// Use canonical Receipt struct fields only
-	ledger.SaveReceipt(&ledger.Receipt{
-		By:     domainA,
-		Action: "jikka.create",
-		// Fill other canonical fields as needed, e.g. Provenance, Metadata, etc.
-	})
+	// Save receipt for provenance, signed by the initiating domain.
+	if _, err := ledger.EmitEnvelope(JikkaSchemaRef, domainA, "jikka.create", field.receiptPayload()); err != nil {
+		return field, fmt.Errorf("jikka receipt: %w", err)
+	}
 
 	return field, nil
 }
@@ -122,10 +123,30 @@ func (j *JikkaField) Dissolve(reason string) {
 	}
 
 	// Record dissolution as a receipt
-	// Use canonical Receipt struct fields only
-	ledger.SaveReceipt(&ledger.Receipt{
-		By:     j.DomainA,
-		Action: "jikka.dissolve",
-		// Fill other canonical fields as needed, e.g. Provenance, Metadata, etc.
-	})
+	payload := j.receiptPayload()
+	payload["reason"] = reason
+	if _, err := ledger.EmitEnvelope(JikkaSchemaRef, j.DomainA, "jikka.dissolve", payload); err != nil {
+		log.Printf("🌑 Jikka dissolve receipt [%s]: %v", j.ID, err)
+	}
+}
+
+// receiptPayload is the field as recorded in jikka receipts.
+func (j *JikkaField) receiptPayload() map[string]any {
+	p := map[string]any{
+		"field_id":       j.ID,
+		"domain_a":       j.DomainA,
+		"domain_b":       j.DomainB,
+		"free_will_a":    j.FreeWillA,
+		"free_will_b":    j.FreeWillB,
+		"recognition":    j.Recognition,
+		"consent":        j.Consent,
+		"reflection":     j.Reflection,
+		"field_strength": j.FieldStrength,
+		"integrity":      j.Integrity,
+		"created_at":     j.CreatedAt.Format(time.RFC3339Nano),
+	}
+	if !j.DissolvedAt.IsZero() {
+		p["dissolved_at"] = j.DissolvedAt.Format(time.RFC3339Nano)
+	}
+	return p
 }
@@ -0,0 +1,222 @@
+package ledger
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"dis-core/internal/util/crypto"
+
+	"github.com/lib/pq"
+)
+
+var ErrBadAttestation = errors.New("invalid attestation")
+
+// AttestationStore keeps TrustEntry rows, attestations among them, and
+// answers which peers have attested a receipt.
+type AttestationStore interface {
+	Add(entry TrustEntry) error
+	ForReceipt(receiptID string) ([]TrustEntry, error)
+	// AttesterCounts returns the number of independent attesters of each
+	// receipt; receipts nobody attested map to 0.
+	AttesterCounts(receiptIDs []string) (map[string]int, error)
+}
+
+// NewAttestation records that attester verified r, a receipt from origin's
+// ledger, now. ok is the verification outcome; notes say why it failed.
+// The entry still has to be signed with SignAttestation.
+func NewAttestation(r *Receipt, origin, attester, coreHash string, ok bool, notes string) TrustEntry {
+	status := "ok"
+	if !ok {
+		status = "fail"
+	}
+	return TrustEntry{
+		Status:    status,
+		ReceiptID: r.ReceiptID,
+		CoreHash:  coreHash,
+		// Postgres keeps microseconds; the signed time must survive it.
+		VerifiedAt:  time.Now().UTC().Truncate(time.Microsecond),
+		Notes:       notes,
+		Attester:    attester,
+		Origin:      origin,
+		ReceiptHash: r.Hash,
+	}
+}
+
+// IsAttestation reports whether e is a signed attestation rather than a
+// plain trust event.
+func (e *TrustEntry) IsAttestation() bool { return e.Signature != "" }
+
+// attestationMessage is what an attester signs. Peer and Action describe
+// the local view of the exchange and are not covered.
+func (e *TrustEntry) attestationMessage() []byte {
+	h := sha256.New()
+	fmt.Fprintf(h, "dis-attest/v1\x00%s\x00%s\x00%s\x00%s\x00%s\x00%s\x00%s",
+		e.ReceiptID, e.ReceiptHash, e.Origin, e.Attester, e.Status, e.CoreHash,
+		e.VerifiedAt.UTC().Format(time.RFC3339Nano))
+	return h.Sum(nil)
+}
+
+// SignAttestation signs e with the Attester domain's active key in ks.
+func (e *TrustEntry) SignAttestation(ks crypto.KeyStore) error {
+	key, err := ks.Active(e.Attester)
+	if err != nil {
+		return fmt.Errorf("attester key: %w", err)
+	}
+	sig, err := ks.Sign(key.KeyID, e.attestationMessage())
+	if err != nil {
+		return fmt.Errorf("sign attestation: %w", err)
+	}
+	e.KeyID, e.PublicKeyB64, e.Signature = key.KeyID, key.PublicKeyB64, sig
+	return nil
+}
+
+// VerifyAttestation checks e's signature against its embedded key.
+func (e *TrustEntry) VerifyAttestation() error {
+	rec := crypto.KeyRecord{KeyID: e.KeyID, Domain: e.Attester, PublicKeyB64: e.PublicKeyB64}
+	pub, err := rec.PublicKey()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadAttestation, err)
+	}
+	if crypto.KeyID(pub) != e.KeyID {
+		return fmt.Errorf("%w: key id does not match public key", ErrBadAttestation)
+	}
+	if !rec.Verify(e.attestationMessage(), e.Signature) {
+		return fmt.Errorf("%w: bad signature from %s on %s", ErrBadAttestation, e.Attester, e.ReceiptID)
+	}
+	return nil
+}
+
+// VerifyAttestationWith checks e's signature and that its key is the one
+// keys resolves for the attester at VerifiedAt, so a self-made key
+// claiming another domain does not verify.
+func (e *TrustEntry) VerifyAttestationWith(keys KeyResolver) error {
+	key, err := keys(e.Attester, e.KeyID, e.VerifiedAt)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadAttestation, err)
+	}
+	if key.PublicKeyB64 != e.PublicKeyB64 {
+		return fmt.Errorf("%w: %s key %s is not the one on record", ErrBadAttestation, e.Attester, e.KeyID)
+	}
+	return e.VerifyAttestation()
+}
+
+// CountAttesters counts the distinct peers with a valid, positive
+// attestation among entries, signed with a key the default KeyStore holds
+// for the attester. A node does not count towards its own receipts.
+func CountAttesters(entries []TrustEntry) int {
+	keys := StoreKeys(nil)
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if !e.IsAttestation() || e.Status != "ok" || e.Attester == e.Origin || seen[e.Attester] {
+			continue
+		}
+		if e.VerifyAttestationWith(keys) == nil {
+			seen[e.Attester] = true
+		}
+	}
+	return len(seen)
+}
+
+// EnsureTrustEntriesSchema creates the trust_entries table.
+func EnsureTrustEntriesSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS trust_entries (
+		id BIGSERIAL PRIMARY KEY,
+		peer TEXT NOT NULL,
+		action TEXT NOT NULL,
+		status TEXT NOT NULL,
+		receipt_id TEXT NOT NULL,
+		core_hash TEXT NOT NULL DEFAULT '',
+		verified_at TIMESTAMPTZ NOT NULL,
+		notes TEXT NOT NULL DEFAULT '',
+		attester TEXT NOT NULL DEFAULT '',
+		origin TEXT NOT NULL DEFAULT '',
+		receipt_hash TEXT NOT NULL DEFAULT '',
+		key_id TEXT NOT NULL DEFAULT '',
+		public_key_b64 TEXT NOT NULL DEFAULT '',
+		signature TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX IF NOT EXISTS trust_entries_receipt_idx ON trust_entries (receipt_id);
+	`)
+	return err
+}
+
+// PGTrustStore keeps TrustEntry rows in the trust_entries table.
+// Attestation signatures are checked before a row is written, so counts
+// can be taken in SQL.
+type PGTrustStore struct {
+	db *sql.DB
+}
+
+// NewPGTrustStore returns a store over db. EnsureTrustEntriesSchema must
+// have been run.
+func NewPGTrustStore(db *sql.DB) *PGTrustStore {
+	return &PGTrustStore{db: db}
+}
+
+func (s *PGTrustStore) Add(e TrustEntry) error {
+	if e.IsAttestation() {
+		if err := e.VerifyAttestation(); err != nil {
+			return err
+		}
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO trust_entries (peer, action, status, receipt_id, core_hash, verified_at, notes,
+			attester, origin, receipt_hash, key_id, public_key_b64, signature)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		e.Peer, e.Action, e.Status, e.ReceiptID, e.CoreHash, e.VerifiedAt, e.Notes,
+		e.Attester, e.Origin, e.ReceiptHash, e.KeyID, e.PublicKeyB64, e.Signature)
+	return err
+}
+
+func (s *PGTrustStore) ForReceipt(receiptID string) ([]TrustEntry, error) {
+	rows, err := s.db.Query(`
+		SELECT peer, action, status, receipt_id, core_hash, verified_at, notes,
+			attester, origin, receipt_hash, key_id, public_key_b64, signature
+		FROM trust_entries WHERE receipt_id = $1 ORDER BY id`, receiptID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []TrustEntry
+	for rows.Next() {
+		var e TrustEntry
+		if err := rows.Scan(&e.Peer, &e.Action, &e.Status, &e.ReceiptID, &e.CoreHash, &e.VerifiedAt, &e.Notes,
+			&e.Attester, &e.Origin, &e.ReceiptHash, &e.KeyID, &e.PublicKeyB64, &e.Signature); err != nil {
+			return nil, err
+		}
+		e.VerifiedAt = e.VerifiedAt.UTC()
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (s *PGTrustStore) AttesterCounts(receiptIDs []string) (map[string]int, error) {
+	out := make(map[string]int, len(receiptIDs))
+	for _, id := range receiptIDs {
+		out[id] = 0
+	}
+	if len(receiptIDs) == 0 {
+		return out, nil
+	}
+	rows, err := s.db.Query(`
+		SELECT receipt_id, COUNT(DISTINCT attester) FROM trust_entries
+		WHERE receipt_id = ANY($1) AND signature <> '' AND status = 'ok' AND attester <> origin
+		GROUP BY receipt_id`, pq.Array(receiptIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		out[id] = n
+	}
+	return out, rows.Err()
+}
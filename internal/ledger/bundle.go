@@ -0,0 +1,461 @@
+package ledger
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"dis-core/internal/bridge"
+	"dis-core/internal/util/crypto"
+)
+
+// BundleFormat identifies the ledger bundle layout: a gzip stream of JSON
+// lines, the first holding the signed BundleManifest and every following
+// line one chain entry in ledger.jsonl form.
+const BundleFormat = "dis.bundle.v1"
+
+var (
+	// ErrBundleInvalid is returned when a bundle fails verification.
+	ErrBundleInvalid = errors.New("invalid ledger bundle")
+	// ErrChainConflict is returned when a bundle's chain diverges from the
+	// local ledger's history.
+	ErrChainConflict = errors.New("bundle chain conflicts with local ledger")
+	// ErrSchemaDrift is returned when a bundle was exported against a
+	// different schema registry.
+	ErrSchemaDrift = errors.New("bundle schema registry differs from local registry")
+)
+
+// BundleManifest describes an exported ledger. It is signed by the
+// exporting node with its NodeDomain key; TreeHead is a checkpoint sealed at
+// export time over the whole chain, and Keys carries the key history of
+// every domain that signed anything in the bundle so it can be verified
+// without access to the source node's key store.
+type BundleManifest struct {
+	Format             string                        `json:"format"`
+	CreatedAt          string                        `json:"created_at"`
+	Source             string                        `json:"source"`
+	Head               ChainHead                     `json:"head"`
+	MerkleRoot         string                        `json:"merkle_root"`
+	TreeHead           *Checkpoint                   `json:"tree_head"`
+	Receipts           int                           `json:"receipts"`
+	Entries            int                           `json:"entries"`
+	Keys               map[string][]crypto.KeyRecord `json:"keys"`
+	SchemaRegistryHash string                        `json:"schema_registry_hash"`
+	KeyID              string                        `json:"key_id"`
+	Signature          string                        `json:"signature"`
+}
+
+// digest is the canonical JSON the manifest signature covers.
+func (m *BundleManifest) digest() ([]byte, error) {
+	unsigned := *m
+	unsigned.Signature = ""
+	return bridge.CanonicalJSON(unsigned)
+}
+
+// ExportOptions tunes ExportBundle.
+type ExportOptions struct {
+	// SchemaRegistryHash is the schema.Registry.HashAll of the exporting node.
+	SchemaRegistryHash string
+}
+
+// ExportBundle writes the chained receipts and checkpoints of store to w as
+// a signed, compressed bundle. Legacy receipts that predate the chain are
+// not exported.
+func ExportBundle(w io.Writer, store ReceiptStore, opts ExportOptions) (*BundleManifest, error) {
+	all, err := store.Chain()
+	if err != nil {
+		return nil, err
+	}
+	var entries []ChainEntry
+	v := NewChainVerifier()
+	for _, e := range all {
+		if e.Receipt != nil && e.Receipt.Seq == 0 {
+			continue
+		}
+		v.Add(e)
+		entries = append(entries, e)
+	}
+	rep := v.Report()
+	if !rep.OK() {
+		return nil, fmt.Errorf("local chain does not verify: %v", rep.Errors)
+	}
+	if rep.Head.Seq == 0 {
+		return nil, errors.New("ledger has no chained receipts to export")
+	}
+
+	m := &BundleManifest{
+		Format:             BundleFormat,
+		CreatedAt:          NowRFC3339Nano(),
+		Source:             NodeDomain,
+		Head:               rep.Head,
+		MerkleRoot:         v.MerkleRoot(),
+		Receipts:           rep.Receipts,
+		Entries:            len(entries),
+		Keys:               map[string][]crypto.KeyRecord{},
+		SchemaRegistryHash: opts.SchemaRegistryHash,
+	}
+	if m.TreeHead, err = signTreeHead(m.Head, m.MerkleRoot); err != nil {
+		return nil, err
+	}
+	domains := map[string]bool{NodeDomain: true}
+	for _, e := range entries {
+		for _, d := range e.signers() {
+			domains[d] = true
+		}
+	}
+	for d := range domains {
+		history, err := crypto.DefaultKeyStore().History(d)
+		if err != nil || len(history) == 0 {
+			return nil, fmt.Errorf("no key history for signer %s: %v", d, err)
+		}
+		m.Keys[d] = history
+	}
+
+	key, err := crypto.DefaultKeyStore().Active(NodeDomain)
+	if err != nil {
+		return nil, fmt.Errorf("bundle signer: %w", err)
+	}
+	m.KeyID = key.KeyID
+	digest, err := m.digest()
+	if err != nil {
+		return nil, err
+	}
+	if m.Signature, err = crypto.DefaultKeyStore().Sign(key.KeyID, digest); err != nil {
+		return nil, fmt.Errorf("bundle signer: %w", err)
+	}
+
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	if err := enc.Encode(m); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// signers lists the domains whose keys signed something in the entry.
+func (e ChainEntry) signers() []string {
+	if cp := e.Checkpoint; cp != nil {
+		return []string{cp.By}
+	}
+	r := e.Receipt
+	var out []string
+	if r.Signature != "" {
+		out = append(out, r.By)
+	}
+	for _, s := range r.Signatures {
+		out = append(out, s.Seat)
+	}
+	if r.Timestamp != nil {
+		out = append(out, r.Timestamp.TSA)
+	}
+	return out
+}
+
+// Bundle is a read and fully verified ledger bundle.
+type Bundle struct {
+	Manifest *BundleManifest
+	Entries  []ChainEntry
+}
+
+// ReadBundle decompresses and verifies a bundle: the manifest signature,
+// the hash chain and every signature in it, the key each signature was made
+// with against the manifest's key set, and the head, Merkle root and tree
+// head against the manifest. Any failure wraps ErrBundleInvalid.
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+	}
+	defer zr.Close()
+	dec := json.NewDecoder(bufio.NewReader(zr))
+
+	var m BundleManifest
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("%w: manifest: %v", ErrBundleInvalid, err)
+	}
+	if m.Format != BundleFormat {
+		return nil, fmt.Errorf("%w: unknown format %q", ErrBundleInvalid, m.Format)
+	}
+	if err := m.verify(); err != nil {
+		return nil, fmt.Errorf("%w: manifest: %v", ErrBundleInvalid, err)
+	}
+
+	b := &Bundle{Manifest: &m}
+	v := NewChainVerifier().WithKeys(HistoryKeys(m.Keys), m.Source)
+	for {
+		var e ChainEntry
+		if err := dec.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: entry %d: %v", ErrBundleInvalid, len(b.Entries)+1, err)
+		}
+		if e.Receipt != nil && e.Receipt.Seq == 0 {
+			return nil, fmt.Errorf("%w: unchained receipt %s", ErrBundleInvalid, e.Receipt.ReceiptID)
+		}
+		v.Add(e)
+		if err := m.checkKeys(e); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBundleInvalid, err)
+		}
+		b.Entries = append(b.Entries, e)
+	}
+
+	rep := v.Report()
+	switch {
+	case !rep.OK():
+		return nil, fmt.Errorf("%w: %v", ErrBundleInvalid, rep.Errors)
+	case len(b.Entries) != m.Entries || rep.Receipts != m.Receipts:
+		return nil, fmt.Errorf("%w: manifest lists %d entries, bundle holds %d", ErrBundleInvalid, m.Entries, len(b.Entries))
+	case rep.Head != m.Head:
+		return nil, fmt.Errorf("%w: chain ends at seq %d, manifest head is %d", ErrBundleInvalid, rep.Head.Seq, m.Head.Seq)
+	case v.MerkleRoot() != m.MerkleRoot:
+		return nil, fmt.Errorf("%w: merkle root mismatch", ErrBundleInvalid)
+	}
+	return b, nil
+}
+
+// verify checks the manifest signature and tree head against the key set.
+func (m *BundleManifest) verify() error {
+	at, err := time.Parse(time.RFC3339Nano, m.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("created_at: %w", err)
+	}
+	key, err := crypto.KeyInHistory(m.Keys[m.Source], m.Source, m.KeyID, at)
+	if err != nil {
+		return err
+	}
+	digest, err := m.digest()
+	if err != nil {
+		return err
+	}
+	if !key.Verify(digest, m.Signature) {
+		return errors.New("signature does not verify")
+	}
+
+	th := m.TreeHead
+	if th == nil {
+		return errors.New("missing tree head")
+	}
+	if th.Seq != m.Head.Seq || th.ChainHash != m.Head.ChainHash || th.MerkleRoot != m.MerkleRoot {
+		return errors.New("tree head does not match manifest head")
+	}
+	return m.checkCheckpoint(th)
+}
+
+// checkKeys checks that every signature in e was made with a key the
+// manifest's key set held for the signer at the time, and that the keys
+// embedded beside the signatures are those keys.
+func (m *BundleManifest) checkKeys(e ChainEntry) error {
+	if e.Checkpoint != nil {
+		return m.checkCheckpoint(e.Checkpoint)
+	}
+	r := e.Receipt
+	signedAt, err := time.Parse(time.RFC3339Nano, r.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("receipt %s: created_at: %w", r.ReceiptID, err)
+	}
+	if r.Timestamp != nil {
+		ts := r.Timestamp
+		at, _ := ts.Time()
+		key, err := crypto.KeyInHistory(m.Keys[ts.TSA], ts.TSA, ts.KeyID, at)
+		if err != nil {
+			return fmt.Errorf("receipt %s: timestamp: %w", r.ReceiptID, err)
+		}
+		if key.PublicKeyB64 != ts.PublicKeyB64 {
+			return fmt.Errorf("receipt %s: timestamp key is not in the key set", r.ReceiptID)
+		}
+		signedAt = at
+	}
+	if r.Signature != "" {
+		key, err := crypto.KeyInHistory(m.Keys[r.By], r.By, r.KeyID, signedAt)
+		if err != nil {
+			return fmt.Errorf("receipt %s: %w", r.ReceiptID, err)
+		}
+		if !key.Verify([]byte(r.Hash), r.Signature) {
+			return fmt.Errorf("receipt %s: signature does not verify against the key set", r.ReceiptID)
+		}
+	}
+	err = r.verifyThreshold(func(s SeatSignature) (crypto.KeyRecord, error) {
+		at, err := time.Parse(time.RFC3339Nano, s.SignedAt)
+		if err != nil {
+			return crypto.KeyRecord{}, err
+		}
+		return crypto.KeyInHistory(m.Keys[s.Seat], s.Seat, s.KeyID, at)
+	})
+	if err != nil {
+		return fmt.Errorf("receipt %s: %w", r.ReceiptID, err)
+	}
+	return nil
+}
+
+func (m *BundleManifest) checkCheckpoint(cp *Checkpoint) error {
+	if err := cp.VerifyWith(HistoryKeys(m.Keys), m.Source); err != nil {
+		return fmt.Errorf("checkpoint %d: %w", cp.Seq, err)
+	}
+	return nil
+}
+
+// ImportOptions tunes ImportBundle.
+type ImportOptions struct {
+	// SchemaRegistryHash is the local schema.Registry.HashAll. When set, a
+	// bundle exported against another registry is refused unless
+	// AllowSchemaDrift is also set.
+	SchemaRegistryHash string
+	AllowSchemaDrift   bool
+	// DryRun verifies the bundle and checks for conflicts without writing.
+	DryRun bool
+	// PinnedKeys holds key histories for peer domains, obtained out of
+	// band, that the bundle may be signed with. A domain's pinned history is
+	// used only when the local key store holds none for it.
+	PinnedKeys map[string][]crypto.KeyRecord
+}
+
+// ImportReport summarizes an import.
+type ImportReport struct {
+	Manifest *BundleManifest `json:"manifest"`
+	LocalSeq uint64          `json:"local_seq"`
+	Present  int             `json:"present"`
+	Imported int             `json:"imported"`
+	DryRun   bool            `json:"dry_run,omitempty"`
+}
+
+// ImportBundle verifies the bundle read from r and merges it into store.
+// The bundle must extend, or be a prefix of, the local chain: the chain
+// hash at the shorter of the two heads must agree, otherwise nothing is
+// written and ErrChainConflict is returned. Receipts past the local head
+// are appended in order and must land on the same chain hash they had at
+// the source. The key set a bundle carries is not trusted on its own: the
+// manifest and every entry must also verify against the local key store,
+// or opts.PinnedKeys for domains it does not know.
+func ImportBundle(r io.Reader, store ReceiptStore, opts ImportOptions) (*ImportReport, error) {
+	b, err := ReadBundle(r)
+	if err != nil {
+		return nil, err
+	}
+	m := b.Manifest
+	if opts.SchemaRegistryHash != "" && m.SchemaRegistryHash != opts.SchemaRegistryHash && !opts.AllowSchemaDrift {
+		return nil, fmt.Errorf("%w: bundle %s, local %s", ErrSchemaDrift, m.SchemaRegistryHash, opts.SchemaRegistryHash)
+	}
+	if err := b.anchor(localOrPinned(opts.PinnedKeys)); err != nil {
+		return nil, err
+	}
+
+	local, err := store.Chain()
+	if err != nil {
+		return nil, err
+	}
+	head := genesisHead()
+	localAt := map[uint64]string{}
+	for _, e := range local {
+		if e.Receipt != nil && e.Receipt.Seq > 0 {
+			head = ChainHead{Seq: e.Receipt.Seq, ChainHash: e.Receipt.ChainHash}
+			localAt[head.Seq] = head.ChainHash
+		}
+	}
+	rep := &ImportReport{Manifest: m, LocalSeq: head.Seq, DryRun: opts.DryRun}
+
+	// A chain hash commits to every receipt before it, so comparing the
+	// two chains at the shorter head compares their whole common history.
+	if m.Head.Seq <= head.Seq {
+		if localAt[m.Head.Seq] != m.Head.ChainHash {
+			return nil, fmt.Errorf("%w: chain hash differs at seq %d", ErrChainConflict, m.Head.Seq)
+		}
+	} else if head.Seq > 0 {
+		var theirs string
+		for _, e := range b.Entries {
+			if e.Receipt != nil && e.Receipt.Seq == head.Seq {
+				theirs = e.Receipt.ChainHash
+				break
+			}
+		}
+		if theirs != head.ChainHash {
+			return nil, fmt.Errorf("%w: chain hash differs at seq %d", ErrChainConflict, head.Seq)
+		}
+	}
+
+	var pending []*Receipt
+	for _, e := range b.Entries {
+		if e.Receipt == nil {
+			continue
+		}
+		if e.Receipt.Seq <= head.Seq {
+			rep.Present++
+			continue
+		}
+		pending = append(pending, e.Receipt)
+	}
+	if opts.DryRun {
+		rep.Imported = len(pending)
+		return rep, nil
+	}
+	for _, r := range pending {
+		want := r.ChainHash
+		if err := store.Append(r); err != nil {
+			return rep, fmt.Errorf("import %s: %w", r.ReceiptID, err)
+		}
+		if r.ChainHash != want {
+			return rep, fmt.Errorf("%w: receipt %s linked at seq %d with a different chain hash", ErrChainConflict, r.ReceiptID, r.Seq)
+		}
+		rep.Imported++
+	}
+	return rep, nil
+}
+
+// localOrPinned resolves keys from the default KeyStore, falling back to
+// pinned for domains it holds no keys for.
+func localOrPinned(pinned map[string][]crypto.KeyRecord) KeyResolver {
+	return func(domain, keyID string, t time.Time) (crypto.KeyRecord, error) {
+		history, err := crypto.DefaultKeyStore().History(domain)
+		if err != nil {
+			return crypto.KeyRecord{}, err
+		}
+		if len(history) == 0 {
+			history = pinned[domain]
+		}
+		return crypto.KeyInHistory(history, domain, keyID, t)
+	}
+}
+
+// anchor verifies the manifest signature, tree head and every entry again
+// with keys instead of the bundle's own key set.
+func (b *Bundle) anchor(keys KeyResolver) error {
+	m := b.Manifest
+	at, err := time.Parse(time.RFC3339Nano, m.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("%w: created_at: %v", ErrBundleInvalid, err)
+	}
+	key, err := keys(m.Source, m.KeyID, at)
+	if err != nil {
+		return fmt.Errorf("%w: manifest signer %s is not anchored: %v", ErrBundleInvalid, m.Source, err)
+	}
+	digest, err := m.digest()
+	if err != nil {
+		return err
+	}
+	if !key.Verify(digest, m.Signature) {
+		return fmt.Errorf("%w: manifest signature does not verify against anchored keys", ErrBundleInvalid)
+	}
+	if err := m.TreeHead.VerifyWith(keys, m.Source); err != nil {
+		return fmt.Errorf("%w: tree head is not anchored: %v", ErrBundleInvalid, err)
+	}
+
+	v := NewChainVerifier().WithKeys(keys, m.Source)
+	for _, e := range b.Entries {
+		v.Add(e)
+	}
+	if rep := v.Report(); !rep.OK() {
+		return fmt.Errorf("%w: not anchored in local or pinned keys: %v", ErrBundleInvalid, rep.Errors)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package ledger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// GenesisHash is the PrevHash of the first receipt in a chain.
+var GenesisHash = strings.Repeat("0", 64)
+
+// CheckpointEvery controls how often (in receipts) a signed Merkle checkpoint
+// is sealed into the ledger. Zero disables checkpointing.
+var CheckpointEvery uint64 = 16
+
+// ChainHead is the tip of a receipt hash chain.
+type ChainHead struct {
+	Seq       uint64 `json:"seq"`
+	ChainHash string `json:"chain_hash"`
+}
+
+// genesisHead returns the head of an empty chain.
+func genesisHead() ChainHead {
+	return ChainHead{Seq: 0, ChainHash: GenesisHash}
+}
+
+// ComputeChainHash returns the link hash that commits a receipt to its predecessor.
+func ComputeChainHash(seq uint64, prevHash, receiptHash string) string {
+	return HashString(fmt.Sprintf("%d|%s|%s", seq, prevHash, receiptHash))
+}
+
+// link stamps r with the next sequence number and chain hash after h and
+// returns the new head.
+func (h ChainHead) link(r *Receipt) ChainHead {
+	r.Seq = h.Seq + 1
+	r.PrevHash = h.ChainHash
+	r.ChainHash = ComputeChainHash(r.Seq, r.PrevHash, r.Hash)
+	return ChainHead{Seq: r.Seq, ChainHash: r.ChainHash}
+}
+
+// checkpointDue reports whether a checkpoint should be sealed at seq.
+func checkpointDue(seq uint64) bool {
+	return CheckpointEvery > 0 && seq > 0 && seq%CheckpointEvery == 0
+}
+
+// ChainEntry is one record of a hash-chained ledger: a receipt or a checkpoint.
+type ChainEntry struct {
+	Receipt    *Receipt
+	Checkpoint *Checkpoint
+}
+
+// MarshalJSON encodes the entry as the bare receipt or checkpoint.
+func (e ChainEntry) MarshalJSON() ([]byte, error) {
+	if e.Checkpoint != nil {
+		return json.Marshal(e.Checkpoint)
+	}
+	return json.Marshal(e.Receipt)
+}
+
+// UnmarshalJSON decodes a ledger line, using the "kind" field to tell
+// checkpoints apart from receipts.
+func (e *ChainEntry) UnmarshalJSON(data []byte) error {
+	var peek struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(data, &peek); err != nil {
+		return err
+	}
+	if peek.Kind == CheckpointKind {
+		var cp Checkpoint
+		if err := json.Unmarshal(data, &cp); err != nil {
+			return err
+		}
+		e.Checkpoint, e.Receipt = &cp, nil
+		return nil
+	}
+	var r Receipt
+	if err := json.Unmarshal(data, &r); err != nil {
+		return err
+	}
+	e.Receipt, e.Checkpoint = &r, nil
+	return nil
+}
+
+// ReadLedgerFile parses a ledger.jsonl file into chain entries in file order.
+// A missing file is treated as an empty ledger.
+func ReadLedgerFile(path string) ([]ChainEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []ChainEntry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		var e ChainEntry
+		if err := json.Unmarshal([]byte(text), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, sc.Err()
+}
+
+// chainState is the in-memory view of a chain needed to append to it.
+type chainState struct {
+	head   ChainHead
+	leaves []string
+	sealer string // domain checkpoints are sealed by; NodeDomain when empty
+}
+
+// chainStateFrom rebuilds the append state from existing entries.
+// Legacy receipts without a sequence number are not part of the chain.
+func chainStateFrom(entries []ChainEntry) *chainState {
+	st := &chainState{head: genesisHead()}
+	for _, e := range entries {
+		if e.Receipt == nil || e.Receipt.Seq == 0 {
+			continue
+		}
+		st.head = ChainHead{Seq: e.Receipt.Seq, ChainHash: e.Receipt.ChainHash}
+		st.leaves = append(st.leaves, e.Receipt.Hash)
+	}
+	return st
+}
+
+// append links r onto the chain and, when one is due, returns the
+// checkpoint that seals the new head. The state only advances once the
+// checkpoint is signed, so a failed append leaves it as it was.
+func (st *chainState) append(r *Receipt) (*Checkpoint, error) {
+	head := st.head.link(r)
+	leaves := append(st.leaves, r.Hash)
+	var cp *Checkpoint
+	if checkpointDue(head.Seq) {
+		var err error
+		if st.sealer != "" {
+			cp, err = signTreeHeadBy(st.sealer, head, MerkleRoot(leaves))
+		} else {
+			cp, err = NewCheckpoint(head, leaves)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	st.head, st.leaves = head, leaves
+	return cp, nil
+}
@@ -0,0 +1,166 @@
+package ledger
+
+import (
+	"fmt"
+)
+
+// ChainReport summarizes a full-history verification of a receipt ledger.
+type ChainReport struct {
+	Receipts    int       `json:"receipts"`
+	Legacy      int       `json:"legacy"`
+	Unsigned    int       `json:"unsigned"`
+	Checkpoints int       `json:"checkpoints"`
+	Timestamped int       `json:"timestamped"`
+	Head        ChainHead `json:"head"`
+	SealedSeq   uint64    `json:"sealed_seq"`
+	Errors      []string  `json:"errors,omitempty"`
+}
+
+// OK reports whether the chain verified without errors.
+func (r *ChainReport) OK() bool { return len(r.Errors) == 0 }
+
+// Unsealed is the number of receipts appended after the last valid checkpoint.
+// Truncation of these receipts cannot be detected until the next checkpoint.
+func (r *ChainReport) Unsealed() uint64 { return r.Head.Seq - r.SealedSeq }
+
+func (r *ChainReport) fail(format string, args ...any) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+// VerifyChain walks ledger entries in order and checks that the receipts
+// form an unbroken hash chain, that every signature present verifies
+// against the key its signer held at the time in the default KeyStore, and
+// that every checkpoint was sealed by NodeDomain and matches the chain head
+// and Merkle root at its position. Timestamp tokens must verify and never
+// go backwards along the chain. Legacy receipts are tolerated only before
+// the chain begins.
+func VerifyChain(entries []ChainEntry) *ChainReport {
+	v := NewChainVerifier()
+	for _, e := range entries {
+		v.Add(e)
+	}
+	return v.Report()
+}
+
+// ChainVerifier applies the VerifyChain checks one entry at a time, so a
+// ledger can be verified as it is streamed in.
+type ChainVerifier struct {
+	rep       *ChainReport
+	leaves    []string
+	lastStamp *TimestampToken
+	keys      KeyResolver
+	sealer    string // domain every checkpoint must be sealed by
+}
+
+// NewChainVerifier starts verifying a chain from genesis, resolving keys
+// from the default KeyStore and expecting checkpoints sealed by NodeDomain.
+func NewChainVerifier() *ChainVerifier {
+	return &ChainVerifier{rep: &ChainReport{Head: genesisHead()}, keys: StoreKeys(nil), sealer: NodeDomain}
+}
+
+// WithKeys makes the verifier resolve signer keys with keys and expect
+// checkpoints sealed by sealer, for chains kept by another node.
+func (v *ChainVerifier) WithKeys(keys KeyResolver, sealer string) *ChainVerifier {
+	v.keys, v.sealer = keys, sealer
+	return v
+}
+
+// Clone returns an independent copy of the verifier, so entries can be
+// checked tentatively and the copy kept only if they verify.
+func (v *ChainVerifier) Clone() *ChainVerifier {
+	rep := *v.rep
+	rep.Errors = append([]string(nil), v.rep.Errors...)
+	return &ChainVerifier{
+		rep:       &rep,
+		leaves:    append([]string(nil), v.leaves...),
+		lastStamp: v.lastStamp,
+		keys:      v.keys,
+		sealer:    v.sealer,
+	}
+}
+
+// Report returns the findings so far.
+func (v *ChainVerifier) Report() *ChainReport { return v.rep }
+
+// MerkleRoot returns the Merkle root over the receipts verified so far.
+func (v *ChainVerifier) MerkleRoot() string { return MerkleRoot(v.leaves) }
+
+// Add verifies the next entry of the chain.
+func (v *ChainVerifier) Add(e ChainEntry) {
+	rep := v.rep
+	switch {
+	case e.Checkpoint != nil:
+		cp := e.Checkpoint
+		rep.Checkpoints++
+		if cp.Seq != rep.Head.Seq {
+			rep.fail("checkpoint seq %d found at chain position %d", cp.Seq, rep.Head.Seq)
+			return
+		}
+		if cp.ChainHash != rep.Head.ChainHash {
+			rep.fail("checkpoint %d: chain hash mismatch", cp.Seq)
+			return
+		}
+		if root := MerkleRoot(v.leaves); cp.MerkleRoot != root {
+			rep.fail("checkpoint %d: merkle root mismatch (have %s, computed %s)", cp.Seq, cp.MerkleRoot, root)
+			return
+		}
+		if err := cp.VerifyWith(v.keys, v.sealer); err != nil {
+			rep.fail("checkpoint %d: invalid signature (%v)", cp.Seq, err)
+			return
+		}
+		rep.SealedSeq = cp.Seq
+
+	case e.Receipt != nil:
+		r := e.Receipt
+		if r.Seq == 0 {
+			if rep.Head.Seq > 0 {
+				rep.fail("unchained receipt %s after chain start", r.ReceiptID)
+			} else {
+				rep.Legacy++
+			}
+			return
+		}
+
+		rep.Receipts++
+		if r.Seq != rep.Head.Seq+1 {
+			rep.fail("receipt %s: seq %d follows %d", r.ReceiptID, r.Seq, rep.Head.Seq)
+		}
+		if r.PrevHash != rep.Head.ChainHash {
+			rep.fail("receipt %s: prev_hash does not match seq %d", r.ReceiptID, rep.Head.Seq)
+		}
+		if want := ComputeChainHash(r.Seq, r.PrevHash, r.Hash); r.ChainHash != want {
+			rep.fail("receipt %s: chain hash mismatch", r.ReceiptID)
+		}
+		if r.Signature == "" {
+			rep.Unsigned++
+			if r.Hash == "" && r.CanonVersion == "" {
+				// Unsigned, unhashed receipts predate content hashing.
+			} else if err := r.checkHash(); err != nil {
+				rep.fail("receipt %s: %v", r.ReceiptID, err)
+			}
+		} else if err := r.verifyKeys(v.keys); err != nil {
+			rep.fail("receipt %s: invalid signature (%v)", r.ReceiptID, err)
+		}
+		if r.Timestamp != nil {
+			rep.Timestamped++
+			if err := r.checkTimestamp(v.lastStamp, v.keys.timestamps()); err != nil {
+				rep.fail("receipt %s: %v", r.ReceiptID, err)
+			}
+			v.lastStamp = r.Timestamp
+		}
+
+		// Continue from the receipt as recorded so one bad link is
+		// reported once rather than cascading through the rest.
+		rep.Head = ChainHead{Seq: r.Seq, ChainHash: r.ChainHash}
+		v.leaves = append(v.leaves, r.Hash)
+	}
+}
+
+// VerifyLedgerFile reads and verifies a ledger.jsonl file.
+func VerifyLedgerFile(path string) (*ChainReport, error) {
+	entries, err := ReadLedgerFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return VerifyChain(entries), nil
+}
@@ -0,0 +1,130 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"dis-core/internal/bridge"
+	"dis-core/internal/util/crypto"
+)
+
+// CheckpointKind marks a ledger line or row as a checkpoint rather than a receipt.
+const CheckpointKind = "checkpoint"
+
+// CheckpointSchemaRef is the schema_ref under which checkpoints are stored in Postgres.
+const CheckpointSchemaRef = "ledger.checkpoint.v1"
+
+// Checkpoint is a signed statement that the first Seq receipts of a ledger
+// hash to MerkleRoot and end at ChainHash. A verifier holding a checkpoint
+// can detect any receipt removed, reordered or altered before it.
+type Checkpoint struct {
+	Kind               string `json:"kind"`
+	Seq                uint64 `json:"seq"`
+	ChainHash          string `json:"chain_hash"`
+	MerkleRoot         string `json:"merkle_root"`
+	CreatedAt          string `json:"created_at"`
+	By                 string `json:"by"`
+	KeyID              string `json:"key_id,omitempty"`
+	Signature          string `json:"signature"`
+	SignerPublicKeyB64 string `json:"signer_public_key_b64"`
+}
+
+// NewCheckpoint seals head with the Merkle root over leaves and signs it
+// with the NodeDomain key. leaves must hold exactly head.Seq hashes.
+func NewCheckpoint(head ChainHead, leaves []string) (*Checkpoint, error) {
+	if uint64(len(leaves)) != head.Seq {
+		return nil, fmt.Errorf("checkpoint at seq %d given %d leaves", head.Seq, len(leaves))
+	}
+	return signTreeHead(head, MerkleRoot(leaves))
+}
+
+// signTreeHead builds and signs a checkpoint for head with a precomputed root.
+func signTreeHead(head ChainHead, root string) (*Checkpoint, error) {
+	return signTreeHeadBy(NodeDomain, head, root)
+}
+
+// signTreeHeadBy is signTreeHead sealing as domain rather than NodeDomain.
+func signTreeHeadBy(domain string, head ChainHead, root string) (*Checkpoint, error) {
+	cp := &Checkpoint{
+		Kind:       CheckpointKind,
+		Seq:        head.Seq,
+		ChainHash:  head.ChainHash,
+		MerkleRoot: root,
+		CreatedAt:  NowRFC3339Nano(),
+		By:         domain,
+	}
+
+	key, err := crypto.DefaultKeyStore().Active(cp.By)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint signer: %w", err)
+	}
+	cp.KeyID = key.KeyID
+	digest, err := cp.Digest()
+	if err != nil {
+		return nil, err
+	}
+	if cp.Signature, err = crypto.DefaultKeyStore().Sign(key.KeyID, []byte(digest)); err != nil {
+		return nil, fmt.Errorf("checkpoint signer: %w", err)
+	}
+	cp.SignerPublicKeyB64 = key.PublicKeyB64
+	return cp, nil
+}
+
+// Digest returns the canonical hash of the signed checkpoint fields.
+// key_id is covered only when set, so checkpoints sealed before key IDs
+// existed keep their digest.
+func (c *Checkpoint) Digest() (string, error) {
+	fields := map[string]interface{}{
+		"kind":        c.Kind,
+		"seq":         c.Seq,
+		"chain_hash":  c.ChainHash,
+		"merkle_root": c.MerkleRoot,
+		"created_at":  c.CreatedAt,
+		"by":          c.By,
+	}
+	if c.KeyID != "" {
+		fields["key_id"] = c.KeyID
+	}
+	return bridge.CanonicalHashJSON(fields)
+}
+
+// Verify checks that the checkpoint was sealed by NodeDomain with the key
+// the default KeyStore holds for it at created_at.
+func (c *Checkpoint) Verify() (bool, error) {
+	if err := c.VerifyWith(StoreKeys(nil), NodeDomain); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// VerifyWith checks that the checkpoint was sealed by sealer with the key
+// keys resolves for it at created_at. The public key carried in the
+// checkpoint is informational and must match that key.
+func (c *Checkpoint) VerifyWith(keys KeyResolver, sealer string) error {
+	if c.Signature == "" {
+		return errors.New("checkpoint is unsigned")
+	}
+	if c.By != sealer {
+		return fmt.Errorf("sealed by %s, expected %s", c.By, sealer)
+	}
+	at, err := time.Parse(time.RFC3339Nano, c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("created_at: %w", err)
+	}
+	key, err := keys(c.By, c.KeyID, at)
+	if err != nil {
+		return err
+	}
+	if c.SignerPublicKeyB64 != "" && c.SignerPublicKeyB64 != key.PublicKeyB64 {
+		return fmt.Errorf("signer key is not %s's key", c.By)
+	}
+	digest, err := c.Digest()
+	if err != nil {
+		return err
+	}
+	if !key.Verify([]byte(digest), c.Signature) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
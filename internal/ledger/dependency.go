@@ -0,0 +1,100 @@
+package ledger
+
+import "fmt"
+
+// DefaultDependencyDepth bounds how far DependencyClosure follows
+// provenance links.
+const DefaultDependencyDepth = 16
+
+// DependencyEdge records that receipt To cites Ref in its provenance.
+type DependencyEdge struct {
+	Ref  string `json:"ref"`
+	Type string `json:"type"` // provenance type of the citation
+	To   string `json:"to"`   // receipt_id of the citing receipt
+}
+
+// Dependent is a receipt reached from the root of a DependencyGraph.
+type Dependent struct {
+	Receipt Receipt `json:"receipt"`
+	Depth   int     `json:"depth"` // 1 for receipts citing the root directly
+	Via     string  `json:"via"`   // the ref it cites on the way from the root
+}
+
+// DependencyGraph is everything downstream of Root: the receipts whose
+// provenance cites Root, the receipts citing those, and so on. Receipts
+// are nodes by receipt_id; any other ref (a consent request ID, a
+// mandate ID) is a node only as a root or a citation.
+type DependencyGraph struct {
+	Root       string           `json:"root"`
+	Dependents []Dependent      `json:"dependents"`
+	Edges      []DependencyEdge `json:"edges"`
+	Truncated  bool             `json:"truncated,omitempty"` // depth limit reached
+}
+
+// Dependents returns the receipts whose provenance cites ref, with the
+// citing provenance types.
+func Dependents(store ReceiptStore, ref string) ([]Receipt, []DependencyEdge, error) {
+	if ref == "" {
+		return nil, nil, fmt.Errorf("%w: empty ref", ErrBadQuery)
+	}
+	var out []Receipt
+	var edges []DependencyEdge
+	cursor := ""
+	for {
+		page, err := store.Search(SearchOptions{Query: "provenance.ref = " + QuoteQueryValue(ref), Limit: 500, Cursor: cursor})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, r := range page.Items {
+			if r.ReceiptID == ref {
+				continue
+			}
+			out = append(out, r)
+			for _, p := range r.Provenance {
+				if p.Ref == ref {
+					edges = append(edges, DependencyEdge{Ref: ref, Type: p.Type, To: r.ReceiptID})
+				}
+			}
+		}
+		if page.NextCursor == "" {
+			return out, edges, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// DependencyClosure walks provenance links breadth-first from root, up to
+// maxDepth levels (DefaultDependencyDepth when <= 0). Each receipt appears
+// once, at the depth it is first reached.
+func DependencyClosure(store ReceiptStore, root string, maxDepth int) (*DependencyGraph, error) {
+	if maxDepth <= 0 {
+		maxDepth = DefaultDependencyDepth
+	}
+	g := &DependencyGraph{Root: root, Dependents: []Dependent{}, Edges: []DependencyEdge{}}
+	seen := map[string]bool{root: true}
+	frontier := []string{root}
+	for depth := 1; len(frontier) > 0; depth++ {
+		if depth > maxDepth {
+			g.Truncated = true
+			break
+		}
+		var next []string
+		for _, ref := range frontier {
+			rs, edges, err := Dependents(store, ref)
+			if err != nil {
+				return nil, err
+			}
+			g.Edges = append(g.Edges, edges...)
+			for _, r := range rs {
+				if seen[r.ReceiptID] {
+					continue
+				}
+				seen[r.ReceiptID] = true
+				g.Dependents = append(g.Dependents, Dependent{Receipt: r, Depth: depth, Via: ref})
+				next = append(next, r.ReceiptID)
+			}
+		}
+		frontier = next
+	}
+	return g, nil
+}
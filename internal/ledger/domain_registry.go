@@ -90,6 +90,7 @@ func (l *Ledger) LoadDomainsFromFS(path string, reg *schema.Registry) ([]*Domain
 				SchemaVersion string `yaml:"schema_version"`
 				DomainID      string `yaml:"domain_id"`
 				Description   string `yaml:"description"`
+				Authority     string `yaml:"authority"`
 			} `yaml:"meta"`
 		}
 		if err := yaml.Unmarshal(data, &meta); err != nil {
@@ -109,6 +110,7 @@ func (l *Ledger) LoadDomainsFromFS(path string, reg *schema.Registry) ([]*Domain
 			ID:         domainID,
 			SchemaRef:  schemaID,
 			Version:    schemaVer,
+			Authority:  strings.TrimSpace(meta.Meta.Authority),
 			SourcePath: p,
 		}
 
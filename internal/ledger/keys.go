@@ -0,0 +1,220 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"dis-core/internal/bridge"
+	"dis-core/internal/util/crypto"
+)
+
+// KeyResolver finds the key domain signed with at t: the key with keyID,
+// or with an empty keyID the first key valid at t.
+type KeyResolver func(domain, keyID string, t time.Time) (crypto.KeyRecord, error)
+
+// StoreKeys resolves keys from the key history in ks, or in the default
+// KeyStore at lookup time when ks is nil.
+func StoreKeys(ks crypto.KeyStore) KeyResolver {
+	return func(domain, keyID string, t time.Time) (crypto.KeyRecord, error) {
+		store := ks
+		if store == nil {
+			store = crypto.DefaultKeyStore()
+		}
+		return crypto.KeyAt(store, domain, keyID, t)
+	}
+}
+
+// HistoryKeys resolves keys from a key set held outside a KeyStore, such as
+// a bundle manifest's.
+func HistoryKeys(keys map[string][]crypto.KeyRecord) KeyResolver {
+	return func(domain, keyID string, t time.Time) (crypto.KeyRecord, error) {
+		return crypto.KeyInHistory(keys[domain], domain, keyID, t)
+	}
+}
+
+// timestamps verifies timestamp tokens with the TSA keys resolved by keys.
+func (keys KeyResolver) timestamps() func(*TimestampToken, string) error {
+	return func(t *TimestampToken, hash string) error {
+		return t.verify(hash, func(t *TimestampToken, at time.Time) (crypto.KeyRecord, error) {
+			return keys(t.TSA, t.KeyID, at)
+		})
+	}
+}
+
+// seats resolves threshold seat keys at the time each seat signed.
+func (keys KeyResolver) seats() func(SeatSignature) (crypto.KeyRecord, error) {
+	return func(s SeatSignature) (crypto.KeyRecord, error) {
+		at, err := time.Parse(time.RFC3339Nano, s.SignedAt)
+		if err != nil {
+			return crypto.KeyRecord{}, fmt.Errorf("seat %s signed_at: %w", s.Seat, err)
+		}
+		return keys(s.Seat, s.KeyID, at)
+	}
+}
+
+// Schema refs for key lifecycle receipts.
+const (
+	KeyGenesisSchemaRef    = "key.genesis.v1"
+	KeyTransitionSchemaRef = "key.transition.v1"
+	KeyRevocationSchemaRef = "key.revocation.v1"
+)
+
+// GenerateDomainKey creates the first key for domain and records a
+// key.genesis.v1 receipt signed by it.
+func GenerateDomainKey(domain string) (crypto.KeyRecord, *Receipt, error) {
+	key, err := crypto.DefaultKeyStore().Generate(domain)
+	if err != nil {
+		return crypto.KeyRecord{}, nil, err
+	}
+	r, err := keyReceipt(KeyGenesisSchemaRef, domain, "key.generate", map[string]any{
+		"key_id":     key.KeyID,
+		"public_key": key.PublicKeyB64,
+		"valid_from": bridge.CanonicalTime(key.ValidFrom),
+	})
+	return key, r, err
+}
+
+// RotateDomainKey retires the active key of domain, activates a new one and
+// records a key.transition.v1 receipt. The receipt is signed by the new key
+// and carries an endorsement of the new key by the old one, so a verifier
+// holding only the old key can follow the handover.
+func RotateDomainKey(domain string) (crypto.KeyRecord, *Receipt, error) {
+	ks := crypto.DefaultKeyStore()
+	old, next, err := ks.Rotate(domain)
+	if err != nil {
+		return crypto.KeyRecord{}, nil, err
+	}
+	statement, err := bridge.CanonicalJSON(keyEndorsement(domain, old, next))
+	if err != nil {
+		return next, nil, err
+	}
+	endorsement, err := ks.Sign(old.KeyID, statement)
+	if err != nil {
+		return next, nil, fmt.Errorf("endorse new key: %w", err)
+	}
+	r, err := keyReceipt(KeyTransitionSchemaRef, domain, "key.rotate", map[string]any{
+		"old_key_id":     old.KeyID,
+		"new_key_id":     next.KeyID,
+		"new_public_key": next.PublicKeyB64,
+		"effective_at":   bridge.CanonicalTime(next.ValidFrom),
+		"endorsement":    endorsement,
+	})
+	return next, r, err
+}
+
+// RevokeDomainKey revokes a retired key of domain from at, when it was
+// compromised (zero for now), and records a key.revocation.v1 receipt
+// signed by the domain's active key.
+func RevokeDomainKey(domain, keyID, reason string, at time.Time) (crypto.KeyRecord, *Receipt, error) {
+	key, err := crypto.DefaultKeyStore().Revoke(domain, keyID, reason, at)
+	if err != nil {
+		return crypto.KeyRecord{}, nil, err
+	}
+	r, err := keyReceipt(KeyRevocationSchemaRef, domain, "key.revoke", map[string]any{
+		"key_id":     key.KeyID,
+		"reason":     reason,
+		"revoked_at": bridge.CanonicalTime(*key.RevokedAt),
+	})
+	return key, r, err
+}
+
+// VerifyKeyTransition checks the old key's endorsement inside a
+// key.transition.v1 receipt against the domain's key history.
+func VerifyKeyTransition(r *Receipt) error {
+	if r.SchemaRef != KeyTransitionSchemaRef {
+		return fmt.Errorf("not a key transition receipt: %s", r.SchemaRef)
+	}
+	str := func(k string) string { s, _ := r.Payload[k].(string); return s }
+	history, err := crypto.DefaultKeyStore().History(r.By)
+	if err != nil {
+		return err
+	}
+	var old, next *crypto.KeyRecord
+	for i := range history {
+		switch history[i].KeyID {
+		case str("old_key_id"):
+			old = &history[i]
+		case str("new_key_id"):
+			next = &history[i]
+		}
+	}
+	if old == nil || next == nil {
+		return crypto.ErrKeyNotFound
+	}
+	if next.PublicKeyB64 != str("new_public_key") {
+		return errors.New("transition names a different public key")
+	}
+	statement, err := bridge.CanonicalJSON(keyEndorsement(r.By, *old, *next))
+	if err != nil {
+		return err
+	}
+	if !old.Verify(statement, str("endorsement")) {
+		return errors.New("old key did not endorse the new key")
+	}
+	return nil
+}
+
+// KeyHandover returns the key a key.transition.v1 receipt hands r.By over
+// to, once the outgoing key, resolved by keys, is found to have endorsed
+// it. Replicas use it to follow a peer's rotation.
+func KeyHandover(r *Receipt, keys KeyResolver) (crypto.KeyRecord, error) {
+	if r.SchemaRef != KeyTransitionSchemaRef {
+		return crypto.KeyRecord{}, fmt.Errorf("not a key transition receipt: %s", r.SchemaRef)
+	}
+	str := func(k string) string { s, _ := r.Payload[k].(string); return s }
+	effective, err := bridge.ParseCanonicalTime(str("effective_at"))
+	if err != nil {
+		return crypto.KeyRecord{}, fmt.Errorf("transition effective_at: %w", err)
+	}
+	old, err := keys(r.By, str("old_key_id"), effective)
+	if err != nil {
+		return crypto.KeyRecord{}, err
+	}
+	next := crypto.KeyRecord{KeyID: str("new_key_id"), Domain: r.By, PublicKeyB64: str("new_public_key"), ValidFrom: effective}
+	pub, err := next.PublicKey()
+	if err != nil {
+		return crypto.KeyRecord{}, err
+	}
+	if crypto.KeyID(pub) != next.KeyID {
+		return crypto.KeyRecord{}, errors.New("transition key id does not match its public key")
+	}
+	statement, err := bridge.CanonicalJSON(keyEndorsement(r.By, old, next))
+	if err != nil {
+		return crypto.KeyRecord{}, err
+	}
+	if !old.Verify(statement, str("endorsement")) {
+		return crypto.KeyRecord{}, errors.New("old key did not endorse the new key")
+	}
+	return next, nil
+}
+
+// keyEndorsement is the statement the outgoing key signs during rotation.
+func keyEndorsement(domain string, old, next crypto.KeyRecord) map[string]any {
+	return map[string]any{
+		"domain":         domain,
+		"old_key_id":     old.KeyID,
+		"new_key_id":     next.KeyID,
+		"new_public_key": next.PublicKeyB64,
+		"effective_at":   bridge.CanonicalTime(next.ValidFrom),
+	}
+}
+
+// keyReceipt seals and stores a key lifecycle receipt.
+func keyReceipt(schemaRef, domain, action string, payload map[string]any) (*Receipt, error) {
+	r := &Receipt{
+		ReceiptID: generateReceiptID(),
+		SchemaRef: schemaRef,
+		By:        domain,
+		Action:    action,
+		CreatedAt: NowRFC3339Nano(),
+		Payload:   payload,
+	}
+	if err := r.Seal(); err != nil {
+		return nil, err
+	}
+	if err := SaveReceipt(r); err != nil {
+		return r, err
+	}
+	return r, nil
+}
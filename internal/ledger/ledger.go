@@ -3,6 +3,7 @@ package ledger
 import (
 	"crypto/sha256"
 	"database/sql"
+	"dis-core/internal/db"
 	"dis-core/internal/schema"
 	"encoding/hex"
 	"encoding/json"
@@ -20,14 +21,14 @@ type Ledger struct {
 }
 
 // Open initializes the ledger. It can accept either an existing DB handle
-// or a DSN string. If db is nil, it opens a new connection using the DSN.
+// or a DSN string. If handle is nil, it opens a new connection using the DSN.
 // Optionally, a schema registry can be attached for validation and linkage.
-func Open(dsn string, db *sql.DB, reg *schema.Registry) (*Ledger, error) {
+func Open(dsn string, handle *sql.DB, reg *schema.Registry) (*Ledger, error) {
 	var conn *sql.DB
 	var err error
 
-	if db != nil {
-		conn = db
+	if handle != nil {
+		conn = handle
 	} else {
 		conn, err = sql.Open("postgres", dsn)
 		if err != nil {
@@ -36,16 +37,10 @@ func Open(dsn string, db *sql.DB, reg *schema.Registry) (*Ledger, error) {
 	}
 
 	// Ensure schema tables exist
+	if err := db.EnsureReceiptsSchema(conn); err != nil {
+		return nil, err
+	}
 	schemaStatements := []string{
-		`CREATE TABLE IF NOT EXISTS receipts (
-			id TEXT PRIMARY KEY DEFAULT gen_random_uuid()::text,
-			type TEXT NOT NULL,
-			actor TEXT,
-			target TEXT,
-			domain TEXT,
-			payload JSONB,
-			created_at TIMESTAMPTZ DEFAULT NOW()
-		);`,
 		`CREATE TABLE IF NOT EXISTS canon (
 			id TEXT PRIMARY KEY,
 			type TEXT,
@@ -82,15 +77,15 @@ func (l *Ledger) Close() error {
 	return l.DB.Close()
 }
 
-// Record inserts a generic event receipt into the ledger.
+// Record appends a generic event receipt to the ledger's receipt chain.
 func (l *Ledger) Record(eventType string, payload map[string]any) error {
-	j, _ := json.Marshal(payload)
-	_, err := l.DB.Exec(`
-		INSERT INTO receipts (type, payload)
-		VALUES ($1, $2);`, eventType, string(j))
+	r, err := NewEnvelope(eventType, NodeDomain, eventType, payload)
 	if err != nil {
 		return fmt.Errorf("record event: %w", err)
 	}
+	if err := NewStore(l.DB).Append(r); err != nil {
+		return fmt.Errorf("record event: %w", err)
+	}
 	return nil
 }
 
@@ -1,26 +1,73 @@
 package ledger_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"dis-core/internal/events"
 	"dis-core/internal/ledger"
+	"dis-core/internal/util/crypto"
 )
 
+// TestMain signs everything in this package with keys held on a mock
+// PKCS#11 token, so tests never touch the on-disk key directory.
+func TestMain(m *testing.M) {
+	token := crypto.NewMockPKCS11Token("1234")
+	if err := token.Login("1234"); err != nil {
+		panic(err)
+	}
+	ks := crypto.NewPKCS11KeyStore(token)
+	for _, d := range []string{"domain.test", ledger.NodeDomain} {
+		if _, err := ks.Generate(d); err != nil {
+			panic(err)
+		}
+	}
+	crypto.SetDefaultKeyStore(ks)
+	os.Exit(m.Run())
+}
+
+// newReceipt is ledger.NewReceipt, failing t if the receipt cannot be
+// sealed.
+func newReceipt(t *testing.T, by, action, frozenCoreHash, consoleID, issuerSeat string) *ledger.Receipt {
+	t.Helper()
+	r, err := ledger.NewReceipt(by, action, frozenCoreHash, consoleID, issuerSeat)
+	if err != nil {
+		t.Fatalf("NewReceipt: %v", err)
+	}
+	return r
+}
+
+// newEnvelope is ledger.NewEnvelope, failing t if the receipt cannot be
+// sealed.
+func newEnvelope(t *testing.T, schemaRef, by, action string, payload map[string]any) *ledger.Receipt {
+	t.Helper()
+	r, err := ledger.NewEnvelope(schemaRef, by, action, payload)
+	if err != nil {
+		t.Fatalf("NewEnvelope: %v", err)
+	}
+	return r
+}
+
 // TestNewReceipt_CreateSaveVerify exercises the basic lifecycle of a Receipt:
 // create → save to disk → read back → verify digital signature.
 func TestNewReceipt_CreateSaveVerify(t *testing.T) {
-	r := ledger.NewReceipt(
+	r, err := ledger.NewReceipt(
 		"domain.terra",
 		"unit.test",
 		"frozen-core-hash-xyz",
 		"console.demo",
 		"seat.demo",
 	)
-	if r == nil {
-		t.Fatalf("expected non-nil receipt")
+	if err != nil {
+		t.Fatalf("NewReceipt: %v", err)
 	}
 	if r.Hash == "" {
 		t.Errorf("expected hash, got empty")
@@ -62,11 +109,18 @@ func TestNewReceipt_CreateSaveVerify(t *testing.T) {
 
 	// Clean up the receipts dir
 	_ = os.RemoveAll("receipts")
+	// A domain with no key gets an error, not an unsigned receipt.
+	if r, err := ledger.NewReceipt("unkeyed.test", "unit.test", "", "console.demo", "seat.demo"); err == nil || r != nil {
+		t.Fatalf("NewReceipt without a key: %+v, %v", r, err)
+	}
+	if r, err := ledger.NewEnvelope("test.event.v0", "unkeyed.test", "unit.test", nil); err == nil || r != nil {
+		t.Fatalf("NewEnvelope without a key: %+v, %v", r, err)
+	}
 }
 
 // TestReceiptJSONRoundTrip ensures JSON marshal/unmarshal integrity.
 func TestReceiptJSONRoundTrip(t *testing.T) {
-	orig := ledger.NewReceipt("domain.test", "roundtrip", "core-hash", "console.demo", "seat.demo")
+	orig := newReceipt(t, "domain.test", "roundtrip", "core-hash", "console.demo", "seat.demo")
 	js, err := json.Marshal(orig)
 	if err != nil {
 		t.Fatalf("marshal: %v", err)
@@ -79,3 +133,895 @@ func TestReceiptJSONRoundTrip(t *testing.T) {
 		t.Errorf("ReceiptID mismatch: got %s, want %s", back.ReceiptID, orig.ReceiptID)
 	}
 }
+
+// TestSaveReceipt_ChainAndCheckpoints appends receipts to the rolling
+// ledger file and checks that the chain verifies, then that deleting a
+// sealed receipt is detected.
+func TestSaveReceipt_ChainAndCheckpoints(t *testing.T) {
+	_ = os.RemoveAll("receipts")
+	defer os.RemoveAll("receipts")
+
+	prev := ledger.CheckpointEvery
+	ledger.CheckpointEvery = 4
+	defer func() { ledger.CheckpointEvery = prev }()
+
+	for i := 0; i < 9; i++ {
+		r := newReceipt(t, "domain.test", "chain.test", "core-hash", "console.demo", "seat.demo")
+		if err := ledger.SaveReceipt(r); err != nil {
+			t.Fatalf("SaveReceipt #%d: %v", i, err)
+		}
+		if r.Seq != uint64(i+1) {
+			t.Fatalf("receipt #%d: got seq %d", i, r.Seq)
+		}
+	}
+
+	path := filepath.Join("receipts", "ledger.jsonl")
+	rep, err := ledger.VerifyLedgerFile(path)
+	if err != nil {
+		t.Fatalf("VerifyLedgerFile: %v", err)
+	}
+	if !rep.OK() {
+		t.Fatalf("expected clean chain, got errors: %v", rep.Errors)
+	}
+	if rep.Receipts != 9 || rep.Checkpoints != 2 || rep.SealedSeq != 8 {
+		t.Errorf("unexpected report: %+v", rep)
+	}
+
+	// Drop the third receipt and confirm verification fails.
+	entries, err := ledger.ReadLedgerFile(path)
+	if err != nil {
+		t.Fatalf("ReadLedgerFile: %v", err)
+	}
+	tampered := append(append([]ledger.ChainEntry{}, entries[:2]...), entries[3:]...)
+	if ledger.VerifyChain(tampered).OK() {
+		t.Errorf("expected deleted receipt to break the chain")
+	}
+}
+
+// TestFileStoreAppendFailure checks that a receipt the ledger file could
+// not take leaves no individual file behind.
+func TestFileStoreAppendFailure(t *testing.T) {
+	dir := t.TempDir()
+	store := ledger.NewFileStore(dir)
+	// A dangling link makes the ledger file impossible to create.
+	if err := os.Symlink(filepath.Join(dir, "missing", "ledger.jsonl"), store.LedgerPath()); err != nil {
+		t.Fatal(err)
+	}
+	r := newEnvelope(t, "test.event.v0", "domain.test", "store.test", map[string]any{})
+	if err := store.Append(r); err == nil {
+		t.Fatal("Append succeeded without a ledger file")
+	}
+	if _, err := store.Get(r.ReceiptID); !errors.Is(err, ledger.ErrReceiptNotFound) {
+		t.Fatalf("Get after failed Append: %v, want ErrReceiptNotFound", err)
+	}
+}
+
+// TestMerkleProofs checks RFC 6962 inclusion and consistency proofs for
+// every leaf and every pair of tree sizes up to a small log.
+func TestMerkleProofs(t *testing.T) {
+	var leaves []string
+	for i := 0; i < 13; i++ {
+		leaves = append(leaves, ledger.HashString(string(rune('a'+i))))
+	}
+	tree := ledger.NewMerkleTree(leaves)
+
+	for n := uint64(1); n <= tree.Size(); n++ {
+		root, _ := tree.RootAt(n)
+		if want := ledger.MerkleRoot(leaves[:n]); root != want {
+			t.Fatalf("RootAt(%d) = %s, want %s", n, root, want)
+		}
+		for i := uint64(0); i < n; i++ {
+			p, err := tree.InclusionProof(i, n, leaves[i])
+			if err != nil {
+				t.Fatalf("InclusionProof(%d, %d): %v", i, n, err)
+			}
+			if err := ledger.VerifyInclusion(p, root); err != nil {
+				t.Errorf("VerifyInclusion(%d, %d): %v", i, n, err)
+			}
+			p.ReceiptHash = "forged"
+			if ledger.VerifyInclusion(p, root) == nil {
+				t.Errorf("forged leaf %d/%d verified", i, n)
+			}
+		}
+		for m := uint64(1); m <= n; m++ {
+			oldRoot, _ := tree.RootAt(m)
+			p, err := tree.ConsistencyProof(m, n)
+			if err != nil {
+				t.Fatalf("ConsistencyProof(%d, %d): %v", m, n, err)
+			}
+			if err := ledger.VerifyConsistency(p, oldRoot, root); err != nil {
+				t.Errorf("VerifyConsistency(%d, %d): %v", m, n, err)
+			}
+			if m < n && ledger.VerifyConsistency(p, ledger.HashString("x"), root) == nil {
+				t.Errorf("consistency %d→%d verified against wrong old root", m, n)
+			}
+		}
+	}
+}
+
+// TestMemoryStore checks the in-memory ReceiptStore: envelopes are chained,
+// listed newest first and filtered by schema, and duplicates are rejected.
+func TestMemoryStore(t *testing.T) {
+	prev := ledger.CheckpointEvery
+	ledger.CheckpointEvery = 2
+	defer func() { ledger.CheckpointEvery = prev }()
+
+	var store ledger.ReceiptStore = ledger.NewMemoryStore()
+	var ids []string
+	for i := 0; i < 3; i++ {
+		r := newEnvelope(t, "test.event.v0", "domain.test", "store.test", map[string]any{"n": i})
+		if err := store.Append(r); err != nil {
+			t.Fatalf("Append #%d: %v", i, err)
+		}
+		ids = append(ids, r.ReceiptID)
+	}
+	if err := store.Append(newReceipt(t, "domain.test", "other", "core-hash", "console.demo", "seat.demo")); err != nil {
+		t.Fatalf("Append ci.call: %v", err)
+	}
+
+	dup, err := store.Get(ids[0])
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if err := store.Append(dup); err != ledger.ErrDuplicateReceipt {
+		t.Errorf("expected ErrDuplicateReceipt, got %v", err)
+	}
+	if _, err := store.Get("missing"); err != ledger.ErrReceiptNotFound {
+		t.Errorf("expected ErrReceiptNotFound, got %v", err)
+	}
+
+	list, err := store.List(ledger.ListOptions{SchemaRef: "test.event.v0", Offset: 1})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 2 || list[0].ReceiptID != ids[1] || list[1].ReceiptID != ids[0] {
+		t.Errorf("unexpected list: %+v", list)
+	}
+
+	entries, err := store.Chain()
+	if err != nil {
+		t.Fatalf("Chain: %v", err)
+	}
+	rep := ledger.VerifyChain(entries)
+	if !rep.OK() || rep.Receipts != 4 || rep.Checkpoints != 2 {
+		t.Errorf("unexpected chain report: %+v", rep)
+	}
+}
+
+// TestMemoryStoreSealFailure checks that an append whose checkpoint cannot
+// be signed leaves the chain as it was.
+func TestMemoryStoreSealFailure(t *testing.T) {
+	prev := ledger.CheckpointEvery
+	ledger.CheckpointEvery = 2
+	defer func() { ledger.CheckpointEvery = prev }()
+
+	store := ledger.NewMemoryStore().WithSealer("unkeyed.test")
+	appendOne := func() (*ledger.Receipt, error) {
+		r := newEnvelope(t, "test.event.v0", "domain.test", "store.test", map[string]any{})
+		return r, store.Append(r)
+	}
+	if _, err := appendOne(); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if _, err := appendOne(); err == nil {
+		t.Fatal("Append succeeded without a key to seal the checkpoint")
+	}
+	store.WithSealer(ledger.NodeDomain)
+	r, err := appendOne()
+	if err != nil || r.Seq != 2 {
+		t.Fatalf("Append after a failed seal: seq %d, %v", r.Seq, err)
+	}
+	entries, _ := store.Chain()
+	if rep := ledger.VerifyChain(entries); !rep.OK() || rep.Receipts != 2 || rep.Checkpoints != 1 {
+		t.Fatalf("unexpected chain report: %+v", rep)
+	}
+}
+
+// TestChainRejectsForeignKeys rewrites a ledger and re-signs every receipt
+// and checkpoint with another key store's keys for the same domains. The
+// forgery is self-consistent, so only resolving signer keys from the key
+// store catches it.
+func TestChainRejectsForeignKeys(t *testing.T) {
+	prev := ledger.CheckpointEvery
+	ledger.CheckpointEvery = 2
+	defer func() { ledger.CheckpointEvery = prev }()
+
+	token := crypto.NewMockPKCS11Token("1234")
+	if err := token.Login("1234"); err != nil {
+		t.Fatal(err)
+	}
+	rogue := crypto.NewPKCS11KeyStore(token)
+	for _, d := range []string{"domain.test", ledger.NodeDomain} {
+		if _, err := rogue.Generate(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trusted := crypto.DefaultKeyStore()
+	crypto.SetDefaultKeyStore(rogue)
+	forged := ledger.NewMemoryStore()
+	for i := 0; i < 4; i++ {
+		if err := forged.Append(newEnvelope(t, "test.event.v0", "domain.test", "forge.test", map[string]any{"n": i})); err != nil {
+			crypto.SetDefaultKeyStore(trusted)
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	entries, _ := forged.Chain()
+	if rep := ledger.VerifyChain(entries); !rep.OK() {
+		t.Fatalf("forged chain does not verify under its own keys: %v", rep.Errors)
+	}
+	crypto.SetDefaultKeyStore(trusted)
+
+	rep := ledger.VerifyChain(entries)
+	if rep.OK() || len(rep.Errors) != 6 {
+		t.Fatalf("forged chain: %d errors, want 4 receipts and 2 checkpoints: %v", len(rep.Errors), rep.Errors)
+	}
+	var cp *ledger.Checkpoint
+	for _, e := range entries {
+		if e.Checkpoint != nil {
+			cp = e.Checkpoint
+		}
+	}
+	if ok, err := cp.Verify(); ok || err == nil {
+		t.Fatal("forged checkpoint verified")
+	}
+	keys := ledger.StoreKeys(rogue)
+	if err := cp.VerifyWith(keys, "domain.test"); err == nil {
+		t.Fatal("checkpoint accepted from a sealer other than its own")
+	}
+	if err := cp.VerifyWith(keys, ledger.NodeDomain); err != nil {
+		t.Fatalf("checkpoint under the rogue keys: %v", err)
+	}
+}
+
+// TestCanonicalSigning checks that receipts are signed over the canonical
+// payload, that tampering with any signed field (including provenance) is
+// caught even when the stored hash is left alone, and that receipts in the
+// legacy pipe-joined format still verify.
+func TestCanonicalSigning(t *testing.T) {
+	r := newReceipt(t, "domain.test", "canon.test", "core-hash", "console.demo", "seat.demo")
+	if r.CanonVersion != ledger.CanonVersion || r.SigAlg != ledger.SigAlgEd25519 {
+		t.Fatalf("unexpected format: canon=%q alg=%q", r.CanonVersion, r.SigAlg)
+	}
+	js, _ := json.Marshal(r)
+	if ok, err := ledger.VerifyWithEmbeddedPub(js); !ok {
+		t.Fatalf("verify canonical receipt: %v", err)
+	}
+
+	tampered := *r
+	tampered.Provenance = []ledger.Provenance{{Type: "receipt", Ref: "forged", Status: "ok"}}
+	js, _ = json.Marshal(tampered)
+	if ok, err := ledger.VerifyWithEmbeddedPub(js); ok || err != ledger.ErrHashMismatch {
+		t.Errorf("expected hash mismatch for tampered provenance, got ok=%v err=%v", ok, err)
+	}
+
+	// A legacy receipt has no canon_version and was signed over the pipe payload.
+	legacy := *r
+	legacy.CanonVersion, legacy.SigAlg = "", ""
+	legacy.Hash, _ = legacy.ComputeHash()
+	legacy.KeyID = ""
+	sig, err := crypto.DefaultKeyStore().Sign(r.KeyID, []byte(legacy.Hash))
+	if err != nil {
+		t.Fatalf("sign legacy: %v", err)
+	}
+	legacy.Signature = sig
+	js, _ = json.Marshal(legacy)
+	if ok, err := ledger.VerifyReceiptJSON(js); !ok {
+		t.Errorf("verify legacy receipt: %v", err)
+	}
+	legacy.Action = "forged"
+	js, _ = json.Marshal(legacy)
+	if ok, _ := ledger.VerifyReceiptJSON(js); ok {
+		t.Errorf("expected tampered legacy receipt to fail")
+	}
+}
+
+// TestKeyRotation rotates a domain key and checks that receipts signed
+// before and after still verify against the key valid at their created_at,
+// that the transition receipt's endorsement checks out, and that revoking
+// the old key rejects receipts claiming to be signed after the revocation.
+func TestKeyRotation(t *testing.T) {
+	_ = os.RemoveAll("receipts")
+	defer os.RemoveAll("receipts")
+
+	const domain = "domain.rotate"
+	if _, _, err := ledger.GenerateDomainKey(domain); err != nil {
+		t.Fatalf("GenerateDomainKey: %v", err)
+	}
+	if _, _, err := ledger.GenerateDomainKey(domain); !errors.Is(err, crypto.ErrKeyExists) {
+		t.Errorf("expected ErrKeyExists on second generate, got %v", err)
+	}
+	before := newReceipt(t, domain, "before.rotate", "core-hash", "console.demo", "seat.demo")
+
+	next, transition, err := ledger.RotateDomainKey(domain)
+	if err != nil {
+		t.Fatalf("RotateDomainKey: %v", err)
+	}
+	if err := ledger.VerifyKeyTransition(transition); err != nil {
+		t.Errorf("VerifyKeyTransition: %v", err)
+	}
+	after := newReceipt(t, domain, "after.rotate", "core-hash", "console.demo", "seat.demo")
+	if after.KeyID != next.KeyID || before.KeyID == after.KeyID {
+		t.Fatalf("unexpected key ids: before=%s after=%s next=%s", before.KeyID, after.KeyID, next.KeyID)
+	}
+
+	for _, r := range []*ledger.Receipt{before, after} {
+		js, _ := json.Marshal(r)
+		if ok, err := ledger.VerifyReceiptJSON(js); !ok {
+			t.Errorf("verify %s: %v", r.Action, err)
+		}
+	}
+
+	if _, _, err := ledger.RevokeDomainKey(domain, next.KeyID, "test", time.Time{}); !errors.Is(err, crypto.ErrKeyActive) {
+		t.Errorf("expected ErrKeyActive revoking the active key, got %v", err)
+	}
+	if _, _, err := ledger.RevokeDomainKey(domain, before.KeyID, "compromised", time.Time{}); err != nil {
+		t.Fatalf("RevokeDomainKey: %v", err)
+	}
+	js, _ := json.Marshal(before)
+	if ok, err := ledger.VerifyReceiptJSON(js); !ok {
+		t.Errorf("receipt signed before revocation should verify: %v", err)
+	}
+	if _, err := crypto.DefaultKeyStore().Sign(before.KeyID, []byte("x")); !errors.Is(err, crypto.ErrKeyRevoked) {
+		t.Errorf("expected revoked key to refuse signing, got %v", err)
+	}
+}
+
+func TestThresholdSignatures(t *testing.T) {
+	seats := []string{"seat.one", "seat.two", "seat.three"}
+	ks := crypto.DefaultKeyStore()
+	for _, s := range seats {
+		if _, err := ks.Generate(s); err != nil {
+			t.Fatalf("Generate %s: %v", s, err)
+		}
+	}
+
+	r := newReceipt(t, "domain.test", "policy.amend", "core-hash", "console.demo", "seat.one")
+	r.Threshold = &ledger.ThresholdPolicy{M: 2, Seats: seats}
+	if err := r.Seal(); err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	hash := r.Hash
+
+	if err := r.CoSign("seat.one"); err != nil {
+		t.Fatalf("CoSign: %v", err)
+	}
+	if err := r.VerifyThreshold(); !errors.Is(err, ledger.ErrThresholdNotMet) {
+		t.Errorf("expected ErrThresholdNotMet with 1 of 2, got %v", err)
+	}
+	js, _ := json.Marshal(r)
+	if ok, _ := ledger.VerifyReceiptJSON(js); ok {
+		t.Errorf("receipt below threshold should not verify")
+	}
+
+	if err := r.CoSign("seat.one"); !errors.Is(err, ledger.ErrAlreadySigned) {
+		t.Errorf("expected ErrAlreadySigned, got %v", err)
+	}
+	if err := r.CoSign("domain.test"); !errors.Is(err, ledger.ErrNotASeat) {
+		t.Errorf("expected ErrNotASeat, got %v", err)
+	}
+
+	// A signature made outside the receipt is checked before it is attached,
+	// and signed_at is bound by the signature and must be current.
+	key, _ := ks.Active("seat.three")
+	sign := func(hash string, at time.Time) ledger.SeatSignature {
+		s := ledger.SeatSignature{Seat: "seat.three", KeyID: key.KeyID, SignedAt: at.UTC().Format(time.RFC3339Nano)}
+		s.Signature, _ = ks.Sign(key.KeyID, []byte(ledger.SeatSignatureDigest(hash, s.Seat, s.SignedAt)))
+		return s
+	}
+	now := time.Now()
+	redated := sign(r.Hash, now)
+	redated.SignedAt = now.Add(time.Minute).UTC().Format(time.RFC3339Nano)
+	for name, s := range map[string]ledger.SeatSignature{
+		"other message": sign("something else", now),
+		"backdated":     sign(r.Hash, now.Add(-time.Hour)),
+		"redated":       redated,
+	} {
+		if err := r.AddSignature(s); !errors.Is(err, ledger.ErrBadSeatSignature) {
+			t.Errorf("%s: expected ErrBadSeatSignature, got %v", name, err)
+		}
+	}
+	if err := r.AddSignature(sign(r.Hash, now)); err != nil {
+		t.Fatalf("AddSignature: %v", err)
+	}
+
+	if r.Hash != hash {
+		t.Errorf("co-signing changed the receipt hash")
+	}
+	if err := r.VerifyThreshold(); err != nil {
+		t.Errorf("VerifyThreshold with 2 of 2: %v", err)
+	}
+	js, _ = json.Marshal(r)
+	if ok, err := ledger.VerifyReceiptJSON(js); !ok {
+		t.Errorf("VerifyReceiptJSON: %v", err)
+	}
+	if ok, err := ledger.VerifyWithEmbeddedPub(js); !ok {
+		t.Errorf("VerifyWithEmbeddedPub: %v", err)
+	}
+
+	// The policy is signed: loosening it after the fact breaks the hash.
+	r.Threshold.M = 1
+	js, _ = json.Marshal(r)
+	if ok, _ := ledger.VerifyReceiptJSON(js); ok {
+		t.Errorf("tampered threshold should not verify")
+	}
+}
+
+func TestTimestamping(t *testing.T) {
+	// A stand-in authority whose clock steps backwards after the first token.
+	start := time.Now().Add(time.Minute)
+	clock := []time.Time{start, start.Add(-time.Hour)}
+	tsa := ledger.NewLocalTSA(ledger.NodeDomain)
+	tsa.Now = func() time.Time {
+		now := clock[0]
+		if len(clock) > 1 {
+			clock = clock[1:]
+		}
+		return now
+	}
+	ledger.SetDefaultTSA(tsa)
+	defer ledger.SetDefaultTSA(nil)
+
+	store := ledger.NewMemoryStore()
+	var rs []*ledger.Receipt
+	for i := 0; i < 3; i++ {
+		r := newReceipt(t, "domain.test", "stamp.test", "core-hash", "console.demo", "seat.demo")
+		if err := store.Append(r); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		if r.Timestamp == nil {
+			t.Fatalf("receipt %d was not timestamped", i)
+		}
+		rs = append(rs, r)
+	}
+	if !strings.HasSuffix(rs[0].CreatedAt, "Z") {
+		t.Errorf("CreatedAt should be UTC, got %s", rs[0].CreatedAt)
+	}
+	for i := 1; i < len(rs); i++ {
+		prev, cur := rs[i-1].Timestamp, rs[i].Timestamp
+		prevAt, _ := prev.Time()
+		curAt, _ := cur.Time()
+		if cur.SerialNumber <= prev.SerialNumber || !curAt.After(prevAt) {
+			t.Errorf("token %d not after token %d: %+v / %+v", i, i-1, cur, prev)
+		}
+	}
+
+	entries, _ := store.Chain()
+	if rep := ledger.VerifyChain(entries); !rep.OK() || rep.Timestamped != 3 {
+		t.Fatalf("VerifyChain: timestamped=%d errors=%v", rep.Timestamped, rep.Errors)
+	}
+	js, _ := json.Marshal(rs[1])
+	if ok, err := ledger.VerifyReceiptJSON(js); !ok {
+		t.Errorf("VerifyReceiptJSON: %v", err)
+	}
+
+	// A token for another receipt does not transfer.
+	forged := *rs[2]
+	forged.Timestamp = rs[1].Timestamp
+	js, _ = json.Marshal(&forged)
+	if _, err := ledger.VerifyReceiptJSON(js); !errors.Is(err, ledger.ErrTimestampImprint) {
+		t.Errorf("expected ErrTimestampImprint, got %v", err)
+	}
+
+	// Re-stamping the last receipt with an earlier time breaks the chain.
+	early := ledger.NewLocalTSA(ledger.NodeDomain)
+	early.Now = func() time.Time { return start.Add(-time.Minute) }
+	tok, err := early.Timestamp(ledger.TimestampRequest{
+		MessageImprint: ledger.MessageImprint{HashAlgorithm: ledger.HashAlgSHA256, HashedMessage: rs[2].Hash},
+	})
+	if err != nil {
+		t.Fatalf("Timestamp: %v", err)
+	}
+	entries[len(entries)-1].Receipt.Timestamp = tok
+	rep := ledger.VerifyChain(entries)
+	if rep.OK() {
+		t.Fatalf("expected a timestamp regression to be reported")
+	}
+}
+
+func TestReceiptQuery(t *testing.T) {
+	store := ledger.NewMemoryStore()
+	add := func(by, action, createdAt, status string, payload map[string]any) {
+		r := &ledger.Receipt{
+			By:         by,
+			Action:     action,
+			CreatedAt:  createdAt,
+			Provenance: []ledger.Provenance{{Type: "consent", Ref: "c-1", Status: status}},
+			Payload:    payload,
+		}
+		if err := store.Append(r); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	add("domain.terra", "consent:grant", "2025-10-02T10:00:00Z", "invalid", map[string]any{"amount": 250})
+	add("domain.terra", "consent:grant", "2025-10-03T10:00:00Z", "valid", map[string]any{"amount": 50})
+	add("domain.terra", "consent:grant", "2025-11-02T10:00:00Z", "invalid", nil)
+	add("domain.null", "consent:grant", "2025-10-04T10:00:00Z", "invalid", nil)
+	add("domain.terra", "consent:revoke", "2025-10-05T10:00:00Z", "invalid", nil)
+
+	cases := map[string]int{
+		``: 5,
+		`action = "consent:grant" and actor = domain.terra and created_at >= 2025-10-01 and created_at < 2025-11-01 and provenance.status = invalid`: 1,
+		`action in (consent:grant, consent:revoke) and not actor = domain.null`: 4,
+		`action ~ REVOKE or payload.amount > 100`: 2,
+		`payload.amount <= 100`: 1,
+		`(by = domain.null or by = domain.terra) and provenance.status != valid`: 4,
+	}
+	for src, want := range cases {
+		page, err := store.Search(ledger.SearchOptions{Query: src})
+		if err != nil {
+			t.Errorf("%q: %v", src, err)
+			continue
+		}
+		if len(page.Items) != want {
+			t.Errorf("%q: got %d receipts, want %d", src, len(page.Items), want)
+		}
+	}
+
+	for _, bad := range []string{`actor =`, `nosuch = 1`, `seq ~ 1`, `created_at > yesterday`, `(action = a`} {
+		if _, err := ledger.ParseQuery(bad); !errors.Is(err, ledger.ErrBadQuery) {
+			t.Errorf("%q: expected ErrBadQuery, got %v", bad, err)
+		}
+	}
+
+	// Cursor pagination walks every match exactly once, newest first.
+	var seen []string
+	opts := ledger.SearchOptions{Query: `action = "consent:grant"`, Limit: 3}
+	for {
+		page, err := store.Search(opts)
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		for _, r := range page.Items {
+			seen = append(seen, r.CreatedAt)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	want := []string{"2025-11-02T10:00:00Z", "2025-10-04T10:00:00Z", "2025-10-03T10:00:00Z", "2025-10-02T10:00:00Z"}
+	if strings.Join(seen, ",") != strings.Join(want, ",") {
+		t.Errorf("paged results %v, want %v", seen, want)
+	}
+
+	// Quoted values round-trip through their escapes.
+	for _, v := range []string{`a"b`, `back\slash`, "tab\tand\nnewline", "naïve ☃"} {
+		q, err := ledger.ParseQuery("actor = " + ledger.QuoteQueryValue(v))
+		if err != nil {
+			t.Errorf("%q: %v", v, err)
+			continue
+		}
+		if _, args := q.SQL(1); len(args) != 1 || args[0] != v {
+			t.Errorf("%q parsed back as %v", v, args)
+		}
+	}
+	if _, err := ledger.ParseQuery(`actor = "bad \q escape"`); !errors.Is(err, ledger.ErrBadQuery) {
+		t.Errorf("unknown escape: expected ErrBadQuery, got %v", err)
+	}
+
+	// Values are always bound as parameters.
+	q, _ := ledger.ParseQuery(`actor = "x'; DROP TABLE receipts; --" and metadata.issuer_seat ~ seat`)
+	where, args := q.SQL(2)
+	if strings.Contains(where, "DROP") || len(args) != 3 || !strings.Contains(where, "$2") {
+		t.Errorf("unexpected SQL %q with args %v", where, args)
+	}
+}
+
+// TestLedgerBundle round-trips a ledger through an export bundle, extends
+// it incrementally, and checks that conflicting, tampered and schema-drifted
+// bundles are refused.
+func TestLedgerBundle(t *testing.T) {
+	prev := ledger.CheckpointEvery
+	ledger.CheckpointEvery = 2
+	defer func() { ledger.CheckpointEvery = prev }()
+
+	fill := func(store ledger.ReceiptStore, action string, n int) {
+		for i := 0; i < n; i++ {
+			r := newEnvelope(t, "test.event.v0", "domain.test", action, map[string]any{"n": i})
+			if err := store.Append(r); err != nil {
+				t.Fatalf("Append: %v", err)
+			}
+		}
+	}
+	export := func(store ledger.ReceiptStore) *bytes.Buffer {
+		var buf bytes.Buffer
+		if _, err := ledger.ExportBundle(&buf, store, ledger.ExportOptions{SchemaRegistryHash: "schemas-1"}); err != nil {
+			t.Fatalf("ExportBundle: %v", err)
+		}
+		return &buf
+	}
+	opts := ledger.ImportOptions{SchemaRegistryHash: "schemas-1"}
+
+	src, dst := ledger.NewMemoryStore(), ledger.NewMemoryStore()
+	fill(src, "bundle.test", 5)
+	bundle := export(src).Bytes()
+
+	rep, err := ledger.ImportBundle(bytes.NewReader(bundle), dst, opts)
+	if err != nil {
+		t.Fatalf("ImportBundle: %v", err)
+	}
+	if rep.Imported != 5 || rep.Present != 0 {
+		t.Errorf("unexpected import report: %+v", rep)
+	}
+	entries, _ := dst.Chain()
+	if chain := ledger.VerifyChain(entries); !chain.OK() || chain.Head != rep.Manifest.Head {
+		t.Errorf("imported chain: %+v, want head %+v", chain, rep.Manifest.Head)
+	}
+
+	// Re-importing is a no-op; a longer export only adds the new tail.
+	if rep, err = ledger.ImportBundle(bytes.NewReader(bundle), dst, opts); err != nil || rep.Present != 5 || rep.Imported != 0 {
+		t.Errorf("re-import: %+v, %v", rep, err)
+	}
+	fill(src, "bundle.test", 2)
+	if rep, err = ledger.ImportBundle(export(src), dst, opts); err != nil || rep.Present != 5 || rep.Imported != 2 {
+		t.Errorf("incremental import: %+v, %v", rep, err)
+	}
+
+	other := ledger.NewMemoryStore()
+	fill(other, "bundle.other", 3)
+	if _, err := ledger.ImportBundle(bytes.NewReader(bundle), other, opts); !errors.Is(err, ledger.ErrChainConflict) {
+		t.Errorf("expected ErrChainConflict, got %v", err)
+	}
+	if _, err := ledger.ImportBundle(bytes.NewReader(bundle), ledger.NewMemoryStore(), ledger.ImportOptions{SchemaRegistryHash: "schemas-2"}); !errors.Is(err, ledger.ErrSchemaDrift) {
+		t.Errorf("expected ErrSchemaDrift, got %v", err)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(bundle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := io.ReadAll(zr)
+	var tampered bytes.Buffer
+	zw := gzip.NewWriter(&tampered)
+	zw.Write(bytes.Replace(plain, []byte(`"n":3`), []byte(`"n":9`), 1))
+	zw.Close()
+	if _, err := ledger.ImportBundle(&tampered, ledger.NewMemoryStore(), opts); !errors.Is(err, ledger.ErrBundleInvalid) {
+		t.Errorf("expected ErrBundleInvalid for a tampered bundle, got %v", err)
+	}
+}
+
+// TestBundleKeyAnchoring exports a bundle under one key store and imports
+// it under others: it is accepted only when its signers' keys are held
+// locally or pinned, never on the strength of the key set it carries.
+func TestBundleKeyAnchoring(t *testing.T) {
+	src := ledger.NewMemoryStore()
+	for i := 0; i < 3; i++ {
+		if err := src.Append(newEnvelope(t, "test.event.v0", "domain.test", "anchor.test", map[string]any{"n": i})); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := ledger.ExportBundle(&buf, src, ledger.ExportOptions{}); err != nil {
+		t.Fatalf("ExportBundle: %v", err)
+	}
+	bundle := buf.Bytes()
+
+	exporter := crypto.DefaultKeyStore()
+	defer crypto.SetDefaultKeyStore(exporter)
+	pinned := map[string][]crypto.KeyRecord{}
+	for _, d := range []string{"domain.test", ledger.NodeDomain} {
+		pinned[d], _ = exporter.History(d)
+	}
+	keyStore := func(domains ...string) crypto.KeyStore {
+		token := crypto.NewMockPKCS11Token("1234")
+		if err := token.Login("1234"); err != nil {
+			t.Fatal(err)
+		}
+		ks := crypto.NewPKCS11KeyStore(token)
+		for _, d := range domains {
+			if _, err := ks.Generate(d); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return ks
+	}
+
+	cases := []struct {
+		name   string
+		ks     crypto.KeyStore
+		pinned map[string][]crypto.KeyRecord
+		ok     bool
+	}{
+		{"other keys for the same domains", keyStore("domain.test", ledger.NodeDomain), nil, false},
+		{"other keys, pins ignored", keyStore("domain.test", ledger.NodeDomain), pinned, false},
+		{"unknown domains", keyStore(), nil, false},
+		{"unknown domains, pinned", keyStore(), pinned, true},
+		{"exporter's key store", exporter, nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			crypto.SetDefaultKeyStore(tc.ks)
+			dst := ledger.NewMemoryStore()
+			rep, err := ledger.ImportBundle(bytes.NewReader(bundle), dst, ledger.ImportOptions{PinnedKeys: tc.pinned})
+			entries, _ := dst.Chain()
+			if !tc.ok {
+				if !errors.Is(err, ledger.ErrBundleInvalid) || len(entries) != 0 {
+					t.Fatalf("ImportBundle = %+v, %v with %d entries written; want ErrBundleInvalid", rep, err, len(entries))
+				}
+				return
+			}
+			if err != nil || rep.Imported != 3 {
+				t.Fatalf("ImportBundle = %+v, %v", rep, err)
+			}
+		})
+	}
+}
+
+// TestDependents finds the receipts citing a ref, whatever characters the
+// ref holds.
+func TestDependents(t *testing.T) {
+	store := ledger.NewMemoryStore()
+	refs := []string{"consent-1", `quoted "ref"`, `back\slash`, `a" or actor = "x`}
+	for _, ref := range refs {
+		r := &ledger.Receipt{By: "domain.test", Action: "cite", Provenance: []ledger.Provenance{{Type: "consent", Ref: ref}}}
+		if err := store.Append(r); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	for _, ref := range refs {
+		t.Run(ref, func(t *testing.T) {
+			rs, edges, err := ledger.Dependents(store, ref)
+			if err != nil {
+				t.Fatalf("Dependents: %v", err)
+			}
+			if len(rs) != 1 || len(edges) != 1 || edges[0].Ref != ref || rs[0].Provenance[0].Ref != ref {
+				t.Fatalf("dependents of %q: %+v, edges %+v", ref, rs, edges)
+			}
+		})
+	}
+	if _, _, err := ledger.Dependents(store, ""); !errors.Is(err, ledger.ErrBadQuery) {
+		t.Fatalf("empty ref: %v", err)
+	}
+}
+
+// TestRevocationPropagation revokes the root of a small provenance graph
+// and checks what is marked, at which depth, and who is notified.
+func TestRevocationPropagation(t *testing.T) {
+	store := ledger.NewMemoryStore()
+	cite := func(id, by string, refs ...string) {
+		r := &ledger.Receipt{ReceiptID: id, By: by, Action: "cite"}
+		for _, ref := range refs {
+			r.Provenance = append(r.Provenance, ledger.Provenance{Type: "depends_on", Ref: ref})
+		}
+		if err := store.Append(r); err != nil {
+			t.Fatalf("Append %s: %v", id, err)
+		}
+	}
+	cite("root", "domain.test")
+	cite("child", "domain.test", "root")
+	cite("both", ledger.NodeDomain, "root", "child")
+	cite("grandchild", ledger.NodeDomain, "child")
+	cite("elsewhere", "domain.test", "consent-9")
+
+	type mark struct {
+		status, via string
+		depth       int
+	}
+	cases := []struct {
+		name      string
+		ref       string
+		maxDepth  int
+		marks     map[string]mark
+		truncated bool
+	}{
+		{"receipt", "root", 0, map[string]mark{
+			"root":       {ledger.StatusRevoked, "", 0},
+			"child":      {ledger.StatusRevokedUpstream, "root", 1},
+			"both":       {ledger.StatusRevokedUpstream, "root", 1},
+			"grandchild": {ledger.StatusRevokedUpstream, "child", 2},
+		}, false},
+		{"depth limit", "root", 1, map[string]mark{
+			"root":  {ledger.StatusRevoked, "", 0},
+			"child": {ledger.StatusRevokedUpstream, "root", 1},
+			"both":  {ledger.StatusRevokedUpstream, "root", 1},
+		}, true},
+		{"ref that is not a receipt", "consent-9", 0, map[string]mark{
+			"elsewhere": {ledger.StatusRevokedUpstream, "consent-9", 1},
+		}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			statuses := ledger.NewMemoryStatusStore()
+			p := ledger.NewRevocationPropagator(store, statuses).WithMaxDepth(tc.maxDepth)
+			revocation := "rev-" + strings.ReplaceAll(tc.name, " ", "-")
+			impact, err := p.Propagate(revocation, tc.ref)
+			if err != nil {
+				t.Fatalf("Propagate: %v", err)
+			}
+			if impact.Graph.Truncated != tc.truncated || len(impact.Statuses) != len(tc.marks) {
+				t.Fatalf("impact %+v", impact)
+			}
+			owners := map[string]int{}
+			for _, st := range impact.Statuses {
+				want, ok := tc.marks[st.ReceiptID]
+				if !ok || st.Status != want.status || st.Via != want.via || st.Depth != want.depth ||
+					st.RevocationID != revocation || st.RevokedRef != tc.ref {
+					t.Fatalf("status %+v, want %+v", st, want)
+				}
+				owners[st.Owner]++
+			}
+			if len(impact.Domains) != len(owners) {
+				t.Fatalf("domains %v, want %v", impact.Domains, owners)
+			}
+			for owner, n := range owners {
+				if impact.Domains[owner] != n {
+					t.Fatalf("domains %v, want %v", impact.Domains, owners)
+				}
+				notified := false
+				for _, e := range events.FetchRecent(owner) {
+					notified = notified || (e.Type == ledger.RevokedUpstreamEvent && e.Target == owner && e.Context["revocation_id"] == revocation)
+				}
+				if !notified {
+					t.Fatalf("%s not notified of %s", owner, revocation)
+				}
+			}
+
+			// Propagating again marks the same receipts once.
+			if _, err := p.Propagate(revocation, tc.ref); err != nil {
+				t.Fatalf("second Propagate: %v", err)
+			}
+			again, err := p.Impact(revocation, tc.ref)
+			if err != nil || len(again.Statuses) != len(tc.marks) {
+				t.Fatalf("Impact after a second Propagate: %+v, %v", again, err)
+			}
+			for id := range tc.marks {
+				if got, err := p.Statuses(id); err != nil || len(got) != 1 {
+					t.Fatalf("Statuses(%s): %+v, %v", id, got, err)
+				}
+			}
+		})
+	}
+}
+
+// TestRevocationAuthorize checks that only a signed revocation by a party
+// to the revoked receipt, or by the node, is accepted.
+func TestRevocationAuthorize(t *testing.T) {
+	ks := crypto.DefaultKeyStore()
+	for _, d := range []string{"party.revoke.test", "outsider.revoke.test"} {
+		if _, err := ks.Generate(d); err != nil {
+			t.Fatalf("Generate %s: %v", d, err)
+		}
+	}
+	store := ledger.NewMemoryStore()
+	grant := &ledger.Receipt{ReceiptID: "grant", By: "domain.test", Action: "consent.grant",
+		Payload: map[string]any{"affected": []any{"party.revoke.test"}}}
+	if err := store.Append(grant); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	p := ledger.NewRevocationPropagator(store, ledger.NewMemoryStatusStore())
+	sign := func(ref, by string) string {
+		key, err := ks.Active(by)
+		if err != nil {
+			t.Fatalf("Active %s: %v", by, err)
+		}
+		sig, err := ks.Sign(key.KeyID, []byte(ledger.RevocationDigest(ref, "receipt", "withdrawn", by)))
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		return sig
+	}
+
+	cases := []struct {
+		name    string
+		ref, by string
+		sig     string
+		want    error
+	}{
+		{"signer", "grant", "domain.test", sign("grant", "domain.test"), nil},
+		{"affected party", "grant", "party.revoke.test", sign("grant", "party.revoke.test"), nil},
+		{"node", "grant", ledger.NodeDomain, sign("grant", ledger.NodeDomain), nil},
+		{"outsider", "grant", "outsider.revoke.test", sign("grant", "outsider.revoke.test"), ledger.ErrRevocationRefused},
+		{"signed by another", "grant", "party.revoke.test", sign("grant", "outsider.revoke.test"), ledger.ErrRevocationRefused},
+		{"unsigned", "grant", "domain.test", "", ledger.ErrRevocationRefused},
+		{"no key", "grant", "nobody.revoke.test", sign("grant", "domain.test"), ledger.ErrRevocationRefused},
+		{"ref that is not a receipt", "consent-9", "domain.test", sign("consent-9", "domain.test"), ledger.ErrRevocationRefused},
+		{"ref that is not a receipt by the node", "consent-9", ledger.NodeDomain, sign("consent-9", ledger.NodeDomain), nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := p.Authorize(ks, tc.ref, "receipt", "withdrawn", tc.by, tc.sig); !errors.Is(err, tc.want) {
+				t.Fatalf("Authorize: %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package ledger
+
+import (
+	"sync"
+)
+
+// MemoryStore is an in-process ReceiptStore, used by tests and by nodes
+// that do not persist receipts.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries []ChainEntry
+	byID    map[string]*Receipt
+	chain   *chainState
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		byID:  make(map[string]*Receipt),
+		chain: chainStateFrom(nil),
+	}
+}
+
+// WithSealer seals the store's checkpoints as domain instead of NodeDomain,
+// so several nodes can keep their chains in one process, and returns the
+// store (chainable). Call it before the first Append.
+func (s *MemoryStore) WithSealer(domain string) *MemoryStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chain.sealer = domain
+	return s
+}
+
+// Append links a copy of r onto the chain. r itself receives the chain fields.
+func (s *MemoryStore) Append(r *Receipt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.fillDefaults()
+	if _, exists := s.byID[r.ReceiptID]; exists {
+		return ErrDuplicateReceipt
+	}
+	if err := r.stamp(); err != nil {
+		return err
+	}
+	cp, err := s.chain.append(r)
+	if err != nil {
+		return err
+	}
+	stored := *r
+	s.entries = append(s.entries, ChainEntry{Receipt: &stored})
+	s.byID[stored.ReceiptID] = &stored
+	if cp != nil {
+		s.entries = append(s.entries, ChainEntry{Checkpoint: cp})
+	}
+	return nil
+}
+
+// Get returns a copy of the receipt with the given ID.
+func (s *MemoryStore) Get(id string) (*Receipt, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.byID[id]
+	if !ok {
+		return nil, ErrReceiptNotFound
+	}
+	out := *r
+	return &out, nil
+}
+
+// List returns receipts newest first.
+func (s *MemoryStore) List(opts ListOptions) ([]Receipt, error) {
+	opts = opts.normalize()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []Receipt
+	skipped := 0
+	for i := len(s.entries) - 1; i >= 0 && len(out) < opts.Limit; i-- {
+		r := s.entries[i].Receipt
+		if r == nil || (opts.SchemaRef != "" && r.SchemaRef != opts.SchemaRef) {
+			continue
+		}
+		if skipped < opts.Offset {
+			skipped++
+			continue
+		}
+		out = append(out, *r)
+	}
+	return out, nil
+}
+
+// Search returns a page of receipts matching opts.Query.
+func (s *MemoryStore) Search(opts SearchOptions) (*SearchPage, error) {
+	s.mu.RLock()
+	all := make([]Receipt, 0, len(s.byID))
+	for _, e := range s.entries {
+		if e.Receipt != nil {
+			all = append(all, *e.Receipt)
+		}
+	}
+	s.mu.RUnlock()
+	return searchReceipts(all, opts)
+}
+
+// Chain returns a snapshot of every entry in chain order.
+func (s *MemoryStore) Chain() ([]ChainEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]ChainEntry(nil), s.entries...), nil
+}
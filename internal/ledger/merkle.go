@@ -0,0 +1,309 @@
+package ledger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Merkle tree hashing follows RFC 6962 (Certificate Transparency):
+// leaves and interior nodes are domain-separated by a one-byte prefix so
+// that a leaf can never be confused with a subtree.
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// merkleLeafHash returns the RFC 6962 leaf hash of a receipt hash.
+func merkleLeafHash(leaf string) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleLeafPrefix})
+	h.Write([]byte(leaf))
+	return h.Sum(nil)
+}
+
+// merkleNodeHash combines two subtree hashes into their parent.
+func merkleNodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleNodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleTreeHash computes MTH(D[n]) over already-hashed leaves.
+func merkleTreeHash(hashes [][]byte) []byte {
+	switch len(hashes) {
+	case 0:
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	case 1:
+		return hashes[0]
+	}
+	k := largestPowerOfTwoBelow(len(hashes))
+	return merkleNodeHash(merkleTreeHash(hashes[:k]), merkleTreeHash(hashes[k:]))
+}
+
+// largestPowerOfTwoBelow returns the largest power of two strictly less than n (n > 1).
+func largestPowerOfTwoBelow(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// MerkleRoot returns the hex-encoded RFC 6962 Merkle tree hash over the
+// receipt hashes in ledger order.
+func MerkleRoot(leaves []string) string {
+	return NewMerkleTree(leaves).Root()
+}
+
+// MerkleTree is an append-only Merkle tree over receipt hashes, modeled on
+// the Certificate Transparency log. It answers root, inclusion and
+// consistency queries for any prefix of the log.
+type MerkleTree struct {
+	leaves [][]byte
+}
+
+// NewMerkleTree builds a tree over the given receipt hashes.
+func NewMerkleTree(leaves []string) *MerkleTree {
+	t := &MerkleTree{leaves: make([][]byte, 0, len(leaves))}
+	for _, l := range leaves {
+		t.Append(l)
+	}
+	return t
+}
+
+// Append adds a receipt hash as the next leaf.
+func (t *MerkleTree) Append(leaf string) {
+	t.leaves = append(t.leaves, merkleLeafHash(leaf))
+}
+
+// Size returns the number of leaves in the tree.
+func (t *MerkleTree) Size() uint64 { return uint64(len(t.leaves)) }
+
+// Root returns the hex root of the full tree.
+func (t *MerkleTree) Root() string {
+	return hex.EncodeToString(merkleTreeHash(t.leaves))
+}
+
+// RootAt returns the hex root of the first size leaves.
+func (t *MerkleTree) RootAt(size uint64) (string, error) {
+	if size > t.Size() {
+		return "", fmt.Errorf("%w: %d exceeds log size %d", ErrBadTreeSize, size, t.Size())
+	}
+	return hex.EncodeToString(merkleTreeHash(t.leaves[:size])), nil
+}
+
+// InclusionProof is an RFC 6962 audit path proving that the receipt hash at
+// LeafIndex is part of the tree of TreeSize leaves.
+type InclusionProof struct {
+	LeafIndex   uint64   `json:"leaf_index"`
+	TreeSize    uint64   `json:"tree_size"`
+	ReceiptHash string   `json:"receipt_hash"`
+	AuditPath   []string `json:"audit_path"`
+}
+
+// ConsistencyProof is an RFC 6962 proof that the tree of FromSize leaves is
+// a prefix of the tree of ToSize leaves.
+type ConsistencyProof struct {
+	FromSize uint64   `json:"from_size"`
+	ToSize   uint64   `json:"to_size"`
+	Path     []string `json:"path"`
+}
+
+// InclusionProof returns the audit path for leaf index within the first size leaves.
+func (t *MerkleTree) InclusionProof(index, size uint64, receiptHash string) (*InclusionProof, error) {
+	if size > t.Size() {
+		return nil, fmt.Errorf("%w: %d exceeds log size %d", ErrBadTreeSize, size, t.Size())
+	}
+	if index >= size {
+		return nil, fmt.Errorf("%w: leaf index %d outside tree of size %d", ErrBadTreeSize, index, size)
+	}
+	return &InclusionProof{
+		LeafIndex:   index,
+		TreeSize:    size,
+		ReceiptHash: receiptHash,
+		AuditPath:   hexAll(auditPath(int(index), t.leaves[:size])),
+	}, nil
+}
+
+// ConsistencyProof returns the proof that the first from leaves are a
+// prefix of the first to leaves.
+func (t *MerkleTree) ConsistencyProof(from, to uint64) (*ConsistencyProof, error) {
+	if to > t.Size() {
+		return nil, fmt.Errorf("%w: %d exceeds log size %d", ErrBadTreeSize, to, t.Size())
+	}
+	if from > to {
+		return nil, fmt.Errorf("%w: from size %d is larger than to size %d", ErrBadTreeSize, from, to)
+	}
+	p := &ConsistencyProof{FromSize: from, ToSize: to, Path: []string{}}
+	if from > 0 && from < to {
+		p.Path = hexAll(subProof(int(from), t.leaves[:to], true))
+	}
+	return p, nil
+}
+
+// auditPath implements PATH(m, D[n]) from RFC 6962 §2.1.1.
+func auditPath(m int, hashes [][]byte) [][]byte {
+	n := len(hashes)
+	if n <= 1 {
+		return nil
+	}
+	k := largestPowerOfTwoBelow(n)
+	if m < k {
+		return append(auditPath(m, hashes[:k]), merkleTreeHash(hashes[k:]))
+	}
+	return append(auditPath(m-k, hashes[k:]), merkleTreeHash(hashes[:k]))
+}
+
+// subProof implements SUBPROOF(m, D[n], b) from RFC 6962 §2.1.2.
+func subProof(m int, hashes [][]byte, complete bool) [][]byte {
+	n := len(hashes)
+	if m == n {
+		if complete {
+			return nil
+		}
+		return [][]byte{merkleTreeHash(hashes)}
+	}
+	k := largestPowerOfTwoBelow(n)
+	if m <= k {
+		return append(subProof(m, hashes[:k], complete), merkleTreeHash(hashes[k:]))
+	}
+	return append(subProof(m-k, hashes[k:], false), merkleTreeHash(hashes[:k]))
+}
+
+func hexAll(hashes [][]byte) []string {
+	out := make([]string, len(hashes))
+	for i, h := range hashes {
+		out[i] = hex.EncodeToString(h)
+	}
+	return out
+}
+
+func decodeAll(path []string) ([][]byte, error) {
+	out := make([][]byte, len(path))
+	for i, p := range path {
+		b, err := hex.DecodeString(p)
+		if err != nil {
+			return nil, fmt.Errorf("proof element %d: %w", i, err)
+		}
+		out[i] = b
+	}
+	return out, nil
+}
+
+// ErrBadTreeSize is returned when a proof is asked for a tree size the log
+// does not have, or for a leaf outside it.
+var ErrBadTreeSize = errors.New("bad tree size")
+
+// ErrProofMismatch is returned when a proof does not reproduce the expected root.
+var ErrProofMismatch = errors.New("merkle proof does not match root")
+
+// VerifyInclusion checks an inclusion proof against the hex root of a tree
+// of p.TreeSize leaves, following RFC 9162 §2.1.3.2. It needs no access to
+// the ledger.
+func VerifyInclusion(p *InclusionProof, root string) error {
+	if p.LeafIndex >= p.TreeSize {
+		return fmt.Errorf("leaf index %d outside tree of size %d", p.LeafIndex, p.TreeSize)
+	}
+	want, err := hex.DecodeString(root)
+	if err != nil {
+		return fmt.Errorf("decode root: %w", err)
+	}
+	path, err := decodeAll(p.AuditPath)
+	if err != nil {
+		return err
+	}
+
+	fn, sn := p.LeafIndex, p.TreeSize-1
+	r := merkleLeafHash(p.ReceiptHash)
+	for _, sib := range path {
+		if sn == 0 {
+			return ErrProofMismatch
+		}
+		if fn&1 == 1 || fn == sn {
+			r = merkleNodeHash(sib, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = merkleNodeHash(r, sib)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 || !bytes.Equal(r, want) {
+		return ErrProofMismatch
+	}
+	return nil
+}
+
+// VerifyConsistency checks that oldRoot (tree of p.FromSize leaves) is a
+// prefix of newRoot (tree of p.ToSize leaves), following RFC 9162 §2.1.4.2.
+func VerifyConsistency(p *ConsistencyProof, oldRoot, newRoot string) error {
+	first, err := hex.DecodeString(oldRoot)
+	if err != nil {
+		return fmt.Errorf("decode old root: %w", err)
+	}
+	second, err := hex.DecodeString(newRoot)
+	if err != nil {
+		return fmt.Errorf("decode new root: %w", err)
+	}
+	path, err := decodeAll(p.Path)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case p.FromSize > p.ToSize:
+		return fmt.Errorf("from size %d is larger than to size %d", p.FromSize, p.ToSize)
+	case p.FromSize == 0:
+		// The empty tree is a prefix of every tree.
+		return nil
+	case p.FromSize == p.ToSize:
+		if len(path) != 0 || !bytes.Equal(first, second) {
+			return ErrProofMismatch
+		}
+		return nil
+	}
+
+	if p.FromSize&(p.FromSize-1) == 0 {
+		path = append([][]byte{first}, path...)
+	}
+	if len(path) == 0 {
+		return ErrProofMismatch
+	}
+
+	fn, sn := p.FromSize-1, p.ToSize-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := path[0], path[0]
+	for _, c := range path[1:] {
+		if sn == 0 {
+			return ErrProofMismatch
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = merkleNodeHash(c, fr)
+			sr = merkleNodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = merkleNodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 || !bytes.Equal(fr, first) || !bytes.Equal(sr, second) {
+		return ErrProofMismatch
+	}
+	return nil
+}
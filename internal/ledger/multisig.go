@@ -0,0 +1,199 @@
+package ledger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"dis-core/internal/bridge"
+	"dis-core/internal/util/crypto"
+)
+
+// ThresholdPolicy requires M distinct seats out of Seats to co-sign a
+// receipt before it counts as authorized. It is part of the signed
+// envelope, so the domain commits to the policy when it seals the receipt.
+type ThresholdPolicy struct {
+	M     int      `json:"m"`
+	Seats []string `json:"seats"`
+}
+
+// Validate checks that the policy can be satisfied.
+func (p ThresholdPolicy) Validate() error {
+	if p.M < 1 || p.M > len(p.Seats) {
+		return fmt.Errorf("threshold %d-of-%d is not satisfiable", p.M, len(p.Seats))
+	}
+	return nil
+}
+
+// SeatSignature is one seat holder's signature over SeatSignatureDigest of
+// the receipt Hash, the seat and SignedAt. Seat signatures sit outside the
+// canonical payload, so co-signing does not change the receipt hash.
+type SeatSignature struct {
+	Seat         string `json:"seat"`
+	KeyID        string `json:"key_id"`
+	PublicKeyB64 string `json:"public_key_b64"`
+	SignedAt     string `json:"signed_at"`
+	Signature    string `json:"signature"`
+}
+
+// SeatSignatureSkew bounds how far a seat signature's signed_at may be
+// from this node's clock when it is attached. signed_at picks the key the
+// signature is checked against, so a backdated one could otherwise revive
+// a revoked key.
+var SeatSignatureSkew = 5 * time.Minute
+
+var (
+	ErrNotASeat         = errors.New("signer is not a seat under the receipt's threshold policy")
+	ErrAlreadySigned    = errors.New("seat has already signed this receipt")
+	ErrThresholdNotMet  = errors.New("receipt does not carry enough seat signatures")
+	ErrBadSeatSignature = errors.New("invalid seat signature")
+)
+
+// SeatSignatureDigest is the message a seat signs to co-sign the receipt
+// with hash at signedAt.
+func SeatSignatureDigest(hash, seat, signedAt string) string {
+	canon, _ := bridge.CanonicalJSON(map[string]any{
+		"hash":      hash,
+		"seat":      seat,
+		"signed_at": signedAt,
+	})
+	sum := sha256.Sum256(canon)
+	return hex.EncodeToString(sum[:])
+}
+
+// digest is the message s was made over.
+func (s SeatSignature) digest(hash string) []byte {
+	return []byte(SeatSignatureDigest(hash, s.Seat, s.SignedAt))
+}
+
+// SignSeat signs r as seat, now, with the seat's active key in ks. It is
+// how a seat holder produces the signature AddSignature takes.
+func SignSeat(ks crypto.KeyStore, r *Receipt, seat string) (SeatSignature, error) {
+	key, err := ks.Active(seat)
+	if err != nil {
+		return SeatSignature{}, err
+	}
+	s := SeatSignature{Seat: seat, KeyID: key.KeyID, PublicKeyB64: key.PublicKeyB64, SignedAt: NowRFC3339Nano()}
+	if s.Signature, err = ks.Sign(key.KeyID, s.digest(r.Hash)); err != nil {
+		return SeatSignature{}, err
+	}
+	return s, nil
+}
+
+// CoSign adds a signature by seat, made with the seat's active key in the
+// default KeyStore.
+func (r *Receipt) CoSign(seat string) error {
+	if err := r.canSign(seat); err != nil {
+		return err
+	}
+	s, err := SignSeat(crypto.DefaultKeyStore(), r, seat)
+	if err != nil {
+		return err
+	}
+	r.Signatures = append(r.Signatures, s)
+	return nil
+}
+
+// AddSignature attaches a seat signature made elsewhere, after checking it
+// against the seat's key history. Its signed_at must be within
+// SeatSignatureSkew of now.
+func (r *Receipt) AddSignature(s SeatSignature) error {
+	if err := r.canSign(s.Seat); err != nil {
+		return err
+	}
+	at, err := time.Parse(time.RFC3339Nano, s.SignedAt)
+	if err != nil {
+		return fmt.Errorf("%w: signed_at: %v", ErrBadSeatSignature, err)
+	}
+	if d := time.Since(at); d > SeatSignatureSkew || d < -SeatSignatureSkew {
+		return fmt.Errorf("%w: signed_at %s is not within %s of now", ErrBadSeatSignature, s.SignedAt, SeatSignatureSkew)
+	}
+	key, err := seatKeyFromStore(s)
+	if err != nil {
+		return err
+	}
+	if !key.Verify(s.digest(r.Hash), s.Signature) {
+		return fmt.Errorf("%w: %s", ErrBadSeatSignature, s.Seat)
+	}
+	s.KeyID, s.PublicKeyB64 = key.KeyID, key.PublicKeyB64
+	r.Signatures = append(r.Signatures, s)
+	return nil
+}
+
+func (r *Receipt) canSign(seat string) error {
+	if r.Hash == "" {
+		return errors.New("receipt must be sealed before seats sign")
+	}
+	if r.Threshold != nil && !slices.Contains(r.Threshold.Seats, seat) {
+		return fmt.Errorf("%w: %s", ErrNotASeat, seat)
+	}
+	for _, s := range r.Signatures {
+		if s.Seat == seat {
+			return fmt.Errorf("%w: %s", ErrAlreadySigned, seat)
+		}
+	}
+	return nil
+}
+
+// SignedSeats returns the seats whose signatures verify against the
+// default KeyStore.
+func (r *Receipt) SignedSeats() []string {
+	var out []string
+	for _, s := range r.Signatures {
+		if key, err := seatKeyFromStore(s); err == nil && key.Verify(s.digest(r.Hash), s.Signature) {
+			out = append(out, s.Seat)
+		}
+	}
+	return out
+}
+
+// VerifyThreshold checks that at least M distinct seats named by the policy
+// have valid signatures, resolving seat keys through the default KeyStore.
+func (r *Receipt) VerifyThreshold() error {
+	return r.verifyThreshold(seatKeyFromStore)
+}
+
+// verifyThreshold counts valid signatures from policy seats, resolving each
+// seat's key with lookup. Receipts without a policy pass trivially.
+func (r *Receipt) verifyThreshold(lookup func(SeatSignature) (crypto.KeyRecord, error)) error {
+	if r.Threshold == nil {
+		return nil
+	}
+	if err := r.Threshold.Validate(); err != nil {
+		return err
+	}
+	valid := map[string]bool{}
+	for _, s := range r.Signatures {
+		if !slices.Contains(r.Threshold.Seats, s.Seat) || valid[s.Seat] {
+			continue
+		}
+		key, err := lookup(s)
+		if err != nil {
+			continue
+		}
+		if key.Verify(s.digest(r.Hash), s.Signature) {
+			valid[s.Seat] = true
+		}
+	}
+	if len(valid) < r.Threshold.M {
+		return fmt.Errorf("%w: %d of %d", ErrThresholdNotMet, len(valid), r.Threshold.M)
+	}
+	return nil
+}
+
+// seatKeyFromStore returns the seat key that was valid when s was made.
+func seatKeyFromStore(s SeatSignature) (crypto.KeyRecord, error) {
+	at, err := time.Parse(time.RFC3339Nano, s.SignedAt)
+	if err != nil {
+		return crypto.KeyRecord{}, fmt.Errorf("seat %s signed_at: %w", s.Seat, err)
+	}
+	return crypto.KeyAt(crypto.DefaultKeyStore(), s.Seat, s.KeyID, at)
+}
+
+// seatKeyEmbedded trusts the public key carried in the signature.
+func seatKeyEmbedded(s SeatSignature) (crypto.KeyRecord, error) {
+	return crypto.KeyRecord{KeyID: s.KeyID, PublicKeyB64: s.PublicKeyB64}, nil
+}
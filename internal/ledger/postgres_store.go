@@ -4,47 +4,336 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"sync"
+	"time"
 )
 
+// Store is the Postgres-backed ReceiptStore. Receipts live in the receipts
+// table created by db.EnsureReceiptsSchema, with checkpoints stored as rows
+// under CheckpointSchemaRef.
 type Store struct {
 	db *sql.DB
+
+	// treeMu guards tree, the Merkle tree cached for proof queries, and
+	// heads, the tree heads already signed, by size.
+	treeMu sync.Mutex
+	tree   *MerkleTree
+	heads  map[uint64]*Checkpoint
 }
 
-func (s *Store) InsertReceipt(r *Receipt) error {
-	meta, _ := json.Marshal(r.Metadata)
-	_, err := s.db.Exec(`
-	       INSERT INTO receipts (id, actor, action, created_at, hash, signature, frozen_core_hash, metadata)
-	       VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-	       ON CONFLICT (id) DO UPDATE SET
-		       actor = EXCLUDED.actor,
-		       action = EXCLUDED.action,
-		       created_at = EXCLUDED.created_at,
-		       hash = EXCLUDED.hash,
-		       signature = EXCLUDED.signature,
-		       frozen_core_hash = EXCLUDED.frozen_core_hash,
-		       metadata = EXCLUDED.metadata;
-       `, r.ReceiptID, r.By, r.Action, r.CreatedAt, r.Hash, r.Signature, r.FrozenCoreHash, string(meta))
+// ReceiptSchemaRef is the schema_ref under which ci.call.v1 receipts are stored.
+const ReceiptSchemaRef = "ci.call.v1"
+
+// receiptChainLockKey is the advisory lock that serializes chain appends.
+const receiptChainLockKey = 0x6469735f63686e // "dis_chn"
+
+var _ ReceiptStore = (*Store)(nil)
+
+// Append links r onto the Postgres receipt chain. The receipt is linked to
+// the current head inside a transaction holding an advisory lock, and a
+// signed checkpoint row is written alongside it when one is due.
+func (s *Store) Append(r *Receipt) error {
+	r.fillDefaults()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, receiptChainLockKey); err != nil {
+		return fmt.Errorf("lock receipt chain: %w", err)
+	}
+
+	head := genesisHead()
+	err = tx.QueryRow(`SELECT seq, chain_hash FROM receipts WHERE seq IS NOT NULL ORDER BY seq DESC LIMIT 1`).
+		Scan(&head.Seq, &head.ChainHash)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("read chain head: %w", err)
+	}
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM receipts WHERE receipt_id = $1)`, r.ReceiptID).Scan(&exists); err != nil {
+		return fmt.Errorf("check receipt id: %w", err)
+	}
+	if exists {
+		return ErrDuplicateReceipt
+	}
+	if err := r.stamp(); err != nil {
+		return err
+	}
+	head = head.link(r)
+
+	createdAt, err := time.Parse(time.RFC3339Nano, r.CreatedAt)
+	if err != nil {
+		createdAt = time.Now().UTC()
+	}
+	content, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+	       INSERT INTO receipts (receipt_id, schema_ref, actor, action, content, created_at, seq, hash, prev_hash, chain_hash, doc)
+	       VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11::jsonb)
+       `, r.ReceiptID, r.SchemaRef, r.By, r.Action, string(content), createdAt, r.Seq, r.Hash, r.PrevHash, r.ChainHash, string(content))
+	if err != nil {
+		return fmt.Errorf("insert receipt: %w", err)
+	}
+
+	if !checkpointDue(head.Seq) {
+		return tx.Commit()
+	}
+	// The cached tree takes the new leaves inside tx, so it is held until
+	// tx commits and dropped if it does not.
+	s.treeMu.Lock()
+	defer s.treeMu.Unlock()
+	err = s.insertCheckpointTx(tx, head)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		s.dropTree()
+	}
 	return err
 }
 
-func (s *Store) ListReceipts() ([]Receipt, error) {
-	rows, err := s.db.Query(`SELECT id, actor, action, created_at, hash, frozen_core_hash FROM receipts ORDER BY created_at DESC`)
+// insertCheckpointTx seals head with a checkpoint row. Its Merkle root
+// comes from the cached tree, brought up to head with the leaves appended
+// since it was last read. Callers must hold s.treeMu.
+func (s *Store) insertCheckpointTx(tx *sql.Tx, head ChainHead) error {
+	if err := s.refreshTreeFrom(tx); err != nil {
+		return err
+	}
+	root, err := s.tree.RootAt(head.Seq)
+	if err != nil {
+		return fmt.Errorf("seal checkpoint: %w", err)
+	}
+	cp, err := signTreeHead(head, root)
+	if err != nil {
+		return fmt.Errorf("seal checkpoint: %w", err)
+	}
+	s.cacheHead(cp)
+	content, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+	       INSERT INTO receipts (receipt_id, schema_ref, content, created_at)
+	       VALUES ($1, $2, $3, NOW())
+       `, fmt.Sprintf("checkpoint-%d", cp.Seq), CheckpointSchemaRef, string(content))
+	if err != nil {
+		return fmt.Errorf("insert checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Get returns the receipt with the given ID. Rows written before the
+// canonical envelope, whose content is plain text, come back with the text
+// under Payload["content"].
+func (s *Store) Get(id string) (*Receipt, error) {
+	var (
+		r       Receipt
+		content sql.NullString
+		created time.Time
+	)
+	err := s.db.QueryRow(`
+	       SELECT receipt_id, COALESCE(schema_ref, ''), content, created_at
+	       FROM receipts
+	       WHERE receipt_id = $1 AND schema_ref IS DISTINCT FROM $2
+       `, id, CheckpointSchemaRef).Scan(&r.ReceiptID, &r.SchemaRef, &content, &created)
+	if err == sql.ErrNoRows {
+		return nil, ErrReceiptNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeReceiptRow(r, content.String, created), nil
+}
+
+// List returns receipts newest first, skipping checkpoint rows.
+func (s *Store) List(opts ListOptions) ([]Receipt, error) {
+	opts = opts.normalize()
+	rows, err := s.db.Query(`
+	       SELECT receipt_id, COALESCE(schema_ref, ''), content, created_at
+	       FROM receipts
+	       WHERE schema_ref IS DISTINCT FROM $1
+	         AND ($2 = '' OR schema_ref = $2)
+	       ORDER BY created_at DESC, id DESC
+	       LIMIT $3 OFFSET $4
+       `, CheckpointSchemaRef, opts.SchemaRef, opts.Limit, opts.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("list receipts: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Receipt
+	for rows.Next() {
+		var (
+			r       Receipt
+			content sql.NullString
+			created time.Time
+		)
+		if err := rows.Scan(&r.ReceiptID, &r.SchemaRef, &content, &created); err != nil {
+			return nil, fmt.Errorf("scan receipt: %w", err)
+		}
+		out = append(out, *decodeReceiptRow(r, content.String, created))
+	}
+	return out, rows.Err()
+}
+
+// Search compiles opts.Query to SQL over the indexed receipt columns and
+// the doc JSONB copy of each receipt, paging by (created_at, receipt_id).
+func (s *Store) Search(opts SearchOptions) (*SearchPage, error) {
+	limit := ListOptions{Limit: opts.Limit}.normalize().Limit
+	q, err := ParseQuery(opts.Query)
 	if err != nil {
 		return nil, err
 	}
+	cur, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []any{CheckpointSchemaRef}
+	where := "schema_ref IS DISTINCT FROM $1"
+	if cur != nil {
+		where += " AND (created_at, receipt_id) < ($2, $3)"
+		args = append(args, cur.CreatedAt, cur.ReceiptID)
+	}
+	cond, qargs := q.SQL(len(args) + 1)
+	args = append(args, qargs...)
+	args = append(args, limit+1)
+
+	rows, err := s.db.Query(`
+	       SELECT receipt_id, COALESCE(schema_ref, ''), content, created_at
+	       FROM receipts
+	       WHERE `+where+` AND `+cond+`
+	       ORDER BY created_at DESC, receipt_id DESC
+	       LIMIT $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("search receipts: %w", err)
+	}
+	defer rows.Close()
+
+	page := &SearchPage{Items: []Receipt{}}
+	var last pageCursor
+	for rows.Next() {
+		var (
+			r       Receipt
+			content sql.NullString
+			created time.Time
+		)
+		if err := rows.Scan(&r.ReceiptID, &r.SchemaRef, &content, &created); err != nil {
+			return nil, fmt.Errorf("scan receipt: %w", err)
+		}
+		if len(page.Items) == limit {
+			page.NextCursor = last.encode()
+			break
+		}
+		page.Items = append(page.Items, *decodeReceiptRow(r, content.String, created))
+		last = pageCursor{CreatedAt: created, ReceiptID: r.ReceiptID}
+	}
+	return page, rows.Err()
+}
+
+// decodeReceiptRow decodes a content column into a Receipt, falling back to
+// the row's own columns for legacy free-text content.
+func decodeReceiptRow(row Receipt, content string, created time.Time) *Receipt {
+	var r Receipt
+	if err := json.Unmarshal([]byte(content), &r); err != nil || r.ReceiptID == "" {
+		r = row
+		r.Payload = map[string]any{"content": content}
+	}
+	if r.SchemaRef == "" {
+		r.SchemaRef = row.SchemaRef
+	}
+	if r.CreatedAt == "" {
+		r.CreatedAt = created.UTC().Format(time.RFC3339Nano)
+	}
+	return &r
+}
+
+// Chain returns the receipts and checkpoints stored in Postgres in chain
+// order, ready for VerifyChain. Unchained legacy receipts come first, and
+// each checkpoint follows the receipt it seals.
+func (s *Store) Chain() ([]ChainEntry, error) {
+	rows, err := s.db.Query(`
+	       SELECT receipt_id, COALESCE(schema_ref, ''), content, created_at, seq, schema_ref = $1 FROM receipts
+	       WHERE seq IS NOT NULL OR schema_ref IN ($1, $2)
+	       ORDER BY seq NULLS FIRST, id
+       `, CheckpointSchemaRef, ReceiptSchemaRef)
+	if err != nil {
+		return nil, fmt.Errorf("load chain: %w", err)
+	}
 	defer rows.Close()
 
-	var list []Receipt
+	var entries []ChainEntry
+	var checkpoints []*Checkpoint
 	for rows.Next() {
-		var r Receipt
-		rows.Scan(&r.ReceiptID, &r.By, &r.Action, &r.CreatedAt, &r.Hash, &r.FrozenCoreHash)
-		list = append(list, r)
+		var (
+			row          Receipt
+			content      sql.NullString
+			created      time.Time
+			seq          sql.NullInt64
+			isCheckpoint sql.NullBool
+		)
+		if err := rows.Scan(&row.ReceiptID, &row.SchemaRef, &content, &created, &seq, &isCheckpoint); err != nil {
+			return nil, err
+		}
+		if isCheckpoint.Bool {
+			var e ChainEntry
+			if err := json.Unmarshal([]byte(content.String), &e); err != nil {
+				return nil, fmt.Errorf("decode checkpoint %s: %w", row.ReceiptID, err)
+			}
+			checkpoints = append(checkpoints, e.Checkpoint)
+			continue
+		}
+		// Legacy ci.call.v1 rows may hold plain text, as in Get.
+		entries = append(entries, ChainEntry{Receipt: decodeReceiptRow(row, content.String, created)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return mergeCheckpoints(entries, checkpoints), nil
+}
+
+// mergeCheckpoints places each checkpoint directly after the receipt whose
+// seq it seals. Checkpoints with no matching receipt go at the end.
+func mergeCheckpoints(entries []ChainEntry, checkpoints []*Checkpoint) []ChainEntry {
+	bySeq := make(map[uint64][]*Checkpoint, len(checkpoints))
+	for _, cp := range checkpoints {
+		if cp != nil {
+			bySeq[cp.Seq] = append(bySeq[cp.Seq], cp)
+		}
+	}
+	out := make([]ChainEntry, 0, len(entries)+len(checkpoints))
+	for _, e := range entries {
+		out = append(out, e)
+		if e.Receipt != nil && e.Receipt.Seq > 0 {
+			for _, cp := range bySeq[e.Receipt.Seq] {
+				out = append(out, ChainEntry{Checkpoint: cp})
+			}
+			delete(bySeq, e.Receipt.Seq)
+		}
+	}
+	for _, cp := range checkpoints {
+		if _, left := bySeq[cp.Seq]; left {
+			out = append(out, ChainEntry{Checkpoint: cp})
+		}
+	}
+	return out
+}
+
+// VerifyChain verifies the full Postgres receipt chain.
+func (s *Store) VerifyChain() (*ChainReport, error) {
+	entries, err := s.Chain()
+	if err != nil {
+		return nil, err
 	}
-	return list, nil
+	return VerifyChain(entries), nil
 }
 
 func (s *Store) VerifyReceipt(id string) error {
-	row := s.db.QueryRow(`SELECT id FROM receipts WHERE id = $1`, id)
+	row := s.db.QueryRow(`SELECT receipt_id FROM receipts WHERE receipt_id = $1`, id)
 	var found string
 	if err := row.Scan(&found); err != nil {
 		return fmt.Errorf("receipt not found: %s", id)
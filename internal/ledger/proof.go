@@ -0,0 +1,240 @@
+package ledger
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"dis-core/internal/util/crypto"
+)
+
+// Checkpoints double as CT-style signed tree heads: Seq is the tree size and
+// MerkleRoot the root over the first Seq receipt hashes.
+
+// ReceiptProof is everything an auditor needs to check, offline, that a
+// single receipt is in the ledger.
+type ReceiptProof struct {
+	ReceiptID string          `json:"receipt_id"`
+	Inclusion *InclusionProof `json:"inclusion"`
+	TreeHead  *Checkpoint     `json:"tree_head"`
+}
+
+// LedgerConsistency proves that one signed tree head extends another.
+type LedgerConsistency struct {
+	Proof    *ConsistencyProof `json:"proof"`
+	FromHead *Checkpoint       `json:"from_head"`
+	ToHead   *Checkpoint       `json:"to_head"`
+}
+
+// ErrReceiptNotChained is returned when a receipt exists but has no position in the chain.
+var ErrReceiptNotChained = errors.New("receipt is not part of the hash chain")
+
+// VerifyReceiptProof checks that the tree head was signed by NodeDomain's
+// key in the default KeyStore and that the inclusion proof reproduces its
+// root.
+func VerifyReceiptProof(p *ReceiptProof) error {
+	if p.Inclusion == nil || p.TreeHead == nil {
+		return errors.New("incomplete receipt proof")
+	}
+	if err := verifyTreeHead(p.TreeHead); err != nil {
+		return err
+	}
+	if p.Inclusion.TreeSize != p.TreeHead.Seq {
+		return fmt.Errorf("proof is for tree size %d but head is %d", p.Inclusion.TreeSize, p.TreeHead.Seq)
+	}
+	return VerifyInclusion(p.Inclusion, p.TreeHead.MerkleRoot)
+}
+
+// VerifyLedgerConsistency checks both tree head signatures, as
+// VerifyReceiptProof does, and the consistency proof between them.
+func VerifyLedgerConsistency(c *LedgerConsistency) error {
+	if c.Proof == nil || c.FromHead == nil || c.ToHead == nil {
+		return errors.New("incomplete consistency proof")
+	}
+	for _, h := range []*Checkpoint{c.FromHead, c.ToHead} {
+		if err := verifyTreeHead(h); err != nil {
+			return err
+		}
+	}
+	if c.Proof.FromSize != c.FromHead.Seq || c.Proof.ToSize != c.ToHead.Seq {
+		return errors.New("proof sizes do not match tree heads")
+	}
+	return VerifyConsistency(c.Proof, c.FromHead.MerkleRoot, c.ToHead.MerkleRoot)
+}
+
+func verifyTreeHead(h *Checkpoint) error {
+	ok, err := h.Verify()
+	if err != nil {
+		return fmt.Errorf("tree head %d: %w", h.Seq, err)
+	}
+	if !ok {
+		return fmt.Errorf("tree head %d: invalid signature", h.Seq)
+	}
+	return nil
+}
+
+// TreeFromEntries builds the Merkle tree over the chained receipts of a ledger.
+func TreeFromEntries(entries []ChainEntry) *MerkleTree {
+	t := &MerkleTree{}
+	for _, e := range entries {
+		if e.Receipt != nil && e.Receipt.Seq > 0 {
+			t.Append(e.Receipt.Hash)
+		}
+	}
+	return t
+}
+
+// --- Postgres-backed proofs ---
+
+// querier is what refreshTree reads leaves through: the database, or the
+// transaction appending to it.
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// refreshTree brings the cached Merkle tree up to the current chain head,
+// reading only the leaves it does not hold yet. Callers must hold s.treeMu.
+func (s *Store) refreshTree() error {
+	return s.refreshTreeFrom(s.db)
+}
+
+func (s *Store) refreshTreeFrom(q querier) error {
+	if s.tree == nil {
+		s.tree = &MerkleTree{}
+	}
+	rows, err := q.Query(`SELECT seq, hash FROM receipts WHERE seq > $1 ORDER BY seq`, s.tree.Size())
+	if err != nil {
+		return fmt.Errorf("load tree leaves: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var seq uint64
+		var hash sql.NullString
+		if err := rows.Scan(&seq, &hash); err != nil {
+			return err
+		}
+		if seq != s.tree.Size()+1 {
+			s.dropTree()
+			return fmt.Errorf("receipt chain has a gap before seq %d", seq)
+		}
+		s.tree.Append(hash.String)
+	}
+	return rows.Err()
+}
+
+// maxCachedHeads bounds the signed tree heads a Store keeps; past it the
+// cache starts over.
+const maxCachedHeads = 1024
+
+// dropTree forgets the cached tree and the heads signed over it. Callers
+// must hold s.treeMu.
+func (s *Store) dropTree() {
+	s.tree = nil
+	s.heads = nil
+}
+
+// cacheHead keeps a signed tree head for reuse. The chain only grows, so
+// the head for a size never changes. Callers must hold s.treeMu.
+func (s *Store) cacheHead(cp *Checkpoint) {
+	if s.heads == nil || len(s.heads) >= maxCachedHeads {
+		s.heads = make(map[uint64]*Checkpoint)
+	}
+	s.heads[cp.Seq] = cp
+}
+
+// treeHead returns the signed tree head for the first size receipts,
+// signing it the first time it is asked for, or again once the node key
+// has changed. Callers must hold s.treeMu.
+func (s *Store) treeHead(size uint64) (*Checkpoint, error) {
+	if cp, ok := s.heads[size]; ok {
+		if key, err := crypto.DefaultKeyStore().Active(NodeDomain); err == nil && key.KeyID == cp.KeyID {
+			return cp, nil
+		}
+	}
+	head := genesisHead()
+	if size > 0 {
+		head.Seq = size
+		if err := s.db.QueryRow(`SELECT chain_hash FROM receipts WHERE seq = $1`, size).Scan(&head.ChainHash); err != nil {
+			return nil, fmt.Errorf("chain hash at %d: %w", size, err)
+		}
+	}
+	root, err := s.tree.RootAt(size)
+	if err != nil {
+		return nil, err
+	}
+	cp, err := signTreeHead(head, root)
+	if err != nil {
+		return nil, err
+	}
+	s.cacheHead(cp)
+	return cp, nil
+}
+
+// TreeHead returns the signed tree head for the current ledger, signed
+// once per chain head.
+func (s *Store) TreeHead() (*Checkpoint, error) {
+	s.treeMu.Lock()
+	defer s.treeMu.Unlock()
+	if err := s.refreshTree(); err != nil {
+		return nil, err
+	}
+	return s.treeHead(s.tree.Size())
+}
+
+// ProveReceipt returns an inclusion proof for the receipt at the given tree
+// size. A size of zero means the current head.
+func (s *Store) ProveReceipt(receiptID string, size uint64) (*ReceiptProof, error) {
+	var seq sql.NullInt64
+	var hash sql.NullString
+	err := s.db.QueryRow(`SELECT seq, hash FROM receipts WHERE receipt_id = $1`, receiptID).Scan(&seq, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReceiptNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !seq.Valid {
+		return nil, ErrReceiptNotChained
+	}
+
+	s.treeMu.Lock()
+	defer s.treeMu.Unlock()
+	if err := s.refreshTree(); err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		size = s.tree.Size()
+	}
+	incl, err := s.tree.InclusionProof(uint64(seq.Int64-1), size, hash.String)
+	if err != nil {
+		return nil, err
+	}
+	head, err := s.treeHead(size)
+	if err != nil {
+		return nil, err
+	}
+	return &ReceiptProof{ReceiptID: receiptID, Inclusion: incl, TreeHead: head}, nil
+}
+
+// ProveConsistency returns a consistency proof between two tree sizes,
+// with signed tree heads for both.
+func (s *Store) ProveConsistency(from, to uint64) (*LedgerConsistency, error) {
+	s.treeMu.Lock()
+	defer s.treeMu.Unlock()
+	if err := s.refreshTree(); err != nil {
+		return nil, err
+	}
+	proof, err := s.tree.ConsistencyProof(from, to)
+	if err != nil {
+		return nil, err
+	}
+	fromHead, err := s.treeHead(from)
+	if err != nil {
+		return nil, err
+	}
+	toHead, err := s.treeHead(to)
+	if err != nil {
+		return nil, err
+	}
+	return &LedgerConsistency{Proof: proof, FromHead: fromHead, ToHead: toHead}, nil
+}
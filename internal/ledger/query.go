@@ -0,0 +1,669 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Receipt query language. A query is a boolean filter over receipts:
+//
+//	action = "consent:grant" and actor = domain.terra
+//	  and created_at >= 2025-10-01 and created_at < 2025-11-01
+//	  and provenance.status = invalid
+//
+// Grammar:
+//
+//	expr   = term { "or" term }
+//	term   = factor { "and" factor }
+//	factor = "not" factor | "(" expr ")" | cond
+//	cond   = field op value | field "in" "(" value { "," value } ")"
+//	op     = "=" | "!=" | "<" | "<=" | ">" | ">=" | "~"
+//
+// Fields are actor (or by), action, schema_ref, receipt_id, hash, seq,
+// created_at, provenance.type/ref/status, metadata.<key> and
+// payload.<key>[.<key>...]. Values are bare words or double-quoted strings;
+// created_at takes a date (2025-10-01) or an RFC 3339 time. "~" is a
+// case-insensitive substring match. A provenance condition holds if any
+// provenance entry satisfies it; conditions on absent fields are false.
+
+// ErrBadQuery wraps query syntax errors.
+var ErrBadQuery = errors.New("invalid receipt query")
+
+// Query is a parsed receipt filter. The zero Query matches every receipt.
+type Query struct {
+	src  string
+	root queryNode
+}
+
+// ParseQuery parses a filter expression. An empty string matches all.
+func ParseQuery(src string) (*Query, error) {
+	p := &queryParser{}
+	if err := p.lex(src); err != nil {
+		return nil, err
+	}
+	q := &Query{src: src}
+	if len(p.toks) == 0 {
+		return q, nil
+	}
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, p.errorf("unexpected %q", p.toks[p.pos].text)
+	}
+	q.root = root
+	return q, nil
+}
+
+// String returns the source expression.
+func (q *Query) String() string { return q.src }
+
+// Match reports whether r satisfies the query.
+func (q *Query) Match(r *Receipt) bool {
+	if q == nil || q.root == nil {
+		return true
+	}
+	return q.root.match(&matchCtx{r: r})
+}
+
+// SQL compiles the query to a parameterized WHERE fragment over the
+// receipts table. Placeholders are numbered from firstArg.
+func (q *Query) SQL(firstArg int) (string, []any) {
+	if q == nil || q.root == nil {
+		return "TRUE", nil
+	}
+	b := &sqlBuilder{next: firstArg}
+	return q.root.sql(b), b.args
+}
+
+// ---- fields ----
+
+type fieldKind int
+
+const (
+	fieldText fieldKind = iota
+	fieldTime
+	fieldNumber
+	fieldProvenance
+	fieldDoc
+)
+
+type queryField struct {
+	name   string
+	kind   fieldKind
+	column string   // receipts column for text, time and number fields
+	path   []string // JSON path into the receipt for doc and provenance fields
+}
+
+var (
+	textColumns = map[string]string{
+		"actor": "actor", "by": "actor",
+		"action":     "action",
+		"schema_ref": "schema_ref",
+		"receipt_id": "receipt_id", "id": "receipt_id",
+		"hash": "hash",
+	}
+	provenanceAttrs = map[string]bool{"type": true, "ref": true, "status": true}
+	keySegment      = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+func parseField(name string) (queryField, error) {
+	if col, ok := textColumns[name]; ok {
+		return queryField{name: name, kind: fieldText, column: col}, nil
+	}
+	switch name {
+	case "created_at", "time":
+		return queryField{name: name, kind: fieldTime, column: "created_at"}, nil
+	case "seq":
+		return queryField{name: name, kind: fieldNumber, column: "seq"}, nil
+	}
+	parts := strings.Split(name, ".")
+	for _, p := range parts[1:] {
+		if !keySegment.MatchString(p) {
+			return queryField{}, fmt.Errorf("%w: bad key %q in %s", ErrBadQuery, p, name)
+		}
+	}
+	switch {
+	case parts[0] == "provenance" && len(parts) == 2 && provenanceAttrs[parts[1]]:
+		return queryField{name: name, kind: fieldProvenance, path: parts[1:]}, nil
+	case parts[0] == "metadata" && len(parts) == 2, parts[0] == "payload" && len(parts) >= 2:
+		return queryField{name: name, kind: fieldDoc, path: parts}, nil
+	}
+	return queryField{}, fmt.Errorf("%w: unknown field %q", ErrBadQuery, name)
+}
+
+// ---- lexer and parser ----
+
+// QuoteQueryValue renders v as a quoted query string. Quoted strings use Go
+// escape syntax, so the lexer reads back exactly v.
+func QuoteQueryValue(v string) string { return strconv.Quote(v) }
+
+type queryToken struct {
+	text   string
+	quoted bool
+}
+
+type queryParser struct {
+	toks []queryToken
+	pos  int
+}
+
+func (p *queryParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrBadQuery, fmt.Sprintf(format, args...))
+}
+
+func (p *queryParser) lex(src string) error {
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		c := rs[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')' || c == ',' || c == '~':
+			p.toks = append(p.toks, queryToken{text: string(c)})
+			i++
+		case c == '=' || c == '!' || c == '<' || c == '>':
+			if i+1 < len(rs) && rs[i+1] == '=' {
+				p.toks = append(p.toks, queryToken{text: string(rs[i : i+2])})
+				i += 2
+			} else if c == '!' {
+				return p.errorf("expected != at offset %d", i)
+			} else {
+				p.toks = append(p.toks, queryToken{text: string(c)})
+				i++
+			}
+		case c == '"':
+			j := i + 1
+			for ; j < len(rs) && rs[j] != '"'; j++ {
+				if rs[j] == '\\' && j+1 < len(rs) {
+					j++
+				}
+			}
+			if j >= len(rs) {
+				return p.errorf("unterminated string at offset %d", i)
+			}
+			text, err := strconv.Unquote(string(rs[i : j+1]))
+			if err != nil {
+				return p.errorf("bad string at offset %d", i)
+			}
+			p.toks = append(p.toks, queryToken{text: text, quoted: true})
+			i = j + 1
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && !strings.ContainsRune(`()",=!<>~`, rs[j]) {
+				j++
+			}
+			p.toks = append(p.toks, queryToken{text: string(rs[i:j])})
+			i = j
+		}
+	}
+	return nil
+}
+
+func (p *queryParser) peekKeyword(kw string) bool {
+	return p.pos < len(p.toks) && !p.toks[p.pos].quoted && strings.EqualFold(p.toks[p.pos].text, kw)
+}
+
+func (p *queryParser) next() (queryToken, error) {
+	if p.pos >= len(p.toks) {
+		return queryToken{}, p.errorf("unexpected end of query")
+	}
+	t := p.toks[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *queryParser) expect(text string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.quoted || t.text != text {
+		return p.errorf("expected %q, got %q", text, t.text)
+	}
+	return nil
+}
+
+func (p *queryParser) expr() (queryNode, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("or") {
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = &boolNode{op: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) term() (queryNode, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("and") {
+		p.pos++
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = &boolNode{op: "AND", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) factor() (queryNode, error) {
+	if p.peekKeyword("not") {
+		p.pos++
+		x, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{x: x}, nil
+	}
+	if p.pos < len(p.toks) && !p.toks[p.pos].quoted && p.toks[p.pos].text == "(" {
+		p.pos++
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	}
+	return p.cond()
+}
+
+func (p *queryParser) cond() (queryNode, error) {
+	name, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if name.quoted {
+		return nil, p.errorf("expected a field name, got %q", name.text)
+	}
+	field, err := parseField(name.text)
+	if err != nil {
+		return nil, err
+	}
+	opTok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	c := &condNode{field: field, op: strings.ToLower(opTok.text)}
+	switch {
+	case opTok.quoted:
+		return nil, p.errorf("expected an operator after %s", field.name)
+	case c.op == "in":
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		for {
+			v, err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			c.values = append(c.values, v.text)
+			sep, err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			if sep.text == ")" && !sep.quoted {
+				break
+			}
+			if sep.text != "," || sep.quoted {
+				return nil, p.errorf("expected , or ) in list, got %q", sep.text)
+			}
+		}
+	case c.op == "=" || c.op == "!=" || c.op == "<" || c.op == "<=" || c.op == ">" || c.op == ">=" || c.op == "~":
+		v, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		c.values = []string{v.text}
+	default:
+		return nil, p.errorf("unknown operator %q", opTok.text)
+	}
+	return c, c.check()
+}
+
+// ---- AST ----
+
+type queryNode interface {
+	match(*matchCtx) bool
+	sql(*sqlBuilder) string
+}
+
+type boolNode struct {
+	op          string
+	left, right queryNode
+}
+
+func (n *boolNode) match(m *matchCtx) bool {
+	if n.op == "AND" {
+		return n.left.match(m) && n.right.match(m)
+	}
+	return n.left.match(m) || n.right.match(m)
+}
+
+func (n *boolNode) sql(b *sqlBuilder) string {
+	return "(" + n.left.sql(b) + " " + n.op + " " + n.right.sql(b) + ")"
+}
+
+type notNode struct{ x queryNode }
+
+func (n *notNode) match(m *matchCtx) bool   { return !n.x.match(m) }
+func (n *notNode) sql(b *sqlBuilder) string { return "NOT " + n.x.sql(b) }
+
+type condNode struct {
+	field  queryField
+	op     string
+	values []string
+	times  []time.Time
+}
+
+// check validates the values against the field type.
+func (c *condNode) check() error {
+	switch c.field.kind {
+	case fieldTime:
+		if c.op == "~" {
+			return fmt.Errorf("%w: ~ does not apply to %s", ErrBadQuery, c.field.name)
+		}
+		for _, v := range c.values {
+			t, err := parseQueryTime(v)
+			if err != nil {
+				return fmt.Errorf("%w: %s: %v", ErrBadQuery, c.field.name, err)
+			}
+			c.times = append(c.times, t)
+		}
+	case fieldNumber:
+		if c.op == "~" {
+			return fmt.Errorf("%w: ~ does not apply to %s", ErrBadQuery, c.field.name)
+		}
+		for _, v := range c.values {
+			if _, err := strconv.ParseUint(v, 10, 64); err != nil {
+				return fmt.Errorf("%w: %s expects a number, got %q", ErrBadQuery, c.field.name, v)
+			}
+		}
+	}
+	return nil
+}
+
+func parseQueryTime(v string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("bad time %q", v)
+}
+
+// ---- evaluation ----
+
+// matchCtx holds a receipt and, once needed, its generic JSON form.
+type matchCtx struct {
+	r   *Receipt
+	doc map[string]any
+}
+
+func (m *matchCtx) document() map[string]any {
+	if m.doc == nil {
+		m.doc = map[string]any{}
+		if raw, err := json.Marshal(m.r); err == nil {
+			dec := json.NewDecoder(bytes.NewReader(raw))
+			dec.UseNumber()
+			_ = dec.Decode(&m.doc)
+		}
+	}
+	return m.doc
+}
+
+func (c *condNode) match(m *matchCtx) bool {
+	r := m.r
+	switch c.field.kind {
+	case fieldText:
+		var have string
+		switch c.field.column {
+		case "actor":
+			have = r.By
+		case "action":
+			have = r.Action
+		case "schema_ref":
+			have = r.SchemaRef
+		case "receipt_id":
+			have = r.ReceiptID
+		case "hash":
+			have = r.Hash
+		}
+		return c.matchText(have)
+	case fieldTime:
+		have, err := time.Parse(time.RFC3339Nano, r.CreatedAt)
+		if err != nil {
+			return false
+		}
+		for _, want := range c.times {
+			if compareOp(c.op, have.Compare(want)) {
+				return true
+			}
+		}
+		return false
+	case fieldNumber:
+		if r.Seq == 0 {
+			return false
+		}
+		return c.any(func(v string) bool {
+			want, _ := strconv.ParseUint(v, 10, 64)
+			return compareOp(c.op, cmpUint(r.Seq, want))
+		})
+	case fieldProvenance:
+		for _, p := range r.Provenance {
+			var have string
+			switch c.field.path[0] {
+			case "type":
+				have = p.Type
+			case "ref":
+				have = p.Ref
+			case "status":
+				have = p.Status
+			}
+			if c.matchText(have) {
+				return true
+			}
+		}
+		return false
+	case fieldDoc:
+		var node any = m.document()
+		for _, k := range c.field.path {
+			obj, ok := node.(map[string]any)
+			if !ok {
+				return false
+			}
+			if node, ok = obj[k]; !ok || node == nil {
+				return false
+			}
+		}
+		num, isNum := node.(json.Number)
+		have := jsonText(node)
+		return c.any(func(v string) bool {
+			if isNum {
+				if want, err := strconv.ParseFloat(v, 64); err == nil {
+					f, _ := num.Float64()
+					return compareOp(c.op, cmpFloat(f, want))
+				}
+			}
+			return c.textOp(have, v)
+		})
+	}
+	return false
+}
+
+// any reports whether test holds for any value (one unless op is "in").
+func (c *condNode) any(test func(string) bool) bool {
+	for _, v := range c.values {
+		if test(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *condNode) matchText(have string) bool {
+	return c.any(func(v string) bool { return c.textOp(have, v) })
+}
+
+func (c *condNode) textOp(have, want string) bool {
+	if c.op == "~" {
+		return strings.Contains(strings.ToLower(have), strings.ToLower(want))
+	}
+	return compareOp(c.op, strings.Compare(have, want))
+}
+
+// compareOp applies op to a three-way comparison result.
+func compareOp(op string, cmp int) bool {
+	switch op {
+	case "=", "in":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+func cmpUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// jsonText renders a JSON value the way Postgres' #>> operator does.
+func jsonText(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case json.Number:
+		return x.String()
+	default:
+		b, _ := json.Marshal(x)
+		return string(b)
+	}
+}
+
+// ---- SQL ----
+
+type sqlBuilder struct {
+	next int
+	args []any
+}
+
+func (b *sqlBuilder) arg(v any) string {
+	b.args = append(b.args, v)
+	b.next++
+	return fmt.Sprintf("$%d", b.next-1)
+}
+
+var sqlOps = map[string]string{"=": "=", "in": "=", "!=": "<>", "<": "<", "<=": "<=", ">": ">", ">=": ">="}
+
+// likePattern turns a substring into an ILIKE pattern.
+func likePattern(v string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(v) + "%"
+}
+
+// textSQL compares a text expression with one value.
+func (c *condNode) textSQL(b *sqlBuilder, expr, v string) string {
+	if c.op == "~" {
+		return expr + " ILIKE " + b.arg(likePattern(v))
+	}
+	return expr + " " + sqlOps[c.op] + " " + b.arg(v)
+}
+
+// each ORs the per-value fragments of an "in" list.
+func (c *condNode) each(b *sqlBuilder, frag func(i int, v string) string) string {
+	parts := make([]string, len(c.values))
+	for i, v := range c.values {
+		parts[i] = frag(i, v)
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+func (c *condNode) sql(b *sqlBuilder) string {
+	var inner string
+	switch c.field.kind {
+	case fieldText:
+		// Equality keeps the bare column so its index applies; other
+		// operators treat NULL as "" like Match does.
+		col := c.field.column
+		if c.op != "=" && c.op != "in" {
+			col = "COALESCE(" + col + ", '')"
+		}
+		inner = c.each(b, func(_ int, v string) string { return c.textSQL(b, col, v) })
+	case fieldTime:
+		inner = c.each(b, func(i int, _ string) string {
+			return "created_at " + sqlOps[c.op] + " " + b.arg(c.times[i])
+		})
+	case fieldNumber:
+		inner = c.each(b, func(_ int, v string) string {
+			n, _ := strconv.ParseUint(v, 10, 64)
+			return "seq " + sqlOps[c.op] + " " + b.arg(int64(n))
+		})
+	case fieldProvenance:
+		attr := c.field.path[0]
+		inner = c.each(b, func(_ int, v string) string {
+			if c.op == "=" || c.op == "in" {
+				// Containment lets the GIN index on doc answer equality.
+				want, _ := json.Marshal(map[string]any{"provenance": []map[string]string{{attr: v}}})
+				return "doc @> " + b.arg(string(want)) + "::jsonb"
+			}
+			return "EXISTS (SELECT 1 FROM jsonb_array_elements(CASE WHEN jsonb_typeof(doc->'provenance') = 'array' " +
+				"THEN doc->'provenance' ELSE '[]'::jsonb END) p WHERE " + c.textSQL(b, "p->>'"+attr+"'", v) + ")"
+		})
+	case fieldDoc:
+		path := "{" + strings.Join(c.field.path, ",") + "}"
+		inner = c.each(b, func(_ int, v string) string {
+			p := b.arg(path)
+			text := c.textSQL(b, "doc #>> "+p+"::text[]", v)
+			if _, err := strconv.ParseFloat(v, 64); err != nil || c.op == "~" {
+				return text
+			}
+			return "(CASE WHEN jsonb_typeof(doc #> " + p + "::text[]) = 'number' THEN (doc #>> " + p +
+				"::text[])::numeric " + sqlOps[c.op] + " " + b.arg(v) + "::numeric ELSE " + text + " END)"
+		})
+	}
+	// Conditions on missing values are false rather than NULL, so NOT
+	// behaves the same here as in Match.
+	return "COALESCE(" + inner + ", FALSE)"
+}
@@ -4,28 +4,49 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"dis-core/internal/util/crypto"
-	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
-	"time"
 )
 
-// Receipt defines the DIS ci.call.v1 structure
+// Receipt is the canonical DIS receipt envelope. Every receipt, whatever
+// emitted it, is stored in this shape through a ReceiptStore; SchemaRef says
+// which schema the Payload follows (ci.call.v1 receipts carry none).
 type Receipt struct {
-	ReceiptID      string       `json:"receipt_id"`
-	By             string       `json:"by"`
-	Action         string       `json:"action"`
-	CreatedAt      string       `json:"created_at"`
-	Hash           string       `json:"hash"`
-	Provenance     []Provenance `json:"provenance"`
-	Signature      string       `json:"signature"`
-	FrozenCoreHash string       `json:"frozen_core_hash"`
-	Metadata       Metadata     `json:"metadata"`
+	ReceiptID      string         `json:"receipt_id"`
+	SchemaRef      string         `json:"schema_ref,omitempty"`
+	By             string         `json:"by"`
+	Action         string         `json:"action"`
+	CreatedAt      string         `json:"created_at"`
+	Hash           string         `json:"hash"`
+	Provenance     []Provenance   `json:"provenance"`
+	Signature      string         `json:"signature"`
+	SigAlg         string         `json:"sig_alg,omitempty"`
+	CanonVersion   string         `json:"canon_version,omitempty"`
+	KeyID          string         `json:"key_id,omitempty"`
+	FrozenCoreHash string         `json:"frozen_core_hash"`
+	Metadata       Metadata       `json:"metadata"`
+	Payload        map[string]any `json:"payload,omitempty"`
+
+	// Seat co-signatures and the policy they must satisfy (see multisig.go).
+	Threshold  *ThresholdPolicy `json:"threshold,omitempty"`
+	Signatures []SeatSignature  `json:"signatures,omitempty"`
+
+	// Countersignature from a timestamp authority (see timestamp.go).
+	Timestamp *TimestampToken `json:"timestamp,omitempty"`
+
+	// Chain linkage, assigned by the ledger backend when the receipt is appended.
+	Seq       uint64 `json:"seq,omitempty"`
+	PrevHash  string `json:"prev_hash,omitempty"`
+	ChainHash string `json:"chain_hash,omitempty"`
 }
 
+// NodeDomain is the domain that signs what the node issues on its own
+// behalf: checkpoints, tree heads and system event receipts.
+var NodeDomain = "domain.terra"
+
 type Provenance struct {
 	Type           string   `json:"type"`
 	Ref            string   `json:"ref"`
@@ -41,37 +62,102 @@ type Metadata struct {
 	SignerPublicKeyB64 string `json:"signer_public_key_b64,omitempty"`
 }
 
-// NewReceipt creates a signed ci.call.v1 receipt for an action.
-func NewReceipt(by, action, frozenCoreHash, consoleID, issuerSeat string) *Receipt {
-	createdAt := time.Now().Format(time.RFC3339Nano)
-
-	// Payload to hash/sign (stable ordering!)
-	payload := fmt.Sprintf("%s|%s|%s|%s|%s|%s",
-		by, action, createdAt, frozenCoreHash, consoleID, issuerSeat)
-
-	// Hash payload
-	hash := sha256.Sum256([]byte(payload))
-	hashHex := hex.EncodeToString(hash[:])
-
-	// Ensure domain keys & sign hashHex
-	signer, _ := crypto.EnsureDomainKeys(by) // domain-scoped keys (e.g., "domain.terra")
-	sigB64 := signer.Sign([]byte(hashHex))
-
-	return &Receipt{
+// NewReceipt creates a signed ci.call.v1 receipt for an action. It fails,
+// rather than return an unsigned receipt, when by has no active key.
+func NewReceipt(by, action, frozenCoreHash, consoleID, issuerSeat string) (*Receipt, error) {
+	r := &Receipt{
 		ReceiptID:      generateReceiptID(),
+		SchemaRef:      ReceiptSchemaRef,
 		By:             by,
 		Action:         action,
-		CreatedAt:      createdAt,
-		Hash:           hashHex,
-		Signature:      sigB64,
+		CreatedAt:      NowRFC3339Nano(),
 		FrozenCoreHash: frozenCoreHash,
 		Metadata: Metadata{
 			IssuedFromConsole: consoleID,
 			IssuerSeat:        issuerSeat,
-			SignerPublicKeyB64: base64.StdEncoding.EncodeToString(
-				signer.Pub), // assuming crypto.Signer has Pub []byte or ed25519.PublicKey
 		},
 	}
+	if err := r.Seal(); err != nil {
+		return nil, fmt.Errorf("seal %s receipt: %w", action, err)
+	}
+	return r, nil
+}
+
+// NewEnvelope creates a signed receipt of the given schema carrying
+// payload. It fails, rather than return an unsigned receipt, when by has
+// no active key.
+func NewEnvelope(schemaRef, by, action string, payload map[string]any) (*Receipt, error) {
+	r := &Receipt{
+		ReceiptID: generateReceiptID(),
+		SchemaRef: schemaRef,
+		By:        by,
+		Action:    action,
+		CreatedAt: NowRFC3339Nano(),
+		Payload:   payload,
+	}
+	if err := r.Seal(); err != nil {
+		return nil, fmt.Errorf("seal %s receipt: %w", action, err)
+	}
+	return r, nil
+}
+
+// EmitEnvelope creates an envelope with NewEnvelope and appends it to the
+// default store.
+func EmitEnvelope(schemaRef, by, action string, payload map[string]any) (*Receipt, error) {
+	r, err := NewEnvelope(schemaRef, by, action, payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := SaveReceipt(r); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// Seal hashes the receipt over its canonical payload and signs the hash
+// with the active key of r.By in the default KeyStore. Callers that change
+// envelope fields after NewReceipt or NewEnvelope must Seal again.
+func (r *Receipt) Seal() error {
+	ks := crypto.DefaultKeyStore()
+	key, err := ks.Active(r.By)
+	if err != nil {
+		return err
+	}
+	r.CanonVersion = CanonVersion
+	r.SigAlg = SigAlgEd25519
+	r.KeyID = key.KeyID
+	hash, err := r.ComputeHash()
+	if err != nil {
+		return fmt.Errorf("hash receipt: %w", err)
+	}
+	r.Hash = hash
+
+	sig, err := ks.Sign(key.KeyID, []byte(r.Hash))
+	if err != nil {
+		return fmt.Errorf("sign receipt: %w", err)
+	}
+	r.Signature = sig
+	r.Metadata.SignerPublicKeyB64 = key.PublicKeyB64
+	return nil
+}
+
+// fillDefaults completes the envelope fields a backend needs before storing
+// a receipt that was built by hand rather than through NewReceipt.
+func (r *Receipt) fillDefaults() {
+	if r.ReceiptID == "" {
+		r.ReceiptID = generateReceiptID()
+	}
+	if r.SchemaRef == "" {
+		r.SchemaRef = ReceiptSchemaRef
+	}
+	if r.CreatedAt == "" {
+		r.CreatedAt = NowRFC3339Nano()
+	}
+	// Unsigned receipts still get a content hash so the chain commits to them.
+	if r.Hash == "" && r.Signature == "" {
+		r.CanonVersion = CanonVersion
+		r.Hash, _ = r.ComputeHash()
+	}
 }
 
 // generateReceiptID returns a random SHA-256-based identifier.
@@ -0,0 +1,100 @@
+package ledger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"dis-core/internal/bridge"
+)
+
+// Receipt signing formats. A receipt names the encoding its Hash was
+// computed over in CanonVersion and the signature scheme in SigAlg; receipts
+// with neither were signed over the pipe-joined legacy payload.
+const (
+	// CanonVersion is the canonical encoding used for new receipts:
+	// bridge.CanonicalJSON over the envelope minus the unsigned fields.
+	CanonVersion = "dis.canon.v1"
+	// SigAlgEd25519 signs the hex Hash with the domain's ed25519 key.
+	SigAlgEd25519 = "ed25519"
+)
+
+// ErrHashMismatch is returned when a receipt's content no longer hashes to
+// its recorded Hash.
+var ErrHashMismatch = errors.New("receipt hash does not match content")
+
+// unsignedFields are the top-level envelope fields left out of the
+// canonical payload: the hash and signatures themselves, the timestamp
+// countersignature, and the chain linkage assigned after signing.
+var unsignedFields = []string{"hash", "signature", "signatures", "timestamp", "seq", "prev_hash", "chain_hash"}
+
+// unsignedMetadata are metadata fields filled in by signers and verifiers.
+var unsignedMetadata = []string{"signer_public_key_b64", "verified_at", "verification_method"}
+
+// CanonicalPayload returns the bytes r.Hash is computed over for its
+// CanonVersion. The receipt is round-tripped through JSON so that a
+// verifier holding only the serialized receipt derives the same bytes.
+func (r *Receipt) CanonicalPayload() ([]byte, error) {
+	switch r.CanonVersion {
+	case "":
+		return []byte(r.legacyPayload()), nil
+	case CanonVersion:
+	default:
+		return nil, fmt.Errorf("unsupported canon_version %q", r.CanonVersion)
+	}
+
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	for _, k := range unsignedFields {
+		delete(m, k)
+	}
+	if md, ok := m["metadata"].(map[string]any); ok {
+		for _, k := range unsignedMetadata {
+			delete(md, k)
+		}
+	}
+	return bridge.CanonicalJSON(m)
+}
+
+// legacyPayload is the pipe-joined payload receipts were signed over before
+// CanonVersion existed.
+func (r *Receipt) legacyPayload() string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%s",
+		r.By, r.Action, r.CreatedAt, r.FrozenCoreHash, r.Metadata.IssuedFromConsole, r.Metadata.IssuerSeat)
+}
+
+// ComputeHash recomputes the receipt hash from its content.
+func (r *Receipt) ComputeHash() (string, error) {
+	payload, err := r.CanonicalPayload()
+	if err != nil {
+		return "", err
+	}
+	if r.CanonVersion == "" {
+		sum := sha256.Sum256(payload)
+		return hex.EncodeToString(sum[:]), nil
+	}
+	return bridge.CanonicalDigest(payload), nil
+}
+
+// checkHash verifies that r.Hash matches the receipt content.
+func (r *Receipt) checkHash() error {
+	want, err := r.ComputeHash()
+	if err != nil {
+		return err
+	}
+	if r.Hash != want {
+		return ErrHashMismatch
+	}
+	return nil
+}
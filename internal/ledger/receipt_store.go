@@ -2,6 +2,7 @@ package ledger
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -9,50 +10,256 @@ import (
 	"sync"
 )
 
-var ledgerLock sync.Mutex
+// ReceiptStore is the single persistence contract for receipts. Every
+// emitter writes through it, whatever the backend. Each backend keeps its
+// own hash chain and assigns Seq, PrevHash and ChainHash on Append.
+type ReceiptStore interface {
+	// Append links r onto the chain and persists it.
+	Append(r *Receipt) error
+	// Get returns a single receipt by ID, or ErrReceiptNotFound.
+	Get(id string) (*Receipt, error)
+	// List returns receipts newest first.
+	List(opts ListOptions) ([]Receipt, error)
+	// Search returns a page of receipts matching a query, newest first.
+	Search(opts SearchOptions) (*SearchPage, error)
+	// Chain returns every receipt and checkpoint in chain order.
+	Chain() ([]ChainEntry, error)
+}
+
+// ListOptions provides filtering/pagination parameters for ReceiptStore.List.
+type ListOptions struct {
+	Limit     int
+	Offset    int
+	SchemaRef string
+}
+
+// normalize applies the default and maximum page size.
+func (o ListOptions) normalize() ListOptions {
+	if o.Limit <= 0 || o.Limit > 500 {
+		o.Limit = 100
+	}
+	if o.Offset < 0 {
+		o.Offset = 0
+	}
+	return o
+}
+
+// ErrReceiptNotFound is returned by ReceiptStore.Get for unknown IDs.
+var ErrReceiptNotFound = errors.New("receipt not found")
 
-// Receipt represents an authoritative, signed event within DIS.
-// It records consent lineage, trust feedback, and final moral status.
-// Use canonical Receipt from receipt.go
-// import "dis-core/internal/receipts" in files that use Receipt
+// ErrDuplicateReceipt is returned by ReceiptStore.Append when the receipt ID
+// is already in the ledger.
+var ErrDuplicateReceipt = errors.New("receipt already recorded")
 
-// SaveReceipt marshals the receipt into JSON, writes an individual file,
-// and appends it to a rolling ledger.jsonl file.
-// It automatically hashes and timestamps each record.
+var (
+	defaultStoreMu sync.RWMutex
+	defaultStore   ReceiptStore = NewFileStore("receipts")
+)
+
+// DefaultStore returns the store SaveReceipt writes through. It is a
+// FileStore under ./receipts until SetDefaultStore replaces it.
+func DefaultStore() ReceiptStore {
+	defaultStoreMu.RLock()
+	defer defaultStoreMu.RUnlock()
+	return defaultStore
+}
+
+// SetDefaultStore replaces the process-wide receipt store.
+func SetDefaultStore(s ReceiptStore) {
+	defaultStoreMu.Lock()
+	defer defaultStoreMu.Unlock()
+	defaultStore = s
+}
+
+// SaveReceipt appends r to the default receipt store.
 func SaveReceipt(r *Receipt) error {
-	ledgerLock.Lock()
-	defer ledgerLock.Unlock()
+	return DefaultStore().Append(r)
+}
+
+// FileStore keeps receipts as individual JSON files plus a rolling,
+// hash-chained ledger.jsonl file in Dir.
+type FileStore struct {
+	Dir string
+
+	mu sync.Mutex
+	// Append state of ledger.jsonl. It is rebuilt from disk whenever the
+	// file size no longer matches the last write, so out-of-band edits or
+	// removals are picked up.
+	size  int64
+	chain *chainState
+}
+
+// NewFileStore returns a FileStore rooted at dir.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{Dir: dir}
+}
+
+// LedgerPath returns the path of the rolling ledger file.
+func (s *FileStore) LedgerPath() string {
+	return filepath.Join(s.Dir, "ledger.jsonl")
+}
+
+// loadChain returns the append state for the ledger file. Callers must hold s.mu.
+func (s *FileStore) loadChain() (*chainState, error) {
+	path := s.LedgerPath()
+	var size int64 = -1
+	if fi, err := os.Stat(path); err == nil {
+		size = fi.Size()
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if s.chain != nil && s.size == size {
+		return s.chain, nil
+	}
+
+	entries, err := ReadLedgerFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s.size = size
+	s.chain = chainStateFrom(entries)
+	return s.chain, nil
+}
+
+// Append links the receipt onto the hash chain, writes an individual file,
+// and appends it to the rolling ledger.jsonl file. If the ledger write
+// fails the individual file is removed again. Every CheckpointEvery
+// receipts a signed Merkle checkpoint is appended after it.
+func (s *FileStore) Append(r *Receipt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	r.fillDefaults()
+	filename := filepath.Join(s.Dir, fmt.Sprintf("%s.json", r.ReceiptID))
+	if _, err := os.Stat(filename); err == nil {
+		return ErrDuplicateReceipt
+	}
 
-	dir := "receipts"
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	chain, err := s.loadChain()
+	if err != nil {
+		return fmt.Errorf("load ledger chain: %w", err)
+	}
+	if err := r.stamp(); err != nil {
 		return err
 	}
+	// Any failure past this point leaves the cached state ahead of the file.
+	s.chain = nil
+
+	cp, err := chain.append(r)
+	if err != nil {
+		return fmt.Errorf("seal checkpoint: %w", err)
+	}
 
 	// Serialize full receipt using canonical fields
 	data, err := json.MarshalIndent(r, "", "  ")
 	if err != nil {
 		return err
 	}
+	lines, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	lines = append(lines, '\n')
+	if cp != nil {
+		cpData, err := json.Marshal(cp)
+		if err != nil {
+			return err
+		}
+		lines = append(append(lines, cpData...), '\n')
+	}
 
-	// --- 1 Save individual file ---
-	filename := filepath.Join(dir, fmt.Sprintf("%s.json", r.ReceiptID))
+	// --- 1 Save individual file ---
 	if err := os.WriteFile(filename, data, 0644); err != nil {
 		return err
 	}
 
-	// --- 2 Append to rolling ledger file ---
-	ledgerFile := filepath.Join(dir, "ledger.jsonl")
-	lf, err := os.OpenFile(ledgerFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	// --- 2 Append to rolling ledger file (and checkpoint, if due) ---
+	lf, err := os.OpenFile(s.LedgerPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
+		os.Remove(filename)
 		return err
 	}
 	defer lf.Close()
 
-	flatData, _ := json.Marshal(r)
-	if _, err := lf.Write(append(flatData, '\n')); err != nil {
+	if _, err := lf.Write(lines); err != nil {
+		// The receipt is not on the chain, so Get must not find it.
+		os.Remove(filename)
 		return err
 	}
 
-	log.Printf("[receipt] Saved receipt → %s", filename)
+	if fi, err := lf.Stat(); err == nil {
+		s.size = fi.Size()
+		s.chain = chain
+	}
+
+	log.Printf("[receipt] Saved receipt → %s (seq %d)", filename, r.Seq)
+	if cp != nil {
+		log.Printf("[receipt] Sealed checkpoint at seq %d root=%s", cp.Seq, cp.MerkleRoot[:12])
+	}
 	return nil
 }
+
+// Get reads the individual receipt file for id.
+func (s *FileStore) Get(id string) (*Receipt, error) {
+	if id == "" || filepath.Base(id) != id {
+		return nil, ErrReceiptNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(s.Dir, id+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrReceiptNotFound
+		}
+		return nil, err
+	}
+	var r Receipt
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// List returns receipts from the ledger file, newest first.
+func (s *FileStore) List(opts ListOptions) ([]Receipt, error) {
+	opts = opts.normalize()
+	entries, err := s.Chain()
+	if err != nil {
+		return nil, err
+	}
+	var out []Receipt
+	skipped := 0
+	for i := len(entries) - 1; i >= 0 && len(out) < opts.Limit; i-- {
+		r := entries[i].Receipt
+		if r == nil || (opts.SchemaRef != "" && r.SchemaRef != opts.SchemaRef) {
+			continue
+		}
+		if skipped < opts.Offset {
+			skipped++
+			continue
+		}
+		out = append(out, *r)
+	}
+	return out, nil
+}
+
+// Search scans the ledger file for receipts matching opts.Query.
+func (s *FileStore) Search(opts SearchOptions) (*SearchPage, error) {
+	entries, err := s.Chain()
+	if err != nil {
+		return nil, err
+	}
+	var all []Receipt
+	for _, e := range entries {
+		if e.Receipt != nil {
+			all = append(all, *e.Receipt)
+		}
+	}
+	return searchReceipts(all, opts)
+}
+
+// Chain reads every entry of the ledger file in order.
+func (s *FileStore) Chain() ([]ChainEntry, error) {
+	return ReadLedgerFile(s.LedgerPath())
+}
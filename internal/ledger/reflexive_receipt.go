@@ -20,9 +20,13 @@ type ReflexiveReceipt struct {
 	Context    map[string]interface{} `json:"context,omitempty"`
 }
 
+// ReflexiveSchemaRef is the schema_ref of reflexive receipts in the ledger.
+const ReflexiveSchemaRef = "reflexive.receipt.v0"
+
 // EmitReflexiveReceipt creates and saves a self-issued receipt
 // tied to moral or trust feedback events. It integrates directly
-// with the unified v0.8.8 receipt structure.
+// with the unified v0.8.8 receipt structure. The domain must hold a
+// signing key; receipts are never recorded unsigned.
 func EmitReflexiveReceipt(domainID string, e events.Event, a rules.Action) error {
 	r := ReflexiveReceipt{
 		DomainID:   domainID,
@@ -40,14 +44,17 @@ func EmitReflexiveReceipt(domainID string, e events.Event, a rules.Action) error
 	}
 	log.Printf("[reflexive] ReflexiveReceipt [%s] — %s", domainID, string(data))
 
-	// Build the new authoritative ledger receipt
-	// Use canonical Receipt struct fields only
-	receipt := &Receipt{
-		By:     domainID,
-		Action: e.Type + ":" + a.Type,
-		// Fill other canonical fields as needed, e.g. Provenance, Metadata, etc.
-	}
-
-	// Store with full integrity (UUID, hash, timestamp)
-	return SaveReceipt(receipt)
+	// The ledger receipt carries the same content, signed by the domain.
+	_, err = EmitEnvelope(ReflexiveSchemaRef, domainID, e.Type+":"+a.Type, map[string]any{
+		"domain_id":    r.DomainID,
+		"event_ref":    r.EventRef,
+		"event_type":   e.Type,
+		"action_type":  r.ActionType,
+		"trust_delta":  a.TrustDelta,
+		"ethics_delta": a.EthicsDelta,
+		"consent_ref":  a.ConsentRef,
+		"feedback_ref": a.FeedbackRef,
+		"context":      r.Context,
+	})
+	return err
 }
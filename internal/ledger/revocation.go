@@ -0,0 +1,364 @@
+package ledger
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"dis-core/internal/bridge"
+	"dis-core/internal/events"
+	"dis-core/internal/util/crypto"
+)
+
+// Receipt statuses recorded out of band; receipts themselves are immutable.
+const (
+	StatusRevoked         = "revoked"          // the revoked ref is this receipt
+	StatusRevokedUpstream = "revoked-upstream" // a receipt it depends on was revoked
+)
+
+// RevokedUpstreamEvent is the event type sent to domains owning receipts a
+// revocation reached.
+const RevokedUpstreamEvent = "receipt.revoked_upstream"
+
+// ReceiptStatus marks a receipt as affected by a revocation.
+type ReceiptStatus struct {
+	ReceiptID    string    `json:"receipt_id"`
+	Status       string    `json:"status"`
+	RevocationID string    `json:"revocation_id"`
+	RevokedRef   string    `json:"revoked_ref"`
+	Via          string    `json:"via,omitempty"` // the upstream ref it cites
+	Depth        int       `json:"depth"`         // 0 for the revoked receipt itself
+	Owner        string    `json:"owner"`         // the receipt's By domain
+	MarkedAt     time.Time `json:"marked_at"`
+}
+
+// StatusStore persists receipt statuses, keyed by receipt and revocation.
+type StatusStore interface {
+	// MarkStatus records st, replacing any status for the same receipt and
+	// revocation.
+	MarkStatus(st ReceiptStatus) error
+	// StatusesFor returns every status recorded for a receipt.
+	StatusesFor(receiptID string) ([]ReceiptStatus, error)
+	// StatusesByRevocation returns every status a revocation produced.
+	StatusesByRevocation(revocationID string) ([]ReceiptStatus, error)
+}
+
+// RevocationImpact is what a revocation reached.
+type RevocationImpact struct {
+	RevocationID string           `json:"revocation_id"`
+	RevokedRef   string           `json:"revoked_ref"`
+	Graph        *DependencyGraph `json:"graph"`
+	Statuses     []ReceiptStatus  `json:"statuses"`
+	Domains      map[string]int   `json:"domains"` // affected receipts by owner
+}
+
+// RevocationPropagator marks receipts downstream of a revoked ref and
+// notifies the domains that own them.
+type RevocationPropagator struct {
+	receipts ReceiptStore
+	statuses StatusStore
+	maxDepth int
+	now      func() time.Time
+}
+
+// NewRevocationPropagator returns a propagator over receipts, recording
+// statuses in statuses.
+func NewRevocationPropagator(receipts ReceiptStore, statuses StatusStore) *RevocationPropagator {
+	return &RevocationPropagator{receipts: receipts, statuses: statuses, now: time.Now}
+}
+
+// WithMaxDepth bounds how far propagation follows provenance links.
+func (p *RevocationPropagator) WithMaxDepth(n int) *RevocationPropagator {
+	p.maxDepth = n
+	return p
+}
+
+// ErrRevocationRefused is returned for a revocation that is not signed by
+// its revoker, or that the revoker may not make.
+var ErrRevocationRefused = errors.New("revocation refused")
+
+// RevocationDigest is what a revoker signs: the revoked ref and its type,
+// the reason and the revoker.
+func RevocationDigest(ref, refType, reason, by string) string {
+	canon, _ := bridge.CanonicalJSON(map[string]any{
+		"revoked_ref":  ref,
+		"revoked_type": refType,
+		"reason":       reason,
+		"revoked_by":   by,
+	})
+	sum := sha256.Sum256(canon)
+	return hex.EncodeToString(sum[:])
+}
+
+// Authorize checks a revocation of ref by by before it is propagated: the
+// signature must verify against by's active key in ks, and by must be the
+// receipt's signer, a party it names as affected, or this node. A ref that
+// is not a receipt only this node may revoke.
+func (p *RevocationPropagator) Authorize(ks crypto.KeyStore, ref, refType, reason, by, signature string) error {
+	key, err := ks.Active(by)
+	if err != nil {
+		return fmt.Errorf("%w: %s has no key: %v", ErrRevocationRefused, by, err)
+	}
+	if !key.Verify([]byte(RevocationDigest(ref, refType, reason, by)), signature) {
+		return fmt.Errorf("%w: signature by %s does not verify", ErrRevocationRefused, by)
+	}
+	if by == NodeDomain {
+		return nil
+	}
+	r, err := p.receipts.Get(ref)
+	if errors.Is(err, ErrReceiptNotFound) {
+		return fmt.Errorf("%w: only %s may revoke %s", ErrRevocationRefused, NodeDomain, ref)
+	}
+	if err != nil {
+		return err
+	}
+	if r.By == by || slices.Contains(affected(r), by) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is not a party to %s", ErrRevocationRefused, by, ref)
+}
+
+// affected returns the parties a receipt's payload names as affected.
+// Payloads decoded from JSON carry []any rather than []string.
+func affected(r *Receipt) []string {
+	switch ids := r.Payload["affected"].(type) {
+	case []string:
+		return ids
+	case []any:
+		var out []string
+		for _, id := range ids {
+			if s, ok := id.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// Propagate marks ref, when it is a receipt, as revoked and every receipt
+// depending on it as revoked-upstream, then emits one event per owning
+// domain. Marking is idempotent, so a revocation may be propagated again.
+func (p *RevocationPropagator) Propagate(revocationID, ref string) (*RevocationImpact, error) {
+	g, err := DependencyClosure(p.receipts, ref, p.maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	at := p.now().UTC()
+	var marked []ReceiptStatus
+	root, err := p.receipts.Get(ref)
+	switch {
+	case err == nil:
+		marked = append(marked, ReceiptStatus{
+			ReceiptID: root.ReceiptID, Status: StatusRevoked, Owner: root.By,
+		})
+	case !errors.Is(err, ErrReceiptNotFound):
+		return nil, err
+	}
+	for _, d := range g.Dependents {
+		marked = append(marked, ReceiptStatus{
+			ReceiptID: d.Receipt.ReceiptID, Status: StatusRevokedUpstream,
+			Via: d.Via, Depth: d.Depth, Owner: d.Receipt.By,
+		})
+	}
+	for i := range marked {
+		marked[i].RevocationID = revocationID
+		marked[i].RevokedRef = ref
+		marked[i].MarkedAt = at
+		if err := p.statuses.MarkStatus(marked[i]); err != nil {
+			return nil, fmt.Errorf("mark %s: %w", marked[i].ReceiptID, err)
+		}
+	}
+
+	impact := newImpact(revocationID, ref, g, marked)
+	p.notify(impact, at)
+	return impact, nil
+}
+
+// Impact reports what a revocation of ref reached, from the recorded
+// statuses and the current dependency graph.
+func (p *RevocationPropagator) Impact(revocationID, ref string) (*RevocationImpact, error) {
+	g, err := DependencyClosure(p.receipts, ref, p.maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	marked, err := p.statuses.StatusesByRevocation(revocationID)
+	if err != nil {
+		return nil, err
+	}
+	return newImpact(revocationID, ref, g, marked), nil
+}
+
+// Statuses returns the statuses recorded for a receipt.
+func (p *RevocationPropagator) Statuses(receiptID string) ([]ReceiptStatus, error) {
+	return p.statuses.StatusesFor(receiptID)
+}
+
+func newImpact(revocationID, ref string, g *DependencyGraph, marked []ReceiptStatus) *RevocationImpact {
+	if marked == nil {
+		marked = []ReceiptStatus{}
+	}
+	impact := &RevocationImpact{
+		RevocationID: revocationID,
+		RevokedRef:   ref,
+		Graph:        g,
+		Statuses:     marked,
+		Domains:      map[string]int{},
+	}
+	for _, st := range marked {
+		impact.Domains[st.Owner]++
+	}
+	return impact
+}
+
+// notify emits one event per domain owning an affected receipt.
+func (p *RevocationPropagator) notify(impact *RevocationImpact, at time.Time) {
+	byOwner := map[string][]string{}
+	for _, st := range impact.Statuses {
+		byOwner[st.Owner] = append(byOwner[st.Owner], st.ReceiptID)
+	}
+	owners := make([]string, 0, len(byOwner))
+	for o := range byOwner {
+		owners = append(owners, o)
+	}
+	sort.Strings(owners)
+	for _, owner := range owners {
+		events.Emit(events.Event{
+			ID:         fmt.Sprintf("evt-%s-%s", impact.RevocationID, owner),
+			Type:       RevokedUpstreamEvent,
+			Source:     NodeDomain,
+			Target:     owner,
+			CreatedAt:  at,
+			ConsentRef: impact.RevokedRef,
+			Context: map[string]interface{}{
+				"revocation_id": impact.RevocationID,
+				"revoked_ref":   impact.RevokedRef,
+				"receipts":      byOwner[owner],
+			},
+		})
+	}
+	log.Printf("🧯 Revocation %s reached %d receipt(s) in %d domain(s)",
+		impact.RevocationID, len(impact.Statuses), len(owners))
+}
+
+// EnsureReceiptStatusSchema creates the receipt_status table.
+func EnsureReceiptStatusSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS receipt_status (
+			receipt_id TEXT NOT NULL,
+			revocation_id TEXT NOT NULL,
+			status TEXT NOT NULL,
+			revoked_ref TEXT NOT NULL,
+			via TEXT,
+			depth INT NOT NULL DEFAULT 0,
+			owner TEXT,
+			marked_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (receipt_id, revocation_id)
+		);
+		CREATE INDEX IF NOT EXISTS receipt_status_revocation_idx ON receipt_status (revocation_id);
+	`)
+	return err
+}
+
+// PGStatusStore keeps receipt statuses in Postgres.
+type PGStatusStore struct {
+	db *sql.DB
+}
+
+// NewPGStatusStore returns a store over db. EnsureReceiptStatusSchema must
+// have been run.
+func NewPGStatusStore(db *sql.DB) *PGStatusStore {
+	return &PGStatusStore{db: db}
+}
+
+func (s *PGStatusStore) MarkStatus(st ReceiptStatus) error {
+	_, err := s.db.Exec(`
+		INSERT INTO receipt_status (receipt_id, revocation_id, status, revoked_ref, via, depth, owner, marked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (receipt_id, revocation_id) DO UPDATE
+		SET status = EXCLUDED.status, revoked_ref = EXCLUDED.revoked_ref, via = EXCLUDED.via,
+		    depth = EXCLUDED.depth, owner = EXCLUDED.owner, marked_at = EXCLUDED.marked_at`,
+		st.ReceiptID, st.RevocationID, st.Status, st.RevokedRef, st.Via, st.Depth, st.Owner, st.MarkedAt)
+	return err
+}
+
+func (s *PGStatusStore) StatusesFor(receiptID string) ([]ReceiptStatus, error) {
+	return s.query(`WHERE receipt_id = $1 ORDER BY marked_at`, receiptID)
+}
+
+func (s *PGStatusStore) StatusesByRevocation(revocationID string) ([]ReceiptStatus, error) {
+	return s.query(`WHERE revocation_id = $1 ORDER BY depth, receipt_id`, revocationID)
+}
+
+func (s *PGStatusStore) query(where string, arg string) ([]ReceiptStatus, error) {
+	rows, err := s.db.Query(`
+		SELECT receipt_id, revocation_id, status, revoked_ref, COALESCE(via, ''), depth, COALESCE(owner, ''), marked_at
+		FROM receipt_status `+where, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ReceiptStatus{}
+	for rows.Next() {
+		var st ReceiptStatus
+		if err := rows.Scan(&st.ReceiptID, &st.RevocationID, &st.Status, &st.RevokedRef,
+			&st.Via, &st.Depth, &st.Owner, &st.MarkedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
+
+// MemoryStatusStore is an in-process StatusStore.
+type MemoryStatusStore struct {
+	mu       sync.RWMutex
+	statuses []ReceiptStatus
+}
+
+// NewMemoryStatusStore returns an empty in-memory status store.
+func NewMemoryStatusStore() *MemoryStatusStore {
+	return &MemoryStatusStore{}
+}
+
+func (s *MemoryStatusStore) MarkStatus(st ReceiptStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, have := range s.statuses {
+		if have.ReceiptID == st.ReceiptID && have.RevocationID == st.RevocationID {
+			s.statuses[i] = st
+			return nil
+		}
+	}
+	s.statuses = append(s.statuses, st)
+	return nil
+}
+
+func (s *MemoryStatusStore) StatusesFor(receiptID string) ([]ReceiptStatus, error) {
+	return s.filter(func(st ReceiptStatus) bool { return st.ReceiptID == receiptID }), nil
+}
+
+func (s *MemoryStatusStore) StatusesByRevocation(revocationID string) ([]ReceiptStatus, error) {
+	out := s.filter(func(st ReceiptStatus) bool { return st.RevocationID == revocationID })
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Depth < out[j].Depth })
+	return out, nil
+}
+
+func (s *MemoryStatusStore) filter(keep func(ReceiptStatus) bool) []ReceiptStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []ReceiptStatus{}
+	for _, st := range s.statuses {
+		if keep(st) {
+			out = append(out, st)
+		}
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+package ledger
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrBadCursor is returned for a pagination cursor the store did not issue.
+var ErrBadCursor = errors.New("invalid search cursor")
+
+// SearchOptions selects one page of receipts matching Query, newest first.
+// Cursor is the NextCursor of the previous page, or empty for the first.
+type SearchOptions struct {
+	Query  string
+	Limit  int
+	Cursor string
+}
+
+// SearchPage is one page of search results.
+type SearchPage struct {
+	Items      []Receipt `json:"items"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// pageCursor is the position after the last receipt of a page: results are
+// ordered by (created_at, receipt_id) descending, so the next page starts
+// strictly below it.
+type pageCursor struct {
+	CreatedAt time.Time
+	ReceiptID string
+}
+
+func (c pageCursor) encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ReceiptID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (*pageCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrBadCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return nil, ErrBadCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return nil, ErrBadCursor
+	}
+	return &pageCursor{CreatedAt: t, ReceiptID: id}, nil
+}
+
+// before reports whether (t, id) sorts after the cursor in result order.
+func (c *pageCursor) before(t time.Time, id string) bool {
+	if c == nil {
+		return true
+	}
+	if cmp := t.Compare(c.CreatedAt); cmp != 0 {
+		return cmp < 0
+	}
+	return id < c.ReceiptID
+}
+
+// searchReceipts pages through an in-memory receipt set; the file and
+// memory stores share it.
+func searchReceipts(all []Receipt, opts SearchOptions) (*SearchPage, error) {
+	limit := ListOptions{Limit: opts.Limit}.normalize().Limit
+	q, err := ParseQuery(opts.Query)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	type hit struct {
+		at time.Time
+		r  *Receipt
+	}
+	var hits []hit
+	for i := range all {
+		r := &all[i]
+		at, _ := time.Parse(time.RFC3339Nano, r.CreatedAt)
+		if cur.before(at, r.ReceiptID) && q.Match(r) {
+			hits = append(hits, hit{at, r})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if c := hits[i].at.Compare(hits[j].at); c != 0 {
+			return c > 0
+		}
+		return hits[i].r.ReceiptID > hits[j].r.ReceiptID
+	})
+
+	page := &SearchPage{Items: []Receipt{}}
+	for i, h := range hits {
+		if i == limit {
+			last := hits[i-1]
+			page.NextCursor = pageCursor{CreatedAt: last.at, ReceiptID: last.r.ReceiptID}.encode()
+			break
+		}
+		page.Items = append(page.Items, *h.r)
+	}
+	return page, nil
+}
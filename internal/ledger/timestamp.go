@@ -0,0 +1,258 @@
+package ledger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"dis-core/internal/bridge"
+	"dis-core/internal/util/crypto"
+)
+
+// Trusted timestamping, modelled on RFC 3161. A TimestampAuthority
+// countersigns a receipt's Hash with a token carrying a serial number and
+// generation time; the token is stored on the receipt, outside the signed
+// payload, when the receipt is appended to a store.
+
+// TSAPolicy is the policy identifier local authorities issue tokens under.
+const TSAPolicy = "dis.tsa.local.v1"
+
+// HashAlgSHA256 names the digest a message imprint was computed with.
+const HashAlgSHA256 = "sha256"
+
+// MaxClockSkew is how far a receipt's CreatedAt may run ahead of its
+// timestamp token before the receipt is rejected as post-dated.
+var MaxClockSkew = 5 * time.Minute
+
+var (
+	ErrTimestampImprint    = errors.New("timestamp token does not cover the receipt hash")
+	ErrTimestampSignature  = errors.New("invalid timestamp token signature")
+	ErrTimestampRegression = errors.New("timestamp goes backwards relative to the chain")
+)
+
+// MessageImprint is the digest a token attests to (RFC 3161 §2.4.1).
+type MessageImprint struct {
+	HashAlgorithm string `json:"hash_algorithm"`
+	HashedMessage string `json:"hashed_message"`
+}
+
+// TimestampRequest asks an authority to timestamp an imprint. The nonce, if
+// set, is echoed in the token so the requester can match the response.
+type TimestampRequest struct {
+	MessageImprint MessageImprint `json:"message_imprint"`
+	Nonce          string         `json:"nonce,omitempty"`
+}
+
+// TimestampToken is the signed TSTInfo of RFC 3161 §2.4.2, in JSON. The
+// signature covers the canonical JSON of every field but itself and the
+// embedded public key.
+type TimestampToken struct {
+	Version        int            `json:"version"`
+	Policy         string         `json:"policy"`
+	MessageImprint MessageImprint `json:"message_imprint"`
+	SerialNumber   uint64         `json:"serial_number"`
+	GenTime        string         `json:"gen_time"`
+	Ordering       bool           `json:"ordering"`
+	Nonce          string         `json:"nonce,omitempty"`
+	TSA            string         `json:"tsa"`
+	KeyID          string         `json:"key_id"`
+	PublicKeyB64   string         `json:"public_key_b64,omitempty"`
+	Signature      string         `json:"signature"`
+}
+
+// TimestampAuthority issues timestamp tokens.
+type TimestampAuthority interface {
+	Timestamp(req TimestampRequest) (*TimestampToken, error)
+}
+
+// tstInfo returns the bytes the token signature covers.
+func (t *TimestampToken) tstInfo() ([]byte, error) {
+	cp := *t
+	cp.Signature, cp.PublicKeyB64 = "", ""
+	return bridge.CanonicalJSON(cp)
+}
+
+// Time parses GenTime.
+func (t *TimestampToken) Time() (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, t.GenTime)
+}
+
+// Verify checks that the token covers hash and was signed by a key the TSA
+// held at GenTime, according to the default KeyStore.
+func (t *TimestampToken) Verify(hash string) error {
+	return t.verify(hash, func(t *TimestampToken, at time.Time) (crypto.KeyRecord, error) {
+		return crypto.KeyAt(crypto.DefaultKeyStore(), t.TSA, t.KeyID, at)
+	})
+}
+
+// verifyEmbedded checks the token against the public key it carries.
+func (t *TimestampToken) verifyEmbedded(hash string) error {
+	return t.verify(hash, func(t *TimestampToken, _ time.Time) (crypto.KeyRecord, error) {
+		return crypto.KeyRecord{KeyID: t.KeyID, PublicKeyB64: t.PublicKeyB64}, nil
+	})
+}
+
+func (t *TimestampToken) verify(hash string, lookup func(*TimestampToken, time.Time) (crypto.KeyRecord, error)) error {
+	if t.MessageImprint.HashAlgorithm != HashAlgSHA256 || t.MessageImprint.HashedMessage != hash {
+		return ErrTimestampImprint
+	}
+	at, err := t.Time()
+	if err != nil {
+		return fmt.Errorf("timestamp gen_time: %w", err)
+	}
+	key, err := lookup(t, at)
+	if err != nil {
+		return err
+	}
+	info, err := t.tstInfo()
+	if err != nil {
+		return err
+	}
+	if !key.Verify(info, t.Signature) {
+		return ErrTimestampSignature
+	}
+	return nil
+}
+
+// LocalTSA is a timestamp authority backed by a domain key in the default
+// KeyStore. Its tokens are strictly ordered: each has a higher serial
+// number and a later GenTime than the one before, even if the clock steps
+// back.
+type LocalTSA struct {
+	Name string
+	// Now is the authority's clock; tests substitute a fixed one.
+	Now func() time.Time
+
+	mu     sync.Mutex
+	serial uint64
+	last   time.Time
+}
+
+// NewLocalTSA returns an authority that signs with name's active key.
+func NewLocalTSA(name string) *LocalTSA {
+	return &LocalTSA{Name: name, Now: time.Now}
+}
+
+// Resume continues numbering after tok, typically the last token in the
+// chain, so a restarted authority never issues an earlier time or serial.
+func (a *LocalTSA) Resume(tok *TimestampToken) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if tok.SerialNumber > a.serial {
+		a.serial = tok.SerialNumber
+	}
+	if t, err := tok.Time(); err == nil && t.After(a.last) {
+		a.last = t
+	}
+}
+
+// Timestamp issues a signed token for req.
+func (a *LocalTSA) Timestamp(req TimestampRequest) (*TimestampToken, error) {
+	if req.MessageImprint.HashAlgorithm != HashAlgSHA256 || req.MessageImprint.HashedMessage == "" {
+		return nil, fmt.Errorf("unsupported message imprint %q", req.MessageImprint.HashAlgorithm)
+	}
+	ks := crypto.DefaultKeyStore()
+	key, err := ks.Active(a.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.Now().UTC()
+	if !now.After(a.last) {
+		now = a.last.Add(time.Nanosecond)
+	}
+	tok := &TimestampToken{
+		Version:        1,
+		Policy:         TSAPolicy,
+		MessageImprint: req.MessageImprint,
+		SerialNumber:   a.serial + 1,
+		GenTime:        now.Format(time.RFC3339Nano),
+		Ordering:       true,
+		Nonce:          req.Nonce,
+		TSA:            a.Name,
+		KeyID:          key.KeyID,
+		PublicKeyB64:   key.PublicKeyB64,
+	}
+	info, err := tok.tstInfo()
+	if err != nil {
+		return nil, err
+	}
+	if tok.Signature, err = ks.Sign(key.KeyID, info); err != nil {
+		return nil, err
+	}
+	a.serial, a.last = tok.SerialNumber, now
+	return tok, nil
+}
+
+var (
+	defaultTSAMu sync.RWMutex
+	defaultTSA   TimestampAuthority
+)
+
+// DefaultTSA returns the authority stores timestamp receipts with, or nil
+// when timestamping is off.
+func DefaultTSA() TimestampAuthority {
+	defaultTSAMu.RLock()
+	defer defaultTSAMu.RUnlock()
+	return defaultTSA
+}
+
+// SetDefaultTSA sets the authority receipts are timestamped with on
+// Append. A nil authority turns timestamping off.
+func SetDefaultTSA(a TimestampAuthority) {
+	defaultTSAMu.Lock()
+	defer defaultTSAMu.Unlock()
+	defaultTSA = a
+}
+
+// stamp requests a timestamp token for r from the default authority.
+// Stores call it while holding their append lock, so token order follows
+// chain order.
+func (r *Receipt) stamp() error {
+	tsa := DefaultTSA()
+	if tsa == nil || r.Timestamp != nil || r.Hash == "" {
+		return nil
+	}
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("timestamp nonce: %w", err)
+	}
+	tok, err := tsa.Timestamp(TimestampRequest{
+		MessageImprint: MessageImprint{HashAlgorithm: HashAlgSHA256, HashedMessage: r.Hash},
+		Nonce:          hex.EncodeToString(nonce),
+	})
+	if err != nil {
+		return fmt.Errorf("timestamp receipt: %w", err)
+	}
+	r.Timestamp = tok
+	return nil
+}
+
+// checkTimestamp verifies r's token with verify and, when prev is the
+// token of the preceding timestamped receipt, that time has not gone
+// backwards. It also rejects receipts created well after they were stamped.
+func (r *Receipt) checkTimestamp(prev *TimestampToken, verify func(*TimestampToken, string) error) error {
+	tok := r.Timestamp
+	if err := verify(tok, r.Hash); err != nil {
+		return err
+	}
+	at, _ := tok.Time()
+	if created, err := time.Parse(time.RFC3339Nano, r.CreatedAt); err == nil && created.Sub(at) > MaxClockSkew {
+		return fmt.Errorf("%w: created_at %s is after gen_time %s", ErrTimestampRegression, r.CreatedAt, tok.GenTime)
+	}
+	if prev == nil {
+		return nil
+	}
+	if prevAt, err := prev.Time(); err == nil && at.Before(prevAt) {
+		return fmt.Errorf("%w: gen_time %s precedes %s", ErrTimestampRegression, tok.GenTime, prev.GenTime)
+	}
+	if prev.TSA == tok.TSA && tok.SerialNumber <= prev.SerialNumber {
+		return fmt.Errorf("%w: serial %d follows %d", ErrTimestampRegression, tok.SerialNumber, prev.SerialNumber)
+	}
+	return nil
+}
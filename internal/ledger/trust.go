@@ -7,7 +7,9 @@ import (
 	"time"
 )
 
-// TrustEntry represents one verification event between peers.
+// TrustEntry represents one verification event between peers. Signed
+// entries are attestations: Attester vouches that it verified the receipt
+// from Origin's ledger at VerifiedAt, running CoreHash.
 type TrustEntry struct {
 	Peer       string    `json:"peer"`
 	Action     string    `json:"action"` // "sent" or "received"
@@ -16,9 +18,17 @@ type TrustEntry struct {
 	CoreHash   string    `json:"core_hash"`
 	VerifiedAt time.Time `json:"verified_at"`
 	Notes      string    `json:"notes,omitempty"`
+
+	Attester     string `json:"attester,omitempty"`
+	Origin       string `json:"origin,omitempty"`
+	ReceiptHash  string `json:"receipt_hash,omitempty"`
+	KeyID        string `json:"key_id,omitempty"`
+	PublicKeyB64 string `json:"public_key_b64,omitempty"`
+	Signature    string `json:"signature,omitempty"`
 }
 
-// TrustLedger stores all trust events persistently.
+// TrustLedger stores all trust events in one JSON file; with no Path it
+// only keeps them in memory. Nodes with a database use PGTrustStore.
 type TrustLedger struct {
 	mu      sync.Mutex
 	Entries []TrustEntry `json:"entries"`
@@ -44,14 +54,47 @@ func LoadTrustLedger(path string) (*TrustLedger, error) {
 }
 
 // Add appends a new entry and writes the updated ledger back to disk.
+// Attestations with an invalid signature are refused.
 func (l *TrustLedger) Add(entry TrustEntry) error {
+	if entry.IsAttestation() {
+		if err := entry.VerifyAttestation(); err != nil {
+			return err
+		}
+	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	l.Entries = append(l.Entries, entry)
+	if l.Path == "" {
+		return nil
+	}
 	data, err := json.MarshalIndent(l, "", "  ")
 	if err != nil {
 		return err
 	}
 	return os.WriteFile(l.Path, data, 0644)
 }
+
+// ForReceipt returns the entries about receiptID in the order added.
+func (l *TrustLedger) ForReceipt(receiptID string) ([]TrustEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []TrustEntry
+	for _, e := range l.Entries {
+		if e.ReceiptID == receiptID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// AttesterCounts returns, for each of receiptIDs, the number of
+// independent peers attesting it.
+func (l *TrustLedger) AttesterCounts(receiptIDs []string) (map[string]int, error) {
+	out := make(map[string]int, len(receiptIDs))
+	for _, id := range receiptIDs {
+		entries, _ := l.ForReceipt(id)
+		out[id] = CountAttesters(entries)
+	}
+	return out, nil
+}
@@ -6,8 +6,16 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
 )
 
+// VerifyReceiptJSON recomputes the receipt hash from its content and checks
+// the signature against the key of the signing domain that was valid at the
+// receipt's created_at (or its timestamp token's gen_time), looked up in the
+// default KeyStore. Receipts without
+// a canon_version are hashed with the legacy pipe-joined payload; receipts
+// without a key_id are checked against whichever key was valid then.
 func VerifyReceiptJSON(jsonBytes []byte) (bool, error) {
 	var r Receipt
 	if err := json.Unmarshal(jsonBytes, &r); err != nil {
@@ -16,13 +24,69 @@ func VerifyReceiptJSON(jsonBytes []byte) (bool, error) {
 	if r.Hash == "" || r.Signature == "" || r.By == "" {
 		return false, errors.New("missing required fields for verification")
 	}
-	// Load domain keys (or you could reconstruct from Metadata.SignerPublicKeyB64)
-	signer, err := crypto.EnsureDomainKeys(r.By)
-	if err != nil {
+	keys := StoreKeys(nil)
+	if r.Timestamp != nil {
+		if err := r.checkTimestamp(nil, keys.timestamps()); err != nil {
+			return false, err
+		}
+	}
+	if err := r.verifyKeys(keys); err != nil {
+		if errors.Is(err, ErrSignatureInvalid) {
+			return false, nil
+		}
 		return false, err
 	}
-	ok := signer.Verify([]byte(r.Hash), r.Signature)
-	return ok, nil
+	return true, nil
+}
+
+// VerifyWith checks r's hash, signature and timestamp token against the
+// keys resolved by keys, as VerifyReceiptJSON does with the default
+// KeyStore.
+func (r *Receipt) VerifyWith(keys KeyResolver) error {
+	if r.Hash == "" || r.Signature == "" || r.By == "" {
+		return errors.New("missing required fields for verification")
+	}
+	if r.Timestamp != nil {
+		if err := r.checkTimestamp(nil, keys.timestamps()); err != nil {
+			return err
+		}
+	}
+	return r.verifyKeys(keys)
+}
+
+// ErrSignatureInvalid is returned when a signature does not verify against
+// the key its signer held at the time.
+var ErrSignatureInvalid = errors.New("signature does not verify")
+
+// verifyKeys recomputes r's hash and checks its signature, and any
+// threshold signatures, against the keys resolved for the signers. A
+// timestamp token bounds when the signature existed, so key validity is
+// judged at its gen_time rather than the issuer's own clock; the token
+// itself is checked by checkTimestamp.
+func (r *Receipt) verifyKeys(keys KeyResolver) error {
+	if err := r.checkSigAlg(); err != nil {
+		return err
+	}
+	if err := r.checkHash(); err != nil {
+		return err
+	}
+	signedAt, err := time.Parse(time.RFC3339Nano, r.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("created_at: %w", err)
+	}
+	if r.Timestamp != nil {
+		if at, err := r.Timestamp.Time(); err == nil {
+			signedAt = at
+		}
+	}
+	key, err := keys(r.By, r.KeyID, signedAt)
+	if err != nil {
+		return err
+	}
+	if !key.Verify([]byte(r.Hash), r.Signature) {
+		return ErrSignatureInvalid
+	}
+	return r.verifyThreshold(keys.seats())
 }
 
 // VerifyWithEmbeddedPub uses the embedded pubkey if present (no disk access).
@@ -31,18 +95,50 @@ func VerifyWithEmbeddedPub(jsonBytes []byte) (bool, error) {
 	if err := json.Unmarshal(jsonBytes, &r); err != nil {
 		return false, err
 	}
+	if r.Timestamp != nil {
+		if err := r.checkTimestamp(nil, (*TimestampToken).verifyEmbedded); err != nil {
+			return false, err
+		}
+	}
+	return r.verifyEmbedded()
+}
+
+// verifyEmbedded recomputes the receipt hash and checks the signature
+// against Metadata.SignerPublicKeyB64.
+func (r *Receipt) verifyEmbedded() (bool, error) {
 	if r.Hash == "" || r.Signature == "" || r.Metadata.SignerPublicKeyB64 == "" {
 		return false, errors.New("insufficient data")
 	}
+	if err := r.checkSigAlg(); err != nil {
+		return false, err
+	}
+	if err := r.checkHash(); err != nil {
+		return false, err
+	}
 
 	pub, err := base64.StdEncoding.DecodeString(r.Metadata.SignerPublicKeyB64)
 	if err != nil {
 		return false, err
 	}
 
-	//  make an addressable value (or use &literal) and cast pub to the right type
+	//  make an addressable value (or use &literal) and cast pub to the right type
 	s := &crypto.Signer{Pub: ed25519.PublicKey(pub)}
-	return s.Verify([]byte(r.Hash), r.Signature), nil
+	if !s.Verify([]byte(r.Hash), r.Signature) {
+		return false, nil
+	}
+	if err := r.verifyThreshold(seatKeyEmbedded); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// checkSigAlg rejects signature schemes this verifier does not implement.
+// Legacy receipts carry no sig_alg and were always ed25519.
+func (r *Receipt) checkSigAlg() error {
+	if r.SigAlg != "" && r.SigAlg != SigAlgEd25519 {
+		return fmt.Errorf("unsupported sig_alg %q", r.SigAlg)
+	}
+	return nil
 }
 
 // DecodePublicKey converts a base64 string to an ed25519.PublicKey.
@@ -0,0 +1,260 @@
+package net
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"dis-core/internal/ledger"
+)
+
+// Attestation message types.
+const (
+	MsgAttestRequest = "attest.request" // receipts the sender wants attested
+	MsgAttestations  = "attest.response"
+)
+
+// DefaultAttestBatch is how many receipts one attestation request carries.
+const DefaultAttestBatch = 64
+
+// AttestRequest asks a peer to verify receipts from the sender's ledger.
+type AttestRequest struct {
+	Receipts []ledger.Receipt `json:"receipts"`
+}
+
+// Attestations answers an AttestRequest with one signed entry per receipt.
+type Attestations struct {
+	Entries []ledger.TrustEntry `json:"entries"`
+}
+
+// Attestor has peers vouch for this node's receipts. Over every session it
+// asks the peer to attest local receipts the peer has not attested yet;
+// the peer verifies each receipt's hash and signature against the keys it
+// knows the signer by, checks it against its replica of this node's chain
+// when it holds one, and answers with attestations signed by its node key.
+// Both sides keep the attestations in the store.
+type Attestor struct {
+	mgr      *Manager
+	local    ledger.ReceiptStore
+	replicas ReplicaStore
+	store    ledger.AttestationStore
+	batch    int
+
+	mu       sync.Mutex
+	cursor   map[string]int         // chain position attested through, by peer domain
+	inflight map[string]attestBatch // unanswered request, by peer domain
+}
+
+// attestBatch is an attestation request awaiting its answer.
+type attestBatch struct {
+	through int               // chain position the request covers up to
+	hashes  map[string]string // receipt hash by ID, for each receipt asked
+	sent    time.Time
+}
+
+// NewAttestor registers attestation on m. local holds the receipts this
+// node asks peers to attest; replicas, if not nil, are checked when
+// attesting a peer's receipts.
+func NewAttestor(m *Manager, local ledger.ReceiptStore, replicas ReplicaStore, store ledger.AttestationStore) *Attestor {
+	a := &Attestor{
+		mgr:      m,
+		local:    local,
+		replicas: replicas,
+		store:    store,
+		batch:    DefaultAttestBatch,
+		cursor:   map[string]int{},
+		inflight: map[string]attestBatch{},
+	}
+	m.Handle(MsgAttestRequest, a.onRequest)
+	m.Handle(MsgAttestations, a.onAttestations)
+	m.OnConnect(a.requestFrom)
+	return a
+}
+
+// WithBatch sets how many receipts each request carries.
+func (a *Attestor) WithBatch(n int) *Attestor {
+	if n > 0 {
+		a.batch = n
+	}
+	return a
+}
+
+// Run asks every connected peer for attestations every interval until
+// stop is closed.
+func (a *Attestor) Run(interval time.Duration, stop <-chan struct{}) {
+	if interval <= 0 {
+		interval = DefaultAntiEntropyInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			for _, s := range a.mgr.Sessions() {
+				a.requestFrom(s)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// requestFrom sends s the next batch of local receipts its peer has not
+// attested. A batch left unanswered for HandshakeTimeout is asked for
+// again.
+func (a *Attestor) requestFrom(s *Session) {
+	peer := s.Peer.Domain
+	a.mu.Lock()
+	if b, ok := a.inflight[peer]; ok && time.Since(b.sent) < HandshakeTimeout {
+		a.mu.Unlock()
+		return
+	}
+	pos := a.cursor[peer]
+	a.mu.Unlock()
+	chain, err := a.local.Chain()
+	if err != nil {
+		log.Printf("⚠️ attestation: %v", err)
+		return
+	}
+
+	var req AttestRequest
+	hashes := map[string]string{}
+	for ; pos < len(chain) && len(req.Receipts) < a.batch; pos++ {
+		r := chain[pos].Receipt
+		if r == nil || r.Signature == "" {
+			continue
+		}
+		if a.attestedBy(r.ReceiptID, peer) {
+			continue
+		}
+		req.Receipts = append(req.Receipts, *r)
+		hashes[r.ReceiptID] = r.Hash
+	}
+	a.mu.Lock()
+	if len(req.Receipts) == 0 {
+		a.cursor[peer] = pos
+	} else {
+		a.inflight[peer] = attestBatch{through: pos, hashes: hashes, sent: time.Now()}
+	}
+	a.mu.Unlock()
+	if len(req.Receipts) > 0 {
+		_ = s.Send(MsgAttestRequest, req)
+	}
+}
+
+func (a *Attestor) attestedBy(receiptID, peer string) bool {
+	entries, err := a.store.ForReceipt(receiptID)
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if e.IsAttestation() && e.Attester == peer {
+			return true
+		}
+	}
+	return false
+}
+
+// onRequest verifies and attests the receipts a peer sent from its ledger.
+// Receipts that fail count toward the peer's demotion; ones that verify do
+// not promote it, since a peer can sign as many receipts as it likes.
+func (a *Attestor) onRequest(s *Session, msg Message) {
+	var req AttestRequest
+	if err := msg.Decode(&req); err != nil {
+		log.Printf("⚠️ %s: %v", s.Peer.Domain, err)
+		return
+	}
+	id := a.mgr.nodeIdentity()
+	if id == nil {
+		return
+	}
+	resp := Attestations{Entries: []ledger.TrustEntry{}}
+	for i := range req.Receipts {
+		r := &req.Receipts[i]
+		ok, notes := a.verify(s.Peer.Domain, r)
+		if !ok {
+			a.mgr.ReportFailure(s, notes)
+		}
+		e := ledger.NewAttestation(r, s.Peer.Domain, id.Domain, id.CoreHash, ok, notes)
+		if err := e.SignAttestation(id.Keys); err != nil {
+			log.Printf("⚠️ attestation: %v", err)
+			return
+		}
+		e.Peer, e.Action = s.Peer.Domain, "sent"
+		if err := a.store.Add(e); err != nil {
+			log.Printf("⚠️ attestation store: %v", err)
+		}
+		resp.Entries = append(resp.Entries, e)
+	}
+	_ = s.Send(MsgAttestations, resp)
+}
+
+// verify checks r's hash and signature against the key its signer is
+// known to have held and, when this node replicates origin's ledger, that
+// r is the receipt the replica holds at its seq.
+func (a *Attestor) verify(origin string, r *ledger.Receipt) (bool, string) {
+	if err := r.VerifyWith(a.mgr.peerKeys); err != nil {
+		return false, err.Error()
+	}
+	if a.replicas == nil || r.Seq == 0 {
+		return true, ""
+	}
+	entries, err := a.replicas.Entries(origin, r.Seq, r.Seq)
+	if err != nil {
+		return false, err.Error()
+	}
+	for _, fe := range entries {
+		if rr := fe.Entry.Receipt; rr != nil && (rr.ReceiptID != r.ReceiptID || rr.ChainHash != r.ChainHash) {
+			return false, "conflicts with the replicated chain"
+		}
+	}
+	return true, ""
+}
+
+// onAttestations stores the attestations a peer returned. Only entries the
+// peer signed with the key it authenticated with are kept. A peer that
+// attests every receipt of the batch asked of it has verified a chain it
+// did not write, which counts toward its promotion.
+func (a *Attestor) onAttestations(s *Session, msg Message) {
+	var resp Attestations
+	if err := msg.Decode(&resp); err != nil {
+		log.Printf("⚠️ %s: %v", s.Peer.Domain, err)
+		return
+	}
+	a.mu.Lock()
+	b, asked := a.inflight[s.Peer.Domain]
+	if asked {
+		a.cursor[s.Peer.Domain] = b.through
+		delete(a.inflight, s.Peer.Domain)
+	}
+	a.mu.Unlock()
+
+	self := ""
+	if id := a.mgr.nodeIdentity(); id != nil {
+		self = id.Domain
+	}
+	attested := map[string]bool{}
+	for _, e := range resp.Entries {
+		if e.Attester != s.Peer.Domain || e.PublicKeyB64 != s.Peer.PublicKeyB64 {
+			log.Printf("⚠️ %s sent an attestation signed as %s", s.Peer.Domain, e.Attester)
+			continue
+		}
+		e.Peer, e.Action = s.Peer.Domain, "received"
+		if err := a.store.Add(e); err != nil {
+			log.Printf("⚠️ attestation from %s: %v", s.Peer.Domain, err)
+			if errors.Is(err, ledger.ErrBadAttestation) {
+				a.mgr.ReportFailure(s, err.Error())
+			}
+			continue
+		}
+		if h, ok := b.hashes[e.ReceiptID]; ok && h == e.ReceiptHash && e.Origin == self && e.Status == "ok" {
+			attested[e.ReceiptID] = true
+		}
+	}
+	if asked && len(b.hashes) > 0 && len(attested) == len(b.hashes) {
+		a.mgr.ReportVerified(s.Peer.Domain)
+	}
+	// Keep going until the peer has attested everything.
+	a.requestFrom(s)
+}
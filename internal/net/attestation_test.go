@@ -0,0 +1,93 @@
+package net
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"dis-core/internal/ledger"
+	"dis-core/internal/util/crypto"
+)
+
+// TestAttestation has two peers attest a node's receipts on connect and
+// checks the node counts both, and itself never.
+func TestAttestation(t *testing.T) {
+	known := crypto.DefaultKeyStore()
+	a := newTestNode(t, "domain.a")
+	b := newTestNode(t, "domain.b")
+	c := newTestNode(t, "domain.c")
+	stores := map[string]*ledger.TrustLedger{}
+	for _, n := range []*testNode{a, b, c} {
+		stores[n.domain] = &ledger.TrustLedger{}
+		NewAttestor(n.mgr, n.local, n.replicas, stores[n.domain]).WithBatch(2)
+	}
+	a.append(t, 5)
+	chain, _ := a.local.Chain()
+	var ids []string
+	for _, e := range chain {
+		if e.Receipt != nil {
+			ids = append(ids, e.Receipt.ReceiptID)
+		}
+	}
+	b.dial(t, a)
+	c.dial(t, a)
+
+	// Each answer triggers the next batch until everything is attested.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		counts, _ := stores["domain.a"].AttesterCounts(ids)
+		done := true
+		for _, id := range ids {
+			done = done && counts[id] == 2
+		}
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("attester counts: %v", counts)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	entries, _ := stores["domain.b"].ForReceipt(ids[0])
+	if len(entries) != 1 || entries[0].Action != "sent" || entries[0].Origin != "domain.a" {
+		t.Fatalf("b's record of its attestation: %+v", entries)
+	}
+	forged := entries[0]
+	forged.Status = "fail"
+	if err := stores["domain.a"].Add(forged); !errors.Is(err, ledger.ErrBadAttestation) {
+		t.Fatalf("forged attestation: %v", err)
+	}
+	self := forged
+	self.Status, self.Attester = "ok", "domain.a"
+	if n := ledger.CountAttesters([]ledger.TrustEntry{self}); n != 0 {
+		t.Fatalf("self attestation counted: %d", n)
+	}
+
+	// A key made up for a known domain signs a well-formed attestation,
+	// but it is not counted, and receipts it signs are not attested.
+	rogue := rogueKeys(t, "domain.b", "domain.a")
+	sham := ledger.NewAttestation(chain[0].Receipt, "domain.a", "domain.b", "core.test", true, "")
+	if err := sham.SignAttestation(rogue); err != nil {
+		t.Fatalf("SignAttestation: %v", err)
+	}
+	if err := sham.VerifyAttestation(); err != nil {
+		t.Fatalf("sham attestation is malformed: %v", err)
+	}
+	if n := ledger.CountAttesters([]ledger.TrustEntry{sham}); n != 0 {
+		t.Fatalf("attestation with an unknown key counted: %d", n)
+	}
+	crypto.SetDefaultKeyStore(rogue)
+	fake, err := ledger.NewEnvelope("test.event.v0", "domain.a", "attestation.test", map[string]any{})
+	crypto.SetDefaultKeyStore(known)
+	if err != nil {
+		t.Fatalf("NewEnvelope: %v", err)
+	}
+	att := NewAttestor(knowing(known), nil, nil, &ledger.TrustLedger{})
+	if ok, _ := att.verify("domain.a", fake); ok {
+		t.Fatal("attested a receipt signed with an unknown key")
+	}
+	if ok, notes := att.verify("domain.a", chain[0].Receipt); !ok {
+		t.Fatalf("genuine receipt: %s", notes)
+	}
+}
@@ -0,0 +1,227 @@
+package net
+
+import (
+	"errors"
+	"log"
+	"net"
+	"time"
+)
+
+// MaxKnownPeers bounds how many peer records discovery keeps; peers
+// configured, seeded or added by hand are always kept.
+const MaxKnownPeers = 256
+
+var ErrPeerBanned = errors.New("peer is banned")
+
+// AccessList decides which peers a node talks to. Entries are peer
+// domains, hosts or host:port addresses. A peer matching Ban is refused;
+// when Allow is not empty, so is every peer not matching it.
+type AccessList struct {
+	Allow []string `yaml:"allow" json:"allow,omitempty"`
+	Ban   []string `yaml:"ban" json:"ban,omitempty"`
+}
+
+// Permits reports whether the peer at addr, authenticated as domain, may
+// be peered with. Before the handshake domain is empty and only bans
+// apply; the allow list is checked once the peer is authenticated.
+func (l AccessList) Permits(domain, addr string) bool {
+	if matchesAny(l.Ban, domain, addr) {
+		return false
+	}
+	if len(l.Allow) == 0 || domain == "" {
+		return true
+	}
+	return matchesAny(l.Allow, domain, addr)
+}
+
+func matchesAny(entries []string, domain, addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	for _, e := range entries {
+		if e == "" {
+			continue
+		}
+		if (domain != "" && e == domain) || e == addr || e == host {
+			return true
+		}
+	}
+	return false
+}
+
+// WithAccessList sets the ban/allow list and returns the manager
+// (chainable).
+func (m *Manager) WithAccessList(l AccessList) *Manager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.access = l
+	return m
+}
+
+// permitted checks addr and domain against the access list and the
+// peer's persisted ban.
+func (m *Manager) permitted(domain, addr string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if p, ok := m.peers[addr]; ok && p.Banned {
+		return false
+	}
+	return m.access.Permits(domain, addr)
+}
+
+// Ban refuses the peer domain, host or address entry from now on, closing
+// any session to it.
+func (m *Manager) Ban(entry string) {
+	m.mu.Lock()
+	m.access.Ban = append(m.access.Ban, entry)
+	var banned []string
+	for addr, p := range m.peers {
+		if matchesAny([]string{entry}, p.Domain, addr) {
+			p.Banned, p.Status, p.Healthy = true, PeerBanned, false
+			banned = append(banned, addr)
+			if s, ok := m.sessions[addr]; ok {
+				s.Close()
+			}
+		}
+	}
+	m.mu.Unlock()
+	for _, addr := range banned {
+		m.persist(addr)
+	}
+	log.Printf("⛔ Banned %s", entry)
+}
+
+// Unban lifts a ban set with Ban or loaded with the peer records.
+func (m *Manager) Unban(entry string) {
+	m.mu.Lock()
+	var kept []string
+	for _, e := range m.access.Ban {
+		if e != entry {
+			kept = append(kept, e)
+		}
+	}
+	m.access.Ban = kept
+	var lifted []string
+	for addr, p := range m.peers {
+		if p.Banned && matchesAny([]string{entry}, p.Domain, addr) {
+			p.Banned, p.Status = false, PeerUnknown
+			lifted = append(lifted, addr)
+		}
+	}
+	m.mu.Unlock()
+	for _, addr := range lifted {
+		m.persist(addr)
+	}
+}
+
+// ApplyConfig takes the access list, configured peers and seeds from cfg,
+// and dials the peers.
+func (m *Manager) ApplyConfig(cfg *NetworkConfig) {
+	m.WithAccessList(AccessList{Allow: cfg.Allow, Ban: cfg.Ban})
+	for _, p := range cfg.Peers {
+		m.discover(p.Address, SourceConfig)
+	}
+	m.AddSeeds(cfg.Seeds)
+}
+
+// AddSeeds adds the static seed addresses and dials them.
+func (m *Manager) AddSeeds(addrs []string) {
+	for _, addr := range addrs {
+		m.discover(addr, SourceSeed)
+	}
+}
+
+// discover records a peer address learned from source and, when the node
+// has an identity, dials it. Banned addresses, this node's own address and,
+// past MaxKnownPeers, gossiped or LAN addresses are ignored. It reports
+// whether the address was new.
+func (m *Manager) discover(addr, source string) bool {
+	if addr == "" || !m.permitted("", addr) {
+		return false
+	}
+	m.mu.Lock()
+	id := m.identity
+	if id != nil && addr == id.ListenAddr {
+		m.mu.Unlock()
+		return false
+	}
+	if _, ok := m.peers[addr]; ok {
+		m.mu.Unlock()
+		return false
+	}
+	if (source == SourcePEX || source == SourceMDNS) && len(m.peers) >= MaxKnownPeers {
+		m.mu.Unlock()
+		return false
+	}
+	m.record(addr, source)
+	m.mu.Unlock()
+	m.persist(addr)
+	log.Printf("➕ Added peer: %s (%s)", addr, source)
+
+	if id != nil {
+		go func() {
+			if _, err := m.Dial(addr); err != nil {
+				log.Printf("⚠️ Peer %s: %v", addr, err)
+			}
+		}()
+	}
+	return true
+}
+
+// record returns the peer record for addr, creating it with source; m.mu
+// must be held.
+func (m *Manager) record(addr, source string) *Peer {
+	p, ok := m.peers[addr]
+	if !ok {
+		now := time.Now()
+		p = &Peer{ID: addr, Address: addr, Status: PeerUnknown, Source: source, FirstSeen: now}
+		p.rescore(now)
+		m.peers[addr] = p
+	}
+	return p
+}
+
+// persist writes addr's peer record to the database, if there is one.
+func (m *Manager) persist(addr string) {
+	if m.db == nil {
+		return
+	}
+	m.mu.RLock()
+	p, ok := m.peers[addr]
+	var cp Peer
+	if ok {
+		cp = *p
+	}
+	m.mu.RUnlock()
+	if !ok {
+		return
+	}
+	if err := SavePeer(m.db, &cp); err != nil {
+		log.Printf("⚠️ Save peer %s: %v", addr, err)
+	}
+}
+
+// ReportFailure counts a verification failure against the peer on s, such
+// as a replicated range or peer exchange that did not verify, and toward
+// its demotion under the trust policy.
+func (m *Manager) ReportFailure(s *Session, reason string) {
+	m.mu.Lock()
+	var addr string
+	for a, open := range m.sessions {
+		if open == s {
+			addr = a
+		}
+	}
+	p, ok := m.peers[addr]
+	if ok {
+		p.Failures++
+		p.Error = reason
+		p.rescore(time.Now())
+	}
+	m.mu.Unlock()
+	if ok {
+		m.persist(addr)
+	}
+	m.recordOutcome(s.Peer.Domain, false)
+}
@@ -0,0 +1,114 @@
+package net
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func peerRecord(m *Manager, addr string) (Peer, bool) {
+	for _, p := range m.ListPeers() {
+		if p.Address == addr {
+			return *p, true
+		}
+	}
+	return Peer{}, false
+}
+
+// TestPeerExchange checks that a node learns of its peer's peers through a
+// signed exchange and connects to them, and that bans close and refuse
+// sessions.
+func TestPeerExchange(t *testing.T) {
+	a := newTestNode(t, "domain.a")
+	b := newTestNode(t, "domain.b")
+	c := newTestNode(t, "domain.c")
+	b.dial(t, a)
+	c.dial(t, a)
+
+	waitFor(t, "c to learn of b", func() bool {
+		for _, s := range a.mgr.Sessions() {
+			if s.Peer.Domain == "domain.c" {
+				a.mgr.sharePeers(s)
+			}
+		}
+		_, ok := c.mgr.Session(b.addr)
+		return ok
+	})
+	p, _ := peerRecord(c.mgr, b.addr)
+	if p.Source != SourcePEX || p.Domain != "domain.b" || p.Status != PeerHealthy {
+		t.Fatalf("c's record of b: %+v", p)
+	}
+
+	a.mgr.Ban("domain.b")
+	waitFor(t, "a to drop b", func() bool {
+		_, ok := a.mgr.Session(b.addr)
+		return !ok
+	})
+	if p, _ := peerRecord(a.mgr, b.addr); !p.Banned || p.Status != PeerBanned {
+		t.Fatalf("a's record of banned b: %+v", p)
+	}
+	if _, err := a.mgr.Dial(b.addr); err == nil {
+		t.Fatal("a dialed a banned peer")
+	}
+	b.dial(t, a) // b has not banned a; a closes the session on registering it
+	waitFor(t, "a to refuse b", func() bool {
+		_, ok := b.mgr.Session(a.addr)
+		return !ok
+	})
+
+	s, _ := c.mgr.Session(b.addr)
+	px := PeerExchange{From: "domain.b", IssuedAt: time.Now(), Peers: []PeerAd{{Address: "10.0.0.1:9090"}}, KeyID: s.Peer.KeyID}
+	px.Signature = "AAAA"
+	if err := verifyPeerExchange(&px, s.Peer); err == nil {
+		t.Fatal("forged peer exchange verified")
+	}
+}
+
+func TestPeerScore(t *testing.T) {
+	now := time.Now()
+	steady := &Peer{FirstSeen: now.Add(-time.Hour), connectedAt: now.Add(-time.Hour), LatencyMS: 20}
+	flaky := &Peer{FirstSeen: now.Add(-time.Hour), UptimeSec: 600, LatencyMS: 20, Failures: 3}
+	steady.rescore(now)
+	flaky.rescore(now)
+	if steady.Score <= flaky.Score || steady.Score > 1 || flaky.Score <= 0 {
+		t.Fatalf("scores: steady %v, flaky %v", steady.Score, flaky.Score)
+	}
+	if (AccessList{Allow: []string{"domain.a"}}).Permits("domain.b", "10.0.0.2:9090") {
+		t.Fatal("allow list admitted an unlisted domain")
+	}
+	if (AccessList{Ban: []string{"10.0.0.2"}}).Permits("", "10.0.0.2:9090") {
+		t.Fatal("banned host permitted")
+	}
+}
+
+// TestMDNS exchanges a query and an answer between two nodes' mDNS
+// handlers without touching the network.
+func TestMDNS(t *testing.T) {
+	one := newMDNS(NewManager(nil), "domain.a", 9001)
+	two := newMDNS(NewManager(nil), "domain.b", 9002)
+
+	answer := two.handle(one.query(), &net.UDPAddr{IP: net.IPv4(192, 168, 1, 20)})
+	if answer == nil {
+		t.Fatal("no answer to a service query")
+	}
+	one.handle(answer, &net.UDPAddr{IP: net.IPv4(192, 168, 1, 20)})
+	one.handle(one.answer(), &net.UDPAddr{IP: net.IPv4(192, 168, 1, 10)})
+
+	peers := one.mgr.ListPeers()
+	want := net.JoinHostPort("192.168.1.20", strconv.Itoa(9002))
+	if len(peers) != 1 || peers[0].Address != want || peers[0].Source != SourceMDNS {
+		t.Fatalf("peers from mDNS: %+v", peers)
+	}
+}
@@ -0,0 +1,398 @@
+package net
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"runtime/debug"
+	"time"
+
+	"dis-core/internal/util/crypto"
+)
+
+// HandshakeTimeout bounds the whole handshake.
+const HandshakeTimeout = 10 * time.Second
+
+var (
+	ErrIncompatibleVersion = errors.New("no common protocol version")
+	ErrBadHandshake        = errors.New("peer handshake failed")
+	ErrSelfConnect         = errors.New("connected to self")
+	ErrUnknownPeerKey      = errors.New("peer key is not in its domain's key history")
+	ErrUnknownPeerDomain   = errors.New("peer domain has no known keys")
+)
+
+// Identity is what this node presents to peers: its domain, the key store
+// holding that domain's active Ed25519 key, and the hashes peers compare.
+type Identity struct {
+	Domain     string
+	Keys       crypto.KeyStore
+	SchemaHash string // schema.Registry.HashAll of the loaded schemas
+	CoreHash   string // frozen core hash; peers must match it
+	ListenAddr string // address peers should dial back, if any
+	// KnownKeys holds the key histories of peer domains, apart from Keys:
+	// what peers present never reaches the store the node verifies its own
+	// parties' votes, mandates and seat signatures against. Domains Keys
+	// has keys for are verified against Keys alone. A peer must present a
+	// key its domain held; domains with no history in either store are
+	// refused unless TrustOnFirstUse is set, in which case the first key
+	// presented is pinned in KnownKeys, valid from the handshake on. The
+	// node's own domain is never pinned on first use.
+	KnownKeys       crypto.KeyStore
+	TrustOnFirstUse bool
+}
+
+// history returns the key history domain is verified against: the one in
+// Keys if there is one, else the one in KnownKeys.
+func (id *Identity) history(domain string) ([]crypto.KeyRecord, error) {
+	if id.Keys != nil {
+		h, err := id.Keys.History(domain)
+		if err != nil || len(h) > 0 {
+			return h, err
+		}
+	}
+	if id.KnownKeys == nil {
+		return nil, nil
+	}
+	return id.KnownKeys.History(domain)
+}
+
+// knownKeys returns the store peer keys are pinned in.
+func (m *Manager) knownKeys() crypto.KeyStore {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.identity == nil {
+		return nil
+	}
+	return m.identity.KnownKeys
+}
+
+// peerKeys is a ledger.KeyResolver over the identity's key histories.
+func (m *Manager) peerKeys(domain, keyID string, t time.Time) (crypto.KeyRecord, error) {
+	m.mu.RLock()
+	id := m.identity
+	m.mu.RUnlock()
+	if id == nil {
+		return crypto.KeyRecord{}, fmt.Errorf("%w: %s", ErrUnknownPeerDomain, domain)
+	}
+	history, err := id.history(domain)
+	if err != nil {
+		return crypto.KeyRecord{}, err
+	}
+	if len(history) == 0 {
+		return crypto.KeyRecord{}, fmt.Errorf("%w: %s", ErrUnknownPeerDomain, domain)
+	}
+	return crypto.KeyInHistory(history, domain, keyID, t)
+}
+
+// PinPeerKeys registers the public keys configured for peers in
+// KnownKeys, so their domains are known before they first connect. The
+// node's own domain is never re-keyed from config.
+func (id *Identity) PinPeerKeys(peers []Peer) error {
+	for _, p := range peers {
+		if p.PublicKeyB64 == "" || p.Domain == id.Domain {
+			continue
+		}
+		if p.Domain == "" {
+			return fmt.Errorf("peer %s: public_key_b64 needs a domain", p.Address)
+		}
+		if _, err := id.KnownKeys.Register(p.Domain, p.PublicKeyB64, time.Time{}); err != nil {
+			return fmt.Errorf("peer %s: %w", p.Domain, err)
+		}
+	}
+	return nil
+}
+
+// BuildCoreHash identifies the core this binary was built from: its VCS
+// revision, marked when the tree was modified, or else the module version.
+func BuildCoreHash() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	rev, dirty := "", false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			rev = s.Value
+		case "vcs.modified":
+			dirty = s.Value == "true"
+		}
+	}
+	if rev == "" {
+		return info.Main.Version
+	}
+	if dirty {
+		rev += "+dirty"
+	}
+	return rev
+}
+
+// Hello opens the handshake in each direction.
+type Hello struct {
+	Versions     []int  `json:"versions"`
+	Domain       string `json:"domain"`
+	KeyID        string `json:"key_id"`
+	PublicKeyB64 string `json:"public_key_b64"`
+	SchemaHash   string `json:"schema_hash"`
+	CoreHash     string `json:"core_hash"`
+	Nonce        string `json:"nonce"`
+	ListenAddr   string `json:"listen_addr,omitempty"`
+}
+
+// Auth proves possession of the key named in a Hello: a signature over
+// both hellos and the signer's role, under the negotiated version.
+type Auth struct {
+	Version   int    `json:"version"`
+	Signature string `json:"signature"`
+}
+
+// handshakeError is the payload of MsgError.
+type handshakeError struct {
+	Reason string `json:"reason"`
+}
+
+const (
+	roleInitiator = "initiator"
+	roleResponder = "responder"
+)
+
+// hello builds this node's Hello with a fresh nonce.
+func (id *Identity) hello() (Hello, crypto.KeyRecord, error) {
+	if id == nil || id.Keys == nil {
+		return Hello{}, crypto.KeyRecord{}, errors.New("node identity not configured")
+	}
+	key, err := id.Keys.Active(id.Domain)
+	if err != nil {
+		return Hello{}, crypto.KeyRecord{}, fmt.Errorf("node key: %w", err)
+	}
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return Hello{}, crypto.KeyRecord{}, err
+	}
+	return Hello{
+		Versions:     SupportedVersions,
+		Domain:       id.Domain,
+		KeyID:        key.KeyID,
+		PublicKeyB64: key.PublicKeyB64,
+		SchemaHash:   id.SchemaHash,
+		CoreHash:     id.CoreHash,
+		Nonce:        base64.StdEncoding.EncodeToString(nonce),
+		ListenAddr:   id.ListenAddr,
+	}, key, nil
+}
+
+// transcript is what each side signs: its role and both hellos, initiator
+// first, so a signature cannot be replayed into another session or
+// reflected back at its signer.
+func transcript(role string, version int, initiator, responder Hello) ([]byte, error) {
+	a, err := json.Marshal(initiator)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(responder)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "dis-net/handshake\x00%s\x00%d\x00", role, version)
+	h.Write(a)
+	h.Write([]byte{0})
+	h.Write(b)
+	return h.Sum(nil), nil
+}
+
+// negotiate returns the highest version both sides support.
+func negotiate(ours, theirs []int) (int, error) {
+	best := 0
+	for _, a := range ours {
+		for _, b := range theirs {
+			if a == b && a > best {
+				best = a
+			}
+		}
+	}
+	if best == 0 {
+		return 0, ErrIncompatibleVersion
+	}
+	return best, nil
+}
+
+// verifyPeer checks that the peer's key is well formed, is a key its
+// domain is known to hold, and signed the transcript for role.
+func (id *Identity) verifyPeer(peer Hello, role string, version int, initiator, responder Hello, auth Auth) error {
+	rec := crypto.KeyRecord{KeyID: peer.KeyID, Domain: peer.Domain, PublicKeyB64: peer.PublicKeyB64}
+	pub, err := rec.PublicKey()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadHandshake, err)
+	}
+	if crypto.KeyID(pub) != peer.KeyID {
+		return fmt.Errorf("%w: key id does not match public key", ErrBadHandshake)
+	}
+	if auth.Version != version {
+		return fmt.Errorf("%w: peer chose version %d, expected %d", ErrBadHandshake, auth.Version, version)
+	}
+	msg, err := transcript(role, version, initiator, responder)
+	if err != nil {
+		return err
+	}
+	if !rec.Verify(msg, auth.Signature) {
+		return fmt.Errorf("%w: bad signature from %s", ErrBadHandshake, peer.Domain)
+	}
+	history, err := id.history(peer.Domain)
+	if err != nil {
+		return fmt.Errorf("%s key history: %w", peer.Domain, err)
+	}
+	if len(history) == 0 {
+		if !id.TrustOnFirstUse || id.KnownKeys == nil || peer.Domain == id.Domain {
+			return fmt.Errorf("%w: %s", ErrUnknownPeerDomain, peer.Domain)
+		}
+		if _, err := id.KnownKeys.Register(peer.Domain, peer.PublicKeyB64, time.Now()); err != nil {
+			return fmt.Errorf("pin %s key: %w", peer.Domain, err)
+		}
+		return nil
+	}
+	if _, err := crypto.KeyInHistory(history, peer.Domain, peer.KeyID, time.Now()); err != nil {
+		return fmt.Errorf("%w: %s key %s: %v", ErrUnknownPeerKey, peer.Domain, peer.KeyID, err)
+	}
+	return nil
+}
+
+func (id *Identity) sign(key crypto.KeyRecord, role string, version int, initiator, responder Hello) (Auth, error) {
+	msg, err := transcript(role, version, initiator, responder)
+	if err != nil {
+		return Auth{}, err
+	}
+	sig, err := id.Keys.Sign(key.KeyID, msg)
+	if err != nil {
+		return Auth{}, fmt.Errorf("sign handshake: %w", err)
+	}
+	return Auth{Version: version, Signature: sig}, nil
+}
+
+// expect reads the next frame and decodes it as typ, turning a MsgError
+// from the peer into an error.
+func expect(conn net.Conn, typ string, v any) error {
+	msg, err := readFrame(conn)
+	if err != nil {
+		return fmt.Errorf("%w: read %s: %v", ErrBadHandshake, typ, err)
+	}
+	if msg.Type == MsgError {
+		var he handshakeError
+		_ = msg.Decode(&he)
+		return fmt.Errorf("%w: peer refused: %s", ErrBadHandshake, he.Reason)
+	}
+	if msg.Type != typ {
+		return fmt.Errorf("%w: expected %s, got %s", ErrBadHandshake, typ, msg.Type)
+	}
+	return msg.Decode(v)
+}
+
+func send(conn net.Conn, typ string, v any) error {
+	msg, err := NewMessage(typ, v)
+	if err != nil {
+		return err
+	}
+	return writeFrame(conn, msg)
+}
+
+// refuse tells the peer why the handshake failed and returns err.
+func refuse(conn net.Conn, err error) error {
+	_ = send(conn, MsgError, handshakeError{Reason: err.Error()})
+	return err
+}
+
+func selfConnect(ours, theirs Hello) bool {
+	return ours.Domain == theirs.Domain && ours.KeyID == theirs.KeyID
+}
+
+// Initiate runs the handshake on an outbound connection:
+//
+//	initiator → hello
+//	responder → hello, auth
+//	initiator → auth
+//
+// Both sides then hold an authenticated session under the highest common
+// protocol version.
+func Initiate(conn net.Conn, id *Identity) (*Session, error) {
+	_ = conn.SetDeadline(time.Now().Add(HandshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	ours, key, err := id.hello()
+	if err != nil {
+		return nil, err
+	}
+	if err := send(conn, MsgHello, ours); err != nil {
+		return nil, err
+	}
+	var theirs Hello
+	if err := expect(conn, MsgHello, &theirs); err != nil {
+		return nil, err
+	}
+	if selfConnect(ours, theirs) {
+		return nil, refuse(conn, ErrSelfConnect)
+	}
+	version, err := negotiate(ours.Versions, theirs.Versions)
+	if err != nil {
+		return nil, refuse(conn, err)
+	}
+	var auth Auth
+	if err := expect(conn, MsgAuth, &auth); err != nil {
+		return nil, err
+	}
+	if err := id.verifyPeer(theirs, roleResponder, version, ours, theirs, auth); err != nil {
+		return nil, refuse(conn, err)
+	}
+	mine, err := id.sign(key, roleInitiator, version, ours, theirs)
+	if err != nil {
+		return nil, err
+	}
+	if err := send(conn, MsgAuth, mine); err != nil {
+		return nil, err
+	}
+	return newSession(conn, id, theirs, version, true), nil
+}
+
+// Accept runs the responder side of the handshake on an inbound
+// connection; see Initiate.
+func Accept(conn net.Conn, id *Identity) (*Session, error) {
+	_ = conn.SetDeadline(time.Now().Add(HandshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	var theirs Hello
+	if err := expect(conn, MsgHello, &theirs); err != nil {
+		return nil, err
+	}
+	ours, key, err := id.hello()
+	if err != nil {
+		return nil, refuse(conn, errors.New("responder not ready"))
+	}
+	if selfConnect(ours, theirs) {
+		return nil, refuse(conn, ErrSelfConnect)
+	}
+	version, err := negotiate(ours.Versions, theirs.Versions)
+	if err != nil {
+		return nil, refuse(conn, err)
+	}
+	mine, err := id.sign(key, roleResponder, version, theirs, ours)
+	if err != nil {
+		return nil, refuse(conn, errors.New("responder not ready"))
+	}
+	if err := send(conn, MsgHello, ours); err != nil {
+		return nil, err
+	}
+	if err := send(conn, MsgAuth, mine); err != nil {
+		return nil, err
+	}
+	var auth Auth
+	if err := expect(conn, MsgAuth, &auth); err != nil {
+		return nil, err
+	}
+	if err := id.verifyPeer(theirs, roleInitiator, version, theirs, ours, auth); err != nil {
+		return nil, refuse(conn, err)
+	}
+	return newSession(conn, id, theirs, version, false), nil
+}
@@ -0,0 +1,121 @@
+package net
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"dis-core/internal/util/crypto"
+)
+
+// rogueKeys returns a key store holding fresh keys for domains, unrelated
+// to the keys the test network knows them by.
+func rogueKeys(t *testing.T, domains ...string) crypto.KeyStore {
+	t.Helper()
+	token := crypto.NewMockPKCS11Token("1234")
+	if err := token.Login("1234"); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	ks := crypto.NewPKCS11KeyStore(token)
+	for _, d := range domains {
+		if _, err := ks.Generate(d); err != nil {
+			t.Fatalf("Generate %s: %v", d, err)
+		}
+	}
+	return ks
+}
+
+// claim returns a node that authenticates as domain with keys.
+func claim(t *testing.T, domain string, keys crypto.KeyStore) *testNode {
+	t.Helper()
+	n := newTestNode(t, domain)
+	n.mgr.WithIdentity(&Identity{
+		Domain:     domain,
+		Keys:       keys,
+		CoreHash:   "core.test",
+		ListenAddr: n.addr,
+		KnownKeys:  crypto.DefaultKeyStore(),
+	})
+	return n
+}
+
+func TestHandshakeKnownKeys(t *testing.T) {
+	a := newTestNode(t, "domain.a")
+	cases := []struct {
+		name   string
+		domain string
+		keys   crypto.KeyStore
+		pin    bool // list the peer's key in a's network config
+		want   error
+	}{
+		{"known key", "domain.b", crypto.DefaultKeyStore(), false, nil},
+		{"forged domain claim", "domain.b", rogueKeys(t, "domain.b"), false, ErrUnknownPeerKey},
+		{"unknown domain", "domain.z", rogueKeys(t, "domain.z"), false, ErrUnknownPeerDomain},
+		{"pinned in config", "domain.y", rogueKeys(t, "domain.y"), true, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			peer := claim(t, tc.domain, tc.keys)
+			if tc.pin {
+				key, err := tc.keys.Active(tc.domain)
+				if err != nil {
+					t.Fatalf("Active: %v", err)
+				}
+				if err := a.mgr.identity.PinPeerKeys([]Peer{{Domain: tc.domain, PublicKeyB64: key.PublicKeyB64}}); err != nil {
+					t.Fatalf("PinPeerKeys: %v", err)
+				}
+			}
+			_, err := a.mgr.Dial(peer.addr)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("Dial: %v, want %v", err, tc.want)
+			}
+			if p, _ := peerRecord(a.mgr, peer.addr); tc.want != nil && p.Status != PeerRejected {
+				t.Fatalf("peer record: %+v", p)
+			}
+		})
+	}
+}
+
+// TestHandshakeFirstUse pins an unknown domain's first key, from the
+// handshake on and apart from the node's own keys, and refuses a different
+// key claiming the same domain afterwards. Domains the node's own key
+// store knows, its own included, are never pinned on first use.
+func TestHandshakeFirstUse(t *testing.T) {
+	c := newTestNode(t, "domain.c")
+	known := rogueKeys(t)
+	c.mgr.WithIdentity(&Identity{
+		Domain:          "domain.c",
+		Keys:            crypto.DefaultKeyStore(),
+		CoreHash:        "core.test",
+		ListenAddr:      c.addr,
+		KnownKeys:       known,
+		TrustOnFirstUse: true,
+	})
+
+	start := time.Now()
+	first := claim(t, "domain.z", rogueKeys(t, "domain.z"))
+	if _, err := c.mgr.Dial(first.addr); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	history, err := known.History("domain.z")
+	if err != nil || len(history) != 1 || history[0].ValidFrom.Before(start) {
+		t.Fatalf("pinned history: %+v, %v", history, err)
+	}
+	if own, _ := crypto.DefaultKeyStore().History("domain.z"); len(own) != 0 {
+		t.Fatalf("peer key reached the node's key store: %+v", own)
+	}
+	second := claim(t, "domain.z", rogueKeys(t, "domain.z"))
+	if _, err := c.mgr.Dial(second.addr); !errors.Is(err, ErrUnknownPeerKey) {
+		t.Fatalf("second key for a pinned domain: %v", err)
+	}
+
+	for _, domain := range []string{"domain.a", "domain.c"} {
+		rogue := claim(t, domain, rogueKeys(t, domain))
+		if _, err := c.mgr.Dial(rogue.addr); err == nil {
+			t.Fatalf("first use of a key for %s accepted", domain)
+		}
+		if h, _ := known.History(domain); len(h) != 0 {
+			t.Fatalf("%s pinned on first use: %+v", domain, h)
+		}
+	}
+}
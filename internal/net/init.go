@@ -1,8 +1,13 @@
 package net
 
-import "database/sql"
+import (
+	"database/sql"
+	"time"
+)
 
-// EnsurePeersTable creates the peers table if it doesn't exist.
+// EnsurePeersTable creates the peers table if it doesn't exist, and brings
+// tables from before peer scoring up to date: it adds the record columns
+// and makes address unique, keeping the oldest row of any duplicates.
 func EnsurePeersTable(db *sql.DB) error {
 	_, err := db.Exec(`
 	CREATE TABLE IF NOT EXISTS peers (
@@ -11,6 +16,80 @@ func EnsurePeersTable(db *sql.DB) error {
 		last_seen TIMESTAMPTZ DEFAULT NOW(),
 		status TEXT DEFAULT 'unknown'
 	);
+	ALTER TABLE peers
+		ADD COLUMN IF NOT EXISTS domain TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS key_id TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS core_hash TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT 'manual',
+		ADD COLUMN IF NOT EXISTS first_seen TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		ADD COLUMN IF NOT EXISTS latency_ms BIGINT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS uptime_s BIGINT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS failures INT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS score DOUBLE PRECISION NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS banned BOOLEAN NOT NULL DEFAULT FALSE,
+		ADD COLUMN IF NOT EXISTS error TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS trust_level TEXT NOT NULL DEFAULT '';
+	DELETE FROM peers a USING peers b
+		WHERE a.address = b.address AND (a.first_seen, a.id) > (b.first_seen, b.id);
+	CREATE UNIQUE INDEX IF NOT EXISTS peers_address_key ON peers (address);
 	`)
 	return err
 }
+
+// SavePeer upserts p's record by address.
+func SavePeer(db *sql.DB, p *Peer) error {
+	if db == nil {
+		return nil
+	}
+	lastSeen := p.LastSeen
+	if lastSeen.IsZero() {
+		lastSeen = time.Now()
+	}
+	firstSeen := p.FirstSeen
+	if firstSeen.IsZero() {
+		firstSeen = lastSeen
+	}
+	_, err := db.Exec(`
+		INSERT INTO peers (id, address, last_seen, status, domain, key_id, core_hash, source,
+			first_seen, latency_ms, uptime_s, failures, score, banned, error, trust_level)
+		VALUES ($1, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (address) DO UPDATE SET
+			last_seen = EXCLUDED.last_seen, status = EXCLUDED.status,
+			domain = EXCLUDED.domain, key_id = EXCLUDED.key_id, core_hash = EXCLUDED.core_hash,
+			latency_ms = EXCLUDED.latency_ms, uptime_s = EXCLUDED.uptime_s,
+			failures = EXCLUDED.failures, score = EXCLUDED.score,
+			banned = EXCLUDED.banned, error = EXCLUDED.error,
+			trust_level = EXCLUDED.trust_level`,
+		p.Address, lastSeen, string(p.Status), p.Domain, p.KeyID, p.CoreHash, p.Source,
+		firstSeen, p.LatencyMS, int64(p.uptime(time.Now())/time.Second), p.Failures, p.Score, p.Banned, p.Error, p.TrustLevel)
+	return err
+}
+
+// LoadPeerRecords returns the persisted peer records, best scored first.
+func LoadPeerRecords(db *sql.DB) ([]*Peer, error) {
+	rows, err := db.Query(`
+		SELECT address, last_seen, COALESCE(status, 'unknown'), domain, key_id, core_hash, source,
+			first_seen, latency_ms, uptime_s, failures, score, banned, error, trust_level
+		FROM peers ORDER BY score DESC, address`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*Peer
+	for rows.Next() {
+		p := &Peer{}
+		var status string
+		var lastSeen sql.NullTime
+		if err := rows.Scan(&p.Address, &lastSeen, &status, &p.Domain, &p.KeyID, &p.CoreHash, &p.Source,
+			&p.FirstSeen, &p.LatencyMS, &p.UptimeSec, &p.Failures, &p.Score, &p.Banned, &p.Error, &p.TrustLevel); err != nil {
+			return nil, err
+		}
+		p.ID, p.LastSeen, p.Status = p.Address, lastSeen.Time, PeerStatus(status)
+		if p.Domain != "" {
+			p.ID = p.Domain
+		}
+		p.Healthy = p.Status == PeerHealthy
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
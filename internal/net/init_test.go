@@ -0,0 +1,86 @@
+package net
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"dis-core/internal/db"
+	"dis-core/internal/ledger"
+)
+
+// TestPeerRecordsPG saves peer records to the scratch database named by
+// DIS_TEST_DB_DSN and reads them back, before and after an update.
+func TestPeerRecordsPG(t *testing.T) {
+	dsn := os.Getenv("DIS_TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("DIS_TEST_DB_DSN not set")
+	}
+	database, err := db.ConnectPostgres(dsn)
+	if err != nil {
+		t.Fatalf("ConnectPostgres: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := EnsurePeersTable(database); err != nil {
+		t.Fatalf("EnsurePeersTable: %v", err)
+	}
+
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	p := &Peer{
+		Address:    "127.0.0.1:" + ledger.GenerateUUID(), // unique per run
+		LastSeen:   t0.Add(time.Hour),
+		Status:     PeerHealthy,
+		Domain:     "domain.b",
+		KeyID:      "key-b",
+		CoreHash:   "core.test",
+		Source:     SourceSeed,
+		FirstSeen:  t0,
+		LatencyMS:  12,
+		UptimeSec:  42,
+		Failures:   1,
+		Score:      0.75,
+		TrustLevel: LevelFederated,
+	}
+	load := func() Peer {
+		t.Helper()
+		records, err := LoadPeerRecords(database)
+		if err != nil {
+			t.Fatalf("LoadPeerRecords: %v", err)
+		}
+		for _, r := range records {
+			if r.Address == p.Address {
+				return *r
+			}
+		}
+		t.Fatalf("peer %s not loaded", p.Address)
+		return Peer{}
+	}
+	check := func(got Peer) {
+		t.Helper()
+		if got.ID != p.Domain || got.Status != p.Status || got.Healthy != (p.Status == PeerHealthy) || got.Domain != p.Domain ||
+			got.KeyID != p.KeyID || got.CoreHash != p.CoreHash || got.Source != p.Source ||
+			got.LatencyMS != p.LatencyMS || got.UptimeSec != p.UptimeSec || got.Failures != p.Failures ||
+			got.Score != p.Score || got.Banned != p.Banned || got.Error != p.Error || got.TrustLevel != p.TrustLevel {
+			t.Fatalf("loaded %+v, saved %+v", got, p)
+		}
+		if !got.LastSeen.Equal(p.LastSeen) || !got.FirstSeen.Equal(t0) {
+			t.Fatalf("loaded seen %s..%s, saved %s..%s", got.FirstSeen, got.LastSeen, t0, p.LastSeen)
+		}
+	}
+
+	if err := SavePeer(database, p); err != nil {
+		t.Fatalf("SavePeer: %v", err)
+	}
+	check(load())
+
+	// An update keeps first_seen and replaces the rest, trust level included.
+	p.LastSeen = t0.Add(2 * time.Hour)
+	p.FirstSeen = t0.Add(time.Hour)
+	p.Status, p.Banned, p.Error = PeerRejected, true, "banned"
+	p.TrustLevel = LevelObserver
+	if err := SavePeer(database, p); err != nil {
+		t.Fatalf("SavePeer update: %v", err)
+	}
+	p.FirstSeen = t0
+	check(load())
+}
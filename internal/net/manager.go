@@ -2,6 +2,7 @@ package net
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -18,17 +19,76 @@ type Manager struct {
 	ln      net.Listener
 	running bool
 	db      *sql.DB
+
+	// identity authenticates this node to peers; without it inbound
+	// connections are refused and peers are only polled over HTTP.
+	identity *Identity
+	sessions map[string]*Session // open sessions by peer address
+	handlers map[string]Handler  // by message type
+	onOpen   []func(*Session)    // called for every served session
+	access   AccessList
+	trust    *TrustPolicy
 }
 
 // NewManager constructs a new network manager with periodic health checks.
 // Optionally accepts a *sql.DB for persistence; pass nil to disable DB ops.
 func NewManager(db *sql.DB) *Manager {
-	return &Manager{
-		peers:  make(map[string]*Peer),
-		ticker: time.NewTicker(30 * time.Second),
-		stop:   make(chan struct{}),
-		db:     db,
+	m := &Manager{
+		peers:    make(map[string]*Peer),
+		ticker:   time.NewTicker(30 * time.Second),
+		stop:     make(chan struct{}),
+		db:       db,
+		sessions: make(map[string]*Session),
+		handlers: make(map[string]Handler),
+	}
+	m.handlers[MsgPeerExchange] = m.onPeerExchange
+	m.onOpen = append(m.onOpen, m.sharePeers)
+	return m
+}
+
+// WithIdentity sets the identity the manager handshakes with and returns
+// the manager (chainable).
+func (m *Manager) WithIdentity(id *Identity) *Manager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.identity = id
+	return m
+}
+
+func (m *Manager) nodeIdentity() *Identity {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.identity
+}
+
+// Handle routes session messages of type typ to h.
+func (m *Manager) Handle(typ string, h Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[typ] = h
+}
+
+// OnConnect calls fn, in its own goroutine, for every session the manager
+// starts serving.
+func (m *Manager) OnConnect(fn func(s *Session)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onOpen = append(m.onOpen, fn)
+}
+
+func (m *Manager) dispatch(s *Session, msg Message) {
+	m.mu.RLock()
+	h := m.handlers[msg.Type]
+	m.mu.RUnlock()
+	if h == nil {
+		log.Printf("⚠️ %s sent unhandled message type %q", s.Peer.Domain, msg.Type)
+		return
+	}
+	if err := m.admitMessage(s, msg); err != nil {
+		log.Printf("⛔ %v", err)
+		return
 	}
+	h(s, msg)
 }
 
 // Listen starts a TCP listener for inbound peer connections on the given port.
@@ -67,31 +127,222 @@ func (m *Manager) acceptLoop() {
 	}
 }
 
-// handleConn processes a single inbound connection.
+// handleConn runs the responder handshake on an inbound connection and,
+// once the peer is authenticated, serves its session until it closes.
 func (m *Manager) handleConn(conn net.Conn) {
-	defer conn.Close()
-	addr := conn.RemoteAddr().String()
+	m.mu.RLock()
+	id := m.identity
+	m.mu.RUnlock()
+	if id == nil {
+		log.Printf("⚠️ Refusing %s: node identity not configured", conn.RemoteAddr())
+		conn.Close()
+		return
+	}
+	if !m.permitted("", conn.RemoteAddr().String()) {
+		conn.Close()
+		return
+	}
+	sess, err := Accept(conn, id)
+	if err != nil {
+		log.Printf("⚠️ Handshake with %s failed: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	addr := sess.Peer.ListenAddr
+	if addr == "" {
+		addr = sess.RemoteAddr()
+	}
+	if m.register(sess, addr) {
+		m.serve(sess, addr)
+	}
+}
 
-	m.mu.Lock()
-	if _, ok := m.peers[addr]; !ok {
-		m.peers[addr] = &Peer{Address: addr, Healthy: true}
-		log.Printf("🔗 Peer connected: %s", addr)
+// Dial connects to addr, runs the initiator handshake and serves the
+// session in the background. The peer's status is updated either way.
+func (m *Manager) Dial(addr string) (*Session, error) {
+	m.mu.RLock()
+	id := m.identity
+	m.mu.RUnlock()
+	if id == nil {
+		return nil, errors.New("node identity not configured")
 	}
-	m.mu.Unlock()
+	if !m.permitted("", addr) {
+		return nil, fmt.Errorf("%w: %s", ErrPeerBanned, addr)
+	}
+	conn, err := net.DialTimeout("tcp", addr, HandshakeTimeout)
+	if err != nil {
+		m.markPeer(addr, PeerUnreachable, err)
+		return nil, err
+	}
+	sess, err := Initiate(conn, id)
+	if err != nil {
+		conn.Close()
+		m.markPeer(addr, PeerRejected, err)
+		return nil, err
+	}
+	if m.register(sess, addr) {
+		go m.serve(sess, addr)
+	}
+	return sess, nil
 }
 
-// AddPeer manually adds a peer by address.
-func (m *Manager) AddPeer(addr string) {
+// register records an authenticated session's peer. Peers the access list
+// refuses are marked banned and peers on a different frozen core are marked
+// incompatible; either way their session is closed. When a session to the
+// peer is already open (both sides dialed at once), the one dialed by the
+// lesser domain is kept, so both sides keep the same one. It reports
+// whether sess should be served.
+func (m *Manager) register(sess *Session, addr string) bool {
+	ok := m.admit(sess, addr)
+	m.persist(addr)
+	return ok
+}
+
+func (m *Manager) admit(sess *Session, addr string) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if _, ok := m.peers[addr]; !ok {
-		m.peers[addr] = &Peer{Address: addr, Healthy: false}
-		log.Printf("➕ Added peer: %s", addr)
-		// Save to DB if available
-		if m.db != nil {
-			_ = m.SavePeerToDB(m.db, addr)
+	now := time.Now()
+	peer := m.record(addr, SourceInbound)
+	peer.ID = sess.Peer.Domain
+	peer.LastSeen = now
+	peer.Domain = sess.Peer.Domain
+	peer.KeyID = sess.Peer.KeyID
+	peer.Protocol = sess.Version
+	peer.SchemaHash = sess.Peer.SchemaHash
+	peer.CoreHash = sess.Peer.CoreHash
+	peer.Error = ""
+	peer.ephemeral = !sess.Initiator && addr != sess.Peer.ListenAddr
+	if m.trust != nil {
+		peer.TrustLevel = m.trust.Level(peer.Domain)
+	}
+	if peer.Banned || !m.access.Permits(peer.Domain, addr) {
+		peer.Status, peer.Healthy = PeerBanned, false
+		sess.Close()
+		log.Printf("⛔ Peer %s (%s) is banned", peer.Domain, addr)
+		return false
+	}
+	if !sess.Compatible {
+		peer.Status = PeerIncompatible
+		peer.Healthy = false
+		peer.Error = "frozen core hash mismatch"
+		peer.Failures++
+		peer.rescore(now)
+		sess.Close()
+		log.Printf("⛔ Peer %s (%s) is incompatible: core %s", peer.Domain, addr, peer.CoreHash)
+		return false
+	}
+	if old, ok := m.sessions[addr]; ok && old != sess && !sessionClosed(old) {
+		if m.keepExisting(old, sess) {
+			sess.Close()
+			return false
+		}
+		old.Close()
+	}
+	peer.Status = PeerHealthy
+	peer.Healthy = true
+	if peer.connectedAt.IsZero() {
+		peer.connectedAt = now
+	}
+	peer.rescore(now)
+	m.sessions[addr] = sess
+	log.Printf("🔗 Peer connected: %s (%s, protocol v%d)", peer.Domain, addr, sess.Version)
+	return true
+}
+
+// keepExisting decides between two open sessions to the same peer; m.mu
+// must be held.
+func (m *Manager) keepExisting(old, next *Session) bool {
+	dialer := func(s *Session) string {
+		if s.Initiator {
+			return m.identity.Domain
+		}
+		return s.Peer.Domain
+	}
+	return dialer(old) <= dialer(next) && old.Initiator != next.Initiator
+}
+
+func sessionClosed(s *Session) bool {
+	select {
+	case <-s.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// serve runs a registered session until it ends.
+func (m *Manager) serve(sess *Session, addr string) {
+	m.mu.RLock()
+	for _, fn := range m.onOpen {
+		go fn(sess)
+	}
+	m.mu.RUnlock()
+	err := sess.Run(m.dispatch)
+	m.mu.Lock()
+	if m.sessions[addr] != sess {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.sessions, addr)
+	if p, ok := m.peers[addr]; ok {
+		now := time.Now()
+		p.UptimeSec = int64(p.uptime(now) / time.Second)
+		p.connectedAt = time.Time{}
+		p.Healthy = false
+		if p.Status != PeerBanned {
+			p.Status = PeerUnreachable
+		}
+		p.LastSeen = sess.LastSeen()
+		if err != nil {
+			p.Error = err.Error()
 		}
+		p.rescore(now)
+	}
+	m.mu.Unlock()
+	m.persist(addr)
+	log.Printf("🔌 Peer disconnected: %s", addr)
+}
+
+// markPeer records a failed contact with addr. A rejected handshake
+// counts as a verification failure.
+func (m *Manager) markPeer(addr string, status PeerStatus, err error) {
+	m.mu.Lock()
+	p := m.record(addr, SourceManual)
+	p.Healthy = false
+	p.Status = status
+	if err != nil {
+		p.Error = err.Error()
 	}
+	if status == PeerRejected {
+		p.Failures++
+	}
+	p.rescore(time.Now())
+	m.mu.Unlock()
+	m.persist(addr)
+}
+
+// Session returns the open session to the peer at addr, if any.
+func (m *Manager) Session(addr string) (*Session, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions[addr]
+	return s, ok
+}
+
+// Sessions returns the open sessions.
+func (m *Manager) Sessions() []*Session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]*Session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		out = append(out, s)
+	}
+	return out
+}
+
+// AddPeer manually adds a peer by address.
+func (m *Manager) AddPeer(addr string) {
+	m.discover(addr, SourceManual)
 }
 
 // ListPeers returns a snapshot of current peers.
@@ -100,30 +351,21 @@ func (m *Manager) ListPeers() []*Peer {
 	defer m.mu.RUnlock()
 	out := make([]*Peer, 0, len(m.peers))
 	for _, p := range m.peers {
-		out = append(out, p)
+		cp := *p
+		out = append(out, &cp)
 	}
 	return out
 }
 
-// StartHealthChecks runs a background goroutine to ping peers every 30 seconds.
+// StartHealthChecks runs a background goroutine every 30 seconds that pings
+// peers with an open session and redials the others. Without an identity,
+// peers are polled over HTTP instead.
 func (m *Manager) StartHealthChecks() {
 	go func() {
 		for {
 			select {
 			case <-m.ticker.C:
-				m.mu.RLock()
-				addrs := make([]string, 0, len(m.peers))
-				for addr := range m.peers {
-					addrs = append(addrs, addr)
-				}
-				m.mu.RUnlock()
-
-				for _, addr := range addrs {
-					peer, _ := PingPeer(addr)
-					m.mu.Lock()
-					m.peers[addr] = peer
-					m.mu.Unlock()
-				}
+				m.checkPeers()
 			case <-m.stop:
 				return
 			}
@@ -131,6 +373,53 @@ func (m *Manager) StartHealthChecks() {
 	}()
 }
 
+func (m *Manager) checkPeers() {
+	m.mu.Lock()
+	id := m.identity
+	now := time.Now()
+	addrs := make([]string, 0, len(m.peers))
+	for addr, p := range m.peers {
+		if s, ok := m.sessions[addr]; ok && s.RTT() > 0 {
+			p.LatencyMS = s.RTT().Milliseconds()
+			if p.LatencyMS == 0 {
+				p.LatencyMS = 1
+			}
+		}
+		p.rescore(now)
+		if !p.Banned {
+			addrs = append(addrs, addr)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, addr := range addrs {
+		if id == nil {
+			polled, _ := PingPeer(addr)
+			m.mu.Lock()
+			p := m.record(addr, SourceManual)
+			p.Healthy, p.Status = polled.Healthy, PeerUnreachable
+			if polled.Healthy {
+				p.Status, p.LastSeen, p.LatencyMS = PeerHealthy, polled.LastSeen, polled.LatencyMS
+			}
+			p.rescore(now)
+			m.mu.Unlock()
+			m.persist(addr)
+			continue
+		}
+		m.persist(addr)
+		if s, ok := m.Session(addr); ok {
+			_ = s.Ping()
+			m.sharePeers(s)
+			continue
+		}
+		go func(addr string) {
+			if _, err := m.Dial(addr); err != nil {
+				log.Printf("⚠️ Peer %s: %v", addr, err)
+			}
+		}(addr)
+	}
+}
+
 // Close stops all network activity and closes the listener.
 func (m *Manager) Close() {
 	if !m.running {
@@ -141,6 +430,9 @@ func (m *Manager) Close() {
 	if m.ln != nil {
 		_ = m.ln.Close()
 	}
+	for _, s := range m.Sessions() {
+		_ = s.Close()
+	}
 	m.running = false
 	log.Println("🛑 DIS-Network manager stopped.")
 }
@@ -150,30 +442,37 @@ func (m *Manager) Stop() {
 	m.Close()
 }
 
+// LoadPeersFromDB restores persisted peer records, with their scores and
+// bans. None is connected yet.
 func (m *Manager) LoadPeersFromDB(db *sql.DB) error {
 	if db == nil {
 		return nil
 	}
-	rows, err := db.Query(`SELECT address FROM peers`)
+	records, err := LoadPeerRecords(db)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
-	for rows.Next() {
-		var addr string
-		if err := rows.Scan(&addr); err != nil {
-			return err
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range records {
+		if _, ok := m.peers[p.Address]; ok {
+			continue
+		}
+		p.Healthy = false
+		if p.Status != PeerBanned {
+			p.Status = PeerUnknown
 		}
-		m.AddPeer(addr)
+		m.peers[p.Address] = p
 	}
 	return nil
 }
 
+// SavePeerToDB records addr in the peers table if it is not there yet.
 func (m *Manager) SavePeerToDB(db *sql.DB, addr string) error {
 	if db == nil {
 		return nil
 	}
-	_, err := db.Exec(`INSERT INTO peers(address) VALUES($1)
+	_, err := db.Exec(`INSERT INTO peers (id, address) VALUES ($1, $1)
 	       ON CONFLICT (address) DO NOTHING`, addr)
 	return err
 }
@@ -0,0 +1,346 @@
+package net
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MDNSService is the DNS-SD service nodes announce on the LAN.
+const MDNSService = "_dis-net._tcp.local."
+
+// MDNSInterval is how often a node queries the LAN for peers.
+const MDNSInterval = time.Minute
+
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+const (
+	dnsTypePTR    = 12
+	dnsTypeTXT    = 16
+	dnsTypeSRV    = 33
+	dnsClassIN    = 1
+	dnsCacheFlush = 0x8000
+	mdnsTTL       = 120
+)
+
+var errBadDNS = errors.New("malformed dns message")
+
+type dnsQuestion struct {
+	Name []string
+	Type uint16
+}
+
+// dnsRecord is a resource record of one of the types DNS-SD uses.
+type dnsRecord struct {
+	Name   []string
+	Type   uint16
+	TTL    uint32
+	Target []string // PTR, SRV
+	Port   uint16   // SRV
+	Text   []string // TXT
+}
+
+type dnsMessage struct {
+	Response  bool
+	Questions []dnsQuestion
+	Records   []dnsRecord // answers and additional records
+}
+
+func labels(name string) []string {
+	return strings.Split(strings.TrimSuffix(name, "."), ".")
+}
+
+func sameName(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func packName(b []byte, name []string) []byte {
+	for _, l := range name {
+		b = append(b, byte(len(l)))
+		b = append(b, l...)
+	}
+	return append(b, 0)
+}
+
+// pack encodes m without name compression.
+func (m *dnsMessage) pack() []byte {
+	b := make([]byte, 12, 512)
+	if m.Response {
+		binary.BigEndian.PutUint16(b[2:], 0x8400) // response, authoritative
+	}
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.Questions)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(m.Records)))
+	for _, q := range m.Questions {
+		b = packName(b, q.Name)
+		b = binary.BigEndian.AppendUint16(b, q.Type)
+		b = binary.BigEndian.AppendUint16(b, dnsClassIN)
+	}
+	for _, r := range m.Records {
+		b = packName(b, r.Name)
+		b = binary.BigEndian.AppendUint16(b, r.Type)
+		class := uint16(dnsClassIN)
+		if r.Type != dnsTypePTR {
+			class |= dnsCacheFlush
+		}
+		b = binary.BigEndian.AppendUint16(b, class)
+		b = binary.BigEndian.AppendUint32(b, r.TTL)
+		var rdata []byte
+		switch r.Type {
+		case dnsTypePTR:
+			rdata = packName(nil, r.Target)
+		case dnsTypeSRV:
+			rdata = binary.BigEndian.AppendUint16(rdata, 0) // priority
+			rdata = binary.BigEndian.AppendUint16(rdata, 0) // weight
+			rdata = binary.BigEndian.AppendUint16(rdata, r.Port)
+			rdata = packName(rdata, r.Target)
+		case dnsTypeTXT:
+			for _, t := range r.Text {
+				rdata = append(rdata, byte(len(t)))
+				rdata = append(rdata, t...)
+			}
+		}
+		b = binary.BigEndian.AppendUint16(b, uint16(len(rdata)))
+		b = append(b, rdata...)
+	}
+	return b
+}
+
+// readName reads a possibly compressed name at off and returns it and the
+// offset just past it.
+func readName(b []byte, off int) ([]string, int, error) {
+	var name []string
+	end, jumps := -1, 0
+	for {
+		if off >= len(b) {
+			return nil, 0, errBadDNS
+		}
+		n := int(b[off])
+		switch {
+		case n == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return name, end, nil
+		case n&0xC0 == 0xC0:
+			if off+1 >= len(b) || jumps > 16 {
+				return nil, 0, errBadDNS
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(b[off:]) & 0x3FFF)
+			jumps++
+		default:
+			if off+1+n > len(b) {
+				return nil, 0, errBadDNS
+			}
+			name = append(name, string(b[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
+
+// unpackDNS decodes a DNS message, keeping PTR, SRV and TXT records.
+func unpackDNS(b []byte) (*dnsMessage, error) {
+	if len(b) < 12 {
+		return nil, errBadDNS
+	}
+	m := &dnsMessage{Response: b[2]&0x80 != 0}
+	qd := int(binary.BigEndian.Uint16(b[4:]))
+	rr := int(binary.BigEndian.Uint16(b[6:])) + int(binary.BigEndian.Uint16(b[8:])) + int(binary.BigEndian.Uint16(b[10:]))
+	off := 12
+	for i := 0; i < qd; i++ {
+		name, next, err := readName(b, off)
+		if err != nil || next+4 > len(b) {
+			return nil, errBadDNS
+		}
+		m.Questions = append(m.Questions, dnsQuestion{Name: name, Type: binary.BigEndian.Uint16(b[next:])})
+		off = next + 4
+	}
+	for i := 0; i < rr; i++ {
+		name, next, err := readName(b, off)
+		if err != nil || next+10 > len(b) {
+			return nil, errBadDNS
+		}
+		r := dnsRecord{Name: name, Type: binary.BigEndian.Uint16(b[next:]), TTL: binary.BigEndian.Uint32(b[next+4:])}
+		rdlen := int(binary.BigEndian.Uint16(b[next+8:]))
+		start := next + 10
+		if start+rdlen > len(b) {
+			return nil, errBadDNS
+		}
+		switch r.Type {
+		case dnsTypePTR:
+			if r.Target, _, err = readName(b, start); err != nil {
+				return nil, err
+			}
+		case dnsTypeSRV:
+			if rdlen < 7 {
+				return nil, errBadDNS
+			}
+			r.Port = binary.BigEndian.Uint16(b[start+4:])
+			if r.Target, _, err = readName(b, start+6); err != nil {
+				return nil, err
+			}
+		case dnsTypeTXT:
+			for p := start; p < start+rdlen; {
+				n := int(b[p])
+				if p+1+n > start+rdlen {
+					return nil, errBadDNS
+				}
+				r.Text = append(r.Text, string(b[p+1:p+1+n]))
+				p += 1 + n
+			}
+		default:
+			off = start + rdlen
+			continue
+		}
+		m.Records = append(m.Records, r)
+		off = start + rdlen
+	}
+	return m, nil
+}
+
+// MDNS announces this node on the LAN and adds the nodes it hears from as
+// peers. Each node answers queries for MDNSService with a record naming its
+// domain and peer port; the address is taken from where the answer came.
+type MDNS struct {
+	mgr      *Manager
+	instance string
+	port     int
+	text     []string
+	conn     *net.UDPConn
+	stop     chan struct{}
+}
+
+// StartMDNS joins the mDNS group and announces this node's peer port.
+// Multicast may be unavailable, in which case it returns an error and the
+// node relies on seeds and peer exchange.
+func (m *Manager) StartMDNS(port int) (*MDNS, error) {
+	id := m.nodeIdentity()
+	if id == nil {
+		return nil, errors.New("node identity not configured")
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		return nil, fmt.Errorf("mdns: %w", err)
+	}
+	md := newMDNS(m, id.Domain, port)
+	md.conn = conn
+	go md.readLoop()
+	go md.browseLoop()
+	log.Printf("📡 mDNS announcing %s on port %d", id.Domain, port)
+	return md, nil
+}
+
+func newMDNS(m *Manager, domain string, port int) *MDNS {
+	return &MDNS{
+		mgr:      m,
+		instance: domain,
+		port:     port,
+		text:     []string{"domain=" + domain, "proto=" + strconv.Itoa(ProtocolVersion)},
+		stop:     make(chan struct{}),
+	}
+}
+
+// Close leaves the group.
+func (md *MDNS) Close() error {
+	select {
+	case <-md.stop:
+		return nil
+	default:
+	}
+	close(md.stop)
+	return md.conn.Close()
+}
+
+func (md *MDNS) query() []byte {
+	return (&dnsMessage{Questions: []dnsQuestion{{Name: labels(MDNSService), Type: dnsTypePTR}}}).pack()
+}
+
+func (md *MDNS) answer() []byte {
+	service := labels(MDNSService)
+	instance := append([]string{md.instance}, service...)
+	return (&dnsMessage{Response: true, Records: []dnsRecord{
+		{Name: service, Type: dnsTypePTR, TTL: mdnsTTL, Target: instance},
+		{Name: instance, Type: dnsTypeSRV, TTL: mdnsTTL, Port: uint16(md.port), Target: []string{md.instance, "local"}},
+		{Name: instance, Type: dnsTypeTXT, TTL: mdnsTTL, Text: md.text},
+	}}).pack()
+}
+
+func (md *MDNS) browseLoop() {
+	t := time.NewTicker(MDNSInterval)
+	defer t.Stop()
+	for {
+		_, _ = md.conn.WriteToUDP(md.query(), mdnsGroup)
+		select {
+		case <-t.C:
+		case <-md.stop:
+			return
+		}
+	}
+}
+
+func (md *MDNS) readLoop() {
+	buf := make([]byte, 9000)
+	for {
+		n, src, err := md.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-md.stop:
+				return
+			default:
+				log.Printf("⚠️ mDNS read: %v", err)
+				return
+			}
+		}
+		if reply := md.handle(buf[:n], src); reply != nil {
+			_, _ = md.conn.WriteToUDP(reply, mdnsGroup)
+		}
+	}
+}
+
+// handle processes one packet from src, returning the answer to send if
+// it queried for MDNSService. Answers from other nodes are added as peers.
+func (md *MDNS) handle(pkt []byte, src *net.UDPAddr) []byte {
+	msg, err := unpackDNS(pkt)
+	if err != nil {
+		return nil
+	}
+	service := labels(MDNSService)
+	if !msg.Response {
+		for _, q := range msg.Questions {
+			if sameName(q.Name, service) && q.Type == dnsTypePTR {
+				return md.answer()
+			}
+		}
+		return nil
+	}
+	for _, ptr := range msg.Records {
+		if ptr.Type != dnsTypePTR || !sameName(ptr.Name, service) || len(ptr.Target) == 0 {
+			continue
+		}
+		for _, srv := range msg.Records {
+			if srv.Type != dnsTypeSRV || !sameName(srv.Name, ptr.Target) {
+				continue
+			}
+			if ptr.Target[0] == md.instance && int(srv.Port) == md.port {
+				continue // our own announcement
+			}
+			md.mgr.discover(net.JoinHostPort(src.IP.String(), strconv.Itoa(int(srv.Port))), SourceMDNS)
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package net
 
 import (
+	"math"
 	"net/http"
 	"os"
 	"time"
@@ -14,6 +15,16 @@ type NetworkConfig struct {
 	Codename    string                       `yaml:"codename"`
 	Peers       []Peer                       `yaml:"peers"`
 	TrustLevels map[string]map[string]string `yaml:"trust_levels"`
+
+	// Discovery
+	Seeds []string `yaml:"seeds"` // addresses dialed at startup
+	Allow []string `yaml:"allow"` // if set, only these domains/hosts are peered with
+	Ban   []string `yaml:"ban"`   // domains/hosts never peered with
+	MDNS  bool     `yaml:"mdns"`  // discover peers on the LAN over multicast DNS
+
+	// Trust policy; see TrustPolicyFromConfig.
+	DefaultTrustLevel string `yaml:"default_trust_level"`  // level of peers not listed
+	MaxAutoTrustLevel string `yaml:"max_auto_trust_level"` // highest level reached by promotion
 }
 
 func LoadNetworkConfig(path string) (*NetworkConfig, error) {
@@ -28,14 +39,82 @@ func LoadNetworkConfig(path string) (*NetworkConfig, error) {
 	return &cfg, nil
 }
 
+// PeerStatus classifies a peer after its last contact.
+type PeerStatus string
+
+const (
+	PeerUnknown      PeerStatus = "unknown"
+	PeerHealthy      PeerStatus = "healthy"      // authenticated, same frozen core, session open
+	PeerIncompatible PeerStatus = "incompatible" // authenticated, but runs a different frozen core
+	PeerRejected     PeerStatus = "rejected"     // handshake failed
+	PeerUnreachable  PeerStatus = "unreachable"  // no connection
+	PeerBanned       PeerStatus = "banned"       // refused by the access list
+)
+
+// Where a peer record came from.
+const (
+	SourceConfig  = "config"  // network.yaml peers
+	SourceSeed    = "seed"    // static seed list
+	SourceMDNS    = "mdns"    // LAN multicast DNS
+	SourcePEX     = "pex"     // peer exchange with a connected node
+	SourceInbound = "inbound" // the peer connected to us
+	SourceManual  = "manual"  // AddPeer
+)
+
 // Peer represents another DIS node.
 type Peer struct {
-	ID        string    `json:"id"`
-	Address   string    `json:"address"`
-	LastSeen  time.Time `json:"last_seen"`
-	Healthy   bool      `json:"healthy"`
-	Version   string    `json:"version"`
-	LatencyMS int64     `json:"latency_ms"`
+	ID         string     `json:"id"`
+	Address    string     `json:"address"`
+	LastSeen   time.Time  `json:"last_seen"`
+	Healthy    bool       `json:"healthy"`
+	Version    string     `json:"version"`
+	LatencyMS  int64      `json:"latency_ms"`
+	Status     PeerStatus `json:"status"`
+	Domain     string     `json:"domain,omitempty"` // authenticated in the handshake
+	KeyID      string     `json:"key_id,omitempty"`
+	Protocol   int        `json:"protocol,omitempty"` // negotiated protocol version
+	SchemaHash string     `json:"schema_hash,omitempty"`
+	CoreHash   string     `json:"core_hash,omitempty"`
+	Error      string     `json:"error,omitempty"` // why the last contact failed
+
+	Source    string    `json:"source,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+	UptimeSec int64     `json:"uptime_s"` // time connected, over all sessions
+	Failures  int       `json:"verification_failures"`
+	Score     float64   `json:"score"`
+	Banned    bool      `json:"banned,omitempty"`
+
+	TrustLevel string `json:"trust_level,omitempty" yaml:"trust_level"`
+	// PublicKeyB64, set in network.yaml, pins the key Domain signs with.
+	PublicKeyB64 string `json:"public_key_b64,omitempty" yaml:"public_key_b64"`
+
+	connectedAt time.Time // start of the open session, if any
+	ephemeral   bool      // known only by an inbound source port; not shared
+}
+
+// uptime is the peer's total time connected, including the open session.
+func (p *Peer) uptime(now time.Time) time.Duration {
+	up := time.Duration(p.UptimeSec) * time.Second
+	if !p.connectedAt.IsZero() {
+		up += now.Sub(p.connectedAt)
+	}
+	return up
+}
+
+// rescore rates the peer from 0 to 1 on latency, on the share of time
+// since it was first seen that it has been connected, and on how many
+// handshakes, receipts or peer exchanges from it failed verification.
+func (p *Peer) rescore(now time.Time) {
+	latency := 0.5 // not measured yet
+	if p.LatencyMS > 0 {
+		latency = 1 / (1 + float64(p.LatencyMS)/100)
+	}
+	uptime := 0.0
+	if life := now.Sub(p.FirstSeen); life > 0 {
+		uptime = math.Min(1, p.uptime(now).Seconds()/life.Seconds())
+	}
+	reliability := 1 / (1 + float64(p.Failures))
+	p.Score = math.Round((0.2*latency+0.4*uptime+0.4*reliability)*1000) / 1000
 }
 
 // PingPeer checks if a peer responds to /api/status.
@@ -0,0 +1,128 @@
+package net
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"dis-core/internal/util/crypto"
+)
+
+// MsgPeerExchange carries a signed list of peers the sender knows.
+const MsgPeerExchange = "pex.peers"
+
+// MaxPeerExchange bounds how many peers one exchange message carries.
+const MaxPeerExchange = 32
+
+// peerExchangeMaxAge is how old a peer exchange may be when it arrives.
+const peerExchangeMaxAge = 5 * time.Minute
+
+// PeerAd advertises one peer in a peer exchange.
+type PeerAd struct {
+	Address string  `json:"address"`
+	Domain  string  `json:"domain,omitempty"`
+	KeyID   string  `json:"key_id,omitempty"`
+	Score   float64 `json:"score"`
+}
+
+// PeerExchange lists healthy peers of From, best scored first, signed with
+// the key From authenticated its session with.
+type PeerExchange struct {
+	From      string    `json:"from"`
+	IssuedAt  time.Time `json:"issued_at"`
+	Peers     []PeerAd  `json:"peers"`
+	KeyID     string    `json:"key_id"`
+	Signature string    `json:"signature"`
+}
+
+func (px *PeerExchange) digest() ([]byte, error) {
+	peers, err := json.Marshal(px.Peers)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "dis-net/pex\x00%s\x00%s\x00", px.From, px.IssuedAt.UTC().Format(time.RFC3339Nano))
+	h.Write(peers)
+	return h.Sum(nil), nil
+}
+
+// sharePeers sends s a signed list of this node's healthy peers, other
+// than s's own.
+func (m *Manager) sharePeers(s *Session) {
+	m.mu.RLock()
+	id := m.identity
+	var ads []PeerAd
+	for addr, p := range m.peers {
+		if p.Status != PeerHealthy || p.Banned || p.ephemeral || p.Domain == s.Peer.Domain {
+			continue
+		}
+		ads = append(ads, PeerAd{Address: addr, Domain: p.Domain, KeyID: p.KeyID, Score: p.Score})
+	}
+	m.mu.RUnlock()
+	if id == nil || len(ads) == 0 {
+		return
+	}
+	sort.Slice(ads, func(i, j int) bool {
+		if ads[i].Score != ads[j].Score {
+			return ads[i].Score > ads[j].Score
+		}
+		return ads[i].Address < ads[j].Address
+	})
+	if len(ads) > MaxPeerExchange {
+		ads = ads[:MaxPeerExchange]
+	}
+	key, err := id.Keys.Active(id.Domain)
+	if err != nil {
+		return
+	}
+	px := PeerExchange{From: id.Domain, IssuedAt: time.Now().UTC(), Peers: ads, KeyID: key.KeyID}
+	msg, err := px.digest()
+	if err != nil {
+		return
+	}
+	if px.Signature, err = id.Keys.Sign(key.KeyID, msg); err != nil {
+		return
+	}
+	_ = s.Send(MsgPeerExchange, px)
+}
+
+// onPeerExchange checks that a peer exchange is fresh and signed by the
+// session's peer, then adds the peers it lists. One that does not verify
+// counts against the sender.
+func (m *Manager) onPeerExchange(s *Session, msg Message) {
+	var px PeerExchange
+	if err := msg.Decode(&px); err != nil {
+		m.ReportFailure(s, err.Error())
+		return
+	}
+	if err := verifyPeerExchange(&px, s.Peer); err != nil {
+		m.ReportFailure(s, err.Error())
+		return
+	}
+	for i, ad := range px.Peers {
+		if i == MaxPeerExchange {
+			break
+		}
+		m.discover(ad.Address, SourcePEX)
+	}
+}
+
+func verifyPeerExchange(px *PeerExchange, from Hello) error {
+	if px.From != from.Domain || px.KeyID != from.KeyID {
+		return fmt.Errorf("peer exchange from %s signed as %s", from.Domain, px.From)
+	}
+	if age := time.Since(px.IssuedAt); age > peerExchangeMaxAge || age < -peerExchangeMaxAge {
+		return fmt.Errorf("stale peer exchange from %s", from.Domain)
+	}
+	digest, err := px.digest()
+	if err != nil {
+		return err
+	}
+	rec := crypto.KeyRecord{KeyID: from.KeyID, Domain: from.Domain, PublicKeyB64: from.PublicKeyB64}
+	if !rec.Verify(digest, px.Signature) {
+		return fmt.Errorf("bad peer exchange signature from %s", from.Domain)
+	}
+	return nil
+}
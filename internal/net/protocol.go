@@ -0,0 +1,93 @@
+package net
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ProtocolVersion is the newest peer wire protocol this node speaks.
+const ProtocolVersion = 1
+
+// SupportedVersions lists every protocol version this node accepts.
+var SupportedVersions = []int{1}
+
+// MaxFrameSize bounds a single frame on the wire.
+const MaxFrameSize = 1 << 20
+
+// Message types of the peer protocol. Types beyond these are dispatched to
+// handlers registered with Manager.Handle.
+const (
+	MsgHello = "hello" // handshake: identity, versions and hashes
+	MsgAuth  = "auth"  // handshake: signature over both hellos
+	MsgError = "error" // handshake refused; the connection closes
+	MsgPing  = "ping"
+	MsgPong  = "pong"
+)
+
+var ErrFrameTooLarge = errors.New("frame exceeds maximum size")
+
+// Message is one frame: a type and a JSON payload.
+type Message struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// NewMessage encodes v as the payload of a message of type typ.
+func NewMessage(typ string, v any) (Message, error) {
+	if v == nil {
+		return Message{Type: typ}, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return Message{}, fmt.Errorf("encode %s: %w", typ, err)
+	}
+	return Message{Type: typ, Payload: b}, nil
+}
+
+// Decode unmarshals the message payload into v.
+func (m Message) Decode(v any) error {
+	if err := json.Unmarshal(m.Payload, v); err != nil {
+		return fmt.Errorf("decode %s: %w", m.Type, err)
+	}
+	return nil
+}
+
+// writeFrame writes msg as a 4-byte big-endian length followed by its JSON.
+func writeFrame(w io.Writer, msg Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if len(b) > MaxFrameSize {
+		return ErrFrameTooLarge
+	}
+	buf := make([]byte, 4+len(b))
+	binary.BigEndian.PutUint32(buf, uint32(len(b)))
+	copy(buf[4:], b)
+	_, err = w.Write(buf)
+	return err
+}
+
+// readFrame reads one frame written by writeFrame.
+func readFrame(r io.Reader) (Message, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return Message{}, err
+	}
+	n := binary.BigEndian.Uint32(hdr[:])
+	if n > MaxFrameSize {
+		return Message{}, ErrFrameTooLarge
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return Message{}, err
+	}
+	var msg Message
+	if err := json.Unmarshal(b, &msg); err != nil {
+		return Message{}, fmt.Errorf("decode frame: %w", err)
+	}
+	return msg, nil
+}
@@ -0,0 +1,195 @@
+package net
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"dis-core/internal/ledger"
+)
+
+// ForeignEntry is a replicated chain entry with where it came from.
+type ForeignEntry struct {
+	Origin     string            `json:"origin"` // domain whose ledger it belongs to
+	Via        string            `json:"via"`    // peer domain that delivered it
+	ReceivedAt time.Time         `json:"received_at"`
+	Entry      ledger.ChainEntry `json:"entry"`
+}
+
+// ReplicaStore keeps other nodes' ledgers, one chain per origin, apart from
+// the node's own receipts.
+type ReplicaStore interface {
+	// Origins lists the origins with replicated entries.
+	Origins() ([]string, error)
+	// Append adds verified entries continuing origin's chain.
+	Append(origin, via string, entries []ledger.ChainEntry) error
+	// Entries returns origin's entries with receipts in seqs [from, to]
+	// and the checkpoints sealing them, in chain order; to == 0 means to
+	// the head.
+	Entries(origin string, from, to uint64) ([]ForeignEntry, error)
+}
+
+// entrySeq is the chain position of a receipt or checkpoint.
+func entrySeq(e ledger.ChainEntry) uint64 {
+	if e.Checkpoint != nil {
+		return e.Checkpoint.Seq
+	}
+	if e.Receipt != nil {
+		return e.Receipt.Seq
+	}
+	return 0
+}
+
+// inRange reports whether e falls in [from, to] (to == 0: unbounded).
+func inRange(e ledger.ChainEntry, from, to uint64) bool {
+	seq := entrySeq(e)
+	return seq >= from && (to == 0 || seq <= to)
+}
+
+// MemoryReplicaStore is an in-process ReplicaStore.
+type MemoryReplicaStore struct {
+	mu      sync.RWMutex
+	origins map[string][]ForeignEntry
+}
+
+// NewMemoryReplicaStore returns an empty in-memory replica store.
+func NewMemoryReplicaStore() *MemoryReplicaStore {
+	return &MemoryReplicaStore{origins: map[string][]ForeignEntry{}}
+}
+
+func (s *MemoryReplicaStore) Origins() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]string, 0, len(s.origins))
+	for o := range s.origins {
+		out = append(out, o)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func (s *MemoryReplicaStore) Append(origin, via string, entries []ledger.ChainEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	for _, e := range entries {
+		s.origins[origin] = append(s.origins[origin], ForeignEntry{Origin: origin, Via: via, ReceivedAt: now, Entry: e})
+	}
+	return nil
+}
+
+func (s *MemoryReplicaStore) Entries(origin string, from, to uint64) ([]ForeignEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []ForeignEntry
+	for _, fe := range s.origins[origin] {
+		if inRange(fe.Entry, from, to) {
+			out = append(out, fe)
+		}
+	}
+	return out, nil
+}
+
+// EnsureForeignReceiptsTable creates the foreign_receipts table.
+func EnsureForeignReceiptsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS foreign_receipts (
+		origin TEXT NOT NULL,
+		seq BIGINT NOT NULL,
+		kind TEXT NOT NULL, -- 'receipt' or 'checkpoint'
+		receipt_id TEXT,
+		via TEXT NOT NULL,
+		received_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		content JSONB NOT NULL,
+		PRIMARY KEY (origin, seq, kind)
+	);
+	CREATE INDEX IF NOT EXISTS foreign_receipts_receipt_idx ON foreign_receipts (receipt_id);
+	`)
+	return err
+}
+
+// PGReplicaStore keeps replicated entries in the foreign_receipts table.
+type PGReplicaStore struct {
+	db *sql.DB
+}
+
+// NewPGReplicaStore returns a store over db. EnsureForeignReceiptsTable
+// must have been run.
+func NewPGReplicaStore(db *sql.DB) *PGReplicaStore {
+	return &PGReplicaStore{db: db}
+}
+
+func (s *PGReplicaStore) Origins() ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT origin FROM foreign_receipts ORDER BY origin`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var o string
+		if err := rows.Scan(&o); err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+func (s *PGReplicaStore) Append(origin, via string, entries []ledger.ChainEntry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, e := range entries {
+		content, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		kind, id := "receipt", any(nil)
+		if e.Checkpoint != nil {
+			kind = "checkpoint"
+		} else {
+			id = e.Receipt.ReceiptID
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO foreign_receipts (origin, seq, kind, receipt_id, via, content)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			origin, entrySeq(e), kind, id, via, content); err != nil {
+			return fmt.Errorf("store %s seq %d: %w", origin, entrySeq(e), err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *PGReplicaStore) Entries(origin string, from, to uint64) ([]ForeignEntry, error) {
+	q := `SELECT via, received_at, content FROM foreign_receipts WHERE origin = $1 AND seq >= $2`
+	args := []any{origin, from}
+	if to > 0 {
+		q += ` AND seq <= $3`
+		args = append(args, to)
+	}
+	// A checkpoint follows the receipt at its seq.
+	rows, err := s.db.Query(q+` ORDER BY seq, kind DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ForeignEntry
+	for rows.Next() {
+		fe := ForeignEntry{Origin: origin}
+		var content []byte
+		if err := rows.Scan(&fe.Via, &fe.ReceivedAt, &content); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(content, &fe.Entry); err != nil {
+			return nil, fmt.Errorf("decode foreign entry: %w", err)
+		}
+		out = append(out, fe)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,551 @@
+package net
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"dis-core/internal/ledger"
+	"dis-core/internal/util/crypto"
+)
+
+// Replication message types.
+const (
+	MsgHeads      = "ledger.heads"   // the ledger heads a node holds, by origin
+	MsgRangeQuery = "ledger.range"   // request for a range of an origin's chain
+	MsgEntries    = "ledger.entries" // the requested range
+)
+
+// DefaultReplicationBatch is how many receipts a range request asks for.
+const DefaultReplicationBatch = 256
+
+// DefaultAntiEntropyInterval is how often heads are re-advertised, so
+// peers catch up after a partition even without new receipts.
+const DefaultAntiEntropyInterval = 30 * time.Second
+
+var ErrReplicaRejected = errors.New("replicated entries rejected")
+
+// Heads is the MsgHeads payload: the head of every chain a node holds, its
+// own and the replicas, by origin domain.
+type Heads struct {
+	Heads map[string]ledger.ChainHead `json:"heads"`
+}
+
+// RangeQuery asks for origin's receipts in seqs [From, To].
+type RangeQuery struct {
+	Origin string `json:"origin"`
+	From   uint64 `json:"from"`
+	To     uint64 `json:"to"`
+}
+
+// Entries answers a RangeQuery with the receipts and the checkpoints
+// sealing them, and the responder's head for the origin.
+type Entries struct {
+	Origin  string              `json:"origin"`
+	From    uint64              `json:"from"`
+	Entries []ledger.ChainEntry `json:"entries"`
+	Head    ledger.ChainHead    `json:"head"`
+}
+
+// TrustRecorder records verification events between peers;
+// ledger.TrustLedger is one.
+type TrustRecorder interface {
+	Add(entry ledger.TrustEntry) error
+}
+
+// replica is the verified state of one origin's replicated chain.
+type replica struct {
+	verifier *ledger.ChainVerifier
+	pending  *Session // session with a range request in flight
+}
+
+func (rp *replica) head() ledger.ChainHead {
+	return rp.verifier.Report().Head
+}
+
+// Replicator gossips ledger heads over a Manager's sessions and pulls the
+// receipts it is missing. The node's own receipts come from local; other
+// origins' chains are verified entry by entry (signatures, chain linkage,
+// checkpoints) and kept in replicas, tagged with the origin and the peer
+// that delivered them. Replicas are served onward, so receipts spread
+// beyond direct peers.
+type Replicator struct {
+	mgr      *Manager
+	self     string
+	local    ledger.ReceiptStore
+	replicas ReplicaStore
+	trust    TrustRecorder
+	batch    uint64
+
+	mu     sync.Mutex
+	chains map[string]*replica
+}
+
+// NewReplicator registers replication on m, serving self's ledger from
+// local and keeping other origins in replicas. Existing replicas are
+// re-verified to rebuild chain state.
+func NewReplicator(m *Manager, self string, local ledger.ReceiptStore, replicas ReplicaStore) (*Replicator, error) {
+	r := &Replicator{
+		mgr:      m,
+		self:     self,
+		local:    local,
+		replicas: replicas,
+		batch:    DefaultReplicationBatch,
+		chains:   map[string]*replica{},
+	}
+	origins, err := replicas.Origins()
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range origins {
+		entries, err := replicas.Entries(o, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		batch := &keyBatch{mgr: m}
+		v := r.newVerifier(o).WithKeys(batch.keys, o)
+		for _, fe := range entries {
+			batch.verify(v, fe.Entry)
+		}
+		if rep := v.Report(); !rep.OK() {
+			return nil, fmt.Errorf("replica %s: %s", o, rep.Errors[0])
+		}
+		batch.commit()
+		r.chains[o] = &replica{verifier: v.WithKeys(m.peerKeys, o)}
+	}
+	m.Handle(MsgHeads, r.onHeads)
+	m.Handle(MsgRangeQuery, r.onRangeQuery)
+	m.Handle(MsgEntries, r.onEntries)
+	m.OnConnect(r.advertiseTo)
+	return r, nil
+}
+
+// WithTrust records every receipt sent and received in t, and returns the
+// replicator (chainable).
+func (r *Replicator) WithTrust(t TrustRecorder) *Replicator {
+	r.trust = t
+	return r
+}
+
+// WithBatch sets how many receipts each range request asks for.
+func (r *Replicator) WithBatch(n int) *Replicator {
+	if n > 0 {
+		r.batch = uint64(n)
+	}
+	return r
+}
+
+// Run re-advertises heads every interval until stop is closed.
+func (r *Replicator) Run(interval time.Duration, stop <-chan struct{}) {
+	if interval <= 0 {
+		interval = DefaultAntiEntropyInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			r.Advertise()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Heads returns the head of the local chain and of every replica.
+func (r *Replicator) Heads() (map[string]ledger.ChainHead, error) {
+	own, err := r.localEntries(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	heads := map[string]ledger.ChainHead{}
+	if h := chainHead(own); h.Seq > 0 {
+		heads[r.self] = h
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for o, rp := range r.chains {
+		if h := rp.head(); h.Seq > 0 {
+			heads[o] = h
+		}
+	}
+	return heads, nil
+}
+
+// Advertise sends this node's heads to every open session.
+func (r *Replicator) Advertise() {
+	for _, s := range r.mgr.Sessions() {
+		r.advertiseTo(s)
+	}
+}
+
+func (r *Replicator) advertiseTo(s *Session) {
+	scope := r.mgr.scope(s)
+	if scope == ScopeNone {
+		return
+	}
+	heads, err := r.Heads()
+	if err != nil {
+		log.Printf("⚠️ replication heads: %v", err)
+		return
+	}
+	if scope == ScopeOwn {
+		for origin := range heads {
+			if origin != r.self {
+				delete(heads, origin)
+			}
+		}
+	}
+	_ = s.Send(MsgHeads, Heads{Heads: heads})
+}
+
+// onHeads requests the next missing range of every origin the peer is
+// ahead on, if the peer's trust level lets it send entries. Peers not
+// trusted with every chain are asked only for their own.
+func (r *Replicator) onHeads(s *Session, msg Message) {
+	var h Heads
+	if err := msg.Decode(&h); err != nil {
+		log.Printf("⚠️ %s: %v", s.Peer.Domain, err)
+		return
+	}
+	if !r.mgr.mayReceive(s, MsgEntries) {
+		return
+	}
+	for origin, theirs := range h.Heads {
+		if origin == r.self || !r.takes(s, origin) {
+			continue
+		}
+		r.mu.Lock()
+		rp := r.replica(origin)
+		ours := rp.head()
+		if theirs.Seq == ours.Seq && theirs.ChainHash != ours.ChainHash {
+			r.mu.Unlock()
+			log.Printf("⚠️ %s reports a different chain for %s at seq %d", s.Peer.Domain, origin, theirs.Seq)
+			continue
+		}
+		if theirs.Seq <= ours.Seq || (rp.pending != nil && !sessionClosed(rp.pending)) {
+			r.mu.Unlock()
+			continue
+		}
+		rp.pending = s
+		r.mu.Unlock()
+		r.request(s, origin, ours.Seq+1, theirs.Seq)
+	}
+}
+
+// takes reports whether origin's chain is taken from the peer on s: its
+// own always, others' only at a level whose replication scope is all.
+func (r *Replicator) takes(s *Session, origin string) bool {
+	return origin == s.Peer.Domain || r.mgr.scope(s) == ScopeAll
+}
+
+func (r *Replicator) request(s *Session, origin string, from, upto uint64) {
+	to := from + r.batch - 1
+	if to > upto {
+		to = upto
+	}
+	if err := s.Send(MsgRangeQuery, RangeQuery{Origin: origin, From: from, To: to}); err != nil {
+		r.clearPending(origin, s)
+	}
+}
+
+// onRangeQuery serves a range of the local chain or of a replica, within
+// the peer's replication scope.
+func (r *Replicator) onRangeQuery(s *Session, msg Message) {
+	var q RangeQuery
+	if err := msg.Decode(&q); err != nil || q.From == 0 || q.To < q.From {
+		log.Printf("⚠️ %s sent a bad range query", s.Peer.Domain)
+		return
+	}
+	switch r.mgr.scope(s) {
+	case ScopeNone:
+		return
+	case ScopeOwn:
+		if q.Origin != r.self {
+			log.Printf("⛔ %s may not replicate %s", s.Peer.Domain, q.Origin)
+			return
+		}
+	}
+	if q.To-q.From >= r.batch {
+		q.To = q.From + r.batch - 1
+	}
+	resp := Entries{Origin: q.Origin, From: q.From, Entries: []ledger.ChainEntry{}}
+	if q.Origin == r.self {
+		all, err := r.localEntries(0, 0)
+		if err != nil {
+			log.Printf("⚠️ replication: %v", err)
+			return
+		}
+		resp.Head = chainHead(all)
+		for _, e := range all {
+			if inRange(e, q.From, q.To) {
+				resp.Entries = append(resp.Entries, e)
+			}
+		}
+	} else {
+		fes, err := r.replicas.Entries(q.Origin, q.From, q.To)
+		if err != nil {
+			log.Printf("⚠️ replication: %v", err)
+			return
+		}
+		for _, fe := range fes {
+			resp.Entries = append(resp.Entries, fe.Entry)
+		}
+		r.mu.Lock()
+		if rp, ok := r.chains[q.Origin]; ok {
+			resp.Head = rp.head()
+		}
+		r.mu.Unlock()
+	}
+	fitFrame(&resp)
+	if err := s.Send(MsgEntries, resp); err != nil {
+		return
+	}
+	for _, e := range resp.Entries {
+		if e.Receipt != nil {
+			r.record(s, "sent", "ok", e.Receipt.ReceiptID, "")
+		}
+	}
+}
+
+// onEntries verifies a delivered range against the replica and stores it,
+// then asks for more if the peer is still ahead. A range that advances the
+// replica counts toward the origin's promotion.
+func (r *Replicator) onEntries(s *Session, msg Message) {
+	var in Entries
+	if err := msg.Decode(&in); err != nil {
+		log.Printf("⚠️ %s: %v", s.Peer.Domain, err)
+		return
+	}
+	if in.Origin == r.self || in.Origin == "" {
+		return
+	}
+	if !r.takes(s, in.Origin) {
+		log.Printf("⛔ %s may not relay %s", s.Peer.Domain, in.Origin)
+		return
+	}
+	r.mu.Lock()
+	rp := r.replica(in.Origin)
+	head, err := r.apply(rp, in, s.Peer.Domain)
+	if rp.pending == s {
+		rp.pending = nil
+	}
+	r.mu.Unlock()
+
+	if err != nil {
+		log.Printf("⚠️ %s: %v", s.Peer.Domain, err)
+		if errors.Is(err, ErrReplicaRejected) {
+			r.mgr.ReportFailure(s, err.Error())
+		}
+		for _, e := range in.Entries {
+			if e.Receipt != nil {
+				r.record(s, "received", "fail", e.Receipt.ReceiptID, err.Error())
+			}
+		}
+		return
+	}
+	for _, e := range in.Entries {
+		if e.Receipt != nil {
+			r.record(s, "received", "ok", e.Receipt.ReceiptID, "")
+		}
+	}
+	if len(in.Entries) == 0 {
+		return
+	}
+	if in.Head.Seq > head.Seq {
+		r.mu.Lock()
+		if rp.pending == nil {
+			rp.pending = s
+		}
+		r.mu.Unlock()
+		r.request(s, in.Origin, head.Seq+1, in.Head.Seq)
+		return
+	}
+	// Caught up: pass the news on.
+	r.Advertise()
+}
+
+// apply checks in against rp and, if every entry verifies, stores it and
+// advances rp. Entries the replica already holds are skipped. r.mu must
+// be held.
+func (r *Replicator) apply(rp *replica, in Entries, via string) (ledger.ChainHead, error) {
+	head := rp.head()
+	var fresh []ledger.ChainEntry
+	for _, e := range in.Entries {
+		seq := entrySeq(e)
+		if seq == 0 {
+			return head, fmt.Errorf("%w: unchained entry from %s", ErrReplicaRejected, in.Origin)
+		}
+		if seq < head.Seq+1 && !(e.Checkpoint != nil && seq == head.Seq && rp.verifier.Report().SealedSeq < seq) {
+			continue
+		}
+		fresh = append(fresh, e)
+	}
+	if len(fresh) == 0 {
+		return head, nil
+	}
+
+	batch := &keyBatch{mgr: r.mgr}
+	v := rp.verifier.Clone().WithKeys(batch.keys, in.Origin)
+	before := len(v.Report().Errors)
+	for _, e := range fresh {
+		batch.verify(v, e)
+		if errs := v.Report().Errors; len(errs) > before {
+			return head, fmt.Errorf("%w: %s: %s", ErrReplicaRejected, in.Origin, errs[before])
+		}
+	}
+	if err := r.replicas.Append(in.Origin, via, fresh); err != nil {
+		return head, err
+	}
+	batch.commit()
+	rp.verifier = v.WithKeys(r.mgr.peerKeys, in.Origin)
+	return rp.head(), nil
+}
+
+// newVerifier starts verifying origin's chain against the key histories
+// the node knows peers by, expecting origin to seal its own checkpoints.
+func (r *Replicator) newVerifier(origin string) *ledger.ChainVerifier {
+	return ledger.NewChainVerifier().WithKeys(r.mgr.peerKeys, origin)
+}
+
+// keyBatch verifies a run of entries with the key transitions seen in it.
+// A transition endorsed by a key the node knows takes effect for the rest
+// of the run, so the chain verifies across the rotation, but reaches
+// KnownKeys only when the run is accepted and commit is called.
+type keyBatch struct {
+	mgr       *Manager
+	handovers []crypto.KeyRecord
+}
+
+// keys resolves domain's key from the node's key histories with the
+// batch's handovers applied, as KeyStore.Register would apply them.
+func (b *keyBatch) keys(domain, keyID string, t time.Time) (crypto.KeyRecord, error) {
+	var next []crypto.KeyRecord
+	for _, k := range b.handovers {
+		if k.Domain == domain {
+			next = append(next, k)
+		}
+	}
+	if len(next) == 0 {
+		return b.mgr.peerKeys(domain, keyID, t)
+	}
+	b.mgr.mu.RLock()
+	id := b.mgr.identity
+	b.mgr.mu.RUnlock()
+	var history []crypto.KeyRecord
+	if id != nil {
+		h, err := id.history(domain)
+		if err != nil {
+			return crypto.KeyRecord{}, err
+		}
+		history = append(history, h...)
+	}
+	for _, k := range next {
+		for i := range history {
+			if history[i].Status == crypto.KeyActive {
+				until := k.ValidFrom
+				history[i].Status, history[i].ValidUntil = crypto.KeyRetired, &until
+			}
+		}
+		k.Status = crypto.KeyActive
+		history = append(history, k)
+	}
+	return crypto.KeyInHistory(history, domain, keyID, t)
+}
+
+// verify adds e to v, which must resolve keys with b.keys.
+func (b *keyBatch) verify(v *ledger.ChainVerifier, e ledger.ChainEntry) {
+	if rc := e.Receipt; rc != nil && rc.SchemaRef == ledger.KeyTransitionSchemaRef {
+		if next, err := ledger.KeyHandover(rc, b.keys); err == nil {
+			b.handovers = append(b.handovers, next)
+		}
+	}
+	v.Add(e)
+}
+
+// commit registers the batch's handovers in KnownKeys.
+func (b *keyBatch) commit() {
+	ks := b.mgr.knownKeys()
+	if ks == nil {
+		return
+	}
+	for _, k := range b.handovers {
+		if _, err := ks.Register(k.Domain, k.PublicKeyB64, k.ValidFrom); err != nil {
+			log.Printf("⚠️  %s key rotation: %v", k.Domain, err)
+		}
+	}
+}
+
+// replica returns origin's replica state, creating it; r.mu must be held.
+func (r *Replicator) replica(origin string) *replica {
+	rp, ok := r.chains[origin]
+	if !ok {
+		rp = &replica{verifier: r.newVerifier(origin)}
+		r.chains[origin] = rp
+	}
+	return rp
+}
+
+func (r *Replicator) clearPending(origin string, s *Session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rp, ok := r.chains[origin]; ok && rp.pending == s {
+		rp.pending = nil
+	}
+}
+
+// localEntries returns the chained entries of the local ledger in [from, to].
+func (r *Replicator) localEntries(from, to uint64) ([]ledger.ChainEntry, error) {
+	all, err := r.local.Chain()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ledger.ChainEntry, 0, len(all))
+	for _, e := range all {
+		if entrySeq(e) > 0 && inRange(e, from, to) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// fitFrame halves resp's entries until it fits in one frame; the requester
+// asks again from where the reply stops.
+func fitFrame(resp *Entries) {
+	for len(resp.Entries) > 1 {
+		msg, err := NewMessage(MsgEntries, resp)
+		if err != nil || len(msg.Payload) < MaxFrameSize-1024 {
+			return
+		}
+		resp.Entries = resp.Entries[:len(resp.Entries)/2]
+	}
+}
+
+// chainHead is the head after the last receipt in entries.
+func chainHead(entries []ledger.ChainEntry) ledger.ChainHead {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if r := entries[i].Receipt; r != nil && r.Seq > 0 {
+			return ledger.ChainHead{Seq: r.Seq, ChainHash: r.ChainHash}
+		}
+	}
+	return ledger.ChainHead{Seq: 0, ChainHash: ledger.GenesisHash}
+}
+
+func (r *Replicator) record(s *Session, action, status, receiptID, notes string) {
+	if r.trust == nil {
+		return
+	}
+	if err := r.trust.Add(ledger.TrustEntry{
+		Peer:       s.Peer.Domain,
+		Action:     action,
+		Status:     status,
+		ReceiptID:  receiptID,
+		CoreHash:   s.Peer.CoreHash,
+		VerifiedAt: time.Now().UTC(),
+		Notes:      notes,
+	}); err != nil {
+		log.Printf("⚠️ trust record: %v", err)
+	}
+}
@@ -0,0 +1,319 @@
+package net
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"dis-core/internal/ledger"
+	"dis-core/internal/util/crypto"
+)
+
+// TestMain signs with keys held on a mock PKCS#11 token, so tests never
+// touch the on-disk key directory.
+func TestMain(m *testing.M) {
+	token := crypto.NewMockPKCS11Token("1234")
+	if err := token.Login("1234"); err != nil {
+		panic(err)
+	}
+	ks := crypto.NewPKCS11KeyStore(token)
+	for _, d := range []string{ledger.NodeDomain, "domain.a", "domain.b", "domain.c"} {
+		if _, err := ks.Generate(d); err != nil {
+			panic(err)
+		}
+	}
+	crypto.SetDefaultKeyStore(ks)
+	os.Exit(m.Run())
+}
+
+type testNode struct {
+	domain   string
+	addr     string
+	mgr      *Manager
+	local    *ledger.MemoryStore
+	replicas *MemoryReplicaStore
+	rep      *Replicator
+}
+
+func newTestNode(t *testing.T, domain string) *testNode {
+	t.Helper()
+	n := &testNode{domain: domain, local: ledger.NewMemoryStore().WithSealer(domain), replicas: NewMemoryReplicaStore()}
+	n.mgr = NewManager(nil)
+	if err := n.mgr.Listen(0); err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	n.addr = fmt.Sprintf("127.0.0.1:%d", n.mgr.ln.Addr().(*net.TCPAddr).Port)
+	n.mgr.WithIdentity(&Identity{
+		Domain:     domain,
+		Keys:       crypto.DefaultKeyStore(),
+		CoreHash:   "core.test",
+		ListenAddr: n.addr,
+		KnownKeys:  crypto.DefaultKeyStore(),
+	})
+	rep, err := NewReplicator(n.mgr, domain, n.local, n.replicas)
+	if err != nil {
+		t.Fatalf("NewReplicator: %v", err)
+	}
+	n.rep = rep.WithBatch(3)
+	t.Cleanup(n.mgr.Close)
+	return n
+}
+
+// newEnvelope is ledger.NewEnvelope, failing t if the receipt cannot be
+// sealed.
+func newEnvelope(t *testing.T, schemaRef, by, action string, payload map[string]any) *ledger.Receipt {
+	t.Helper()
+	r, err := ledger.NewEnvelope(schemaRef, by, action, payload)
+	if err != nil {
+		t.Fatalf("NewEnvelope: %v", err)
+	}
+	return r
+}
+
+func (n *testNode) append(t *testing.T, count int) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		r := newEnvelope(t, "test.event.v0", n.domain, "replication.test", map[string]any{"n": i})
+		if err := n.local.Append(r); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	n.rep.Advertise()
+}
+
+func (n *testNode) dial(t *testing.T, to *testNode) {
+	t.Helper()
+	if _, err := n.mgr.Dial(to.addr); err != nil {
+		t.Fatalf("Dial %s: %v", to.domain, err)
+	}
+}
+
+func (n *testNode) head(origin string) uint64 {
+	heads, err := n.rep.Heads()
+	if err != nil {
+		return 0
+	}
+	return heads[origin].Seq
+}
+
+func waitHead(t *testing.T, n *testNode, origin string, seq uint64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if n.head(origin) == seq {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s: head of %s is %d, want %d", n.domain, origin, n.head(origin), seq)
+}
+
+// TestReplication runs three in-process nodes in a line, a — b — c (c bans
+// a, so peer exchange cannot connect them), and checks that receipts reach
+// every node, that c catches up after being partitioned from b, and that
+// replicas record their origin and carrier.
+func TestReplication(t *testing.T) {
+	prev := ledger.CheckpointEvery
+	ledger.CheckpointEvery = 4
+	defer func() { ledger.CheckpointEvery = prev }()
+
+	a := newTestNode(t, "domain.a")
+	b := newTestNode(t, "domain.b")
+	c := newTestNode(t, "domain.c")
+	c.mgr.WithAccessList(AccessList{Ban: []string{"domain.a"}})
+	a.append(t, 2) // advertised on connect
+	b.dial(t, a)
+	c.dial(t, b)
+	waitHead(t, c, "domain.a", 2)
+
+	a.append(t, 8)
+	b.append(t, 2)
+	waitHead(t, b, "domain.a", 10)
+	waitHead(t, c, "domain.a", 10)
+	waitHead(t, a, "domain.b", 2)
+	waitHead(t, c, "domain.b", 2)
+
+	// Partition c from b; a keeps writing.
+	c.mgr.Ban("domain.b")
+	waitFor(t, "b to lose c", func() bool { return len(b.mgr.Sessions()) == 1 })
+	a.append(t, 7)
+	waitHead(t, b, "domain.a", 17)
+	if got := c.head("domain.a"); got != 10 {
+		t.Fatalf("partitioned c advanced to %d", got)
+	}
+
+	// Reconnecting exchanges heads and c catches up.
+	c.mgr.Unban("domain.b")
+	c.dial(t, b)
+	waitHead(t, c, "domain.a", 17)
+
+	entries, err := c.replicas.Entries("domain.a", 0, 0)
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	var receipts, checkpoints int
+	for _, fe := range entries {
+		if fe.Origin != "domain.a" || fe.Via != "domain.b" {
+			t.Fatalf("entry tagged %s via %s", fe.Origin, fe.Via)
+		}
+		if fe.Entry.Checkpoint != nil {
+			checkpoints++
+		} else {
+			receipts++
+		}
+	}
+	if receipts != 17 || checkpoints != 4 {
+		t.Fatalf("c holds %d receipts and %d checkpoints of a, want 17 and 4", receipts, checkpoints)
+	}
+	own, _ := a.local.Chain()
+	var sent []ledger.ChainEntry
+	for _, fe := range entries {
+		sent = append(sent, fe.Entry)
+	}
+	v := ledger.NewChainVerifier().WithKeys(ledger.StoreKeys(nil), "domain.a")
+	for _, e := range sent {
+		v.Add(e)
+	}
+	if rep := v.Report(); !rep.OK() || rep.Head != chainHead(own) || rep.SealedSeq != 16 {
+		t.Fatalf("replica chain: %+v", rep)
+	}
+
+	// A restarted replicator rebuilds its state from the replica store.
+	again, err := NewReplicator(knowing(crypto.DefaultKeyStore()), "domain.c", c.local, c.replicas)
+	if err != nil {
+		t.Fatalf("NewReplicator: %v", err)
+	}
+	heads, _ := again.Heads()
+	if heads["domain.a"].Seq != 17 || heads["domain.b"].Seq != 2 {
+		t.Fatalf("rebuilt heads: %+v", heads)
+	}
+}
+
+// TestReplicationRejectsTampering checks that a range whose receipts do not
+// verify or do not link to the replica's head is refused and not stored.
+func TestReplicationRejectsTampering(t *testing.T) {
+	src := ledger.NewMemoryStore()
+	for i := 0; i < 3; i++ {
+		if err := src.Append(newEnvelope(t, "test.event.v0", "domain.a", "replication.test", map[string]any{"n": i})); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	chain, _ := src.Chain()
+	r, err := NewReplicator(knowing(crypto.DefaultKeyStore()), "domain.b", ledger.NewMemoryStore(), NewMemoryReplicaStore())
+	if err != nil {
+		t.Fatalf("NewReplicator: %v", err)
+	}
+
+	forged := *chain[1].Receipt
+	forged.Payload = map[string]any{"n": 99}
+	cases := map[string][]ledger.ChainEntry{
+		"gap":    {chain[1], chain[2]},
+		"forged": {chain[0], {Receipt: &forged}, chain[2]},
+	}
+	for name, entries := range cases {
+		r.mu.Lock()
+		_, err := r.apply(r.replica("domain.a"), Entries{Origin: "domain.a", Entries: entries}, "domain.c")
+		r.mu.Unlock()
+		if !errors.Is(err, ErrReplicaRejected) {
+			t.Fatalf("%s: apply = %v, want ErrReplicaRejected", name, err)
+		}
+	}
+	if got, _ := r.replicas.Entries("domain.a", 0, 0); len(got) != 0 {
+		t.Fatalf("stored %d rejected entries", len(got))
+	}
+
+	r.mu.Lock()
+	head, err := r.apply(r.replica("domain.a"), Entries{Origin: "domain.a", Entries: chain}, "domain.c")
+	r.mu.Unlock()
+	if err != nil || head.Seq != 3 {
+		t.Fatalf("apply = %+v, %v", head, err)
+	}
+}
+
+// knowing returns an unconnected manager that verifies peers against keys.
+func knowing(keys crypto.KeyStore) *Manager {
+	return NewManager(nil).WithIdentity(&Identity{Domain: "domain.b", KnownKeys: keys})
+}
+
+// TestReplicationFollowsRotation replicates a chain whose origin rotates
+// its key midway, to a node that pinned only the origin's first key. The
+// new key is pinned only if the whole range is accepted.
+func TestReplicationFollowsRotation(t *testing.T) {
+	var ks crypto.KeyStore
+	endorse := func(d string) error {
+		_, _, err := ledger.RotateDomainKey(d)
+		return err
+	}
+	cases := []struct {
+		name   string
+		origin string
+		rotate func(domain string) error
+		forge  bool // tamper with the receipt after the rotation
+		want   error
+	}{
+		{"endorsed rotation", "domain.r", endorse, false, nil},
+		{"unannounced rotation", "domain.s", func(d string) error {
+			_, _, err := ks.Rotate(d)
+			return err
+		}, false, ErrReplicaRejected},
+		{"endorsed rotation in a forged range", "domain.t", endorse, true, ErrReplicaRejected},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ks = rogueKeys(t, tc.origin)
+			prevKeys := crypto.DefaultKeyStore()
+			crypto.SetDefaultKeyStore(ks)
+			defer crypto.SetDefaultKeyStore(prevKeys)
+			first, err := ks.Active(tc.origin)
+			if err != nil {
+				t.Fatalf("Active: %v", err)
+			}
+			known := rogueKeys(t)
+			if _, err := known.Register(tc.origin, first.PublicKeyB64, time.Time{}); err != nil {
+				t.Fatalf("Register: %v", err)
+			}
+			src := ledger.NewMemoryStore().WithSealer(tc.origin)
+			prev := ledger.DefaultStore()
+			ledger.SetDefaultStore(src)
+			defer ledger.SetDefaultStore(prev)
+
+			add := func() {
+				if err := src.Append(newEnvelope(t, "test.event.v0", tc.origin, "replication.test", map[string]any{})); err != nil {
+					t.Fatalf("Append: %v", err)
+				}
+			}
+			add()
+			if err := tc.rotate(tc.origin); err != nil {
+				t.Fatalf("rotate: %v", err)
+			}
+			add()
+			chain, _ := src.Chain()
+			if tc.forge {
+				forged := *chain[len(chain)-1].Receipt
+				forged.Payload = map[string]any{"forged": true}
+				chain[len(chain)-1] = ledger.ChainEntry{Receipt: &forged}
+			}
+
+			r, err := NewReplicator(knowing(known), "domain.b", ledger.NewMemoryStore(), NewMemoryReplicaStore())
+			if err != nil {
+				t.Fatalf("NewReplicator: %v", err)
+			}
+			r.mu.Lock()
+			_, err = r.apply(r.replica(tc.origin), Entries{Origin: tc.origin, Entries: chain}, "domain.c")
+			r.mu.Unlock()
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("apply: %v, want %v", err, tc.want)
+			}
+			want := 2
+			if tc.want != nil {
+				want = 1
+			}
+			if history, _ := known.History(tc.origin); len(history) != want {
+				t.Fatalf("known %s keys: %+v, want %d", tc.origin, history, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package net
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// IdleTimeout closes a session that has sent nothing, not even a pong, for
+// this long. Managers ping every health-check interval, well inside it.
+const IdleTimeout = 90 * time.Second
+
+// ErrSessionClosed is returned by Send on a closed session.
+var ErrSessionClosed = errors.New("session closed")
+
+// Handler processes a message received on a session.
+type Handler func(s *Session, msg Message)
+
+// Session is an authenticated, long-lived framed message channel to a peer.
+type Session struct {
+	conn      net.Conn
+	Peer      Hello // the peer's handshake hello
+	Version   int   // negotiated protocol version
+	Initiator bool  // whether this side dialed
+
+	// Compatible reports whether the peer runs the same frozen core.
+	Compatible bool
+
+	wmu       sync.Mutex
+	mu        sync.Mutex
+	lastSeen  time.Time
+	pingSent  time.Time
+	rtt       time.Duration
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newSession(conn net.Conn, id *Identity, peer Hello, version int, initiator bool) *Session {
+	return &Session{
+		conn:       conn,
+		Peer:       peer,
+		Version:    version,
+		Initiator:  initiator,
+		Compatible: peer.CoreHash == id.CoreHash,
+		lastSeen:   time.Now(),
+		done:       make(chan struct{}),
+	}
+}
+
+// RemoteAddr is the address the session's connection comes from.
+func (s *Session) RemoteAddr() string {
+	return s.conn.RemoteAddr().String()
+}
+
+// Send writes a message of type typ carrying v.
+func (s *Session) Send(typ string, v any) error {
+	msg, err := NewMessage(typ, v)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-s.done:
+		return ErrSessionClosed
+	default:
+	}
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	_ = s.conn.SetWriteDeadline(time.Now().Add(HandshakeTimeout))
+	if err := writeFrame(s.conn, msg); err != nil {
+		s.Close()
+		return err
+	}
+	return nil
+}
+
+// Ping sends a ping; the matching pong updates RTT.
+func (s *Session) Ping() error {
+	s.mu.Lock()
+	s.pingSent = time.Now()
+	s.mu.Unlock()
+	return s.Send(MsgPing, nil)
+}
+
+// RTT is the round-trip time of the last answered ping, or zero.
+func (s *Session) RTT() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rtt
+}
+
+// LastSeen is when the peer last sent a frame.
+func (s *Session) LastSeen() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSeen
+}
+
+// Done is closed when the session ends.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close ends the session.
+func (s *Session) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.conn.Close()
+	})
+	return err
+}
+
+// Run reads frames until the session ends, answering pings and passing
+// every other message to handle. It closes the session before returning.
+func (s *Session) Run(handle Handler) error {
+	defer s.Close()
+	for {
+		_ = s.conn.SetReadDeadline(time.Now().Add(IdleTimeout))
+		msg, err := readFrame(s.conn)
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		s.mu.Lock()
+		s.lastSeen = time.Now()
+		if msg.Type == MsgPong && !s.pingSent.IsZero() {
+			s.rtt, s.pingSent = s.lastSeen.Sub(s.pingSent), time.Time{}
+		}
+		s.mu.Unlock()
+
+		switch msg.Type {
+		case MsgPing:
+			_ = s.Send(MsgPong, nil)
+		case MsgPong:
+		default:
+			if handle != nil {
+				handle(s, msg)
+			}
+		}
+	}
+}
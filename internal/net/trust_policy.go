@@ -0,0 +1,454 @@
+package net
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Trust levels, least privileged first.
+const (
+	LevelObserver  = "observer"  // may read and serve its own chain
+	LevelReplica   = "replica"   // may also relay other chains and attest
+	LevelFederated = "federated" // as replica, at a higher rate
+	LevelSovereign = "sovereign" // as replica, at the highest rate
+)
+
+// Governance message types. Each trust level states which of them a peer
+// may send; a node handles them with Manager.Handle.
+const (
+	MsgSchemaProposal = "schema.propose" // a proposed schema or schema change
+	MsgAmendmentVote  = "amendment.vote" // a vote on a proposed amendment
+	MsgRevocation     = "revocation"     // a revocation to propagate
+)
+
+// ReplicationScope is which chains are served to a peer.
+type ReplicationScope string
+
+const (
+	ScopeNone ReplicationScope = "none" // nothing
+	ScopeOwn  ReplicationScope = "own"  // this node's ledger only
+	ScopeAll  ReplicationScope = "all"  // this node's ledger and its replicas
+)
+
+var (
+	ErrMessageNotAllowed = errors.New("message type not allowed at peer's trust level")
+	ErrRateLimited       = errors.New("peer exceeded its rate limit")
+)
+
+// LevelPolicy is what a peer at one trust level may do.
+type LevelPolicy struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Allow       []string         `json:"allow"` // message types; "x.*" matches a prefix
+	Rate        float64          `json:"rate"`  // messages per second
+	Burst       int              `json:"burst"`
+	Replication ReplicationScope `json:"replication"`
+}
+
+func (lp *LevelPolicy) allows(typ string) bool {
+	for _, a := range lp.Allow {
+		if a == typ || a == "*" || (strings.HasSuffix(a, ".*") && strings.HasPrefix(typ, strings.TrimSuffix(a, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultLevels are the built-in trust levels, least privileged first.
+// Observers may answer attestation requests, so the receipts they attest
+// for this node count toward their promotion; the replication scope
+// decides whose chains are taken from them. Governance is by level:
+// observers send none of it, replicas relay revocations, and federated
+// and sovereign peers may also propose schemas and vote on amendments.
+func DefaultLevels() []LevelPolicy {
+	read := []string{MsgHeads, MsgRangeQuery, MsgEntries, MsgPeerExchange, MsgAttestations}
+	relay := append(append([]string{}, read...), MsgAttestRequest, MsgRevocation)
+	govern := append(append([]string{}, relay...), MsgSchemaProposal, MsgAmendmentVote)
+	return []LevelPolicy{
+		{Name: LevelObserver, Allow: read, Rate: 5, Burst: 20, Replication: ScopeOwn},
+		{Name: LevelReplica, Allow: relay, Rate: 50, Burst: 200, Replication: ScopeAll},
+		{Name: LevelFederated, Allow: govern, Rate: 100, Burst: 400, Replication: ScopeAll},
+		{Name: LevelSovereign, Allow: govern, Rate: 200, Burst: 800, Replication: ScopeAll},
+	}
+}
+
+// levelAliases maps the trust levels of older network.yaml files.
+var levelAliases = map[string]string{
+	"trusted": LevelFederated,
+	"self":    LevelSovereign,
+}
+
+// Automatic promotion and demotion thresholds. At most
+// DefaultCreditPerWindow successes count per DefaultCreditWindow, so
+// promotion takes sustained good behaviour rather than a burst of it.
+const (
+	DefaultPromoteAfter    = 100 // verified outcomes in a row to rise a level
+	DefaultDemoteAfter     = 3   // failed outcomes since the last change to drop one
+	DefaultCreditPerWindow = 10
+	DefaultCreditWindow    = time.Hour
+)
+
+// peerTrust is a peer's current level and the outcomes since it last
+// changed.
+type peerTrust struct {
+	level     string
+	successes int
+	failures  int
+	credited  int       // successes counted in the current window
+	window    time.Time // start of the current credit window
+	tokens    float64
+	refilled  time.Time
+}
+
+// TrustPolicy assigns peers trust levels and enforces them: which message
+// types a peer may send, how fast, and which chains are served to it.
+// Levels move with verification outcomes: a peer that keeps correctly
+// attesting this node's receipts is promoted, up to a ceiling, and one that
+// fails verification is demoted, down to the lowest level.
+type TrustPolicy struct {
+	mu           sync.Mutex
+	levels       map[string]*LevelPolicy
+	order        []string          // least privileged first
+	assigned     map[string]string // configured level by peer domain
+	defaultLevel string
+	maxAuto      string
+	promoteAfter int
+	demoteAfter  int
+	creditCap    int // successes counted per creditWindow
+	creditWindow time.Duration
+	peers        map[string]*peerTrust // by peer domain
+}
+
+// NewTrustPolicy returns a policy over levels (least privileged first)
+// that starts unlisted peers at the lowest level and promotes up to the
+// second highest.
+func NewTrustPolicy(levels []LevelPolicy) *TrustPolicy {
+	tp := &TrustPolicy{
+		levels:       map[string]*LevelPolicy{},
+		assigned:     map[string]string{},
+		promoteAfter: DefaultPromoteAfter,
+		demoteAfter:  DefaultDemoteAfter,
+		creditCap:    DefaultCreditPerWindow,
+		creditWindow: DefaultCreditWindow,
+		peers:        map[string]*peerTrust{},
+	}
+	for i := range levels {
+		lp := levels[i]
+		tp.levels[lp.Name] = &lp
+		tp.order = append(tp.order, lp.Name)
+	}
+	if len(tp.order) > 0 {
+		tp.defaultLevel = tp.order[0]
+		tp.maxAuto = tp.order[max(0, len(tp.order)-2)]
+	}
+	return tp
+}
+
+// TrustPolicyFromConfig builds the policy for cfg: the default levels,
+// changed or extended by cfg.TrustLevels, and the levels of cfg.Peers,
+// matched by domain. A trust_levels entry may set, besides its
+// description:
+//
+//	allow:       comma-separated message types ("x.*" matches a prefix)
+//	rate, burst: messages per second and burst size
+//	replication: none, own or all
+//
+// A new level ranks above the built-in ones and starts from the lowest.
+// The "trusted" and "self" levels of older files mean federated and
+// sovereign.
+func TrustPolicyFromConfig(cfg *NetworkConfig) (*TrustPolicy, error) {
+	levels := DefaultLevels()
+	index := map[string]int{}
+	for i, lp := range levels {
+		index[lp.Name] = i
+	}
+	names := make([]string, 0, len(cfg.TrustLevels))
+	for name := range cfg.TrustLevels {
+		names = append(names, name)
+	}
+	sort.Strings(names) // new levels rank in name order
+	for _, name := range names {
+		settings := cfg.TrustLevels[name]
+		if alias, ok := levelAliases[name]; ok {
+			name = alias
+		}
+		i, ok := index[name]
+		if !ok {
+			lp := levels[0]
+			lp.Name, lp.Allow = name, append([]string{}, lp.Allow...)
+			levels = append(levels, lp)
+			i = len(levels) - 1
+			index[name] = i
+		}
+		lp := &levels[i]
+		for k, v := range settings {
+			var err error
+			switch k {
+			case "description":
+				lp.Description = v
+			case "allow":
+				lp.Allow = nil
+				for _, t := range strings.Split(v, ",") {
+					if t = strings.TrimSpace(t); t != "" {
+						lp.Allow = append(lp.Allow, t)
+					}
+				}
+			case "rate":
+				lp.Rate, err = strconv.ParseFloat(v, 64)
+			case "burst":
+				lp.Burst, err = strconv.Atoi(v)
+			case "replication":
+				switch s := ReplicationScope(v); s {
+				case ScopeNone, ScopeOwn, ScopeAll:
+					lp.Replication = s
+				default:
+					err = fmt.Errorf("unknown scope %q", v)
+				}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("trust level %s: %s: %w", name, k, err)
+			}
+		}
+	}
+
+	tp := NewTrustPolicy(levels)
+	for _, set := range []struct {
+		key   string
+		level string
+		into  *string
+	}{
+		{"default_trust_level", cfg.DefaultTrustLevel, &tp.defaultLevel},
+		{"max_auto_trust_level", cfg.MaxAutoTrustLevel, &tp.maxAuto},
+	} {
+		if set.level == "" {
+			continue
+		}
+		level := tp.canonical(set.level)
+		if _, ok := tp.levels[level]; !ok {
+			return nil, fmt.Errorf("%s: unknown trust level %q", set.key, set.level)
+		}
+		*set.into = level
+	}
+	for _, p := range cfg.Peers {
+		if p.TrustLevel == "" || p.Domain == "" {
+			continue
+		}
+		if err := tp.Assign(p.Domain, p.TrustLevel); err != nil {
+			return nil, err
+		}
+	}
+	return tp, nil
+}
+
+func (tp *TrustPolicy) canonical(level string) string {
+	if alias, ok := levelAliases[level]; ok {
+		return alias
+	}
+	return level
+}
+
+func (tp *TrustPolicy) rank(level string) int {
+	for i, l := range tp.order {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// Assign sets domain's configured level, restarting its outcome count.
+func (tp *TrustPolicy) Assign(domain, level string) error {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	level = tp.canonical(level)
+	if _, ok := tp.levels[level]; !ok {
+		return fmt.Errorf("peer %s: unknown trust level %q", domain, level)
+	}
+	tp.assigned[domain] = level
+	delete(tp.peers, domain)
+	return nil
+}
+
+// peer returns domain's state, starting it at its configured level; tp.mu
+// must be held.
+func (tp *TrustPolicy) peer(domain string) *peerTrust {
+	pt, ok := tp.peers[domain]
+	if !ok {
+		level, ok := tp.assigned[domain]
+		if !ok {
+			level = tp.defaultLevel
+		}
+		pt = &peerTrust{level: level, refilled: time.Now()}
+		pt.tokens = float64(tp.levels[level].Burst)
+		tp.peers[domain] = pt
+	}
+	return pt
+}
+
+// Level returns domain's current trust level.
+func (tp *TrustPolicy) Level(domain string) string {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	return tp.peer(domain).level
+}
+
+// Levels returns the level policies, least privileged first.
+func (tp *TrustPolicy) Levels() []LevelPolicy {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	out := make([]LevelPolicy, 0, len(tp.order))
+	for _, l := range tp.order {
+		out = append(out, *tp.levels[l])
+	}
+	return out
+}
+
+// Allows reports whether domain's level allows typ.
+func (tp *TrustPolicy) Allows(domain, typ string) bool {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	return tp.levels[tp.peer(domain).level].allows(typ)
+}
+
+// Admit checks that domain's level allows typ and takes one message from
+// its rate budget.
+func (tp *TrustPolicy) Admit(domain, typ string) error {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	pt := tp.peer(domain)
+	lp := tp.levels[pt.level]
+	if !lp.allows(typ) {
+		return fmt.Errorf("%w: %s (%s) sent %s", ErrMessageNotAllowed, domain, pt.level, typ)
+	}
+	now := time.Now()
+	pt.tokens = min(float64(lp.Burst), pt.tokens+now.Sub(pt.refilled).Seconds()*lp.Rate)
+	pt.refilled = now
+	if pt.tokens < 1 {
+		return fmt.Errorf("%w: %s (%s)", ErrRateLimited, domain, pt.level)
+	}
+	pt.tokens--
+	return nil
+}
+
+// Scope is which chains are served to domain.
+func (tp *TrustPolicy) Scope(domain string) ReplicationScope {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	return tp.levels[tp.peer(domain).level].Replication
+}
+
+// Record counts a verification outcome for domain: a batch of this node's
+// receipts it attested, or anything it sent that failed to verify. Successes beyond the
+// per-window credit are ignored. It returns the level after the outcome
+// and whether it changed.
+func (tp *TrustPolicy) Record(domain string, ok bool) (string, bool) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	pt := tp.peer(domain)
+	r := tp.rank(pt.level)
+	if ok {
+		if now := time.Now(); now.Sub(pt.window) >= tp.creditWindow {
+			pt.window, pt.credited = now, 0
+		}
+		if pt.credited >= tp.creditCap {
+			return pt.level, false
+		}
+		pt.credited++
+		pt.successes++
+		if pt.failures == 0 && pt.successes >= tp.promoteAfter && r < tp.rank(tp.maxAuto) {
+			return tp.move(domain, pt, tp.order[r+1]), true
+		}
+		return pt.level, false
+	}
+	pt.failures++
+	if pt.failures >= tp.demoteAfter && r > 0 {
+		return tp.move(domain, pt, tp.order[r-1]), true
+	}
+	return pt.level, false
+}
+
+func (tp *TrustPolicy) move(domain string, pt *peerTrust, level string) string {
+	log.Printf("⚖️ Peer %s trust level %s → %s", domain, pt.level, level)
+	pt.level, pt.successes, pt.failures = level, 0, 0
+	return level
+}
+
+// WithTrustPolicy enforces tp on every session: messages a peer's level
+// does not allow, or that exceed its rate, are dropped. Without a policy
+// every peer may send everything.
+func (m *Manager) WithTrustPolicy(tp *TrustPolicy) *Manager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.trust = tp
+	return m
+}
+
+func (m *Manager) trustPolicy() *TrustPolicy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.trust
+}
+
+// admitMessage applies the trust policy to msg from s.
+func (m *Manager) admitMessage(s *Session, msg Message) error {
+	tp := m.trustPolicy()
+	if tp == nil {
+		return nil
+	}
+	return tp.Admit(s.Peer.Domain, msg.Type)
+}
+
+// mayReceive reports whether the peer on s may send typ, without taking
+// from its rate budget.
+func (m *Manager) mayReceive(s *Session, typ string) bool {
+	tp := m.trustPolicy()
+	if tp == nil {
+		return true
+	}
+	return tp.Allows(s.Peer.Domain, typ)
+}
+
+// scope is which chains are served to the peer on s.
+func (m *Manager) scope(s *Session) ReplicationScope {
+	tp := m.trustPolicy()
+	if tp == nil {
+		return ScopeAll
+	}
+	return tp.Scope(s.Peer.Domain)
+}
+
+// ReportVerified counts an attestation batch domain answered for this
+// node toward its promotion. Ranges of domain's own chain do not count: a
+// peer can sign as many receipts as it likes.
+func (m *Manager) ReportVerified(domain string) {
+	m.recordOutcome(domain, true)
+}
+
+func (m *Manager) recordOutcome(domain string, ok bool) {
+	tp := m.trustPolicy()
+	if tp == nil {
+		return
+	}
+	level, changed := tp.Record(domain, ok)
+	if !changed {
+		return
+	}
+	m.mu.Lock()
+	var addrs []string
+	for addr, p := range m.peers {
+		if p.Domain == domain {
+			p.TrustLevel = level
+			addrs = append(addrs, addr)
+		}
+	}
+	m.mu.Unlock()
+	for _, addr := range addrs {
+		m.persist(addr)
+	}
+}
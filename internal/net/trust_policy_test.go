@@ -0,0 +1,206 @@
+package net
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"dis-core/internal/ledger"
+)
+
+func TestTrustPolicyConfig(t *testing.T) {
+	cfg := &NetworkConfig{
+		TrustLevels: map[string]map[string]string{
+			"observer": {"rate": "1", "burst": "2"},
+			"trusted":  {"description": "Verified by governance"},
+			"auditor":  {"allow": "ledger.*", "replication": "all"},
+		},
+		Peers: []Peer{
+			{Domain: "domain.b", TrustLevel: "trusted"},
+			{Domain: "domain.c", TrustLevel: "auditor"},
+		},
+	}
+	tp, err := TrustPolicyFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("TrustPolicyFromConfig: %v", err)
+	}
+	if got := tp.Level("domain.b"); got != LevelFederated {
+		t.Fatalf("trusted peer at %s", got)
+	}
+	if got := tp.Level("domain.x"); got != LevelObserver {
+		t.Fatalf("unlisted peer at %s", got)
+	}
+
+	if err := tp.Admit("domain.x", MsgAttestRequest); !errors.Is(err, ErrMessageNotAllowed) {
+		t.Fatalf("observer asked for attestations: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := tp.Admit("domain.x", MsgHeads); err != nil {
+			t.Fatalf("observer heads %d: %v", i, err)
+		}
+	}
+	if err := tp.Admit("domain.x", MsgHeads); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("observer past its burst: %v", err)
+	}
+	if err := tp.Admit("domain.b", "schema.withdraw"); !errors.Is(err, ErrMessageNotAllowed) {
+		t.Fatalf("federated peer sent an unknown type: %v", err)
+	}
+	if err := tp.Admit("domain.b", MsgAttestRequest); err != nil {
+		t.Fatalf("federated peer asked for attestations: %v", err)
+	}
+	if err := tp.Admit("domain.c", MsgEntries); err != nil {
+		t.Fatalf("auditor sent entries: %v", err)
+	}
+
+	if tp.Scope("domain.x") != ScopeOwn || tp.Scope("domain.c") != ScopeAll {
+		t.Fatalf("scopes: %s, %s", tp.Scope("domain.x"), tp.Scope("domain.c"))
+	}
+
+	// Governance messages by level.
+	gov := NewTrustPolicy(DefaultLevels())
+	for level, allowed := range map[string][]bool{
+		LevelObserver:  {false, false, false},
+		LevelReplica:   {false, false, true},
+		LevelFederated: {true, true, true},
+		LevelSovereign: {true, true, true},
+	} {
+		if err := gov.Assign("domain."+level, level); err != nil {
+			t.Fatalf("Assign: %v", err)
+		}
+		for i, typ := range []string{MsgSchemaProposal, MsgAmendmentVote, MsgRevocation} {
+			if got := gov.Allows("domain."+level, typ); got != allowed[i] {
+				t.Fatalf("%s may send %s: %v, want %v", level, typ, got, allowed[i])
+			}
+		}
+	}
+
+	bad := []*NetworkConfig{
+		{TrustLevels: map[string]map[string]string{"observer": {"replication": "some"}}},
+		{Peers: []Peer{{Domain: "domain.b", TrustLevel: "king"}}},
+		{DefaultTrustLevel: "king"},
+	}
+	for i, cfg := range bad {
+		if _, err := TrustPolicyFromConfig(cfg); err == nil {
+			t.Fatalf("bad config %d accepted", i)
+		}
+	}
+}
+
+func TestTrustPromotion(t *testing.T) {
+	tp := NewTrustPolicy(DefaultLevels())
+	tp.promoteAfter = 3
+	for _, want := range []string{LevelReplica, LevelFederated, LevelFederated} {
+		for i := 0; i < 3; i++ {
+			tp.Record("domain.b", true)
+		}
+		if got := tp.Level("domain.b"); got != want {
+			t.Fatalf("level %s, want %s", got, want)
+		}
+	}
+
+	// A failure holds promotion back; enough of them demote.
+	tp.Record("domain.c", false)
+	for i := 0; i < 5; i++ {
+		tp.Record("domain.c", true)
+	}
+	if got := tp.Level("domain.c"); got != LevelObserver {
+		t.Fatalf("promoted past a failure to %s", got)
+	}
+	for i := 0; i < DefaultDemoteAfter; i++ {
+		tp.Record("domain.b", false)
+	}
+	if got := tp.Level("domain.b"); got != LevelReplica {
+		t.Fatalf("demoted to %s", got)
+	}
+
+	// Successes past the window's credit do not count.
+	tp.creditCap = 2
+	for i := 0; i < 10; i++ {
+		tp.Record("domain.d", true)
+	}
+	if got := tp.Level("domain.d"); got != LevelObserver {
+		t.Fatalf("promoted on a burst to %s", got)
+	}
+	tp.mu.Lock()
+	tp.peers["domain.d"].window = time.Now().Add(-tp.creditWindow)
+	tp.mu.Unlock()
+	if got, _ := tp.Record("domain.d", true); got != LevelReplica {
+		t.Fatalf("next window: %s", got)
+	}
+}
+
+// TestReplicationScope runs a — b — c where b holds a at replica level and
+// c at the default observer level: c may replicate b's own chain but not
+// a's, and b takes c's own chain only.
+func TestReplicationScope(t *testing.T) {
+	a := newTestNode(t, "domain.a")
+	b := newTestNode(t, "domain.b")
+	c := newTestNode(t, "domain.c")
+	tp := NewTrustPolicy(DefaultLevels())
+	if err := tp.Assign("domain.a", LevelReplica); err != nil {
+		t.Fatalf("Assign: %v", err)
+	}
+	b.mgr.WithTrustPolicy(tp)
+	c.mgr.WithAccessList(AccessList{Ban: []string{"domain.a"}})
+
+	a.append(t, 2)
+	b.dial(t, a)
+	c.dial(t, b)
+	waitHead(t, b, "domain.a", 2)
+	b.append(t, 1)
+	c.append(t, 1)
+	waitHead(t, c, "domain.b", 1)
+	waitHead(t, a, "domain.b", 1)
+
+	c.rep.Advertise()
+	time.Sleep(200 * time.Millisecond)
+	if got := c.head("domain.a"); got != 0 {
+		t.Fatalf("observer c replicated a to %d", got)
+	}
+	waitHead(t, b, "domain.c", 1)
+	if p, _ := peerRecord(b.mgr, c.addr); p.TrustLevel != LevelObserver {
+		t.Fatalf("b's record of c: %+v", p)
+	}
+}
+
+// TestObserverPromotion checks that observer c is not promoted by b for
+// ranges of its own chain, but is once it has attested b's receipts, and
+// only then does b take the chain c relays from a.
+func TestObserverPromotion(t *testing.T) {
+	a := newTestNode(t, "domain.a")
+	b := newTestNode(t, "domain.b")
+	c := newTestNode(t, "domain.c")
+	tp := NewTrustPolicy(DefaultLevels())
+	tp.promoteAfter = 2
+	b.mgr.WithTrustPolicy(tp)
+	b.mgr.WithAccessList(AccessList{Ban: []string{"domain.a"}}) // a's chain only via c
+	attestor := NewAttestor(b.mgr, b.local, b.replicas, &ledger.TrustLedger{}).WithBatch(1)
+	NewAttestor(c.mgr, c.local, c.replicas, &ledger.TrustLedger{})
+
+	a.append(t, 2)
+	c.dial(t, a)
+	waitHead(t, c, "domain.a", 2)
+	c.append(t, 1)
+	b.dial(t, c)
+	waitHead(t, b, "domain.c", 1)
+	c.append(t, 2)
+	waitHead(t, b, "domain.c", 3)
+	if got := tp.Level("domain.c"); got != LevelObserver {
+		t.Fatalf("c at %s for ranges of its own chain", got)
+	}
+	if got := b.head("domain.a"); got != 0 {
+		t.Fatalf("b took a's chain from observer c up to %d", got)
+	}
+
+	// Each answered batch of one receipt counts once.
+	b.append(t, 2)
+	for _, s := range b.mgr.Sessions() {
+		attestor.requestFrom(s)
+	}
+	waitFor(t, "c promoted", func() bool { return tp.Level("domain.c") == LevelReplica })
+	if p, _ := peerRecord(b.mgr, c.addr); p.TrustLevel != LevelReplica {
+		t.Fatalf("b's record of c: %+v", p)
+	}
+	c.rep.Advertise()
+	waitHead(t, b, "domain.a", 2)
+}
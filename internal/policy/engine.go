@@ -7,11 +7,17 @@ import (
 	"path/filepath"
 
 	"github.com/open-policy-agent/opa/rego"
+
+	"dis-core/internal/redaction"
 )
 
 type OPAEngine struct {
 	gatesRego *rego.PreparedEvalQuery
 	riskRego  *rego.PreparedEvalQuery
+
+	// Redaction is the policy loaded from EngineConfig.PathRedactionYAML,
+	// applied to receipts in API responses and exports.
+	Redaction *redaction.Policy
 }
 
 func NewOPAEngine() (*OPAEngine, error) {
@@ -6,10 +6,13 @@ import (
 	"os"
 
 	"github.com/open-policy-agent/opa/rego"
+
+	"dis-core/internal/redaction"
 )
 
 // NewEngine builds an OPAEngine from the file paths in cfg.
 // (Freeze/Cedar/thresholds are ignored here for the minimal bring-up.)
+// The redaction policy is optional; without one nothing is redacted.
 func NewEngine(cfg EngineConfig) (*OPAEngine, error) {
 	gatesSrc, err := os.ReadFile(cfg.PathGatesRego)
 	if err != nil {
@@ -36,5 +39,11 @@ func NewEngine(cfg EngineConfig) (*OPAEngine, error) {
 		return nil, fmt.Errorf("prepare risk: %w", err)
 	}
 
-	return &OPAEngine{gatesRego: &gq, riskRego: &rq}, nil
+	eng := &OPAEngine{gatesRego: &gq, riskRego: &rq}
+	if cfg.PathRedactionYAML != "" {
+		if eng.Redaction, err = redaction.LoadPolicy(cfg.PathRedactionYAML); err != nil {
+			return nil, fmt.Errorf("load redaction policy: %w", err)
+		}
+	}
+	return eng, nil
 }
@@ -1,3 +0,0 @@
-package ledger
-
-// All logic has been moved to the canonical receipt.go file.
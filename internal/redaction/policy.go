@@ -0,0 +1,169 @@
+package redaction
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Method is how a matched value is redacted.
+type Method string
+
+const (
+	// Mask replaces the matched text (or the whole value) with Replacement.
+	Mask Method = "mask"
+	// Hash replaces the value with a keyed SHA-256 pseudonym, so equal
+	// values stay joinable across views without being revealed.
+	Hash Method = "hash"
+	// Drop removes the field from the view entirely.
+	Drop Method = "drop"
+	// Generalize coarsens the value: numbers are floored to Granularity,
+	// timestamps truncated to an hour/day/month/year, other strings replaced
+	// with a category label.
+	Generalize Method = "generalize"
+)
+
+// DefaultMask is the replacement text for masked values.
+const DefaultMask = "****"
+
+// Rule selects values by JSON path, by a regex over string values, or both,
+// and redacts them with Method.
+//
+// Paths are dot separated from the document root ("payload.email"); "*"
+// matches any single key or array index, and "[*]" / "[0]" may be used for
+// array elements. A rule without a path applies to every string value.
+type Rule struct {
+	Field       string `yaml:"field"`
+	Path        string `yaml:"path,omitempty"`
+	Pattern     string `yaml:"pattern,omitempty"`
+	Method      Method `yaml:"method"`
+	Replacement string `yaml:"replacement,omitempty"`
+	Granularity string `yaml:"granularity,omitempty"`
+
+	path []string
+	re   *regexp.Regexp
+}
+
+// Policy is a parsed redaction.yaml. Salt keys the pseudonyms produced by
+// the hash method; it can be overridden with DIS_REDACTION_SALT so that it
+// need not be committed alongside the rules.
+type Policy struct {
+	Version string `yaml:"version"`
+	Salt    string `yaml:"salt,omitempty"`
+	Rules   []Rule `yaml:"rules"`
+
+	source string
+}
+
+// LoadPolicy reads and compiles a redaction policy.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if s := os.Getenv("DIS_REDACTION_SALT"); s != "" {
+		p.Salt = s
+	}
+	p.source = path
+	if err := p.Compile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// Compile validates the rules and prepares their paths and patterns. It
+// must be called on policies built in code before they are used.
+func (p *Policy) Compile() error {
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		switch r.Method {
+		case Mask, Hash, Drop, Generalize:
+		default:
+			return fmt.Errorf("rule %d (%s): unknown method %q", i, r.Field, r.Method)
+		}
+		if r.Method == Hash && p.Salt == "" {
+			// An unkeyed pseudonym of a low-entropy value is reversed by
+			// hashing the candidates.
+			return fmt.Errorf("rule %d (%s): hash needs a salt (set salt or DIS_REDACTION_SALT)", i, r.Field)
+		}
+		if r.Path == "" && r.Pattern == "" {
+			return fmt.Errorf("rule %d (%s): needs a path or a pattern", i, r.Field)
+		}
+		if r.Pattern != "" {
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return fmt.Errorf("rule %d (%s): %w", i, r.Field, err)
+			}
+			r.re = re
+		}
+		r.path = splitPath(r.Path)
+	}
+	return nil
+}
+
+// Ref identifies the policy in redaction provenance.
+func (p *Policy) Ref() string {
+	src := "redaction"
+	if p.source != "" {
+		src = filepath.Base(p.source)
+	}
+	if p.Version == "" {
+		return src
+	}
+	return src + "@" + p.Version
+}
+
+// splitPath turns "$.payload.items[*].email" into its segments.
+func splitPath(path string) []string {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil
+	}
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	return strings.Split(path, ".")
+}
+
+// matchPath reports whether the concrete path matches the rule path.
+func (r *Rule) matchPath(path []string) bool {
+	if r.path == nil {
+		return true
+	}
+	if len(path) != len(r.path) {
+		return false
+	}
+	for i, seg := range r.path {
+		if seg != "*" && seg != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultPolicy *Policy
+)
+
+// Default returns the process-wide redaction policy, or nil when responses
+// and exports are not redacted.
+func Default() *Policy {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultPolicy
+}
+
+// SetDefault replaces the process-wide redaction policy.
+func SetDefault(p *Policy) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultPolicy = p
+}
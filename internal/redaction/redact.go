@@ -0,0 +1,425 @@
+package redaction
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dis-core/internal/bridge"
+	"dis-core/internal/ledger"
+)
+
+// AnnotationKey is the top-level key a redacted view records its
+// redactions under.
+const AnnotationKey = "redaction"
+
+var (
+	ErrIncompleteDisclosure = errors.New("no disclosure for a redacted field")
+	ErrCommitmentMismatch   = errors.New("disclosure does not open the commitment")
+)
+
+// Commitment binds a view to the value redacted at Path: the hex SHA-256
+// of a random salt followed by the value's canonical JSON. The salt is only
+// handed out in the matching Disclosure.
+type Commitment struct {
+	Path       string `json:"path"`
+	Field      string `json:"field"`
+	Method     Method `json:"method"`
+	Commitment string `json:"commitment"`
+}
+
+// Disclosure opens one commitment, giving an authorized party the original
+// value so the full record, and its signature, can be checked.
+type Disclosure struct {
+	Path  string          `json:"path"`
+	Salt  string          `json:"salt"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Annotation is stored in a view under AnnotationKey. Its provenance entry
+// lists the redacted paths; the commitments cover their original values.
+type Annotation struct {
+	Provenance  ledger.Provenance `json:"provenance"`
+	Commitments []Commitment      `json:"commitments"`
+}
+
+// Redact returns a redacted view of v (any JSON-encodable value) together
+// with the disclosures that undo it. Views without redactions carry no
+// annotation.
+func (p *Policy) Redact(v any) (map[string]any, []Disclosure, error) {
+	doc, err := toDocument(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	w := &walker{policy: p}
+	for _, k := range sortedKeys(doc) {
+		if err := w.visit(doc, k, []string{k}); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(w.commitments) == 0 {
+		return doc, nil, nil
+	}
+	paths := make([]string, len(w.commitments))
+	for i, c := range w.commitments {
+		paths[i] = c.Path
+	}
+	doc[AnnotationKey] = Annotation{
+		Provenance: ledger.Provenance{
+			Type:           "redaction",
+			Ref:            p.Ref(),
+			Status:         "redacted",
+			RedactedFields: paths,
+		},
+		Commitments: w.commitments,
+	}
+	return doc, w.disclosures, nil
+}
+
+// RedactReceipt returns a redacted view of r.
+func (p *Policy) RedactReceipt(r *ledger.Receipt) (map[string]any, []Disclosure, error) {
+	return p.Redact(r)
+}
+
+// RedactAll redacts a list of values, discarding the disclosures. It is
+// what API responses use.
+func (p *Policy) RedactAll(items any) ([]map[string]any, error) {
+	raw, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, err
+	}
+	out := make([]map[string]any, 0, len(list))
+	for _, item := range list {
+		doc, _, err := p.Redact(item)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, doc)
+	}
+	return out, nil
+}
+
+// Restore rebuilds the original document from a view and the disclosures
+// for every commitment in it. Each disclosure is checked against its
+// commitment before the value is put back.
+func Restore(view map[string]any, disclosures []Disclosure) (map[string]any, error) {
+	doc, err := toDocument(view)
+	if err != nil {
+		return nil, err
+	}
+	raw, ok := doc[AnnotationKey]
+	if !ok {
+		return doc, nil
+	}
+	delete(doc, AnnotationKey)
+	var ann Annotation
+	if err := remarshal(raw, &ann); err != nil {
+		return nil, fmt.Errorf("decode redaction annotation: %w", err)
+	}
+
+	byPath := make(map[string]Disclosure, len(disclosures))
+	for _, d := range disclosures {
+		byPath[d.Path] = d
+	}
+	for _, c := range ann.Commitments {
+		d, ok := byPath[c.Path]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrIncompleteDisclosure, c.Path)
+		}
+		if !VerifyDisclosure(c, d) {
+			return nil, fmt.Errorf("%w: %s", ErrCommitmentMismatch, c.Path)
+		}
+		value, err := decodeValue(d.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Path, err)
+		}
+		if err := setPath(doc, strings.Split(c.Path, "."), value); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// RestoreReceipt rebuilds the full receipt behind a view. The result can be
+// passed to ledger.VerifyReceiptJSON to check the original signature.
+func RestoreReceipt(view map[string]any, disclosures []Disclosure) (*ledger.Receipt, error) {
+	doc, err := Restore(view, disclosures)
+	if err != nil {
+		return nil, err
+	}
+	var r ledger.Receipt
+	if err := remarshal(doc, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// VerifyDisclosure reports whether d opens c.
+func VerifyDisclosure(c Commitment, d Disclosure) bool {
+	if c.Path != d.Path {
+		return false
+	}
+	salt, err := hex.DecodeString(d.Salt)
+	if err != nil {
+		return false
+	}
+	value, err := decodeValue(d.Value)
+	if err != nil {
+		return false
+	}
+	canon, err := bridge.CanonicalJSON(value)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(commit(salt, canon)), []byte(c.Commitment))
+}
+
+func commit(salt, canon []byte) string {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write(canon)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// walker applies a policy to one document, collecting commitments.
+type walker struct {
+	policy      *Policy
+	commitments []Commitment
+	disclosures []Disclosure
+}
+
+// visit redacts the value at key of parent (a map or slice), or descends
+// into it when no rule applies.
+func (w *walker) visit(parent any, key string, path []string) error {
+	value := get(parent, key)
+	for i := range w.policy.Rules {
+		rule := &w.policy.Rules[i]
+		if !rule.matchPath(path) {
+			continue
+		}
+		if rule.re != nil {
+			s, ok := value.(string)
+			if !ok || !rule.re.MatchString(s) {
+				continue
+			}
+		}
+		return w.apply(rule, parent, key, path, value)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, k := range sortedKeys(v) {
+			if err := w.visit(v, k, append(path, k)); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i := range v {
+			if err := w.visit(v, strconv.Itoa(i), append(path, strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *walker) apply(rule *Rule, parent any, key string, path []string, value any) error {
+	canon, err := bridge.CanonicalJSON(value)
+	if err != nil {
+		return err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	joined := strings.Join(path, ".")
+	w.commitments = append(w.commitments, Commitment{
+		Path:       joined,
+		Field:      rule.Field,
+		Method:     rule.Method,
+		Commitment: commit(salt, canon),
+	})
+	w.disclosures = append(w.disclosures, Disclosure{Path: joined, Salt: hex.EncodeToString(salt), Value: canon})
+
+	if rule.Method == Drop {
+		del(parent, key)
+		return nil
+	}
+	set(parent, key, w.policy.replace(rule, value, canon))
+	return nil
+}
+
+// replace computes the redacted stand-in for value under rule.
+func (p *Policy) replace(rule *Rule, value any, canon []byte) any {
+	s, isString := value.(string)
+	switch rule.Method {
+	case Mask:
+		repl := rule.Replacement
+		if repl == "" {
+			repl = DefaultMask
+		}
+		if rule.re != nil && isString {
+			return rule.re.ReplaceAllLiteralString(s, repl)
+		}
+		return repl
+	case Hash:
+		if rule.re != nil && isString {
+			return rule.re.ReplaceAllStringFunc(s, func(m string) string { return p.pseudonym([]byte(m)) })
+		}
+		return p.pseudonym(canon)
+	case Generalize:
+		return generalize(rule, value)
+	}
+	return nil
+}
+
+// pseudonym is the keyed hash the hash method substitutes for a value.
+func (p *Policy) pseudonym(data []byte) string {
+	mac := hmac.New(sha256.New, []byte(p.Salt))
+	mac.Write(data)
+	return "sha256:" + hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// generalize coarsens numbers and timestamps; anything else becomes the
+// rule's category label.
+func generalize(rule *Rule, value any) any {
+	label := rule.Replacement
+	if label == "" {
+		label = "<" + rule.Field + ">"
+	}
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return label
+		}
+		step := 10.0
+		if g, err := strconv.ParseFloat(rule.Granularity, 64); err == nil && g > 0 {
+			step = g
+		}
+		return json.Number(strconv.FormatFloat(math.Floor(f/step)*step, 'f', -1, 64))
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			if rule.re != nil {
+				return rule.re.ReplaceAllLiteralString(v, label)
+			}
+			return label
+		}
+		t = t.UTC()
+		switch rule.Granularity {
+		case "year":
+			t = time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		case "month":
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		case "hour":
+			t = t.Truncate(time.Hour)
+		default:
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		}
+		return t.Format(time.RFC3339)
+	}
+	return label
+}
+
+// toDocument deep-copies v into a generic JSON object, keeping numbers as
+// json.Number so restored documents re-encode byte for byte.
+func toDocument(v any) (map[string]any, error) {
+	var doc map[string]any
+	if err := remarshal(v, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, errors.New("redaction input is not a JSON object")
+	}
+	return doc, nil
+}
+
+func remarshal(v any, out any) error {
+	raw, ok := v.(json.RawMessage)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return dec.Decode(out)
+}
+
+func decodeValue(raw json.RawMessage) (any, error) {
+	var v any
+	err := remarshal(raw, &v)
+	return v, err
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func get(parent any, key string) any {
+	switch p := parent.(type) {
+	case map[string]any:
+		return p[key]
+	case []any:
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(p) {
+			return p[i]
+		}
+	}
+	return nil
+}
+
+func set(parent any, key string, value any) {
+	switch p := parent.(type) {
+	case map[string]any:
+		p[key] = value
+	case []any:
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(p) {
+			p[i] = value
+		}
+	}
+}
+
+// del removes a key from a map. Dropped array elements become null so the
+// indices of their siblings, and so their paths, stay stable.
+func del(parent any, key string) {
+	switch p := parent.(type) {
+	case map[string]any:
+		delete(p, key)
+	case []any:
+		set(p, key, nil)
+	}
+}
+
+// setPath puts value back at path, creating the final key if it was dropped.
+func setPath(doc map[string]any, path []string, value any) error {
+	var node any = doc
+	for _, seg := range path[:len(path)-1] {
+		node = get(node, seg)
+		if node == nil {
+			return fmt.Errorf("path %s not found in view", strings.Join(path, "."))
+		}
+	}
+	set(node, path[len(path)-1], value)
+	return nil
+}
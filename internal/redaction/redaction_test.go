@@ -0,0 +1,206 @@
+package redaction_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"dis-core/internal/ledger"
+	"dis-core/internal/redaction"
+	"dis-core/internal/util/crypto"
+)
+
+// TestMain signs everything in this package with keys held on a mock
+// PKCS#11 token, so tests never touch the on-disk key directory.
+func TestMain(m *testing.M) {
+	token := crypto.NewMockPKCS11Token("1234")
+	if err := token.Login("1234"); err != nil {
+		panic(err)
+	}
+	ks := crypto.NewPKCS11KeyStore(token)
+	if _, err := ks.Generate("domain.test"); err != nil {
+		panic(err)
+	}
+	crypto.SetDefaultKeyStore(ks)
+	os.Exit(m.Run())
+}
+
+func policy(t *testing.T, rules ...redaction.Rule) *redaction.Policy {
+	t.Helper()
+	p := &redaction.Policy{Version: "test", Salt: "pepper", Rules: rules}
+	if err := p.Compile(); err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return p
+}
+
+func TestRedactMethods(t *testing.T) {
+	doc := map[string]any{
+		"payload": map[string]any{
+			"email":   "contact alice@example.org today",
+			"subject": "alice",
+			"amount":  1234,
+			"born":    "1990-06-15T08:30:00Z",
+			"items":   []any{map[string]any{"token": "t-1"}, map[string]any{"token": "t-2"}},
+		},
+	}
+	cases := []struct {
+		name  string
+		rule  redaction.Rule
+		paths []string
+		check func(view map[string]any) any
+		want  any
+	}{
+		{
+			"mask pattern", redaction.Rule{Field: "pii", Method: redaction.Mask, Pattern: `[a-z]+@[a-z.]+`},
+			[]string{"payload.email"},
+			func(v map[string]any) any { return payload(v)["email"] }, "contact **** today",
+		},
+		{
+			"hash path", redaction.Rule{Field: "subject", Path: "payload.subject", Method: redaction.Hash},
+			[]string{"payload.subject"},
+			func(v map[string]any) any { return strings.HasPrefix(payload(v)["subject"].(string), "sha256:") }, true,
+		},
+		{
+			"drop array elements", redaction.Rule{Field: "secret", Path: "payload.items[*].token", Method: redaction.Drop},
+			[]string{"payload.items.0.token", "payload.items.1.token"},
+			func(v map[string]any) any { return len(payload(v)["items"].([]any)[0].(map[string]any)) }, 0,
+		},
+		{
+			"generalize number", redaction.Rule{Field: "amount", Path: "payload.amount", Method: redaction.Generalize, Granularity: "100"},
+			[]string{"payload.amount"},
+			func(v map[string]any) any { return payload(v)["amount"] }, json.Number("1200"),
+		},
+		{
+			"generalize timestamp", redaction.Rule{Field: "born", Path: "payload.born", Method: redaction.Generalize, Granularity: "year"},
+			[]string{"payload.born"},
+			func(v map[string]any) any { return payload(v)["born"] }, "1990-01-01T00:00:00Z",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := policy(t, tc.rule)
+			view, disclosures, err := p.Redact(doc)
+			if err != nil {
+				t.Fatalf("Redact: %v", err)
+			}
+			if got := tc.check(view); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("redacted value %#v, want %#v", got, tc.want)
+			}
+			var ann redaction.Annotation
+			raw, _ := json.Marshal(view[redaction.AnnotationKey])
+			if err := json.Unmarshal(raw, &ann); err != nil {
+				t.Fatalf("annotation: %v", err)
+			}
+			if !reflect.DeepEqual(ann.Provenance.RedactedFields, tc.paths) || ann.Provenance.Ref != "redaction@test" {
+				t.Fatalf("provenance %+v", ann.Provenance)
+			}
+			restored, err := redaction.Restore(view, disclosures)
+			if err != nil {
+				t.Fatalf("Restore: %v", err)
+			}
+			want, _ := json.Marshal(doc)
+			got, _ := json.Marshal(restored)
+			if string(got) != string(want) {
+				t.Fatalf("restored %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func payload(view map[string]any) map[string]any {
+	return view["payload"].(map[string]any)
+}
+
+// TestRestoreReceipt redacts a signed receipt and checks that only the
+// complete, untampered disclosures give back a receipt that verifies.
+func TestRestoreReceipt(t *testing.T) {
+	r, err := ledger.NewEnvelope("test.event.v0", "domain.test", "redact.test", map[string]any{
+		"subject_id": "alice",
+		"note":       "reach me at alice@example.org",
+	})
+	if err != nil {
+		t.Fatalf("NewEnvelope: %v", err)
+	}
+	p := policy(t,
+		redaction.Rule{Field: "subject", Path: "payload.subject_id", Method: redaction.Hash},
+		redaction.Rule{Field: "pii", Method: redaction.Mask, Pattern: `[a-z]+@[a-z.]+`},
+	)
+	view, disclosures, err := p.RedactReceipt(r)
+	if err != nil {
+		t.Fatalf("RedactReceipt: %v", err)
+	}
+	if raw, _ := json.Marshal(view); strings.Contains(string(raw), "alice") {
+		t.Fatalf("view leaks the subject: %s", raw)
+	}
+	tampered := append([]redaction.Disclosure(nil), disclosures...)
+	tampered[0].Value = json.RawMessage(`"mallory"`)
+
+	cases := []struct {
+		name        string
+		disclosures []redaction.Disclosure
+		want        error
+	}{
+		{"all disclosures", disclosures, nil},
+		{"one missing", disclosures[1:], redaction.ErrIncompleteDisclosure},
+		{"tampered value", tampered, redaction.ErrCommitmentMismatch},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			restored, err := redaction.RestoreReceipt(view, tc.disclosures)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("RestoreReceipt: %v, want %v", err, tc.want)
+			}
+			if err != nil {
+				return
+			}
+			raw, _ := json.Marshal(restored)
+			if ok, err := ledger.VerifyReceiptJSON(raw); !ok || err != nil {
+				t.Fatalf("restored receipt does not verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	t.Setenv("DIS_REDACTION_SALT", "")
+	dir := t.TempDir()
+	cases := []struct {
+		name string
+		yaml string
+		ok   bool
+	}{
+		{"valid", "version: 1\nrules:\n  - field: pii\n    method: mask\n    pattern: '@'\n", true},
+		{"unknown method", "rules:\n  - field: pii\n    method: shred\n    pattern: '@'\n", false},
+		{"no selector", "rules:\n  - field: pii\n    method: mask\n", false},
+		{"bad pattern", "rules:\n  - field: pii\n    method: mask\n    pattern: '('\n", false},
+		{"hash without salt", "rules:\n  - field: subject\n    path: payload.subject\n    method: hash\n", false},
+		{"hash with salt", "version: 1\nsalt: pepper\nrules:\n  - field: subject\n    path: payload.subject\n    method: hash\n", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tc.name, " ", "_")+".yaml")
+			if err := os.WriteFile(path, []byte(tc.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			p, err := redaction.LoadPolicy(path)
+			if (err == nil) != tc.ok {
+				t.Fatalf("LoadPolicy: %v", err)
+			}
+			if tc.ok && p.Ref() != filepath.Base(path)+"@1" {
+				t.Fatalf("Ref %q", p.Ref())
+			}
+		})
+	}
+	if _, err := redaction.LoadPolicy("../../policies/redaction.yaml"); err == nil {
+		t.Fatal("shipped policy loaded without a salt")
+	}
+	t.Setenv("DIS_REDACTION_SALT", "pepper")
+	if _, err := redaction.LoadPolicy("../../policies/redaction.yaml"); err != nil {
+		t.Fatalf("shipped policy: %v", err)
+	}
+}
@@ -1,11 +0,0 @@
-package auth
-
-// stub for auth registry
-import (
-	"database/sql"
-	"net/http"
-)
-
-func Register(mux *http.ServeMux, db *sql.DB) {
-	// TODO: implement auth routes
-}
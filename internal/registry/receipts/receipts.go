@@ -1,11 +0,0 @@
-package receipts
-
-// stub for receipts registry
-import (
-	"database/sql"
-	"net/http"
-)
-
-func Register(mux *http.ServeMux, db *sql.DB) {
-	// TODO: implement receipts routes
-}
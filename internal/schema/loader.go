@@ -7,14 +7,6 @@
 //     return nil
 // }
 
-// Stub for missing InsertReceipt method
-// Stub for missing InsertReceipt method
-//
-//	func (l *Ledger) InsertReceipt(r *Receipt) error {
-//	    // TODO: Implement actual DB insert logic
-//	    return nil
-//	}
-//
 // AUTOGEN-COPILOT: initial scaffold, verified by RickF71
 // Ref: MOAR Phase 2 – Schema Loader
 //
@@ -35,35 +27,14 @@ import (
 	"fmt"
 	"os"
 	"strings"
-	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
-type Receipt struct {
-	ReceiptID      string
-	By             string
-	Action         string
-	CreatedAt      time.Time
-	Hash           string
-	FrozenCoreHash string
-	Metadata       map[string]any
-}
-
-type ImportReceipt struct {
-	ID      string
-	Type    string
-	Target  string
-	Summary string
-}
-
 // DefaultSchemaDir defines the canonical relative location for DIS schema files.
 // All loaders should build paths from this root to remain portable.
 var DefaultSchemaDir = "disyaml/schemas"
 
-// Stub for generateReceiptID
-func generateReceiptID() string { return "stub-id" }
-
 // SchemaVerify checks if the schema file has a valid "version" field.
 func SchemaVerify(schemaFile string) error {
 	data, err := os.ReadFile(schemaFile)
@@ -1,11 +1,14 @@
 package trust
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"math"
+	"sync"
 	"time"
 
+	"dis-core/internal/feedback"
 	"dis-core/internal/ledger"
 )
 
@@ -21,21 +24,58 @@ type TrustFeedback struct {
 	SourceReceipt string    `json:"source_receipt"`
 }
 
-// TrustMap simulates dynamic trust evolution across domains.
-var TrustMap = map[string]float64{}
+// neutralTrust is the prior trust of a domain with no score, as in
+// feedback.Loop.
+const neutralTrust = 0.5
+
+var (
+	storeMu sync.RWMutex
+	store   feedback.TrustStore = feedback.NewMemStore()
+)
+
+// DefaultStore returns the store ApplyFeedback scores domains in.
+func DefaultStore() feedback.TrustStore {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	return store
+}
+
+// SetDefaultStore makes ApplyFeedback score domains in s, the same store the
+// feedback loop and consent gate use for actors.
+func SetDefaultStore(s feedback.TrustStore) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	store = s
+}
 
 // ApplyFeedback updates a target domain’s trust score
-// using a decaying memory of prior trust.
-func ApplyFeedback(fb *TrustFeedback) {
-	prev := TrustMap[fb.TargetDomain]
-	newScore := prev*0.9 + fb.MoralDelta*fb.Confidence*0.1
-	newScore = math.Max(0, math.Min(1, newScore)) // clamp 0–1
-	fb.TrustScore = math.Round(newScore*100) / 100
-
-	TrustMap[fb.TargetDomain] = fb.TrustScore
+// using a decaying memory of prior trust. The change is recorded against
+// fb.SourceReceipt, and is made atomically in the store, so concurrent
+// feedback from any node sharing it is never lost.
+//
+// A domain with no score starts from the neutral prior the feedback loop
+// and actor hydration use (0.5); before domain trust moved into the
+// shared store, it started from 0.
+func ApplyFeedback(ctx context.Context, fb *TrustFeedback) error {
+	if fb.SourceReceipt != "" {
+		ctx = feedback.WithReceipt(ctx, fb.SourceReceipt)
+	}
+	score, err := DefaultStore().Update(ctx, fb.TargetDomain, feedback.KindTrust, func(prev float64, ok bool) float64 {
+		if !ok {
+			prev = neutralTrust
+		}
+		newScore := prev*0.9 + fb.MoralDelta*fb.Confidence*0.1
+		newScore = math.Max(0, math.Min(1, newScore)) // clamp 0–1
+		return math.Round(newScore*100) / 100
+	})
+	if err != nil {
+		return err
+	}
+	fb.TrustScore = score
 	fb.CreatedAt = time.Now().UTC()
 	fb.FeedbackID = ledger.GenerateUUID()
 
 	data, _ := json.MarshalIndent(fb, "", "  ")
 	log.Printf("🤝 TrustFeedback applied:\n%s", string(data))
+	return nil
 }
@@ -0,0 +1,75 @@
+package trust_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"dis-core/internal/feedback"
+	"dis-core/internal/trust"
+)
+
+func TestApplyFeedback(t *testing.T) {
+	ctx := context.Background()
+	store := feedback.NewMemStore()
+	trust.SetDefaultStore(store)
+	defer trust.SetDefaultStore(feedback.NewMemStore())
+
+	// Steps run in order against the same domain.
+	steps := []struct {
+		name       string
+		delta      float64
+		confidence float64
+		want       float64
+	}{
+		{"unseen domain starts neutral", 1, 1, 0.55},
+		{"decays toward the feedback", -1, 1, 0.4},
+		{"confidence scales the feedback", 1, 0.5, 0.41},
+		{"no confidence only decays", -1, 0, 0.37},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			fb := &trust.TrustFeedback{TargetDomain: "domain.x", MoralDelta: step.delta, Confidence: step.confidence, SourceReceipt: "r-" + step.name}
+			if err := trust.ApplyFeedback(ctx, fb); err != nil {
+				t.Fatalf("ApplyFeedback: %v", err)
+			}
+			if fb.TrustScore != step.want || fb.FeedbackID == "" {
+				t.Fatalf("feedback %+v, want score %v", fb, step.want)
+			}
+			if v, ok, err := store.ScoreAsOf(ctx, "domain.x", feedback.KindTrust, fb.SourceReceipt); err != nil || !ok || v != step.want {
+				t.Fatalf("score as of %s: %v, %v, %v", fb.SourceReceipt, v, ok, err)
+			}
+		})
+	}
+}
+
+// TestApplyFeedbackConcurrent checks that concurrent feedback on one domain
+// is applied once each.
+func TestApplyFeedbackConcurrent(t *testing.T) {
+	ctx := context.Background()
+	store := feedback.NewMemStore()
+	trust.SetDefaultStore(store)
+	defer trust.SetDefaultStore(feedback.NewMemStore())
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := trust.ApplyFeedback(ctx, &trust.TrustFeedback{TargetDomain: "domain.y", MoralDelta: 1, Confidence: 1}); err != nil {
+				t.Errorf("ApplyFeedback: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	history, err := store.History(ctx, "domain.y")
+	if err != nil || len(history) != n {
+		t.Fatalf("history: %d changes, %v", len(history), err)
+	}
+	for i, c := range history[1:] {
+		if c.Previous == nil || *c.Previous != history[i].Value {
+			t.Fatalf("change %d did not build on change %d: %+v", i+1, i, c)
+		}
+	}
+}
@@ -20,6 +20,9 @@ type Signer struct {
 
 // EnsureDomainKeys loads keys if present; otherwise generates and saves them.
 // Keys are stored in base64 at: versions/v0.6/keys/<domain>.priv / <domain>.pub
+//
+// Deprecated: a missing file silently becomes a new identity. Sign through
+// DefaultKeyStore, which imports these files as each domain's first key.
 func EnsureDomainKeys(domain string) (*Signer, error) {
 	if err := os.MkdirAll(keyDir, 0755); err != nil {
 		return nil, err
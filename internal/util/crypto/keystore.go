@@ -0,0 +1,396 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// KeyStatus is the lifecycle state of a domain key.
+type KeyStatus string
+
+const (
+	KeyActive  KeyStatus = "active"  // the key a domain signs with
+	KeyRetired KeyStatus = "retired" // superseded by rotation; still verifies old signatures
+	KeyRevoked KeyStatus = "revoked" // withdrawn; signatures after RevokedAt are rejected
+)
+
+var (
+	ErrNoActiveKey = errors.New("domain has no active key")
+	ErrKeyExists   = errors.New("domain already has an active key")
+	ErrKeyNotFound = errors.New("key not found")
+	ErrKeyActive   = errors.New("cannot revoke the active key; rotate first")
+	ErrKeyRevoked  = errors.New("key is revoked")
+	ErrKeyNotValid = errors.New("key was not valid at that time")
+	ErrRevokeAhead = errors.New("revocation time is in the future")
+)
+
+// KeyRecord is one entry in a domain's key history. Only public material
+// is kept here; private keys stay inside the KeyStore backend.
+type KeyRecord struct {
+	KeyID        string     `json:"key_id"`
+	Domain       string     `json:"domain"`
+	PublicKeyB64 string     `json:"public_key_b64"`
+	Status       KeyStatus  `json:"status"`
+	ValidFrom    time.Time  `json:"valid_from"`
+	ValidUntil   *time.Time `json:"valid_until,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	RevokeReason string     `json:"revoke_reason,omitempty"`
+}
+
+// ValidAt reports whether the key could sign at t. Keys imported from the
+// pre-history layout have a zero ValidFrom and are valid from the start.
+func (k KeyRecord) ValidAt(t time.Time) bool {
+	if t.Before(k.ValidFrom) {
+		return false
+	}
+	if k.ValidUntil != nil && t.After(*k.ValidUntil) {
+		return false
+	}
+	if k.RevokedAt != nil && !t.Before(*k.RevokedAt) {
+		return false
+	}
+	return true
+}
+
+// PublicKey decodes the record's public key.
+func (k KeyRecord) PublicKey() (ed25519.PublicKey, error) {
+	pub, err := base64.StdEncoding.DecodeString(k.PublicKeyB64)
+	if err != nil {
+		return nil, err
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key size")
+	}
+	return ed25519.PublicKey(pub), nil
+}
+
+// Verify checks a base64 signature over msg with this key.
+func (k KeyRecord) Verify(msg []byte, b64sig string) bool {
+	pub, err := k.PublicKey()
+	if err != nil {
+		return false
+	}
+	return (&Signer{Pub: pub}).Verify(msg, b64sig)
+}
+
+// KeyID derives the stable identifier of a public key.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// KeyStore manages domain signing keys. Keys are never created implicitly:
+// a domain signs only after Generate, and Rotate keeps the old key in the
+// history so receipts it signed still verify.
+type KeyStore interface {
+	// Generate creates the first key for a domain.
+	Generate(domain string) (KeyRecord, error)
+	// Active returns the key the domain currently signs with.
+	Active(domain string) (KeyRecord, error)
+	// Sign signs msg with the given key. Revoked keys cannot sign.
+	Sign(keyID string, msg []byte) (string, error)
+	// Rotate retires the active key and activates a new one.
+	Rotate(domain string) (old, next KeyRecord, err error)
+	// Revoke withdraws a non-active key from at, the time it is known to
+	// have been compromised; a zero at means now. Signatures made from at
+	// on no longer verify. Revoking a revoked key again can only move its
+	// RevokedAt earlier.
+	Revoke(domain, keyID, reason string, at time.Time) (KeyRecord, error)
+	// Register records a public key whose private half is held elsewhere
+	// as the domain's active key from validFrom, retiring the previous one
+	// then. A zero validFrom makes the key valid from the start.
+	Register(domain, publicKeyB64 string, validFrom time.Time) (KeyRecord, error)
+	// History returns every key the domain has had, oldest first.
+	History(domain string) ([]KeyRecord, error)
+}
+
+// KeyAt finds the key that verifies a signature made by domain at t. With an
+// empty keyID (receipts signed before key IDs existed) the first key valid
+// at t is returned.
+func KeyAt(ks KeyStore, domain, keyID string, t time.Time) (KeyRecord, error) {
+	history, err := ks.History(domain)
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	return KeyInHistory(history, domain, keyID, t)
+}
+
+// KeyInHistory is KeyAt over a key history held outside a KeyStore, such
+// as the key set shipped with a ledger bundle.
+func KeyInHistory(history []KeyRecord, domain, keyID string, t time.Time) (KeyRecord, error) {
+	for _, k := range history {
+		if keyID != "" && k.KeyID != keyID {
+			continue
+		}
+		if !k.ValidAt(t) {
+			if keyID != "" {
+				return KeyRecord{}, fmt.Errorf("%w: %s at %s", ErrKeyNotValid, keyID, t.UTC().Format(time.RFC3339))
+			}
+			continue
+		}
+		return k, nil
+	}
+	if keyID != "" {
+		return KeyRecord{}, fmt.Errorf("%w: %s/%s", ErrKeyNotFound, domain, keyID)
+	}
+	return KeyRecord{}, fmt.Errorf("%w: no key for %s at %s", ErrKeyNotValid, domain, t.UTC().Format(time.RFC3339))
+}
+
+var (
+	defaultKeyStoreMu sync.RWMutex
+	defaultKeyStore   KeyStore = NewFileKeyStore(keyDir)
+)
+
+// DefaultKeyStore returns the process-wide key store: a FileKeyStore over
+// versions/v0.6/keys until SetDefaultKeyStore replaces it.
+func DefaultKeyStore() KeyStore {
+	defaultKeyStoreMu.RLock()
+	defer defaultKeyStoreMu.RUnlock()
+	return defaultKeyStore
+}
+
+// SetDefaultKeyStore replaces the process-wide key store.
+func SetDefaultKeyStore(ks KeyStore) {
+	defaultKeyStoreMu.Lock()
+	defer defaultKeyStoreMu.Unlock()
+	defaultKeyStore = ks
+}
+
+// keyBackend is the storage a managedKeyStore drives: where private keys
+// live and how each domain's history is persisted.
+type keyBackend interface {
+	newKey() (KeyRecord, error)
+	sign(keyID string, msg []byte) ([]byte, error)
+	loadHistory(domain string) ([]KeyRecord, error)
+	saveHistory(domain string, history []KeyRecord) error
+}
+
+// managedKeyStore implements the KeyStore lifecycle on top of a backend.
+type managedKeyStore struct {
+	mu      sync.Mutex
+	backend keyBackend
+	owner   map[string]string // keyID → domain, filled as histories are read
+	now     func() time.Time
+}
+
+func newManagedKeyStore(b keyBackend) *managedKeyStore {
+	return &managedKeyStore{backend: b, owner: make(map[string]string), now: time.Now}
+}
+
+// history loads a domain's history. Callers must hold m.mu.
+func (m *managedKeyStore) history(domain string) ([]KeyRecord, error) {
+	h, err := m.backend.loadHistory(domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range h {
+		m.owner[k.KeyID] = domain
+	}
+	return h, nil
+}
+
+func activeIndex(h []KeyRecord) int {
+	for i := len(h) - 1; i >= 0; i-- {
+		if h[i].Status == KeyActive {
+			return i
+		}
+	}
+	return -1
+}
+
+// mint creates a new active key record for domain. Callers must hold m.mu.
+func (m *managedKeyStore) mint(domain string, at time.Time) (KeyRecord, error) {
+	k, err := m.backend.newKey()
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	k.Domain = domain
+	k.Status = KeyActive
+	k.ValidFrom = at
+	m.owner[k.KeyID] = domain
+	return k, nil
+}
+
+func (m *managedKeyStore) Generate(domain string) (KeyRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, err := m.history(domain)
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	if activeIndex(h) >= 0 {
+		return KeyRecord{}, fmt.Errorf("%w: %s", ErrKeyExists, domain)
+	}
+	k, err := m.mint(domain, m.now().UTC())
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	if err := m.backend.saveHistory(domain, append(h, k)); err != nil {
+		return KeyRecord{}, err
+	}
+	return k, nil
+}
+
+func (m *managedKeyStore) Active(domain string) (KeyRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, err := m.history(domain)
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	i := activeIndex(h)
+	if i < 0 {
+		return KeyRecord{}, fmt.Errorf("%w: %s", ErrNoActiveKey, domain)
+	}
+	return h[i], nil
+}
+
+func (m *managedKeyStore) Sign(keyID string, msg []byte) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	domain, ok := m.owner[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	h, err := m.history(domain)
+	if err != nil {
+		return "", err
+	}
+	for _, k := range h {
+		if k.KeyID == keyID && k.Status == KeyRevoked {
+			return "", fmt.Errorf("%w: %s", ErrKeyRevoked, keyID)
+		}
+	}
+	sig, err := m.backend.sign(keyID, msg)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+func (m *managedKeyStore) Rotate(domain string) (KeyRecord, KeyRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, err := m.history(domain)
+	if err != nil {
+		return KeyRecord{}, KeyRecord{}, err
+	}
+	i := activeIndex(h)
+	if i < 0 {
+		return KeyRecord{}, KeyRecord{}, fmt.Errorf("%w: %s", ErrNoActiveKey, domain)
+	}
+	now := m.now().UTC()
+	next, err := m.mint(domain, now)
+	if err != nil {
+		return KeyRecord{}, KeyRecord{}, err
+	}
+	h[i].Status = KeyRetired
+	h[i].ValidUntil = &now
+	if err := m.backend.saveHistory(domain, append(h, next)); err != nil {
+		return KeyRecord{}, KeyRecord{}, err
+	}
+	return h[i], next, nil
+}
+
+func (m *managedKeyStore) Revoke(domain, keyID, reason string, at time.Time) (KeyRecord, error) {
+	now := m.now().UTC()
+	if at.IsZero() {
+		at = now
+	}
+	if at.After(now) {
+		return KeyRecord{}, ErrRevokeAhead
+	}
+	at = at.UTC()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, err := m.history(domain)
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	for i := range h {
+		if h[i].KeyID != keyID {
+			continue
+		}
+		switch h[i].Status {
+		case KeyActive:
+			return KeyRecord{}, ErrKeyActive
+		case KeyRevoked:
+			if !at.Before(*h[i].RevokedAt) {
+				return h[i], nil
+			}
+		}
+		h[i].Status = KeyRevoked
+		h[i].RevokedAt = &at
+		h[i].RevokeReason = reason
+		if err := m.backend.saveHistory(domain, h); err != nil {
+			return KeyRecord{}, err
+		}
+		return h[i], nil
+	}
+	return KeyRecord{}, fmt.Errorf("%w: %s/%s", ErrKeyNotFound, domain, keyID)
+}
+
+func (m *managedKeyStore) Register(domain, publicKeyB64 string, validFrom time.Time) (KeyRecord, error) {
+	k := KeyRecord{Domain: domain, PublicKeyB64: publicKeyB64}
+	pub, err := k.PublicKey()
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	k.KeyID = KeyID(pub)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, err := m.history(domain)
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	for _, old := range h {
+		if old.KeyID == k.KeyID {
+			return old, nil
+		}
+	}
+	until := validFrom.UTC()
+	if validFrom.IsZero() {
+		until = m.now().UTC()
+	}
+	if i := activeIndex(h); i >= 0 {
+		h[i].Status = KeyRetired
+		h[i].ValidUntil = &until
+	}
+	k.Status = KeyActive
+	k.ValidFrom = validFrom.UTC()
+	if err := m.backend.saveHistory(domain, append(h, k)); err != nil {
+		return KeyRecord{}, err
+	}
+	m.owner[k.KeyID] = domain
+	return k, nil
+}
+
+func (m *managedKeyStore) History(domain string) ([]KeyRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, err := m.history(domain)
+	if err != nil {
+		return nil, err
+	}
+	return append([]KeyRecord(nil), h...), nil
+}
+
+// generateKeyPair returns a fresh Ed25519 pair and its public record.
+func generateKeyPair() (ed25519.PrivateKey, KeyRecord, error) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, KeyRecord{}, err
+	}
+	return priv, KeyRecord{KeyID: KeyID(pub), PublicKeyB64: encodePub(pub)}, nil
+}
+
+func encodePub(pub ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(pub)
+}
@@ -0,0 +1,100 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Encrypted key files are age-style armored text: a header line, then the
+// base64 salt and the base64 GCM nonce+ciphertext, one per line. The file
+// key is derived from the passphrase with PBKDF2-HMAC-SHA256.
+const (
+	encryptedKeyHeader = "dis-keystore-v1"
+	encryptedKeyIter   = 210000
+)
+
+// ErrBadPassphrase is returned when an encrypted key cannot be opened.
+var ErrBadPassphrase = errors.New("wrong passphrase or corrupted key file")
+
+// NewEncryptedFileKeyStore returns a KeyStore like NewFileKeyStore whose
+// private key files are encrypted under passphrase. A legacy plaintext
+// <domain>.priv is encrypted on import and then deleted.
+func NewEncryptedFileKeyStore(dir, passphrase string) (KeyStore, error) {
+	if passphrase == "" {
+		return nil, errors.New("encrypted key store requires a passphrase")
+	}
+	pass := []byte(passphrase)
+	return newManagedKeyStore(&fileBackend{
+		dir:  dir,
+		ext:  ".key.enc",
+		seal: func(priv []byte) ([]byte, error) { return encryptKey(pass, priv) },
+		open: func(data []byte) ([]byte, error) { return decryptKey(pass, data) },
+
+		dropLegacy: true,
+	}), nil
+}
+
+func encryptKey(pass, plain []byte) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := keyAEAD(pass, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(nonce, nonce, plain, []byte(encryptedKeyHeader))
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\n%s\n%s\n", encryptedKeyHeader,
+		base64.StdEncoding.EncodeToString(salt),
+		base64.StdEncoding.EncodeToString(sealed))
+	return buf.Bytes(), nil
+}
+
+func decryptKey(pass, data []byte) ([]byte, error) {
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	if len(lines) != 3 || string(lines[0]) != encryptedKeyHeader {
+		return nil, errors.New("not an encrypted key file")
+	}
+	salt, err := base64.StdEncoding.DecodeString(string(lines[1]))
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(string(lines[2]))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := keyAEAD(pass, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrBadPassphrase
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(encryptedKeyHeader))
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return plain, nil
+}
+
+func keyAEAD(pass, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2.Key(pass, salt, encryptedKeyIter, 32, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
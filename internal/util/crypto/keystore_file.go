@@ -0,0 +1,182 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileBackend keeps each domain's key history in <domain>.keys.json and each
+// private key in its own file named after the key ID. seal/open wrap the
+// private key bytes at rest; for the plain FileKeyStore they are base64.
+// Opened keys are cached so an encrypted store derives its file key once.
+type fileBackend struct {
+	dir    string
+	ext    string
+	seal   func(priv []byte) ([]byte, error)
+	open   func(data []byte) ([]byte, error)
+	opened map[string]ed25519.PrivateKey
+
+	// dropLegacy removes the plaintext <domain>.priv once it has been
+	// imported, so an encrypted store leaves no clear copy behind.
+	dropLegacy bool
+}
+
+// NewFileKeyStore returns a KeyStore that keeps base64 private keys in dir.
+// Keys written by the old EnsureDomainKeys layout (<domain>.priv/.pub) are
+// imported as the domain's first key the first time its history is read.
+func NewFileKeyStore(dir string) KeyStore {
+	return newManagedKeyStore(&fileBackend{
+		dir: dir,
+		ext: ".key",
+		seal: func(priv []byte) ([]byte, error) {
+			return []byte(base64.StdEncoding.EncodeToString(priv)), nil
+		},
+		open: func(data []byte) ([]byte, error) {
+			return base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		},
+	})
+}
+
+func (b *fileBackend) historyPath(domain string) string {
+	return filepath.Join(b.dir, domain+".keys.json")
+}
+
+func (b *fileBackend) keyPath(keyID string) string {
+	return filepath.Join(b.dir, keyID+b.ext)
+}
+
+func checkDomainName(domain string) error {
+	if domain == "" || filepath.Base(domain) != domain || strings.HasPrefix(domain, ".") {
+		return fmt.Errorf("invalid domain name %q", domain)
+	}
+	return nil
+}
+
+func (b *fileBackend) writePrivate(keyID string, priv ed25519.PrivateKey) error {
+	if err := os.MkdirAll(b.dir, 0700); err != nil {
+		return err
+	}
+	data, err := b.seal(priv)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(b.keyPath(keyID), data, 0600); err != nil {
+		return err
+	}
+	b.remember(keyID, priv)
+	return nil
+}
+
+func (b *fileBackend) remember(keyID string, priv ed25519.PrivateKey) {
+	if b.opened == nil {
+		b.opened = make(map[string]ed25519.PrivateKey)
+	}
+	b.opened[keyID] = priv
+}
+
+func (b *fileBackend) newKey() (KeyRecord, error) {
+	priv, rec, err := generateKeyPair()
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	if err := b.writePrivate(rec.KeyID, priv); err != nil {
+		return KeyRecord{}, err
+	}
+	return rec, nil
+}
+
+func (b *fileBackend) sign(keyID string, msg []byte) ([]byte, error) {
+	if priv, ok := b.opened[keyID]; ok {
+		return ed25519.Sign(priv, msg), nil
+	}
+	data, err := os.ReadFile(b.keyPath(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("read key %s: %w", keyID, err)
+	}
+	priv, err := b.open(data)
+	if err != nil {
+		return nil, fmt.Errorf("open key %s: %w", keyID, err)
+	}
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid private key size")
+	}
+	b.remember(keyID, priv)
+	return ed25519.Sign(ed25519.PrivateKey(priv), msg), nil
+}
+
+func (b *fileBackend) loadHistory(domain string) ([]KeyRecord, error) {
+	if err := checkDomainName(domain); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(b.historyPath(domain))
+	if os.IsNotExist(err) {
+		return b.importLegacy(domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var h []KeyRecord
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("decode key history for %s: %w", domain, err)
+	}
+	return h, nil
+}
+
+func (b *fileBackend) saveHistory(domain string, h []KeyRecord) error {
+	if err := checkDomainName(domain); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(b.dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := b.historyPath(domain) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.historyPath(domain))
+}
+
+// importLegacy adopts a <domain>.priv/.pub pair as the domain's first key,
+// valid from the beginning of time so that existing receipts still verify.
+// A domain without a legacy pair has no history; a pair that cannot be
+// read is an error rather than a missing key. With dropLegacy the private
+// half is deleted once the history that replaces it is saved.
+func (b *fileBackend) importLegacy(domain string) ([]KeyRecord, error) {
+	priv, pub := filepath.Join(b.dir, domain+".priv"), filepath.Join(b.dir, domain+".pub")
+	if _, err := os.Stat(priv); os.IsNotExist(err) {
+		return nil, nil
+	}
+	s, err := LoadDomainKeys(priv, pub)
+	if err != nil {
+		return nil, fmt.Errorf("import legacy key for %s: %w", domain, err)
+	}
+	rec := KeyRecord{
+		KeyID:        KeyID(s.Pub),
+		Domain:       domain,
+		PublicKeyB64: base64.StdEncoding.EncodeToString(s.Pub),
+		Status:       KeyActive,
+	}
+	if err := b.writePrivate(rec.KeyID, s.Priv); err != nil {
+		return nil, err
+	}
+	h := []KeyRecord{rec}
+	if err := b.saveHistory(domain, h); err != nil {
+		return nil, err
+	}
+	if b.dropLegacy {
+		if err := os.Remove(priv); err != nil {
+			return nil, fmt.Errorf("remove legacy key for %s: %w", domain, err)
+		}
+	}
+	return h, nil
+}
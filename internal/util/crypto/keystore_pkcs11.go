@@ -0,0 +1,145 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ObjectHandle identifies an object on a token, as CK_OBJECT_HANDLE does.
+type ObjectHandle uint32
+
+var (
+	ErrTokenNotLoggedIn  = errors.New("pkcs11: CKR_USER_NOT_LOGGED_IN")
+	ErrTokenPINIncorrect = errors.New("pkcs11: CKR_PIN_INCORRECT")
+	ErrTokenObject       = errors.New("pkcs11: CKR_OBJECT_HANDLE_INVALID")
+)
+
+// MockPKCS11Token is an in-process stand-in for a PKCS#11 token holding
+// Ed25519 keys (CKM_EC_EDWARDS_KEY_PAIR_GEN / CKM_EDDSA). Private keys never
+// leave the token: callers hold object handles and ask the token to sign.
+// Key operations require a logged-in session, as on a real HSM.
+type MockPKCS11Token struct {
+	mu       sync.Mutex
+	pin      string
+	loggedIn bool
+	next     ObjectHandle
+	objects  map[ObjectHandle]ed25519.PrivateKey
+	ids      map[string]ObjectHandle // CKA_ID → private key handle
+	data     map[string][]KeyRecord  // CKO_DATA objects: per-domain key history
+}
+
+// NewMockPKCS11Token returns an empty token protected by pin.
+func NewMockPKCS11Token(pin string) *MockPKCS11Token {
+	return &MockPKCS11Token{
+		pin:     pin,
+		objects: make(map[ObjectHandle]ed25519.PrivateKey),
+		ids:     make(map[string]ObjectHandle),
+		data:    make(map[string][]KeyRecord),
+	}
+}
+
+// Login opens the user session (C_Login).
+func (t *MockPKCS11Token) Login(pin string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if pin != t.pin {
+		return ErrTokenPINIncorrect
+	}
+	t.loggedIn = true
+	return nil
+}
+
+// Logout closes the user session (C_Logout).
+func (t *MockPKCS11Token) Logout() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loggedIn = false
+}
+
+// GenerateKeyPair creates a key pair on the token (C_GenerateKeyPair) and
+// returns the public key and the private key handle. The key's CKA_ID is
+// its KeyID.
+func (t *MockPKCS11Token) GenerateKeyPair() (ed25519.PublicKey, ObjectHandle, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.loggedIn {
+		return nil, 0, ErrTokenNotLoggedIn
+	}
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	t.next++
+	t.objects[t.next] = priv
+	t.ids[KeyID(pub)] = t.next
+	return pub, t.next, nil
+}
+
+// FindObject looks up a private key handle by CKA_ID (C_FindObjects).
+func (t *MockPKCS11Token) FindObject(id string) (ObjectHandle, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h, ok := t.ids[id]
+	if !ok {
+		return 0, fmt.Errorf("%w: CKA_ID %s", ErrTokenObject, id)
+	}
+	return h, nil
+}
+
+// Sign signs msg with the private key behind h (C_SignInit + C_Sign).
+func (t *MockPKCS11Token) Sign(h ObjectHandle, msg []byte) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.loggedIn {
+		return nil, ErrTokenNotLoggedIn
+	}
+	priv, ok := t.objects[h]
+	if !ok {
+		return nil, ErrTokenObject
+	}
+	return ed25519.Sign(priv, msg), nil
+}
+
+// NewPKCS11KeyStore returns a KeyStore backed by token. The caller logs in
+// before the store is used.
+func NewPKCS11KeyStore(token *MockPKCS11Token) KeyStore {
+	return newManagedKeyStore(&pkcs11Backend{token: token})
+}
+
+type pkcs11Backend struct {
+	token *MockPKCS11Token
+}
+
+func (b *pkcs11Backend) newKey() (KeyRecord, error) {
+	pub, _, err := b.token.GenerateKeyPair()
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	return KeyRecord{KeyID: KeyID(pub), PublicKeyB64: encodePub(pub)}, nil
+}
+
+func (b *pkcs11Backend) sign(keyID string, msg []byte) ([]byte, error) {
+	h, err := b.token.FindObject(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return b.token.Sign(h, msg)
+}
+
+func (b *pkcs11Backend) loadHistory(domain string) ([]KeyRecord, error) {
+	b.token.mu.Lock()
+	defer b.token.mu.Unlock()
+	return append([]KeyRecord(nil), b.token.data[domain]...), nil
+}
+
+func (b *pkcs11Backend) saveHistory(domain string, h []KeyRecord) error {
+	b.token.mu.Lock()
+	defer b.token.mu.Unlock()
+	if !b.token.loggedIn {
+		return ErrTokenNotLoggedIn
+	}
+	b.token.data[domain] = append([]KeyRecord(nil), h...)
+	return nil
+}
@@ -0,0 +1,291 @@
+package crypto_test
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dis-core/internal/util/crypto"
+)
+
+// keyStores returns a fresh store of every kind.
+func keyStores(t *testing.T) map[string]crypto.KeyStore {
+	t.Helper()
+	token := crypto.NewMockPKCS11Token("1234")
+	if err := token.Login("1234"); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	encrypted, err := crypto.NewEncryptedFileKeyStore(t.TempDir(), "correct horse")
+	if err != nil {
+		t.Fatalf("NewEncryptedFileKeyStore: %v", err)
+	}
+	return map[string]crypto.KeyStore{
+		"pkcs11":    crypto.NewPKCS11KeyStore(token),
+		"file":      crypto.NewFileKeyStore(t.TempDir()),
+		"encrypted": encrypted,
+	}
+}
+
+func verifies(t *testing.T, ks crypto.KeyStore, k crypto.KeyRecord, msg string) {
+	t.Helper()
+	sig, err := ks.Sign(k.KeyID, []byte(msg))
+	if err != nil {
+		t.Fatalf("Sign with %s: %v", k.KeyID, err)
+	}
+	if !k.Verify([]byte(msg), sig) {
+		t.Fatalf("signature by %s does not verify", k.KeyID)
+	}
+}
+
+// TestKeyLifecycle generates, rotates and revokes a domain's keys and
+// checks which key verifies at which time.
+func TestKeyLifecycle(t *testing.T) {
+	for name, ks := range keyStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ks.Active("domain.test"); !errors.Is(err, crypto.ErrNoActiveKey) {
+				t.Fatalf("Active before Generate: %v", err)
+			}
+			first, err := ks.Generate("domain.test")
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if _, err := ks.Generate("domain.test"); !errors.Is(err, crypto.ErrKeyExists) {
+				t.Fatalf("second Generate: %v", err)
+			}
+			verifies(t, ks, first, "before rotation")
+			beforeRotation := time.Now().UTC()
+
+			old, next, err := ks.Rotate("domain.test")
+			if err != nil {
+				t.Fatalf("Rotate: %v", err)
+			}
+			if old.KeyID != first.KeyID || old.Status != crypto.KeyRetired || old.ValidUntil == nil || next.Status != crypto.KeyActive {
+				t.Fatalf("Rotate: old %+v, next %+v", old, next)
+			}
+			if active, err := ks.Active("domain.test"); err != nil || active.KeyID != next.KeyID {
+				t.Fatalf("Active after Rotate: %+v, %v", active, err)
+			}
+			// Retired keys still sign, for receipts still being countersigned.
+			verifies(t, ks, old, "retired")
+			verifies(t, ks, next, "after rotation")
+
+			if _, err := ks.Revoke("domain.test", next.KeyID, "in use", time.Time{}); !errors.Is(err, crypto.ErrKeyActive) {
+				t.Fatalf("Revoke active key: %v", err)
+			}
+			revoked, err := ks.Revoke("domain.test", first.KeyID, "leaked", time.Time{})
+			if err != nil || revoked.Status != crypto.KeyRevoked || revoked.RevokeReason != "leaked" {
+				t.Fatalf("Revoke: %+v, %v", revoked, err)
+			}
+			if _, err := ks.Sign(first.KeyID, []byte("after revocation")); !errors.Is(err, crypto.ErrKeyRevoked) {
+				t.Fatalf("Sign with revoked key: %v", err)
+			}
+			if _, err := ks.Revoke("domain.test", "no-such-key", "", time.Time{}); !errors.Is(err, crypto.ErrKeyNotFound) {
+				t.Fatalf("Revoke unknown key: %v", err)
+			}
+
+			cases := []struct {
+				name  string
+				keyID string
+				at    time.Time
+				want  string // key ID found; empty when an error is expected
+				err   error
+			}{
+				{"first key before rotation", first.KeyID, beforeRotation, first.KeyID, nil},
+				{"first key after revocation", first.KeyID, time.Now().Add(time.Hour), "", crypto.ErrKeyNotValid},
+				{"next key now", next.KeyID, time.Now().Add(time.Hour), next.KeyID, nil},
+				{"next key before it existed", next.KeyID, first.ValidFrom.Add(-time.Hour), "", crypto.ErrKeyNotValid},
+				{"no key ID", "", time.Now().Add(time.Hour), next.KeyID, nil},
+				{"unknown key", "no-such-key", time.Now(), "", crypto.ErrKeyNotFound},
+			}
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					k, err := crypto.KeyAt(ks, "domain.test", tc.keyID, tc.at)
+					if !errors.Is(err, tc.err) || k.KeyID != tc.want {
+						t.Fatalf("KeyAt: %s, %v; want %q, %v", k.KeyID, err, tc.want, tc.err)
+					}
+				})
+			}
+
+			// A compromise found later moves the revocation back, never forward.
+			if _, err := ks.Revoke("domain.test", first.KeyID, "leaked", time.Now().Add(time.Hour)); !errors.Is(err, crypto.ErrRevokeAhead) {
+				t.Fatalf("Revoke in the future: %v", err)
+			}
+			compromised := beforeRotation.Add(-time.Nanosecond)
+			if k, err := ks.Revoke("domain.test", first.KeyID, "compromised", compromised); err != nil || !k.RevokedAt.Equal(compromised) {
+				t.Fatalf("Revoke from compromise time: %+v, %v", k, err)
+			}
+			if k, err := ks.Revoke("domain.test", first.KeyID, "leaked", time.Time{}); err != nil || !k.RevokedAt.Equal(compromised) {
+				t.Fatalf("Revoke again: %+v, %v", k, err)
+			}
+			if _, err := crypto.KeyAt(ks, "domain.test", first.KeyID, beforeRotation); !errors.Is(err, crypto.ErrKeyNotValid) {
+				t.Fatalf("KeyAt after the compromise time: %v", err)
+			}
+
+			history, err := ks.History("domain.test")
+			if err != nil || len(history) != 2 || history[0].KeyID != first.KeyID || history[1].KeyID != next.KeyID {
+				t.Fatalf("History: %+v, %v", history, err)
+			}
+		})
+	}
+}
+
+// TestRegister records keys held outside the store and checks the history
+// hands over between them at the requested times.
+func TestRegister(t *testing.T) {
+	external := func() (ed25519.PrivateKey, string) {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return priv, base64.StdEncoding.EncodeToString(pub)
+	}
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, ks := range keyStores(t) {
+		t.Run(name, func(t *testing.T) {
+			priv1, pub1 := external()
+			priv2, pub2 := external()
+			k1, err := ks.Register("alice", pub1, time.Time{})
+			if err != nil || k1.Status != crypto.KeyActive || !k1.ValidFrom.IsZero() {
+				t.Fatalf("Register first key: %+v, %v", k1, err)
+			}
+			k2, err := ks.Register("alice", pub2, t0)
+			if err != nil || !k2.ValidFrom.Equal(t0) {
+				t.Fatalf("Register second key: %+v, %v", k2, err)
+			}
+			if again, err := ks.Register("alice", pub1, t0.Add(time.Hour)); err != nil || again.KeyID != k1.KeyID || again.Status != crypto.KeyRetired {
+				t.Fatalf("Register known key: %+v, %v", again, err)
+			}
+			if _, err := ks.Register("alice", "not base64!", t0); err == nil {
+				t.Fatal("malformed key registered")
+			}
+			if _, err := ks.Sign(k2.KeyID, []byte("msg")); err == nil {
+				t.Fatal("store signed with a key it does not hold")
+			}
+
+			msg := []byte("signed elsewhere")
+			sig1 := base64.StdEncoding.EncodeToString(ed25519.Sign(priv1, msg))
+			sig2 := base64.StdEncoding.EncodeToString(ed25519.Sign(priv2, msg))
+			cases := []struct {
+				name  string
+				keyID string
+				at    time.Time
+				sig   string
+				ok    bool
+			}{
+				{"first key before hand-over", k1.KeyID, t0.Add(-time.Hour), sig1, true},
+				{"first key after hand-over", k1.KeyID, t0.Add(time.Hour), sig1, false},
+				{"second key after hand-over", k2.KeyID, t0.Add(time.Hour), sig2, true},
+				{"second key before hand-over", k2.KeyID, t0.Add(-time.Hour), sig2, false},
+				{"wrong signature", k2.KeyID, t0.Add(time.Hour), sig1, false},
+			}
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					k, err := crypto.KeyAt(ks, "alice", tc.keyID, tc.at)
+					if ok := err == nil && k.Verify(msg, tc.sig); ok != tc.ok {
+						t.Fatalf("verified %v (%v), want %v", ok, err, tc.ok)
+					}
+				})
+			}
+		})
+	}
+}
+
+// TestFileKeyStoreReopen checks that file stores keep their history and
+// keys across restarts, and that encrypted keys need the passphrase.
+func TestFileKeyStoreReopen(t *testing.T) {
+	open := func(dir, passphrase string) (crypto.KeyStore, error) {
+		if passphrase == "" {
+			return crypto.NewFileKeyStore(dir), nil
+		}
+		return crypto.NewEncryptedFileKeyStore(dir, passphrase)
+	}
+	cases := []struct {
+		name         string
+		pass, reopen string // passphrases; empty for a plain file store
+		err          error
+	}{
+		{"file", "", "", nil},
+		{"encrypted", "correct horse", "correct horse", nil},
+		{"encrypted, wrong passphrase", "correct horse", "battery staple", crypto.ErrBadPassphrase},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			ks, err := open(dir, tc.pass)
+			if err != nil {
+				t.Fatal(err)
+			}
+			k, err := ks.Generate("domain.test")
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			reopened, err := open(dir, tc.reopen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if active, err := reopened.Active("domain.test"); err != nil || active.KeyID != k.KeyID {
+				t.Fatalf("Active after reopen: %+v, %v", active, err)
+			}
+			sig, err := reopened.Sign(k.KeyID, []byte("msg"))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Sign after reopen: %v, want %v", err, tc.err)
+			}
+			if err == nil && !k.Verify([]byte("msg"), sig) {
+				t.Fatal("signature after reopen does not verify")
+			}
+		})
+	}
+	if _, err := crypto.NewEncryptedFileKeyStore(t.TempDir(), ""); err == nil {
+		t.Fatal("empty passphrase accepted")
+	}
+	if _, err := crypto.NewFileKeyStore(t.TempDir()).Generate("../escape"); err == nil {
+		t.Fatal("path-like domain accepted")
+	}
+}
+
+// TestImportLegacy adopts an old <domain>.priv/.pub pair and checks that an
+// encrypted store deletes the plaintext private key once it is imported.
+func TestImportLegacy(t *testing.T) {
+	cases := []struct {
+		name     string
+		pass     string // empty for a plain file store
+		keepPriv bool
+	}{
+		{"file", "", true},
+		{"encrypted", "correct horse", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			pub, priv, err := ed25519.GenerateKey(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			privPath, pubPath := filepath.Join(dir, "domain.test.priv"), filepath.Join(dir, "domain.test.pub")
+			if err := crypto.SaveDomainKeys(privPath, pubPath, priv, pub); err != nil {
+				t.Fatal(err)
+			}
+			ks := crypto.NewFileKeyStore(dir)
+			if tc.pass != "" {
+				if ks, err = crypto.NewEncryptedFileKeyStore(dir, tc.pass); err != nil {
+					t.Fatal(err)
+				}
+			}
+			active, err := ks.Active("domain.test")
+			if err != nil || active.KeyID != crypto.KeyID(pub) {
+				t.Fatalf("Active: %+v, %v", active, err)
+			}
+			if _, err := os.Stat(privPath); (err == nil) != tc.keepPriv {
+				t.Fatalf("legacy private key kept = %v, want %v", err == nil, tc.keepPriv)
+			}
+			sig, err := ks.Sign(active.KeyID, []byte("msg"))
+			if err != nil || !active.Verify([]byte("msg"), sig) {
+				t.Fatalf("Sign after import: %v", err)
+			}
+		})
+	}
+}
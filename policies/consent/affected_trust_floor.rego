@@ -0,0 +1,10 @@
+# Consent rule: block actions that affect parties whose trust is below the
+# rule's threshold. Enable it by listing `affected_trust_floor` under
+# gate_rules in policies/consent.yaml.
+package consent.rules.affected_trust_floor
+
+low := [a.id | a := input.request.affected[_]; a.trust < input.rule.threshold]
+
+verdict = {"kind": "block", "reason": sprintf("affected parties below the trust floor: %v", [low])} {
+  count(low) > 0
+}